@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,11 +12,20 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/ai"
 	"github.com/open-same/backend/internal/api"
 	"github.com/open-same/backend/internal/config"
 	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/email"
+	"github.com/open-same/backend/internal/embedding"
+	"github.com/open-same/backend/internal/jwtkeys"
+	"github.com/open-same/backend/internal/metrics"
 	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/queue"
 	"github.com/open-same/backend/internal/redis"
+	"github.com/open-same/backend/internal/storage"
+	"github.com/open-same/backend/internal/tracing"
+	"github.com/open-same/backend/internal/webhook"
 	"github.com/open-same/backend/internal/websocket"
 	"golang.org/x/time/rate"
 )
@@ -24,6 +34,12 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing, cfg.Version)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
 	// Initialize database
 	db, err := database.Init(cfg.Database)
 	if err != nil {
@@ -36,10 +52,126 @@ func main() {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
+	// Initialize JWT signing keys
+	jwtKeys, err := jwtkeys.Init(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to initialize JWT keys: %v", err)
+	}
+
+	// Initialize attachment storage
+	if _, err := storage.Init(cfg.Storage); err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Initialize outgoing email
+	if _, err := email.Init(cfg.Email); err != nil {
+		log.Fatalf("Failed to initialize email: %v", err)
+	}
+
+	// Initialize content embeddings for semantic search
+	if _, err := embedding.Init(cfg.AI); err != nil {
+		log.Fatalf("Failed to initialize embeddings: %v", err)
+	}
+
+	// Initialize background jobs
+	if _, err := queue.Init(cfg.RabbitMQ, map[string]queue.Handler{
+		queue.JobEmail:     email.HandleSendJob,
+		queue.JobWebhook:   webhook.HandleDeliveryJob,
+		queue.JobEmbedding: api.HandleEmbeddingJob,
+		queue.JobExport:    api.HandleExportJob,
+	}); err != nil {
+		log.Fatalf("Failed to initialize job queue: %v", err)
+	}
+
+	// Initialize AI service
+	aiService := ai.NewAIService(cfg)
+
 	// Initialize WebSocket hub
-	wsHub := websocket.NewHub()
+	wsHub := websocket.NewHub(api.CanAccessContentRoom, api.CanEditContentRoom, api.ContentRoomStore{}, cfg)
+	if cfg.WebSocket.RedisBackplane {
+		wsHub.UseRedisBackplane(context.Background())
+	}
+	if cfg.WebSocket.RedisPresence {
+		wsHub.UseRedisPresence()
+	}
 	go wsHub.Run()
 
+	// Purge trash older than the retention window
+	if cfg.Content.TrashRetention > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for ; ; <-ticker.C {
+				purged, err := api.PurgeTrash(cfg.Content.TrashRetention)
+				if err != nil {
+					log.Printf("Failed to purge trash: %v", err)
+				} else if purged > 0 {
+					log.Printf("Purged %d deleted content items from trash", purged)
+				}
+			}
+		}()
+	}
+
+	// Delete background exports past their retention
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			purged, err := api.PurgeExpiredExports(context.Background())
+			if err != nil {
+				log.Printf("Failed to purge exports: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired exports", purged)
+			}
+		}
+	}()
+
+	// Publish scheduled drafts, starting with those that came due while the
+	// server was down
+	if cfg.Content.PublishCheckInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.Content.PublishCheckInterval)
+			defer ticker.Stop()
+			for ; ; <-ticker.C {
+				published, err := api.PublishScheduledContent(context.Background())
+				if err != nil {
+					log.Printf("Failed to publish scheduled content: %v", err)
+				} else if published > 0 {
+					log.Printf("Published %d scheduled content items", published)
+				}
+			}
+		}()
+	}
+
+	// Archive drafts nobody worked on for too long
+	if cfg.Content.AutoArchiveAfter > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for ; ; <-ticker.C {
+				archived, err := api.ArchiveStaleDrafts(context.Background(), cfg.Content.AutoArchiveAfter)
+				if err != nil {
+					log.Printf("Failed to archive stale drafts: %v", err)
+				} else if archived > 0 {
+					log.Printf("Archived %d stale drafts", archived)
+				}
+			}
+		}()
+	}
+
+	// Flush content view and share counters to the database
+	if cfg.Content.StatsFlushInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.Content.StatsFlushInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				if err := api.FlushContentStats(context.Background()); err != nil {
+					log.Printf("Failed to flush content stats: %v", err)
+				}
+			}
+		}()
+	}
+
 	// Set Gin mode
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -49,87 +181,263 @@ func main() {
 	router := gin.New()
 
 	// Global middleware
-	router.Use(gin.Logger())
+	if cfg.Logging.Format == "json" {
+		router.Use(middleware.StructuredLogging(newLogger(cfg.Logging)))
+	} else {
+		router.Use(gin.Logger())
+	}
 	router.Use(gin.Recovery())
-	router.Use(middleware.CORS())
-	router.Use(middleware.RateLimit(rate.Limit(cfg.RateLimit)))
+	router.Use(middleware.Tracing())
+	corsMiddleware, err := middleware.CORS(cfg.CORS, cfg.Environment)
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	router.Use(corsMiddleware)
+	if cfg.RateLimitBackend == "redis" {
+		router.Use(middleware.RedisRateLimit(int(cfg.RateLimit*60), time.Minute))
+	} else {
+		router.Use(middleware.RateLimit(rate.Limit(cfg.RateLimit)))
+	}
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics())
+	}
 	router.Use(middleware.RequestID())
+	if cfg.Compression.Enabled {
+		router.Use(middleware.Compression(cfg.Compression))
+	}
 	router.Use(middleware.SecurityHeaders())
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().UTC(),
-			"version":   cfg.Version,
-		})
-	})
+	// Health checks and Kubernetes probes
+	router.GET("/health", api.Health(cfg.Version))
+	router.GET("/ready", api.Ready)
+	router.GET("/live", api.Live)
+
+	// Public keys verifying issued tokens
+	router.GET("/.well-known/jwks.json", api.GetJWKS)
+
+	// Prometheus metrics, optionally on a separate admin listener
+	var metricsSrv *http.Server
+	if cfg.Metrics.Enabled {
+		metrics.RegisterHub(wsHub)
+		if cfg.Metrics.Port == 0 {
+			router.GET("/metrics", gin.WrapH(metrics.Handler()))
+		} else {
+			metricsMux := http.NewServeMux()
+			metricsMux.Handle("/metrics", metrics.Handler())
+			metricsSrv = &http.Server{
+				Addr:    fmt.Sprintf(":%d", cfg.Metrics.Port),
+				Handler: metricsMux,
+			}
+			go func() {
+				log.Printf("Serving metrics on port %d", cfg.Metrics.Port)
+				if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Printf("Metrics server failed: %v", err)
+				}
+			}()
+		}
+	}
+
+	// WebSocket handler, authenticated on the upgrade request
+	wsAuth := middleware.WebSocketAuth(jwtKeys, cfg.WebSocket.AllowQueryIdentity)
+	wsHandler := func(c *gin.Context) {
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			// Legacy clients identify themselves until WS_ALLOW_QUERY_IDENTITY is disabled
+			websocket.HandleWebSocket(wsHub, c.Writer, c.Request, c.Query("user_id"), c.Query("username"))
+			return
+		}
+		websocket.HandleWebSocket(wsHub, c.Writer, c.Request, user.ID.String(), user.Username)
+	}
 
 	// API routes
 	apiGroup := router.Group("/api/v1")
+	apiGroup.Use(middleware.Timeout(cfg.Server.RequestTimeout))
 	{
 		// Public routes
 		apiGroup.GET("/docs", api.ServeDocs)
+		apiGroup.GET("/openapi.json", api.OpenAPISpec(router.Routes))
 		apiGroup.POST("/auth/register", api.Register)
 		apiGroup.POST("/auth/login", api.Login)
 		apiGroup.POST("/auth/refresh", api.RefreshToken)
+		apiGroup.POST("/auth/verify-email", api.VerifyEmail)
+		apiGroup.GET("/auth/password-policy", api.GetPasswordPolicy)
+		apiGroup.POST("/auth/2fa/validate", api.ValidateTwoFactor)
+		apiGroup.GET("/auth/oauth/:provider/start", api.OAuthStart)
+		apiGroup.GET("/auth/oauth/:provider/callback", api.OAuthCallback)
 		apiGroup.GET("/content/public", api.GetPublicContent)
+		apiGroup.GET("/content/trending", api.GetTrendingContent)
+		apiGroup.GET("/templates", api.GetTemplates)
+		apiGroup.GET("/share/:token", api.GetSharedContent)
+		apiGroup.GET("/users/:username/profile", api.GetPublicProfile)
+
+		// Real-time collaboration
+		apiGroup.GET("/ws", wsAuth, wsHandler)
+
+		// Routes calling an AI provider share a separate per user budget
+		aiRateLimit := middleware.AIRateLimit(cfg.AI.UserRateLimit, cfg.RateLimitBackend == "redis")
 
 		// Protected routes
 		protected := apiGroup.Group("/")
-		protected.Use(middleware.Auth(cfg.JWT.Secret))
+		protected.Use(middleware.Auth(jwtKeys))
+		if cfg.RateLimitBackend == "redis" {
+			protected.Use(middleware.RedisRateLimitByUser(int(cfg.UserRateLimit*60), time.Minute))
+		} else {
+			protected.Use(middleware.RateLimitByUser(rate.Limit(cfg.UserRateLimit)))
+		}
 		{
+			// Session management
+			protected.POST("/auth/logout", api.Logout)
+			protected.POST("/auth/logout_all", api.LogoutAll)
+			protected.POST("/auth/resend-verification", api.ResendVerification)
+
+			// Two-factor authentication
+			protected.POST("/auth/2fa/enable", api.EnableTwoFactor)
+			protected.POST("/auth/2fa/verify", api.VerifyTwoFactor)
+			protected.POST("/auth/2fa/disable", api.DisableTwoFactor)
+
 			// User management
 			protected.GET("/user/profile", api.GetUserProfile)
 			protected.PUT("/user/profile", api.UpdateUserProfile)
-			protected.DELETE("/user/account", api.DeleteUserAccount)
+			protected.POST("/user/change-password", api.ChangePassword)
+			protected.GET("/user/sessions", api.GetSessions)
+			protected.DELETE("/user/sessions/:id", api.RevokeSession)
+			protected.POST("/user/avatar", api.UploadAvatar)
+			protected.DELETE("/user/avatar", api.DeleteAvatar)
+			protected.GET("/user/favorites", api.GetFavorites)
+			protected.GET("/user/limits", api.GetUserLimits)
+			protected.DELETE("/user/account", api.DeleteUserAccount(wsHub))
+			protected.GET("/user/export", api.ExportUserData)
 
 			// Content management
-			protected.POST("/content", api.CreateContent)
+			protected.POST("/content", middleware.RequireVerified(), api.CreateContent)
+			protected.POST("/content/import", middleware.RequireVerified(), api.ImportContent)
 			protected.GET("/content", api.GetUserContent)
+			protected.POST("/content/batch", api.GetContentBatch)
+			protected.GET("/content/trash", api.GetTrash)
+			protected.GET("/content/tags", api.GetTagCloud)
+			protected.GET("/content/search/semantic", api.SemanticSearch)
 			protected.GET("/content/:id", api.GetContent)
-			protected.PUT("/content/:id", api.UpdateContent)
+			protected.PUT("/content/:id", api.UpdateContent(wsHub))
 			protected.DELETE("/content/:id", api.DeleteContent)
+			protected.POST("/content/:id/restore", api.RestoreContent)
+			protected.POST("/content/:id/archive", api.ArchiveContent)
+			protected.POST("/content/:id/unarchive", api.UnarchiveContent)
+			protected.DELETE("/content/:id/permanent", api.DeleteContentPermanently)
+			protected.GET("/content/:id/versions/diff", api.DiffContentVersions)
+			protected.POST("/content/:id/fork", middleware.RequireVerified(), api.ForkContent)
+			protected.POST("/content/:id/lock", api.LockContent(wsHub))
+			protected.POST("/content/:id/unlock", api.UnlockContent(wsHub))
+			protected.POST("/content/:id/move", api.MoveContent)
+			protected.GET("/content/:id/export", api.ExportContent)
+			protected.GET("/exports/:exportId", api.GetExport)
+			protected.GET("/exports/:exportId/download", api.DownloadExport)
+			protected.GET("/content/:id/activity", api.GetContentActivity)
+			protected.POST("/content/:id/comments", api.CreateComment(wsHub))
+			protected.GET("/content/:id/comments", api.GetComments)
+			protected.PUT("/content/:id/comments/:commentId", api.UpdateComment(wsHub))
+			protected.DELETE("/content/:id/comments/:commentId", api.DeleteComment(wsHub))
+			protected.POST("/content/:id/comments/:commentId/resolve", api.ResolveComment(wsHub))
+			protected.POST("/content/:id/comments/:commentId/unresolve", api.UnresolveComment(wsHub))
+			protected.POST("/content/:id/react", api.AddReaction)
+			protected.DELETE("/content/:id/react", api.RemoveReaction)
+			protected.GET("/content/:id/reactions", api.GetReactions)
+			protected.GET("/content/:id/similar", api.GetSimilarContent)
+			protected.GET("/content/:id/stats", api.GetContentStats)
+			protected.POST("/content/:id/summarize", middleware.Timeout(cfg.Server.AIRequestTimeout), aiRateLimit, api.SummarizeContent(aiService))
+			protected.GET("/content/:id/suggestions", middleware.Timeout(cfg.Server.AIRequestTimeout), aiRateLimit, api.GetContentSuggestions(aiService))
+			protected.POST("/content/:id/suggestions/apply", api.ApplyContentSuggestion(wsHub))
+			protected.GET("/templates/ai", middleware.Timeout(cfg.Server.AIRequestTimeout), aiRateLimit, api.GenerateAITemplate(aiService))
+			protected.POST("/templates/:id/use", middleware.RequireVerified(), api.UseTemplate)
+			protected.POST("/content/:id/favorite", api.AddFavorite)
+			protected.DELETE("/content/:id/favorite", api.RemoveFavorite)
+			protected.POST("/content/:id/pin", api.PinContent)
+			protected.DELETE("/content/:id/pin", api.UnpinContent)
+			protected.POST("/content/:id/attachments", api.UploadAttachment)
+			protected.GET("/content/:id/attachments", api.GetAttachments)
+			protected.GET("/content/:id/attachments/:attachmentId", api.DownloadAttachment)
+			protected.DELETE("/content/:id/attachments/:attachmentId", api.DeleteAttachment)
+			protected.POST("/content/:id/versions/:version/restore", api.RestoreContentVersion(wsHub))
+			protected.GET("/content/:id/presence", api.GetContentPresence(wsHub))
 			protected.POST("/content/:id/share", api.ShareContent)
-			protected.POST("/content/:id/collaborate", api.AddCollaborator)
+			protected.DELETE("/content/:id/share/:shareId", api.RevokeShare)
+			protected.POST("/content/:id/collaborate", api.AddCollaborator(wsHub))
+			protected.GET("/content/:id/collaborators", api.GetContentCollaborators)
+
+			// Folders
+			protected.POST("/folders", api.CreateFolder)
+			protected.GET("/folders", api.GetFolderChildren)
+			protected.GET("/folders/:id", api.GetFolder)
+			protected.GET("/folders/:id/children", api.GetFolderChildren)
+			protected.PUT("/folders/:id", api.RenameFolder)
+			protected.POST("/folders/:id/move", api.MoveFolder)
+			protected.DELETE("/folders/:id", api.DeleteFolder)
 
 			// Collaboration
 			protected.GET("/collaborations", api.GetCollaborations)
-			protected.PUT("/collaborations/:id", api.UpdateCollaboration)
-			protected.DELETE("/collaborations/:id", api.RemoveCollaborator)
+			protected.PUT("/collaborations/:id", api.UpdateCollaboration(wsHub))
+			protected.DELETE("/collaborations/:id", api.RemoveCollaborator(wsHub))
+			protected.POST("/collaborations/:id/accept", api.AcceptCollaboration)
+			protected.POST("/collaborations/:id/decline", api.DeclineCollaboration)
 
-			// Real-time collaboration
-			protected.GET("/ws", func(c *gin.Context) {
-				websocket.HandleWebSocket(wsHub, c.Writer, c.Request)
-			})
+			// Webhooks
+			protected.POST("/webhooks", api.CreateWebhook)
+			protected.GET("/webhooks", api.GetWebhooks)
+			protected.PUT("/webhooks/:id", api.UpdateWebhook)
+			protected.DELETE("/webhooks/:id", api.DeleteWebhook)
+			protected.GET("/webhooks/:id/deliveries", api.GetWebhookDeliveries)
+
+			// AI, with a longer timeout for slow providers
+			aiGroup := protected.Group("/ai")
+			aiGroup.Use(middleware.Timeout(cfg.Server.AIRequestTimeout))
+			aiGroup.GET("/usage", api.GetAIUsage)
+			aiGroup.POST("/moderate", aiRateLimit, api.ModerateContent(aiService))
+			aiGroup.POST("/translate", aiRateLimit, api.TranslateContent(aiService))
 		}
 
 		// Admin routes
 		admin := apiGroup.Group("/admin")
+		admin.Use(middleware.Auth(jwtKeys))
 		admin.Use(middleware.AdminOnly())
 		{
 			admin.GET("/users", api.AdminGetUsers)
 			admin.GET("/content", api.AdminGetAllContent)
-			admin.GET("/stats", api.AdminGetStats)
+			admin.GET("/stats", api.AdminGetStats(wsHub))
 			admin.POST("/users/:id/ban", api.AdminBanUser)
+			admin.PUT("/users/:id/tier", api.AdminSetUserTier)
+			admin.DELETE("/users/:id", api.AdminDeleteUser(wsHub))
+			admin.GET("/ai/usage", api.AdminGetAIUsage)
+			admin.GET("/activity", api.AdminGetActivity)
+			admin.POST("/embeddings/backfill", api.AdminBackfillEmbeddings)
+			admin.POST("/broadcast", api.AdminBroadcast(wsHub))
 		}
 	}
 
-	// GraphQL endpoint
-	router.POST("/graphql", api.GraphQLHandler)
+	// GraphQL endpoint, authenticated like the REST API when a token is sent
+	graphQLSchema, err := api.NewGraphQLSchema(wsHub)
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+	router.POST("/graphql",
+		middleware.OptionalAuth(jwtKeys),
+		middleware.RateLimitByUser(rate.Limit(cfg.UserRateLimit)),
+		api.GraphQLHandler(graphQLSchema),
+	)
+	// GraphQL subscriptions over WebSocket, authenticated on connection_init
+	router.GET("/graphql/ws", api.GraphQLSubscriptions(graphQLSchema, wsHub, jwtKeys))
+	if cfg.Environment != "production" && cfg.GraphQL.Playground {
+		router.GET("/graphql", api.GraphQLPlayground)
+	}
 
 	// WebSocket endpoint for real-time collaboration
-	router.GET("/ws", func(c *gin.Context) {
-		websocket.HandleWebSocket(wsHub, c.Writer, c.Request)
-	})
+	router.GET("/ws", wsAuth, wsHandler)
 
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	// Start server in a goroutine
@@ -154,5 +462,33 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			log.Printf("Metrics server forced to shutdown: %v", err)
+		}
+	}
+
+	// Close WebSocket connections, which Shutdown does not track
+	if err := wsHub.Shutdown(ctx); err != nil {
+		log.Printf("WebSocket hub forced to shutdown: %v", err)
+	}
+
+	if err := queue.Close(); err != nil {
+		log.Printf("Failed to close job queue: %v", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+
 	log.Println("Server exited")
-}
\ No newline at end of file
+}
+
+// newLogger creates the JSON request logger at the configured level
+func newLogger(cfg config.LoggingConfig) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
@@ -2,15 +2,27 @@ package ai
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
+	"github.com/open-same/backend/internal/metrics"
 	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+	"github.com/open-same/backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AIService provides AI-powered content generation and assistance
@@ -18,10 +30,22 @@ type AIService struct {
 	config     *config.Config
 	openAI     *OpenAIClient
 	anthropic  *AnthropicClient
+	gemini     *GeminiClient
 	localLLM   *LocalLLMClient
 	rateLimiter *RateLimiter
+	moderator  *ModerationClient
 }
 
+// ErrQuotaExceeded is returned when a user has used their monthly token budget
+var ErrQuotaExceeded = errors.New("monthly AI token quota exceeded")
+
+// suggestionsTimeout bounds generating all suggestions for content, leaving
+// time to respond within the AI request timeout
+const suggestionsTimeout = 45 * time.Second
+
+// ErrRateLimited is returned when the service-wide AI rate limit is reached
+var ErrRateLimited = errors.New("AI rate limit exceeded")
+
 // ContentGenerationRequest represents a request for AI content generation
 type ContentGenerationRequest struct {
 	Type        string                 `json:"type"`        // document, code, diagram, etc.
@@ -29,10 +53,14 @@ type ContentGenerationRequest struct {
 	Context     string                 `json:"context"`
 	Style       string                 `json:"style"`
 	Length      int                    `json:"length"`
+	Tone        string                 `json:"tone"`
+	Temperature float64                `json:"temperature"`
 	Language    string                 `json:"language"`
 	Metadata    map[string]interface{} `json:"metadata"`
 	UserID      string                 `json:"user_id"`
 	CollaborationID string             `json:"collaboration_id"`
+	NoCache     bool                   `json:"no_cache"`    // bypass the generation cache
+	Race        bool                   `json:"race"`        // race the available providers
 }
 
 // ContentGenerationResponse represents AI-generated content
@@ -43,6 +71,8 @@ type ContentGenerationResponse struct {
 	Metadata    map[string]interface{} `json:"metadata"`
 	Model       string                 `json:"model"`
 	Tokens      int                    `json:"tokens"`
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
 	Cost        float64                `json:"cost"`
 	Latency     time.Duration          `json:"latency"`
 }
@@ -83,9 +113,19 @@ func NewAIService(cfg *config.Config) *AIService {
 		service.anthropic = NewAnthropicClient(cfg.AI.Anthropic)
 	}
 
+	// Initialize Gemini client if configured
+	if cfg.AI.Gemini.APIKey != "" {
+		service.gemini = NewGeminiClient(cfg.AI.Gemini, cfg.AI.Retry)
+	}
+
 	// Initialize local LLM client if enabled
 	if cfg.AI.LocalLLM.Enabled {
-		service.localLLM = NewLocalLLMClient(cfg.AI.LocalLLM)
+		service.localLLM = NewLocalLLMClient(cfg.AI.LocalLLM, cfg.AI.Retry)
+	}
+
+	// Initialize the moderation client if moderation is enabled
+	if cfg.AI.Moderation.Enabled {
+		service.moderator = NewModerationClient(cfg.AI.OpenAI, cfg.AI.Moderation, cfg.AI.Retry)
 	}
 
 	return service
@@ -93,11 +133,6 @@ func NewAIService(cfg *config.Config) *AIService {
 
 // GenerateContent generates AI-powered content based on the request
 func (s *AIService) GenerateContent(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
-	// Check rate limits
-	if !s.rateLimiter.Allow() {
-		return nil, fmt.Errorf("rate limit exceeded")
-	}
-
 	start := time.Now()
 	
 	// Select the best AI model based on request type and availability
@@ -106,63 +141,111 @@ func (s *AIService) GenerateContent(ctx context.Context, req *ContentGenerationR
 		return nil, fmt.Errorf("failed to select AI model: %w", err)
 	}
 
-	var response *ContentGenerationResponse
+	// Reject disallowed prompts before they reach the cache or a provider
+	if err := s.checkModeration(ctx, strings.TrimSpace(req.Prompt+"\n\n"+req.Context)); err != nil {
+		return nil, err
+	}
 
-	// Generate content using the selected model
-	switch model {
-	case "openai":
-		response, err = s.generateWithOpenAI(ctx, req)
-	case "anthropic":
-		response, err = s.generateWithAnthropic(ctx, req)
-	case "local":
-		response, err = s.generateWithLocalLLM(ctx, req)
-	default:
-		return nil, fmt.Errorf("unsupported AI model: %s", model)
+	// Serve identical requests from the cache without calling the provider
+	cacheKey := s.cacheKey(model, req)
+	if !req.NoCache {
+		if cached, ok := s.getCachedResponse(ctx, cacheKey); ok {
+			cached.Latency = time.Since(start)
+			metrics.AIGenerations.WithLabelValues(cached.Model, "cached").Inc()
+			return cached, nil
+		}
 	}
 
-	if err != nil {
+	// Check rate limits
+	if !s.rateLimiter.Allow() {
+		return nil, ErrRateLimited
+	}
+
+	// Check the user's monthly token budget
+	if err := s.checkQuota(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	// Generate content using the selected model, or race the available
+	// providers when requested
+	var response *ContentGenerationResponse
+	if s.shouldRace(req) {
+		response, err = s.raceProviders(ctx, req)
+		if err != nil {
+			metrics.AIGenerations.WithLabelValues(s.modelName(model), "error").Inc()
+			return nil, fmt.Errorf("AI content generation failed: %w", err)
+		}
+	} else if response, err = s.generateWith(ctx, model, req); err != nil {
 		// Try fallback model if enabled
 		if s.config.AI.Fallback.Enabled {
 			log.Printf("Primary AI model failed, trying fallback: %v", err)
-			response, err = s.generateWithFallback(ctx, req)
+			response, err = s.generateWithFallback(ctx, req, model)
 			if err != nil {
+				metrics.AIGenerations.WithLabelValues(s.modelName(model), "error").Inc()
 				return nil, fmt.Errorf("both primary and fallback AI models failed: %w", err)
 			}
 		} else {
+			metrics.AIGenerations.WithLabelValues(s.modelName(model), "error").Inc()
 			return nil, fmt.Errorf("AI content generation failed: %w", err)
 		}
 	}
 
 	// Calculate latency
 	response.Latency = time.Since(start)
+	metrics.ObserveAIGeneration(response.Model, response.Latency, response.PromptTokens, response.CompletionTokens)
 
 	// Log the generation for analytics
 	s.logGeneration(req, response)
 
+	// Never cache or return disallowed output
+	if err := s.checkModeration(ctx, response.Content); err != nil {
+		return nil, err
+	}
+
+	s.cacheResponse(ctx, cacheKey, response)
+
 	return response, nil
 }
 
 // GenerateSuggestions generates AI-powered suggestions for existing content
 func (s *AIService) GenerateSuggestions(ctx context.Context, content *models.Content, userID string) ([]*AISuggestion, error) {
 	if !s.rateLimiter.Allow() {
-		return nil, fmt.Errorf("rate limit exceeded")
+		return nil, ErrRateLimited
 	}
 
-	suggestions := []*AISuggestion{}
+	// Run the generators concurrently under one deadline
+	ctx, cancel := context.WithTimeout(ctx, suggestionsTimeout)
+	defer cancel()
 
-	// Generate completion suggestions
-	if completion, err := s.generateCompletionSuggestion(ctx, content, userID); err == nil {
-		suggestions = append(suggestions, completion)
+	generators := []func(context.Context, *models.Content, string) (*AISuggestion, error){
+		s.generateCompletionSuggestion,
+		s.generateImprovementSuggestion,
+		s.generateCorrectionSuggestion,
 	}
+	results := make([]*AISuggestion, len(generators))
+	errs := make([]error, len(generators))
 
-	// Generate improvement suggestions
-	if improvement, err := s.generateImprovementSuggestion(ctx, content, userID); err == nil {
-		suggestions = append(suggestions, improvement)
+	var wg sync.WaitGroup
+	for i, generate := range generators {
+		wg.Add(1)
+		go func(i int, generate func(context.Context, *models.Content, string) (*AISuggestion, error)) {
+			defer wg.Done()
+			results[i], errs[i] = generate(ctx, content, userID)
+		}(i, generate)
 	}
+	wg.Wait()
 
-	// Generate correction suggestions
-	if correction, err := s.generateCorrectionSuggestion(ctx, content, userID); err == nil {
-		suggestions = append(suggestions, correction)
+	// Keep the completion, improvement, correction order and drop failures
+	suggestions := []*AISuggestion{}
+	for i, suggestion := range results {
+		if errs[i] == nil {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	// Report why nothing could be suggested
+	if len(suggestions) == 0 {
+		return nil, errs[0]
 	}
 
 	return suggestions, nil
@@ -171,7 +254,7 @@ func (s *AIService) GenerateSuggestions(ctx context.Context, content *models.Con
 // GenerateTemplate generates AI-powered templates
 func (s *AIService) GenerateTemplate(ctx context.Context, templateType, category string, userID string) (*AITemplate, error) {
 	if !s.rateLimiter.Allow() {
-		return nil, fmt.Errorf("rate limit exceeded")
+		return nil, ErrRateLimited
 	}
 
 	prompt := fmt.Sprintf("Generate a %s template for %s category. Include placeholders and examples.", templateType, category)
@@ -205,7 +288,7 @@ func (s *AIService) GenerateTemplate(ctx context.Context, templateType, category
 
 // selectBestModel selects the best AI model based on request type and availability
 func (s *AIService) selectBestModel(req *ContentGenerationRequest) (string, error) {
-	// Priority order: OpenAI > Anthropic > Local LLM
+	// Priority order: OpenAI > Anthropic > Gemini > Local LLM
 	
 	if s.openAI != nil && s.openAI.IsAvailable() {
 		return "openai", nil
@@ -215,6 +298,10 @@ func (s *AIService) selectBestModel(req *ContentGenerationRequest) (string, erro
 		return "anthropic", nil
 	}
 	
+	if s.gemini != nil && s.gemini.IsAvailable() {
+		return "gemini", nil
+	}
+	
 	if s.localLLM != nil && s.localLLM.IsAvailable() {
 		return "local", nil
 	}
@@ -222,6 +309,43 @@ func (s *AIService) selectBestModel(req *ContentGenerationRequest) (string, erro
 	return "", fmt.Errorf("no AI models available")
 }
 
+// generateWith generates content using the named provider inside a span
+// covering the provider call
+func (s *AIService) generateWith(ctx context.Context, model string, req *ContentGenerationRequest) (response *ContentGenerationResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ai.generate "+model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("ai.provider", model),
+			attribute.String("ai.model", s.modelName(model)),
+			attribute.String("ai.content_type", req.Type),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(
+				attribute.Int("ai.prompt_tokens", response.PromptTokens),
+				attribute.Int("ai.completion_tokens", response.CompletionTokens),
+			)
+		}
+		span.End()
+	}()
+
+	switch model {
+	case "openai":
+		return s.generateWithOpenAI(ctx, req)
+	case "anthropic":
+		return s.generateWithAnthropic(ctx, req)
+	case "gemini":
+		return s.generateWithGemini(ctx, req)
+	case "local":
+		return s.generateWithLocalLLM(ctx, req)
+	}
+	return nil, fmt.Errorf("unsupported AI model: %s", model)
+}
+
 // generateWithOpenAI generates content using OpenAI
 func (s *AIService) generateWithOpenAI(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
 	if s.openAI == nil {
@@ -250,6 +374,20 @@ func (s *AIService) generateWithAnthropic(ctx context.Context, req *ContentGener
 	return response, nil
 }
 
+// generateWithGemini generates content using Google Gemini
+func (s *AIService) generateWithGemini(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
+	if s.gemini == nil {
+		return nil, fmt.Errorf("Gemini client not configured")
+	}
+
+	response, err := s.gemini.GenerateContent(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("Gemini generation failed: %w", err)
+	}
+
+	return response, nil
+}
+
 // generateWithLocalLLM generates content using local LLM
 func (s *AIService) generateWithLocalLLM(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
 	if s.localLLM == nil {
@@ -264,15 +402,37 @@ func (s *AIService) generateWithLocalLLM(ctx context.Context, req *ContentGenera
 	return response, nil
 }
 
-// generateWithFallback generates content using the fallback model
-func (s *AIService) generateWithFallback(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
+// generateWithFallback generates content using the fallback model, then
+// the remaining available providers in priority order
+func (s *AIService) generateWithFallback(ctx context.Context, req *ContentGenerationRequest, failed string) (*ContentGenerationResponse, error) {
+	lastErr := fmt.Errorf("no fallback model available")
+
 	// Use OpenAI with fallback model if available
 	if s.openAI != nil {
+		if req.Metadata == nil {
+			req.Metadata = map[string]interface{}{}
+		}
 		req.Metadata["fallback_model"] = s.config.AI.Fallback.Model
-		return s.generateWithOpenAI(ctx, req)
+		response, err := s.generateWithOpenAI(ctx, req)
+		if err == nil {
+			return response, nil
+		}
+		delete(req.Metadata, "fallback_model")
+		lastErr = err
+	}
+
+	for _, model := range s.GetAvailableModels() {
+		if model == failed || model == "openai" {
+			continue
+		}
+		response, err := s.generateWith(ctx, model, req)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
 	}
 
-	return nil, fmt.Errorf("no fallback model available")
+	return nil, lastErr
 }
 
 // generateCompletionSuggestion generates completion suggestions
@@ -368,6 +528,180 @@ func (s *AIService) logGeneration(req *ContentGenerationRequest, response *Conte
 
 	logBytes, _ := json.Marshal(logData)
 	log.Printf("AI Generation: %s", string(logBytes))
+
+	s.recordUsage(req, response)
+}
+
+// recordUsage persists token usage for quota enforcement and reporting
+func (s *AIService) recordUsage(req *ContentGenerationRequest, response *ContentGenerationResponse) {
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return
+	}
+
+	usage := models.AIUsage{
+		UserID:           userID,
+		Model:            response.Model,
+		PromptTokens:     response.PromptTokens,
+		CompletionTokens: response.CompletionTokens,
+		TotalTokens:      response.Tokens,
+		Cost:             response.Cost,
+	}
+
+	if err := database.GetDB().Create(&usage).Error; err != nil {
+		log.Printf("Failed to record AI usage: %v", err)
+	}
+}
+
+// checkQuota returns ErrQuotaExceeded when the user has no tokens left this
+// month under their tier
+func (s *AIService) checkQuota(ctx context.Context, userID string) error {
+	if s.config.AI.MonthlyTokenQuota <= 0 && len(s.config.Tiers.AITokens) == 0 {
+		return nil
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+
+	var user models.User
+	if err := database.GetDB().WithContext(ctx).Select("id", "tier").First(&user, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to check AI quota: %w", err)
+	}
+
+	allowed, err := features.Allow(ctx, &user, features.AITokens)
+	if err != nil {
+		return fmt.Errorf("failed to check AI quota: %w", err)
+	}
+	if !allowed {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// systemPrompt builds the provider system prompt from the request type and style
+func systemPrompt(req *ContentGenerationRequest) string {
+	prompt := "You are an expert content creator. Generate high-quality, engaging content based on the user's request."
+
+	switch req.Type {
+	case "text":
+		prompt += " Focus on creating well-structured, informative text."
+	case "code":
+		prompt += " Generate clean, well-commented, and efficient code."
+	case "diagram":
+		prompt += " Provide detailed descriptions for creating diagrams or visual content."
+	case "document":
+		prompt += " Create professional, well-formatted documents."
+	case "template":
+		prompt += " Generate reusable templates that can be easily customized."
+	case "summary":
+		prompt = "You are an expert editor. Write accurate, neutral summaries that only use facts from the provided content."
+	case "translation":
+		prompt = "You are a professional translator. Translate faithfully, preserving meaning, formatting and markup."
+	}
+
+	if req.Style != "" {
+		prompt += fmt.Sprintf(" Use a %s style.", req.Style)
+	}
+
+	if req.Tone != "" {
+		prompt += fmt.Sprintf(" Maintain a %s tone.", req.Tone)
+	}
+
+	if req.Language != "" && req.Language != "en" {
+		prompt += fmt.Sprintf(" Write in %s.", req.Language)
+	}
+
+	return prompt
+}
+
+// userPrompt builds the provider user prompt from the request
+func userPrompt(req *ContentGenerationRequest) string {
+	prompt := req.Prompt
+
+	if req.Length > 0 {
+		prompt += fmt.Sprintf("\n\nLength: about %d words", req.Length)
+	}
+
+	if req.Context != "" {
+		prompt += fmt.Sprintf("\n\nContext: %s", req.Context)
+	}
+
+	return prompt
+}
+
+// modelName returns the configured model name for a provider
+func (s *AIService) modelName(provider string) string {
+	switch provider {
+	case "openai":
+		return s.config.AI.OpenAI.Model
+	case "anthropic":
+		return s.config.AI.Anthropic.Model
+	case "gemini":
+		return s.config.AI.Gemini.Model
+	case "local":
+		return s.config.AI.LocalLLM.Model
+	}
+	return ""
+}
+
+// cacheKey builds the generation cache key from the inputs that shape the output
+func (s *AIService) cacheKey(provider string, req *ContentGenerationRequest) string {
+	data, _ := json.Marshal([]interface{}{
+		provider,
+		s.modelName(provider),
+		req.Prompt,
+		req.Context,
+		req.Type,
+		req.Style,
+		req.Tone,
+		req.Temperature,
+		req.Length,
+		req.Language,
+	})
+	hash := sha256.Sum256(data)
+	return "ai_cache:" + hex.EncodeToString(hash[:])
+}
+
+// getCachedResponse returns a cached generation marked as cached
+func (s *AIService) getCachedResponse(ctx context.Context, key string) (*ContentGenerationResponse, bool) {
+	if s.config.AI.CacheTTL <= 0 || redis.GetClient() == nil {
+		return nil, false
+	}
+
+	data, err := redis.GetBytes(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+
+	var response ContentGenerationResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false
+	}
+
+	if response.Metadata == nil {
+		response.Metadata = map[string]interface{}{}
+	}
+	response.Metadata["cached"] = true
+	return &response, true
+}
+
+// cacheResponse stores a generation for identical future requests
+func (s *AIService) cacheResponse(ctx context.Context, key string, response *ContentGenerationResponse) {
+	if s.config.AI.CacheTTL <= 0 || redis.GetClient() == nil {
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	if err := redis.Set(ctx, key, data, s.config.AI.CacheTTL); err != nil {
+		log.Printf("Failed to cache AI generation: %v", err)
+	}
 }
 
 // GetAvailableModels returns available AI models
@@ -382,6 +716,10 @@ func (s *AIService) GetAvailableModels() []string {
 		models = append(models, "anthropic")
 	}
 	
+	if s.gemini != nil && s.gemini.IsAvailable() {
+		models = append(models, "gemini")
+	}
+	
 	if s.localLLM != nil && s.localLLM.IsAvailable() {
 		models = append(models, "local")
 	}
@@ -407,6 +745,13 @@ func (s *AIService) GetModelStatus() map[string]interface{} {
 		}
 	}
 	
+	if s.gemini != nil {
+		status["gemini"] = map[string]interface{}{
+			"available": s.gemini.IsAvailable(),
+			"model":     s.config.AI.Gemini.Model,
+		}
+	}
+	
 	if s.localLLM != nil {
 		status["local"] = map[string]interface{}{
 			"available": s.localLLM.IsAvailable(),
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCachingService returns a service generating with a local LLM served
+// by a test server, caching generations in an in-memory Redis. The counter
+// tracks the generations that reached the server.
+func newCachingService(t *testing.T) (*AIService, *int32) {
+	t.Helper()
+
+	var generations int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/chat" {
+			atomic.AddInt32(&generations, 1)
+			json.NewEncoder(w).Encode(ollamaChatResponse{
+				Model:     "llama3",
+				Message:   ollamaMessage{Role: "assistant", Content: "generated"},
+				Done:      true,
+				EvalCount: 5,
+			})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	redisServer := miniredis.RunT(t)
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = nil
+	})
+
+	cfg := &config.Config{}
+	cfg.AI.RateLimit = 100
+	cfg.AI.MaxConcurrentRequests = 10
+	cfg.AI.CacheTTL = time.Hour
+	cfg.AI.LocalLLM = config.LocalLLMConfig{
+		Enabled: true,
+		URL:     server.URL,
+		Model:   "llama3",
+		Timeout: 5 * time.Second,
+	}
+
+	return NewAIService(cfg), &generations
+}
+
+func TestGenerateContentServesIdenticalRequestsFromCache(t *testing.T) {
+	service, generations := newCachingService(t)
+	ctx := context.Background()
+	request := func() *ContentGenerationRequest {
+		return &ContentGenerationRequest{Prompt: "Write a haiku", Type: "text", Temperature: 0.7}
+	}
+
+	first, err := service.GenerateContent(ctx, request())
+	require.NoError(t, err)
+	assert.Equal(t, "generated", first.Content)
+	assert.Nil(t, first.Metadata["cached"])
+
+	second, err := service.GenerateContent(ctx, request())
+	require.NoError(t, err)
+	assert.Equal(t, "generated", second.Content)
+	assert.Equal(t, true, second.Metadata["cached"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(generations), "the second call is served from the cache")
+
+	// A different prompt is generated again
+	other := request()
+	other.Prompt = "Write a limerick"
+	_, err = service.GenerateContent(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(generations))
+}
+
+func TestGenerateContentNoCacheBypassesCache(t *testing.T) {
+	service, generations := newCachingService(t)
+	ctx := context.Background()
+
+	_, err := service.GenerateContent(ctx, &ContentGenerationRequest{Prompt: "Write a haiku", Type: "text"})
+	require.NoError(t, err)
+
+	response, err := service.GenerateContent(ctx, &ContentGenerationRequest{Prompt: "Write a haiku", Type: "text", NoCache: true})
+	require.NoError(t, err)
+	assert.Nil(t, response.Metadata["cached"])
+	assert.Equal(t, int32(2), atomic.LoadInt32(generations))
+}
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/open-same/backend/internal/config"
+)
+
+// geminiBaseURL is the Generative Language API endpoint
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiClient generates content with Google Gemini
+type GeminiClient struct {
+	config config.GeminiConfig
+	client *http.Client
+}
+
+// geminiPart represents a piece of content in the Gemini API
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent represents a message in the Gemini API
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiRequest represents a generateContent request
+type geminiRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+	GenerationConfig  struct {
+		MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+		Temperature     float64 `json:"temperature,omitempty"`
+	} `json:"generationConfig"`
+}
+
+// geminiResponse represents a generateContent response
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+	Error        *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+// NewGeminiClient creates a new Gemini client
+func NewGeminiClient(cfg config.GeminiConfig, retryCfg config.AIRetryConfig) *GeminiClient {
+	return &GeminiClient{
+		config: cfg,
+		client: newHTTPClient("Gemini API", cfg.Timeout, retryCfg),
+	}
+}
+
+// IsAvailable reports whether the client is configured
+func (c *GeminiClient) IsAvailable() bool {
+	return c.config.APIKey != ""
+}
+
+// GenerateContent generates content using the Gemini generateContent API
+func (c *GeminiClient) GenerateContent(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
+	geminiReq := geminiRequest{
+		SystemInstruction: &geminiContent{
+			Parts: []geminiPart{{Text: systemPrompt(req)}},
+		},
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: userPrompt(req)}}},
+		},
+	}
+	geminiReq.GenerationConfig.MaxOutputTokens = c.config.MaxTokens
+	geminiReq.GenerationConfig.Temperature = req.Temperature
+
+	body, err := json.Marshal(geminiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", geminiBaseURL, url.PathEscape(c.config.Model))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", c.config.APIKey)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(respBody, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if geminiResp.Error != nil {
+			return nil, fmt.Errorf("Gemini API error (status %d): %s", resp.StatusCode, geminiResp.Error.Message)
+		}
+		return nil, fmt.Errorf("Gemini API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	if len(geminiResp.Candidates) == 0 {
+		return nil, fmt.Errorf("no content generated")
+	}
+
+	var text strings.Builder
+	for _, part := range geminiResp.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+
+	model := geminiResp.ModelVersion
+	if model == "" {
+		model = c.config.Model
+	}
+
+	return &ContentGenerationResponse{
+		Content: text.String(),
+		Metadata: map[string]interface{}{
+			"provider":      "gemini",
+			"finish_reason": geminiResp.Candidates[0].FinishReason,
+		},
+		Model:            model,
+		Tokens:           geminiResp.UsageMetadata.TotalTokenCount,
+		PromptTokens:     geminiResp.UsageMetadata.PromptTokenCount,
+		CompletionTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/retry"
+)
+
+// newHTTPClient creates a provider HTTP client retrying transient failures.
+// The timeout bounds all attempts of a request together.
+func newHTTPClient(name string, timeout time.Duration, cfg config.AIRetryConfig) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: retry.NewTransport(name, retryPolicy(cfg)),
+	}
+}
+
+// retryPolicy converts the retry configuration into a retry policy
+func retryPolicy(cfg config.AIRetryConfig) retry.Policy {
+	return retry.Policy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+		Jitter:         true,
+	}
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-same/backend/internal/config"
+)
+
+// localLLMHealthTTL is how long an availability check result is reused
+const localLLMHealthTTL = 30 * time.Second
+
+// LocalLLMClient generates content with a self-hosted Ollama server
+type LocalLLMClient struct {
+	config config.LocalLLMConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	available bool
+	checkedAt time.Time
+}
+
+// ollamaMessage represents a chat message in the Ollama API
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaChatRequest represents an Ollama /api/chat request
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaChatResponse represents an Ollama /api/chat response
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// NewLocalLLMClient creates a new Ollama client
+func NewLocalLLMClient(cfg config.LocalLLMConfig, retryCfg config.AIRetryConfig) *LocalLLMClient {
+	return &LocalLLMClient{
+		config: cfg,
+		client: newHTTPClient("Local LLM", cfg.Timeout, retryCfg),
+	}
+}
+
+// IsAvailable reports whether the Ollama server is reachable
+func (c *LocalLLMClient) IsAvailable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < localLLMHealthTTL {
+		return c.available
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	c.available = false
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/api/tags"), nil)
+	if err == nil {
+		if resp, err := c.client.Do(httpReq); err == nil {
+			resp.Body.Close()
+			c.available = resp.StatusCode == http.StatusOK
+		}
+	}
+	c.checkedAt = time.Now()
+
+	return c.available
+}
+
+// GenerateContent generates content using the Ollama chat API
+func (c *LocalLLMClient) GenerateContent(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
+	options := map[string]interface{}{}
+	if req.Temperature > 0 {
+		options["temperature"] = req.Temperature
+	}
+
+	chatReq := ollamaChatRequest{
+		Model: c.config.Model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: systemPrompt(req)},
+			{Role: "user", Content: userPrompt(req)},
+		},
+		Stream:  false,
+		Options: options,
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/api/chat"), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if chatResp.Error != "" {
+			return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, chatResp.Error)
+		}
+		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	return &ContentGenerationResponse{
+		Content:          chatResp.Message.Content,
+		Metadata:         map[string]interface{}{"provider": "local"},
+		Model:            chatResp.Model,
+		Tokens:           chatResp.PromptEvalCount + chatResp.EvalCount,
+		PromptTokens:     chatResp.PromptEvalCount,
+		CompletionTokens: chatResp.EvalCount,
+	}, nil
+}
+
+// url joins a path onto the configured Ollama base URL
+func (c *LocalLLMClient) url(path string) string {
+	return strings.TrimRight(c.config.URL, "/") + path
+}
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/open-same/backend/internal/config"
+)
+
+// openAIModerationURL is the OpenAI moderations endpoint
+const openAIModerationURL = "https://api.openai.com/v1/moderations"
+
+// ErrModerationUnavailable is returned when moderation is required but no provider can be reached
+var ErrModerationUnavailable = errors.New("content moderation unavailable")
+
+// FlaggedError is returned when text is rejected by content moderation
+type FlaggedError struct {
+	Categories []string
+}
+
+func (e *FlaggedError) Error() string {
+	if len(e.Categories) == 0 {
+		return "content flagged by moderation"
+	}
+	return fmt.Sprintf("content flagged by moderation: %s", strings.Join(e.Categories, ", "))
+}
+
+// ModerationResult represents the outcome of a moderation check
+type ModerationResult struct {
+	Flagged    bool               `json:"flagged"`
+	Categories []string           `json:"categories"` // flagged categories only
+	Scores     map[string]float64 `json:"scores,omitempty"`
+	Checked    bool               `json:"checked"` // false when moderation was skipped
+}
+
+// ModerationClient checks text with the OpenAI moderations API
+type ModerationClient struct {
+	openAI config.OpenAIConfig
+	model  string
+	client *http.Client
+}
+
+// moderationRequest represents a moderations request
+type moderationRequest struct {
+	Model string `json:"model,omitempty"`
+	Input string `json:"input"`
+}
+
+// moderationResponse represents a moderations response
+type moderationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// NewModerationClient creates a new moderation client
+func NewModerationClient(openAI config.OpenAIConfig, cfg config.ModerationConfig, retryCfg config.AIRetryConfig) *ModerationClient {
+	return &ModerationClient{
+		openAI: openAI,
+		model:  cfg.Model,
+		client: newHTTPClient("OpenAI moderation API", cfg.Timeout, retryCfg),
+	}
+}
+
+// IsAvailable reports whether the client is configured
+func (c *ModerationClient) IsAvailable() bool {
+	return c.openAI.APIKey != ""
+}
+
+// Moderate classifies text with the moderations API
+func (c *ModerationClient) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	body, err := json.Marshal(moderationRequest{Model: c.model, Input: text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIModerationURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.openAI.APIKey)
+	if c.openAI.Organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", c.openAI.Organization)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var modResp moderationResponse
+	if err := json.Unmarshal(respBody, &modResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if modResp.Error != nil {
+			return nil, fmt.Errorf("OpenAI moderation API error (status %d): %s", resp.StatusCode, modResp.Error.Message)
+		}
+		return nil, fmt.Errorf("OpenAI moderation API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	if len(modResp.Results) == 0 {
+		return nil, fmt.Errorf("no moderation result returned")
+	}
+
+	result := modResp.Results[0]
+	categories := []string{}
+	for category, flagged := range result.Categories {
+		if flagged {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	return &ModerationResult{
+		Flagged:    result.Flagged,
+		Categories: categories,
+		Scores:     result.CategoryScores,
+		Checked:    true,
+	}, nil
+}
+
+// ModerationEnabled reports whether prompts and output are moderated
+func (s *AIService) ModerationEnabled() bool {
+	return s.config.AI.Moderation.Enabled
+}
+
+// Moderate checks text against the moderation provider. Provider failures
+// are tolerated when moderation is configured to fail open, in which case
+// the result is returned unchecked.
+func (s *AIService) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	if !s.ModerationEnabled() || strings.TrimSpace(text) == "" {
+		return &ModerationResult{Categories: []string{}}, nil
+	}
+
+	var err error
+	if s.moderator != nil && s.moderator.IsAvailable() {
+		var result *ModerationResult
+		result, err = s.moderator.Moderate(ctx, text)
+		if err == nil {
+			return result, nil
+		}
+	} else {
+		err = errors.New("no moderation provider configured")
+	}
+
+	if s.config.AI.Moderation.FailOpen {
+		log.Printf("Content moderation failed, allowing content: %v", err)
+		return &ModerationResult{Categories: []string{}}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrModerationUnavailable, err)
+}
+
+// checkModeration moderates text and converts a flagged result into a FlaggedError
+func (s *AIService) checkModeration(ctx context.Context, text string) error {
+	result, err := s.Moderate(ctx, text)
+	if err != nil {
+		return err
+	}
+	if result.Flagged {
+		return &FlaggedError{Categories: result.Categories}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// raceResult is the outcome of one provider in a race
+type raceResult struct {
+	provider string
+	response *ContentGenerationResponse
+	err      error
+}
+
+// shouldRace reports whether a request races providers instead of trying
+// them in priority order
+func (s *AIService) shouldRace(req *ContentGenerationRequest) bool {
+	if !req.Race && !s.config.AI.Race.Enabled {
+		return false
+	}
+	return len(s.raceProviderNames()) > 1
+}
+
+// raceProviderNames returns the providers raced at once, in priority order
+func (s *AIService) raceProviderNames() []string {
+	providers := s.GetAvailableModels()
+	if limit := s.config.AI.Race.MaxProviders; limit > 1 && len(providers) > limit {
+		providers = providers[:limit]
+	}
+	return providers
+}
+
+// raceProviders sends the request to several providers at once and returns
+// the first successful response. The others are cancelled through the
+// context. Providers may bill for work done before they stop, so the usage
+// of losers that still completed is recorded too.
+func (s *AIService) raceProviders(ctx context.Context, req *ContentGenerationRequest) (*ContentGenerationResponse, error) {
+	providers := s.raceProviderNames()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so losers finishing after the race never block
+	results := make(chan raceResult, len(providers))
+	for _, provider := range providers {
+		go func(provider string) {
+			response, err := s.generateWith(ctx, provider, req)
+			results <- raceResult{provider: provider, response: response, err: err}
+		}(provider)
+	}
+
+	var errs []error
+	for remaining := len(providers); remaining > 0; remaining-- {
+		result := <-results
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.provider, result.err))
+			continue
+		}
+
+		cancel()
+		if remaining > 1 {
+			go s.drainRace(results, remaining-1, req)
+		}
+
+		if result.response.Metadata == nil {
+			result.response.Metadata = map[string]interface{}{}
+		}
+		result.response.Metadata["race_winner"] = result.provider
+		result.response.Metadata["raced_providers"] = providers
+		return result.response, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// drainRace waits for the losers of a race and records the usage of those
+// that completed before they were cancelled
+func (s *AIService) drainRace(results <-chan raceResult, remaining int, req *ContentGenerationRequest) {
+	for ; remaining > 0; remaining-- {
+		result := <-results
+		if result.err != nil {
+			continue
+		}
+		log.Printf("AI race loser %s completed after the winner, recording %d tokens", result.provider, result.response.Tokens)
+		s.recordUsage(req, result.response)
+	}
+}
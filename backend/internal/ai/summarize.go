@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-same/backend/internal/models"
+)
+
+// Summary modes supported by SummarizeContent
+const (
+	SummaryModeBullet    = "bullet"
+	SummaryModeParagraph = "paragraph"
+	SummaryModeTweet     = "tweet"
+)
+
+// summaryModes maps each summary mode to its instruction and target length in words
+var summaryModes = map[string]struct {
+	instruction string
+	length      int
+}{
+	SummaryModeBullet:    {"Summarize it as 3 to 7 concise bullet points, one per line starting with \"- \".", 150},
+	SummaryModeParagraph: {"Summarize it as a single well-formed paragraph.", 120},
+	SummaryModeTweet:     {"Summarize it in one sentence of at most 280 characters, without hashtags.", 40},
+}
+
+// ContentSummary represents an AI-generated summary of content
+type ContentSummary struct {
+	Mode        string    `json:"mode"`
+	Summary     string    `json:"summary"`
+	Model       string    `json:"model"`
+	Tokens      int       `json:"tokens"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SummarizeContent summarizes content in the given mode through
+// GenerateContent, so provider selection, fallback, rate limiting and usage
+// tracking apply. The content itself is not modified.
+func (s *AIService) SummarizeContent(ctx context.Context, content *models.Content, mode string, userID string) (*ContentSummary, error) {
+	spec, ok := summaryModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("unsupported summary mode: %s", mode)
+	}
+
+	prompt := fmt.Sprintf("Summarize the following %s content titled %q. %s Reply with the summary only.\n\n%s",
+		content.Type, content.Title, spec.instruction, content.Content)
+
+	response, err := s.GenerateContent(ctx, &ContentGenerationRequest{
+		Type:     "summary",
+		Prompt:   prompt,
+		Length:   spec.length,
+		UserID:   userID,
+		Metadata: map[string]interface{}{"summary_mode": mode, "content_id": content.ID.String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := strings.TrimSpace(response.Content)
+	if mode == SummaryModeTweet {
+		if runes := []rune(summary); len(runes) > 280 {
+			summary = strings.TrimSpace(string(runes[:279])) + "…"
+		}
+	}
+
+	return &ContentSummary{
+		Mode:        mode,
+		Summary:     summary,
+		Model:       response.Model,
+		Tokens:      response.Tokens,
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TranslationRequest represents a request to translate text
+type TranslationRequest struct {
+	Text           string `json:"text"`
+	TargetLanguage string `json:"target_language"`
+	SourceLanguage string `json:"source_language"` // detected when empty
+	UserID         string `json:"user_id"`
+}
+
+// TranslationResult represents translated text
+type TranslationResult struct {
+	Text           string `json:"text"`
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Model          string `json:"model"`
+	Tokens         int    `json:"tokens"`
+}
+
+// translationOutput is the JSON shape the model is asked to reply with
+type translationOutput struct {
+	SourceLanguage string `json:"source_language"`
+	Translation    string `json:"translation"`
+}
+
+// Translate translates text into the target language through GenerateContent,
+// so provider selection, fallback, rate limiting and usage tracking apply
+func (s *AIService) Translate(ctx context.Context, req *TranslationRequest) (*TranslationResult, error) {
+	source := "Detect the source language"
+	if req.SourceLanguage != "" {
+		source = fmt.Sprintf("The source language is %s", req.SourceLanguage)
+	}
+
+	prompt := fmt.Sprintf("Translate the text below into %s. %s. "+
+		"Reply only with a JSON object of the form "+
+		`{"source_language": "<ISO 639-1 code>", "translation": "<translated text>"}`+
+		"\n\nText:\n%s", req.TargetLanguage, source, req.Text)
+
+	response, err := s.GenerateContent(ctx, &ContentGenerationRequest{
+		Type:     "translation",
+		Prompt:   prompt,
+		UserID:   req.UserID,
+		Metadata: map[string]interface{}{"target_language": req.TargetLanguage},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := parseTranslationOutput(response.Content)
+	if err != nil {
+		return nil, err
+	}
+	if output.SourceLanguage == "" {
+		output.SourceLanguage = req.SourceLanguage
+	}
+
+	return &TranslationResult{
+		Text:           output.Translation,
+		SourceLanguage: strings.ToLower(output.SourceLanguage),
+		TargetLanguage: req.TargetLanguage,
+		Model:          response.Model,
+		Tokens:         response.Tokens,
+	}, nil
+}
+
+// parseTranslationOutput extracts the JSON reply, tolerating markdown code
+// fences around it
+func parseTranslationOutput(content string) (*translationOutput, error) {
+	content = strings.TrimSpace(content)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	var output translationOutput
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse translation: %w", err)
+	}
+	if output.Translation == "" {
+		return nil, fmt.Errorf("no translation returned")
+	}
+	return &output, nil
+}
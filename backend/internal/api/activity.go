@@ -0,0 +1,161 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ActivityListResponse represents a page of activity log entries
+type ActivityListResponse struct {
+	Activities  []models.ActivityLog `json:"activities"`
+	Total       int64                `json:"total"`
+	Page        int                  `json:"page"`
+	PerPage     int                  `json:"per_page"`
+	TotalPages  int                  `json:"total_pages"`
+	HasNext     bool                 `json:"has_next"`
+	HasPrevious bool                 `json:"has_previous"`
+}
+
+// recordActivity writes an activity log entry. Failures are logged rather
+// than returned since the action itself has already succeeded.
+func recordActivity(contentID, userID uuid.UUID, action string, details models.JSON) {
+	activity := models.ActivityLog{
+		ContentID: contentID,
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+	}
+	if err := database.GetDB().Create(&activity).Error; err != nil {
+		log.Printf("Failed to record %s activity on content %s: %v", action, contentID, err)
+	}
+}
+
+// GetContentActivity lists the activity on content, newest first
+func GetContentActivity(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if !models.Authorize(user, &content, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to access this content",
+		})
+		return
+	}
+
+	query := database.GetDB().Model(&models.ActivityLog{}).Where("content_id = ?", content.ID)
+	listActivity(c, query)
+}
+
+// AdminGetActivity lists activity across all content, optionally filtered by
+// action, user_id or content_id
+func AdminGetActivity(c *gin.Context) {
+	query := database.GetDB().Model(&models.ActivityLog{})
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	for _, filter := range []string{"user_id", "content_id"} {
+		value := c.Query(filter)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid filter",
+				"code":    "INVALID_FILTER",
+				"message": filter + " must be a valid UUID",
+			})
+			return
+		}
+		query = query.Where(filter+" = ?", id)
+	}
+
+	listActivity(c, query)
+}
+
+// listActivity writes a page of the activity entries matched by query
+func listActivity(c *gin.Context, query *gorm.DB) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	var activities []models.ActivityLog
+	if err := query.Preload("User").Offset(offset).Limit(perPage).Order("created_at DESC").Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve activity",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving activity",
+		})
+		return
+	}
+
+	response := ActivityListResponse{
+		Activities:  activities,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Activity retrieved successfully",
+		"data":    response,
+	})
+}
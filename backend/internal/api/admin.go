@@ -0,0 +1,376 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/websocket"
+)
+
+// UserListResponse represents a page of users
+type UserListResponse struct {
+	Users       []models.User `json:"users"`
+	Total       int64         `json:"total"`
+	Page        int           `json:"page"`
+	PerPage     int           `json:"per_page"`
+	TotalPages  int           `json:"total_pages"`
+	HasNext     bool          `json:"has_next"`
+	HasPrevious bool          `json:"has_previous"`
+}
+
+// BanUserRequest represents a user ban request
+type BanUserRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// AdminStats represents platform wide totals
+type AdminStats struct {
+	Users struct {
+		Total    int64 `json:"total"`
+		Active   int64 `json:"active"`
+		Verified int64 `json:"verified"`
+		Banned   int64 `json:"banned"`
+		Admins   int64 `json:"admins"`
+	} `json:"users"`
+	Content struct {
+		Total        int64            `json:"total"`
+		Public       int64            `json:"public"`
+		ByType       map[string]int64 `json:"by_type"`
+		ByStatus     map[string]int64 `json:"by_status"`
+		ByVisibility map[string]int64 `json:"by_visibility"`
+	} `json:"content"`
+	ActiveCollaborations int64 `json:"active_collaborations"`
+	WebSocket            struct {
+		Rooms   int `json:"rooms"`
+		Clients int `json:"clients"`
+	} `json:"websocket"`
+}
+
+// AdminGetUsers lists users, optionally filtered by a search on email or
+// username and by the is_active, is_banned, is_admin and is_verified flags
+func AdminGetUsers(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := database.GetDB().Model(&models.User{})
+
+	// Apply filters
+	if search := c.Query("search"); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("email ILIKE ? OR username ILIKE ?", pattern, pattern)
+	}
+	for _, flag := range []string{"is_active", "is_banned", "is_admin", "is_verified"} {
+		value := c.Query(flag)
+		if value == "" {
+			continue
+		}
+		set, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid filter",
+				"code":    "INVALID_FILTER",
+				"message": flag + " must be true or false",
+			})
+			return
+		}
+		query = query.Where(flag+" = ?", set)
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	var users []models.User
+	if err := query.Offset(offset).Limit(perPage).Order("created_at DESC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve users",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving users",
+		})
+		return
+	}
+
+	response := UserListResponse{
+		Users:       users,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Users retrieved successfully",
+		"data":    response,
+	})
+}
+
+// AdminGetAllContent lists content of every user, optionally filtered by
+// type, status, visibility, user_id, is_public and a title search
+func AdminGetAllContent(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := database.GetDB().Model(&models.Content{})
+
+	// Apply filters
+	if contentType := c.Query("type"); contentType != "" {
+		query = query.Where("type = ?", contentType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if visibility := c.Query("visibility"); visibility != "" {
+		query = query.Where("visibility = ?", visibility)
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("title ILIKE ?", "%"+search+"%")
+	}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid filter",
+				"code":    "INVALID_FILTER",
+				"message": "user_id must be a valid UUID",
+			})
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if value := c.Query("is_public"); value != "" {
+		isPublic, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid filter",
+				"code":    "INVALID_FILTER",
+				"message": "is_public must be true or false",
+			})
+			return
+		}
+		query = query.Where("is_public = ?", isPublic)
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	var contents []models.Content
+	if err := query.Preload("User").Offset(offset).Limit(perPage).Order("created_at DESC").Find(&contents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving content",
+		})
+		return
+	}
+
+	response := ContentListResponse{
+		Contents:    contents,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content retrieved successfully",
+		"data":    response,
+	})
+}
+
+// AdminGetStats reports totals of users, content, collaborations and live
+// WebSocket rooms
+func AdminGetStats(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := database.GetDB()
+		var stats AdminStats
+
+		db.Model(&models.User{}).Count(&stats.Users.Total)
+		db.Model(&models.User{}).Where("is_active = ?", true).Count(&stats.Users.Active)
+		db.Model(&models.User{}).Where("is_verified = ?", true).Count(&stats.Users.Verified)
+		db.Model(&models.User{}).Where("is_banned = ?", true).Count(&stats.Users.Banned)
+		db.Model(&models.User{}).Where("is_admin = ?", true).Count(&stats.Users.Admins)
+
+		db.Model(&models.Content{}).Count(&stats.Content.Total)
+		db.Model(&models.Content{}).Where("visibility = ?", models.VisibilityPublic).Count(&stats.Content.Public)
+
+		var err error
+		if stats.Content.ByType, err = countContentBy("type"); err == nil {
+			stats.Content.ByStatus, err = countContentBy("status")
+		}
+		if err == nil {
+			stats.Content.ByVisibility, err = countContentBy("visibility")
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve statistics",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving statistics",
+			})
+			return
+		}
+
+		db.Model(&models.Collaboration{}).
+			Where("is_active = ? AND status = ?", true, models.CollaborationStatusAccepted).
+			Count(&stats.ActiveCollaborations)
+
+		stats.WebSocket.Rooms = hub.GetTotalRooms()
+		stats.WebSocket.Clients = hub.GetTotalClients()
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Statistics retrieved successfully",
+			"data":    stats,
+		})
+	}
+}
+
+// countContentBy counts content grouped by the given column
+func countContentBy(column string) (map[string]int64, error) {
+	var rows []struct {
+		Value string
+		Count int64
+	}
+	if err := database.GetDB().Model(&models.Content{}).
+		Select(column + " AS value, COUNT(*) AS count").
+		Group(column).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	return counts, nil
+}
+
+// AdminBanUser bans a user, deactivating the account and revoking all of
+// its tokens so existing sessions cannot be refreshed
+func AdminBanUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid user ID",
+			"code":    "INVALID_USER_ID",
+			"message": "User ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req BanUserRequest
+	// The request body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get admin from context
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if id == admin.ID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Cannot ban yourself",
+			"code":    "CANNOT_BAN_SELF",
+			"message": "Admins cannot ban their own account",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "User not found",
+			"code":    "USER_NOT_FOUND",
+			"message": "The requested user was not found",
+		})
+		return
+	}
+
+	now := time.Now()
+	user.IsBanned = true
+	user.IsActive = false
+	user.BannedAt = &now
+	user.BanReason = req.Reason
+	if err := database.GetDB().Model(&user).Select("IsBanned", "IsActive", "BannedAt", "BanReason").Updates(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to ban user",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while banning the user",
+		})
+		return
+	}
+
+	// Revoke every active token so the user cannot refresh their session
+	var tokens []models.Token
+	err = database.GetDB().Where("user_id = ? AND is_revoked = ?", user.ID, false).Find(&tokens).Error
+	if err == nil {
+		err = revokeTokens(tokens)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke tokens",
+			"code":    "TOKEN_REVOKE_ERROR",
+			"message": "The user was banned but their tokens could not be revoked",
+		})
+		return
+	}
+
+	revokeUserAccessTokens(c.Request.Context(), user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User banned successfully",
+		"data": gin.H{
+			"user":    user,
+			"revoked": len(tokens),
+		},
+	})
+}
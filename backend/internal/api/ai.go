@@ -0,0 +1,644 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/ai"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/webhook"
+	"github.com/open-same/backend/internal/websocket"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// aiUsageTotalsSelect aggregates AIUsage rows into AIUsageTotals
+const aiUsageTotalsSelect = "COUNT(*) AS requests, " +
+	"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, " +
+	"COALESCE(SUM(total_tokens), 0) AS total_tokens, " +
+	"COALESCE(SUM(cost), 0) AS cost"
+
+// AIUsageResponse represents a user's AI usage for a month
+type AIUsageResponse struct {
+	Month     string               `json:"month"`
+	Totals    models.AIUsageTotals `json:"totals"`
+	Quota     int64                `json:"quota"` // zero means unlimited
+	Remaining *int64               `json:"remaining,omitempty"`
+}
+
+// GetAIUsage returns the current user's AI usage totals for a month
+func GetAIUsage(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	start, ok := parseUsageMonth(c)
+	if !ok {
+		return
+	}
+
+	var totals models.AIUsageTotals
+	if err := database.GetDB().Model(&models.AIUsage{}).
+		Select(aiUsageTotalsSelect).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", user.ID, start, start.AddDate(0, 1, 0)).
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve AI usage",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving AI usage",
+		})
+		return
+	}
+	totals.UserID = user.ID
+
+	response := AIUsageResponse{
+		Month:  start.Format("2006-01"),
+		Totals: totals,
+		Quota:  features.Limit(user, features.AITokens),
+	}
+	if response.Quota > 0 {
+		remaining := response.Quota - totals.TotalTokens
+		if remaining < 0 {
+			remaining = 0
+		}
+		response.Remaining = &remaining
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "AI usage retrieved successfully",
+		"data":    response,
+	})
+}
+
+// AdminGetAIUsage returns AI usage totals for every user for a month
+func AdminGetAIUsage(c *gin.Context) {
+	start, ok := parseUsageMonth(c)
+	if !ok {
+		return
+	}
+
+	var totals []models.AIUsageTotals
+	if err := database.GetDB().Model(&models.AIUsage{}).
+		Select("user_id, "+aiUsageTotalsSelect).
+		Where("created_at >= ? AND created_at < ?", start, start.AddDate(0, 1, 0)).
+		Group("user_id").
+		Order("total_tokens DESC").
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve AI usage",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving AI usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "AI usage retrieved successfully",
+		"data": gin.H{
+			"month": start.Format("2006-01"),
+			"users": totals,
+		},
+	})
+}
+
+// ModerateRequest represents a request to pre-check user input
+type ModerateRequest struct {
+	Text string `json:"text" binding:"required,max=32000"`
+}
+
+// ModerateContent checks text against the AI content moderation provider so
+// the frontend can reject input before submitting a generation request
+func ModerateContent(aiService *ai.AIService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ModerateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		result, err := aiService.Moderate(c.Request.Context(), req.Text)
+		if err == nil && result.Flagged {
+			err = &ai.FlaggedError{Categories: result.Categories}
+		}
+		if err != nil {
+			if !respondModerationError(c, err) {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to moderate content",
+					"code":    "MODERATION_ERROR",
+					"message": "An error occurred while checking the content",
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content passed moderation",
+			"data":    result,
+		})
+	}
+}
+
+// respondModerationError writes the response for a moderation failure,
+// returning false when err is not moderation related
+func respondModerationError(c *gin.Context, err error) bool {
+	var flagged *ai.FlaggedError
+	switch {
+	case errors.As(err, &flagged):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Content flagged",
+			"code":    "CONTENT_FLAGGED",
+			"message": "The content violates the usage policy",
+			"reasons": flagged.Categories,
+		})
+	case errors.Is(err, ai.ErrModerationUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Moderation unavailable",
+			"code":    "MODERATION_UNAVAILABLE",
+			"message": "Content could not be checked, please try again later",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// TranslateRequest represents a request to translate text or content
+type TranslateRequest struct {
+	Text           string `json:"text" binding:"omitempty,max=32000"`
+	ContentID      string `json:"content_id" binding:"omitempty,uuid"`
+	TargetLanguage string `json:"target_language" binding:"required,max=35"`
+	SourceLanguage string `json:"source_language" binding:"omitempty,max=35"`
+	// CreateContent saves a translated copy of content_id linked to it
+	CreateContent bool `json:"create_content"`
+}
+
+// TranslateContent translates text, or the body of content the user can
+// read, into a target language and optionally saves the translation as new
+// content linked to its source
+func TranslateContent(aiService *ai.AIService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req TranslateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if (req.Text == "") == (req.ContentID == "") || (req.CreateContent && req.ContentID == "") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": "Provide either text or content_id, create_content requires content_id",
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		// Refuse before spending tokens on a translation that can't be saved
+		if req.CreateContent && !requireFeature(c, user, features.Content, 1) {
+			return
+		}
+
+		text := req.Text
+		var source models.Content
+		if req.ContentID != "" {
+			var ok bool
+			if source, _, ok = loadReadableContent(c, uuid.MustParse(req.ContentID)); !ok {
+				return
+			}
+			if strings.TrimSpace(source.Content) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Empty content",
+					"code":    "EMPTY_CONTENT",
+					"message": "The content has no text to translate",
+				})
+				return
+			}
+			text = source.Content
+		}
+
+		result, err := aiService.Translate(c.Request.Context(), &ai.TranslationRequest{
+			Text:           text,
+			TargetLanguage: req.TargetLanguage,
+			SourceLanguage: req.SourceLanguage,
+			UserID:         user.ID.String(),
+		})
+		if err != nil {
+			respondAIError(c, err)
+			return
+		}
+
+		if !req.CreateContent {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Text translated successfully",
+				"data":    result,
+			})
+			return
+		}
+
+		translation, err := createTranslatedContent(source, user.ID, result)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to save translation",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while saving the translated content",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Content translated successfully",
+			"data": gin.H{
+				"translation": result,
+				"content":     translation,
+			},
+		})
+	}
+}
+
+// createTranslatedContent saves a translation of source as a private draft
+// owned by userID, linked to source through ParentID and tagged with the
+// target language
+func createTranslatedContent(source models.Content, userID uuid.UUID, result *ai.TranslationResult) (models.Content, error) {
+	languageTag := "lang:" + strings.ToLower(result.TargetLanguage)
+	tags := append([]string{}, source.Tags...)
+	if !containsString(tags, languageTag) {
+		tags = append(tags, languageTag)
+	}
+
+	metadata := make(models.JSON, len(source.Metadata)+3)
+	for key, value := range source.Metadata {
+		metadata[key] = value
+	}
+	metadata["language"] = result.TargetLanguage
+	metadata["source_language"] = result.SourceLanguage
+	metadata["translated_from"] = source.ID
+
+	translation := models.Content{
+		UserID:      userID,
+		Title:       fmt.Sprintf("%s (%s)", source.Title, result.TargetLanguage),
+		Description: source.Description,
+		Content:     result.Text,
+		Type:        source.Type,
+		Status:      models.ContentStatusDraft,
+		Visibility:  models.VisibilityPrivate,
+		Tags:        tags,
+		Metadata:    metadata,
+		AIGenerated: true,
+		AIModel:     result.Model,
+		ParentID:    &source.ID,
+		Version:     1,
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&translation).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ContentVersion{
+			ContentID:   translation.ID,
+			Version:     1,
+			Content:     translation.Content,
+			Title:       translation.Title,
+			Description: translation.Description,
+			Tags:        translation.Tags,
+			Metadata:    translation.Metadata,
+			CreatedBy:   &userID,
+		}).Error
+	})
+	if err != nil {
+		return translation, err
+	}
+
+	// Load relationships
+	database.GetDB().Preload("User").First(&translation, translation.ID)
+
+	recordActivity(translation.ID, userID, models.ActivityContentCreated, models.JSON{
+		"translated_from": source.ID,
+		"language":        result.TargetLanguage,
+	})
+	webhook.Dispatch(translation.UserID, models.WebhookEventContentCreated, translation)
+	indexContentEmbedding(translation)
+
+	return translation, nil
+}
+
+// SummarizeRequest represents a request to summarize content
+type SummarizeRequest struct {
+	Mode string `json:"mode" binding:"omitempty,oneof=bullet paragraph tweet"`
+	// Store saves the summary in the content metadata, requires edit access
+	Store bool `json:"store"`
+}
+
+// SummarizeContent returns an AI summary of content the user can read,
+// optionally storing it in the content metadata under "summary"
+func SummarizeContent(aiService *ai.AIService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SummarizeRequest
+		// The request body is optional
+		if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = ai.SummaryModeParagraph
+		}
+
+		content, user, ok := readableContent(c)
+		if !ok {
+			return
+		}
+
+		if req.Store && !content.CanEdit(user.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Access denied",
+				"code":    "ACCESS_DENIED",
+				"message": "You don't have permission to edit this content",
+			})
+			return
+		}
+
+		if strings.TrimSpace(content.Content) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Empty content",
+				"code":    "EMPTY_CONTENT",
+				"message": "The content has no text to summarize",
+			})
+			return
+		}
+
+		summary, err := aiService.SummarizeContent(c.Request.Context(), &content, req.Mode, user.ID.String())
+		if err != nil {
+			respondAIError(c, err)
+			return
+		}
+
+		if req.Store {
+			patch, _ := json.Marshal(models.JSON{"summary": summary})
+			// Merge into the metadata without creating a version or touching updated_at
+			if err := database.GetDB().Model(&content).
+				UpdateColumn("metadata", gorm.Expr("COALESCE(metadata, '{}'::jsonb) || ?::jsonb", string(patch))).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to store summary",
+					"code":    "DATABASE_ERROR",
+					"message": "An error occurred while storing the summary",
+				})
+				return
+			}
+			invalidateContentCache(c.Request.Context(), content.ID)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content summarized successfully",
+			"data":    summary,
+		})
+	}
+}
+
+// errVersionConflict aborts applying a suggestion generated for an older version
+var errVersionConflict = errors.New("content version changed")
+
+// ApplySuggestionRequest represents a request to apply an AI suggestion
+type ApplySuggestionRequest struct {
+	Type    string `json:"type" binding:"required,oneof=completion improvement correction"`
+	Content string `json:"content" binding:"required"`
+	// BaseVersion is the content version the suggestion was generated for
+	BaseVersion int `json:"base_version" binding:"required,min=1"`
+}
+
+// GetContentSuggestions returns AI completion, improvement and correction
+// suggestions for content the user can read
+func GetContentSuggestions(aiService *ai.AIService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		content, user, ok := readableContent(c)
+		if !ok {
+			return
+		}
+
+		if strings.TrimSpace(content.Content) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Empty content",
+				"code":    "EMPTY_CONTENT",
+				"message": "The content has no text to make suggestions for",
+			})
+			return
+		}
+
+		suggestions, err := aiService.GenerateSuggestions(c.Request.Context(), &content, user.ID.String())
+		if err != nil {
+			respondAIError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Suggestions generated successfully",
+			"data": gin.H{
+				"suggestions":  suggestions,
+				"base_version": content.Version,
+			},
+		})
+	}
+}
+
+// ApplyContentSuggestion applies a suggestion as an edit recorded as a new
+// version. A completion is appended to the body, an improvement or
+// correction replaces it. Suggestions for an outdated version are rejected.
+func ApplyContentSuggestion(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ApplySuggestionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		content, user, ok := readableContent(c)
+		if !ok {
+			return
+		}
+
+		if !content.CanEdit(user.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Edit permission denied",
+				"code":    "EDIT_PERMISSION_DENIED",
+				"message": "You don't have permission to edit this content",
+			})
+			return
+		}
+
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			// Lock the row so the version check and the edit are atomic
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&content, "id = ?", content.ID).Error; err != nil {
+				return err
+			}
+			if content.IsLockedFor(user.ID) {
+				return errContentLocked
+			}
+			if content.Version != req.BaseVersion {
+				return errVersionConflict
+			}
+
+			if req.Type == "completion" {
+				content.Content = appendCompletion(content.Content, req.Content)
+			} else {
+				content.Content = req.Content
+			}
+			content.Version++
+			content.UpdatedAt = time.Now()
+
+			if err := tx.Omit(clause.Associations).Save(&content).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.ContentVersion{
+				ContentID:   content.ID,
+				Version:     content.Version,
+				Content:     content.Content,
+				Title:       content.Title,
+				Description: content.Description,
+				Tags:        content.Tags,
+				Metadata:    content.Metadata,
+				CreatedBy:   &user.ID,
+			}).Error
+		})
+		if errors.Is(err, errContentLocked) {
+			respondContentLockedBy(c, content)
+			return
+		}
+		if errors.Is(err, errVersionConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Version conflict",
+				"code":    "VERSION_CONFLICT",
+				"message": "The content changed since the suggestion was generated",
+				"version": content.Version,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to apply suggestion",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while applying the suggestion",
+			})
+			return
+		}
+
+		// Keep live editors from saving over the suggestion
+		hub.SyncRoomContent(content.ID.String(), content.Content, user.ID.String(), int64(content.Version))
+
+		// Load relationships
+		database.GetDB().Preload("User").First(&content, content.ID)
+
+		invalidateContentCache(c.Request.Context(), content.ID)
+		recordActivity(content.ID, user.ID, models.ActivityContentUpdated, models.JSON{
+			"fields":     []string{"content"},
+			"version":    content.Version,
+			"suggestion": req.Type,
+		})
+		webhook.Dispatch(content.UserID, models.WebhookEventContentUpdated, content)
+		indexContentEmbedding(content)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Suggestion applied successfully",
+			"data":    content,
+		})
+	}
+}
+
+// appendCompletion continues body with a completion, separated by a space
+// unless either side already breaks the line
+func appendCompletion(body, completion string) string {
+	body = strings.TrimRight(body, " \t")
+	completion = strings.TrimLeft(completion, " \t")
+	if body == "" || strings.HasSuffix(body, "\n") || strings.HasPrefix(completion, "\n") {
+		return body + completion
+	}
+	return body + " " + completion
+}
+
+// respondAIError writes the response for a failed AI service call
+func respondAIError(c *gin.Context, err error) {
+	if respondModerationError(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, ai.ErrRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":   "Rate limit exceeded",
+			"code":    "AI_RATE_LIMITED",
+			"message": "Too many AI requests, please try again shortly",
+		})
+	case errors.Is(err, ai.ErrQuotaExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":   "Quota exceeded",
+			"code":    "AI_QUOTA_EXCEEDED",
+			"message": "You have used your monthly AI token quota",
+		})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "AI request failed",
+			"code":    "AI_ERROR",
+			"message": "The AI provider could not complete the request",
+		})
+	}
+}
+
+// parseUsageMonth reads the optional month query parameter (YYYY-MM),
+// defaulting to the current month
+func parseUsageMonth(c *gin.Context) (time.Time, bool) {
+	month := c.Query("month")
+	if month == "" {
+		return models.StartOfMonth(time.Now()), true
+	}
+
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid month",
+			"code":    "INVALID_MONTH",
+			"message": "Month must be formatted as YYYY-MM",
+		})
+		return time.Time{}, false
+	}
+	return start, true
+}
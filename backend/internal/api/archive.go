@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// ArchiveContent archives content, hiding it from listings and public
+// readers while its owner and collaborators can still read it. Archiving
+// cancels a scheduled publication.
+func ArchiveContent(c *gin.Context) {
+	setContentArchived(c, true)
+}
+
+// UnarchiveContent returns archived content to draft so it is reviewed
+// before it is published again
+func UnarchiveContent(c *gin.Context) {
+	setContentArchived(c, false)
+}
+
+// setContentArchived moves the content named by the :id param into or out
+// of the archive on behalf of a user who may manage it
+func setContentArchived(c *gin.Context, archived bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var content models.Content
+	if err := db.Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if !content.CanAdmin(user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Archive permission denied",
+			"code":    "ARCHIVE_PERMISSION_DENIED",
+			"message": "You don't have permission to archive this content",
+		})
+		return
+	}
+
+	if (content.Status == models.ContentStatusArchived) == archived {
+		code, message := "CONTENT_ARCHIVED", "The content is already archived"
+		if !archived {
+			code, message = "CONTENT_NOT_ARCHIVED", "The content is not archived"
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Invalid archive state",
+			"code":    code,
+			"message": message,
+		})
+		return
+	}
+
+	previousStatus := content.Status
+	status, action := models.ContentStatusArchived, models.ActivityContentArchived
+	if !archived {
+		status, action = models.ContentStatusDraft, models.ActivityContentUnarchived
+	}
+
+	// Only move the content if nobody changed its status meanwhile
+	now := time.Now()
+	result := db.Model(&models.Content{}).
+		Where("id = ? AND status = ?", content.ID, previousStatus).
+		Updates(map[string]interface{}{"status": status, "publish_at": nil, "updated_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while updating content",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Content changed",
+			"code":    "CONTENT_CHANGED",
+			"message": "The content status changed meanwhile, please try again",
+		})
+		return
+	}
+	content.Status = status
+	content.PublishAt = nil
+	content.UpdatedAt = now
+
+	invalidateContentCache(c.Request.Context(), content.ID)
+	recordActivity(content.ID, user.ID, action, models.JSON{
+		"previous_status": previousStatus,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content " + string(status) + " successfully",
+		"data":    content,
+	})
+}
+
+// ArchiveStaleDrafts archives drafts that haven't been updated for longer
+// than after and aren't scheduled for publishing, returning how many were
+// archived
+func ArchiveStaleDrafts(ctx context.Context, after time.Duration) (int, error) {
+	var archived []models.Content
+	err := database.GetDB().WithContext(ctx).Model(&archived).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "user_id"}}}).
+		Where("status = ? AND publish_at IS NULL AND updated_at < ?", models.ContentStatusDraft, time.Now().Add(-after)).
+		Updates(map[string]interface{}{"status": models.ContentStatusArchived, "updated_at": time.Now()}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, content := range archived {
+		invalidateContentCache(ctx, content.ID)
+		recordActivity(content.ID, content.UserID, models.ActivityContentArchived, models.JSON{
+			"previous_status": models.ContentStatusDraft,
+			"reason":          "inactive",
+		})
+	}
+	return len(archived), nil
+}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/storage"
+)
+
+// attachmentType describes an accepted attachment extension: the MIME type
+// it is stored with and the types its sniffed contents may have
+type attachmentType struct {
+	mimeType string
+	sniffed  []string
+}
+
+// attachmentTypes lists the accepted attachment extensions. SVG and HTML are
+// left out since they can carry scripts.
+var attachmentTypes = map[string]attachmentType{
+	".png":  {"image/png", []string{"image/png"}},
+	".jpg":  {"image/jpeg", []string{"image/jpeg"}},
+	".jpeg": {"image/jpeg", []string{"image/jpeg"}},
+	".gif":  {"image/gif", []string{"image/gif"}},
+	".webp": {"image/webp", []string{"image/webp"}},
+	".pdf":  {"application/pdf", []string{"application/pdf"}},
+	".txt":  {"text/plain", []string{"text/plain"}},
+	".md":   {"text/markdown", []string{"text/plain"}},
+	".csv":  {"text/csv", []string{"text/plain"}},
+	".json": {"application/json", []string{"text/plain"}},
+	".zip":  {"application/zip", []string{"application/zip"}},
+}
+
+// sniffLength is the number of bytes http.DetectContentType considers
+const sniffLength = 512
+
+// errAttachmentTooLarge is returned while streaming an upload over the limit
+var errAttachmentTooLarge = errors.New("attachment exceeds the maximum size")
+
+// limitedReader counts the bytes read and fails once more than max are read
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		return n, errAttachmentTooLarge
+	}
+	return n, err
+}
+
+// UploadAttachment streams a multipart file upload into storage and
+// attaches it to content
+func UploadAttachment(c *gin.Context) {
+	content, user, ok := contentForAttachments(c, true)
+	if !ok {
+		return
+	}
+
+	maxSize := config.Load().Storage.MaxUploadSize
+
+	// Files may only fill the storage the user's tier has left
+	fileMax := maxSize
+	if limit := features.Limit(user, features.Storage); limit > 0 {
+		used, err := features.Used(c.Request.Context(), user, features.Storage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to check limits",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while checking your plan's limits",
+			})
+			return
+		}
+		if limit-used <= 0 {
+			respondTierLimitReached(c, user, features.Storage)
+			return
+		}
+		if limit-used < fileMax {
+			fileMax = limit - used
+		}
+	}
+
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64*1024)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid upload",
+			"code":    "INVALID_UPLOAD",
+			"message": "Attachments must be uploaded as multipart/form-data",
+		})
+		return
+	}
+
+	// Read parts until the file, streaming it instead of buffering the form
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "File required",
+				"code":    "MISSING_FILE",
+				"message": "Upload the attachment in the file field",
+			})
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		filename := filepath.Base(part.FileName())
+		fileType, ok := attachmentTypes[strings.ToLower(filepath.Ext(filename))]
+		if !ok {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"error":   "Unsupported file type",
+				"code":    "UNSUPPORTED_MEDIA_TYPE",
+				"message": "This file extension is not accepted as an attachment",
+			})
+			return
+		}
+
+		// Check the leading bytes match the extension before storing anything
+		head := make([]byte, sniffLength)
+		n, err := io.ReadFull(part, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to read file",
+				"code":    "INVALID_FILE",
+				"message": "The uploaded file could not be read",
+			})
+			return
+		}
+		head = head[:n]
+
+		sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+		if !containsString(fileType.sniffed, sniffed) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"error":   "File type mismatch",
+				"code":    "UNSUPPORTED_MEDIA_TYPE",
+				"message": fmt.Sprintf("The file contents do not match the %s extension", filepath.Ext(filename)),
+			})
+			return
+		}
+
+		attachmentID := uuid.New()
+		key := fmt.Sprintf("attachments/%s/%s%s", content.ID, attachmentID, strings.ToLower(filepath.Ext(filename)))
+		body := &limitedReader{r: io.MultiReader(bytes.NewReader(head), part), max: fileMax}
+
+		if err := storage.Get().Put(c.Request.Context(), key, body, -1, fileType.mimeType); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.Is(err, errAttachmentTooLarge) && fileMax < maxSize {
+				respondTierLimitReached(c, user, features.Storage)
+				return
+			}
+			if errors.Is(err, errAttachmentTooLarge) || errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error":   "File too large",
+					"code":    "FILE_TOO_LARGE",
+					"message": fmt.Sprintf("Attachments may be at most %d bytes", maxSize),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to store attachment",
+				"code":    "STORAGE_ERROR",
+				"message": "An error occurred while storing the attachment",
+			})
+			return
+		}
+
+		url := storage.Get().URL(key)
+		if url == "" {
+			url = fmt.Sprintf("/api/v1/content/%s/attachments/%s", content.ID, attachmentID)
+		}
+
+		attachment := models.Attachment{
+			ID:         attachmentID,
+			ContentID:  content.ID,
+			UserID:     user.ID,
+			Filename:   filename,
+			StorageKey: key,
+			Size:       body.n,
+			MimeType:   fileType.mimeType,
+			URL:        url,
+		}
+
+		if err := database.GetDB().Create(&attachment).Error; err != nil {
+			deleteStoredObjects([]string{key})
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to save attachment",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while saving the attachment",
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Attachment uploaded successfully",
+			"data":    attachment,
+		})
+		return
+	}
+}
+
+// GetAttachments lists the attachments of content
+func GetAttachments(c *gin.Context) {
+	content, _, ok := contentForAttachments(c, false)
+	if !ok {
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := database.GetDB().Where("content_id = ?", content.ID).Order("created_at ASC").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve attachments",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving attachments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attachments retrieved successfully",
+		"data":    attachments,
+	})
+}
+
+// DownloadAttachment streams an attachment from storage
+func DownloadAttachment(c *gin.Context) {
+	content, _, ok := contentForAttachments(c, false)
+	if !ok {
+		return
+	}
+
+	attachment, ok := findAttachment(c, content.ID)
+	if !ok {
+		return
+	}
+
+	file, err := storage.Get().Open(c.Request.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Attachment not found",
+				"code":    "ATTACHMENT_NOT_FOUND",
+				"message": "The attachment file is missing from storage",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read attachment",
+			"code":    "STORAGE_ERROR",
+			"message": "An error occurred while reading the attachment",
+		})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.MimeType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// DeleteAttachment removes an attachment and its stored file
+func DeleteAttachment(c *gin.Context) {
+	content, _, ok := contentForAttachments(c, true)
+	if !ok {
+		return
+	}
+
+	attachment, ok := findAttachment(c, content.ID)
+	if !ok {
+		return
+	}
+
+	if err := database.GetDB().Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete attachment",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while deleting the attachment",
+		})
+		return
+	}
+	deleteStoredObjects([]string{attachment.StorageKey})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attachment deleted successfully",
+	})
+}
+
+// contentForAttachments loads the content named by the :id param and checks
+// the user may read it, or edit it when edit is set, writing the error
+// response and returning false otherwise
+func contentForAttachments(c *gin.Context, edit bool) (models.Content, *models.User, bool) {
+	var content models.Content
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return content, nil, false
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return content, nil, false
+	}
+
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return content, nil, false
+	}
+
+	if edit && !content.CanEdit(user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Edit permission denied",
+			"code":    "EDIT_PERMISSION_DENIED",
+			"message": "You don't have permission to change this content's attachments",
+		})
+		return content, nil, false
+	}
+	if !edit && !models.Authorize(user, &content, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to access this content",
+		})
+		return content, nil, false
+	}
+
+	return content, user, true
+}
+
+// findAttachment loads the attachment named by the :attachmentId param,
+// writing the error response and returning false when it does not exist
+func findAttachment(c *gin.Context, contentID uuid.UUID) (models.Attachment, bool) {
+	var attachment models.Attachment
+
+	id, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid attachment ID",
+			"code":    "INVALID_ATTACHMENT_ID",
+			"message": "Attachment ID must be a valid UUID",
+		})
+		return attachment, false
+	}
+
+	if err := database.GetDB().First(&attachment, "id = ? AND content_id = ?", id, contentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Attachment not found",
+			"code":    "ATTACHMENT_NOT_FOUND",
+			"message": "The requested attachment was not found",
+		})
+		return attachment, false
+	}
+
+	return attachment, true
+}
+
+// deleteStoredObjects removes objects from storage, logging failures since
+// the database rows referencing them are already gone
+func deleteStoredObjects(keys []string) {
+	for _, key := range keys {
+		if err := storage.Get().Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete attachment %s from storage: %v", key, err)
+		}
+	}
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
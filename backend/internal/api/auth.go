@@ -1,6 +1,12 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -9,21 +15,33 @@ import (
 	"github.com/google/uuid"
 	"github.com/open-same/backend/internal/config"
 	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/email"
+	"github.com/open-same/backend/internal/jwtkeys"
 	"github.com/open-same/backend/internal/middleware"
 	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/password"
+	"github.com/open-same/backend/internal/redis"
+)
+
+const (
+	// Lifetime of an email verification token
+	verificationTokenTTL = 24 * time.Hour
+
+	// Minimum delay between two verification emails for the same user
+	verificationResendCooldown = time.Minute
 )
 
 // AuthRequest represents authentication request
 type AuthRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 // RegisterRequest represents user registration request
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Username  string `json:"username" binding:"required,min=3,max=30"`
-	Password  string `json:"password" binding:"required,min=6"`
+	Password  string `json:"password" binding:"required"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 }
@@ -42,6 +60,16 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// VerifyEmailRequest represents email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// LogoutRequest represents logout request
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // Register handles user registration
 func Register(c *gin.Context) {
 	var req RegisterRequest
@@ -54,6 +82,10 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if !validateNewPassword(c, req.Password) {
+		return
+	}
+
 	// Check if user already exists
 	var existingUser models.User
 	if err := database.GetDB().Where("email = ? OR username = ?", req.Email, req.Username).First(&existingUser).Error; err == nil {
@@ -94,9 +126,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Generate tokens
+	// Issue email verification token
 	cfg := config.Load()
-	accessToken, refreshToken, err := generateTokens(&user, cfg.JWT)
+	if _, err := issueVerificationToken(&user, cfg); err != nil {
+		log.Printf("Failed to issue verification token for user %s: %v", user.ID, err)
+	}
+
+	// Generate tokens
+	accessToken, refreshToken, err := generateTokens(c.Request.Context(), &user, cfg.JWT)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to generate tokens",
@@ -106,12 +143,15 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Save refresh token to database
+	// Save refresh token to database, starting a new token family
 	token := models.Token{
 		UserID:    user.ID,
 		Token:     refreshToken,
 		Type:      "refresh",
+		FamilyID:  uuid.New(),
 		ExpiresAt: time.Now().Add(time.Duration(cfg.JWT.RefreshHours) * time.Hour),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
 	}
 
 	if err := database.GetDB().Create(&token).Error; err != nil {
@@ -159,6 +199,27 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Verify password before revealing anything about the account. Accounts
+	// created through social login have no password and always fail here.
+	if !user.CheckPassword(req.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid credentials",
+			"code":    "INVALID_CREDENTIALS",
+			"message": "Email or password is incorrect",
+		})
+		return
+	}
+
+	// Check if user is banned
+	if user.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account banned",
+			"code":    "USER_BANNED",
+			"message": "Your account has been banned",
+		})
+		return
+	}
+
 	// Check if user is active
 	if !user.IsActive {
 		c.JSON(http.StatusForbidden, gin.H{
@@ -169,12 +230,25 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Verify password
-	if !user.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Invalid credentials",
-			"code":    "INVALID_CREDENTIALS",
-			"message": "Email or password is incorrect",
+	// Require a second factor before issuing tokens
+	if user.TOTPEnabled {
+		challenge, err := issueTwoFactorChallenge(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create challenge",
+				"code":    "TOKEN_GENERATION_ERROR",
+				"message": "An error occurred while logging in",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Two-factor authentication required",
+			"data": TwoFactorChallengeResponse{
+				TwoFactorRequired: true,
+				ChallengeToken:    challenge.Token,
+				ExpiresIn:         int64(twoFactorChallengeTTL.Seconds()),
+			},
 		})
 		return
 	}
@@ -186,7 +260,7 @@ func Login(c *gin.Context) {
 
 	// Generate tokens
 	cfg := config.Load()
-	accessToken, refreshToken, err := generateTokens(&user, cfg.JWT)
+	accessToken, refreshToken, err := generateTokens(c.Request.Context(), &user, cfg.JWT)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to generate tokens",
@@ -196,12 +270,15 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Save refresh token to database
+	// Save refresh token to database, starting a new token family
 	token := models.Token{
 		UserID:    user.ID,
 		Token:     refreshToken,
 		Type:      "refresh",
+		FamilyID:  uuid.New(),
 		ExpiresAt: time.Now().Add(time.Duration(cfg.JWT.RefreshHours) * time.Hour),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
 	}
 
 	if err := database.GetDB().Create(&token).Error; err != nil {
@@ -238,9 +315,41 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// Only refresh tokens may be exchanged, not access tokens. Expired ones
+	// are reported once their database record has been checked.
+	claims := &middleware.Claims{}
+	if _, err := jwtkeys.Get().Parse(req.RefreshToken, claims); (err != nil && !errors.Is(err, jwt.ErrTokenExpired)) || claims.TokenType != middleware.TokenTypeRefresh {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid refresh token",
+			"code":    "INVALID_REFRESH_TOKEN",
+			"message": "Invalid or expired refresh token",
+		})
+		return
+	}
+
 	// Find refresh token in database
 	var token models.Token
-	if err := database.GetDB().Where("token = ? AND type = ? AND is_revoked = ?", req.RefreshToken, "refresh", false).First(&token).Error; err != nil {
+	if err := database.GetDB().Where("token = ? AND type = ?", req.RefreshToken, "refresh").First(&token).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid refresh token",
+			"code":    "INVALID_REFRESH_TOKEN",
+			"message": "Invalid or expired refresh token",
+		})
+		return
+	}
+
+	// A revoked token being presented again means it was replayed
+	if token.IsRevoked {
+		if revoked := revokeTokenFamily(&token); revoked > 0 {
+			log.Printf("Refresh token reuse detected for user %s, revoked %d tokens in family %s", token.UserID, revoked, token.FamilyID)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Refresh token reuse detected",
+				"code":    "REFRESH_TOKEN_REUSE_DETECTED",
+				"message": "This session has been terminated for your security, please log in again",
+			})
+			return
+		}
+
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Invalid refresh token",
 			"code":    "INVALID_REFRESH_TOKEN",
@@ -270,6 +379,16 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// Check if user is banned
+	if user.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account banned",
+			"code":    "USER_BANNED",
+			"message": "Your account has been banned",
+		})
+		return
+	}
+
 	// Check if user is active
 	if !user.IsActive {
 		c.JSON(http.StatusForbidden, gin.H{
@@ -280,13 +399,33 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Revoke old refresh token
-	token.Revoke()
-	database.GetDB().Save(&token)
+	// Revoke old refresh token. Only one concurrent refresh can revoke it;
+	// losing the race means the token was presented twice.
+	result := database.GetDB().Model(&models.Token{}).
+		Where("id = ? AND is_revoked = ?", token.ID, false).
+		Updates(map[string]interface{}{"is_revoked": true, "updated_at": time.Now()})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke token",
+			"code":    "TOKEN_REVOKE_ERROR",
+			"message": "An error occurred while refreshing tokens",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		revoked := revokeTokenFamily(&token)
+		log.Printf("Refresh token reuse detected for user %s, revoked %d tokens in family %s", token.UserID, revoked, token.FamilyID)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Refresh token reuse detected",
+			"code":    "REFRESH_TOKEN_REUSE_DETECTED",
+			"message": "This session has been terminated for your security, please log in again",
+		})
+		return
+	}
 
 	// Generate new tokens
 	cfg := config.Load()
-	accessToken, refreshToken, err := generateTokens(&user, cfg.JWT)
+	accessToken, refreshToken, err := generateTokens(c.Request.Context(), &user, cfg.JWT)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to generate tokens",
@@ -296,12 +435,15 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Save new refresh token
+	// Save new refresh token in the same family
 	newToken := models.Token{
 		UserID:    user.ID,
 		Token:     refreshToken,
 		Type:      "refresh",
+		FamilyID:  token.FamilyID,
 		ExpiresAt: time.Now().Add(time.Duration(cfg.JWT.RefreshHours) * time.Hour),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
 	}
 
 	if err := database.GetDB().Create(&newToken).Error; err != nil {
@@ -326,49 +468,400 @@ func RefreshToken(c *gin.Context) {
 	})
 }
 
-// generateTokens generates access and refresh tokens
-func generateTokens(user *models.User, jwtConfig config.JWTConfig) (string, string, error) {
+// VerifyEmail handles email verification
+func VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Find verification token in database
+	var token models.Token
+	if err := database.GetDB().Where("token = ? AND type = ? AND is_revoked = ?", req.Token, "verify", false).First(&token).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid verification token",
+			"code":    "INVALID_VERIFICATION_TOKEN",
+			"message": "Invalid or expired verification token",
+		})
+		return
+	}
+
+	// Check if token is expired
+	if token.IsExpired() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Verification token expired",
+			"code":    "VERIFICATION_TOKEN_EXPIRED",
+			"message": "Verification token has expired, please request a new one",
+		})
+		return
+	}
+
+	// Get user
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", token.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "User not found",
+			"code":    "USER_NOT_FOUND",
+			"message": "User associated with token not found",
+		})
+		return
+	}
+
+	// Mark email as verified
+	now := time.Now()
+	user.IsVerified = true
+	user.EmailVerifiedAt = &now
+	if err := database.GetDB().Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify email",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while verifying your email",
+		})
+		return
+	}
+
+	// Consume verification token
+	token.Revoke()
+	database.GetDB().Save(&token)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+		"data":    user,
+	})
+}
+
+// ResendVerification handles re-issuing an email verification token
+func ResendVerification(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if user.IsVerified {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Email already verified",
+			"code":    "EMAIL_ALREADY_VERIFIED",
+			"message": "Your email address has already been verified",
+		})
+		return
+	}
+
+	// Throttle resends per user
+	ctx := c.Request.Context()
+	cooldownKey := fmt.Sprintf("verification_resend:%s", user.ID)
+	if recent, err := redis.Exists(ctx, cooldownKey); err == nil && recent {
+		retryAfter, _ := redis.TTL(ctx, cooldownKey)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Rate limit exceeded",
+			"code":        "RATE_LIMIT_EXCEEDED",
+			"message":     "Please wait before requesting another verification email",
+			"retry_after": time.Now().Add(retryAfter).Unix(),
+		})
+		return
+	}
+
+	// Revoke previously issued verification tokens
+	var tokens []models.Token
+	database.GetDB().Where("user_id = ? AND type = ? AND is_revoked = ?", user.ID, "verify", false).Find(&tokens)
+	if err := revokeTokens(tokens); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke tokens",
+			"code":    "TOKEN_REVOKE_ERROR",
+			"message": "An error occurred while resending verification",
+		})
+		return
+	}
+
+	cfg := config.Load()
+	if _, err := issueVerificationToken(user, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to issue verification token",
+			"code":    "TOKEN_GENERATION_ERROR",
+			"message": "An error occurred while resending verification",
+		})
+		return
+	}
+
+	redis.Set(ctx, cooldownKey, 1, verificationResendCooldown)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Verification email sent",
+	})
+}
+
+// Logout handles revoking the refresh token of the current session
+func Logout(c *gin.Context) {
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Find matching refresh tokens owned by the user
+	var tokens []models.Token
+	if err := database.GetDB().Where("user_id = ? AND token = ? AND type = ? AND is_revoked = ?", user.ID, req.RefreshToken, "refresh", false).Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve tokens",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while logging out",
+		})
+		return
+	}
+
+	// Revoke tokens
+	if err := revokeTokens(tokens); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke tokens",
+			"code":    "TOKEN_REVOKE_ERROR",
+			"message": "An error occurred while logging out",
+		})
+		return
+	}
+
+	// Stop the access token of this session from being used until it expires
+	if err := middleware.RevokeCurrentAccessToken(c); err != nil {
+		log.Printf("Failed to revoke access token of user %s: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll handles revoking every active token of the user
+func LogoutAll(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Find all active tokens owned by the user
+	var tokens []models.Token
+	if err := database.GetDB().Where("user_id = ? AND is_revoked = ?", user.ID, false).Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve tokens",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while logging out",
+		})
+		return
+	}
+
+	// Revoke tokens
+	if err := revokeTokens(tokens); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke tokens",
+			"code":    "TOKEN_REVOKE_ERROR",
+			"message": "An error occurred while logging out",
+		})
+		return
+	}
+
+	revokeUserAccessTokens(c.Request.Context(), user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out from all devices successfully",
+		"data": gin.H{
+			"revoked": len(tokens),
+		},
+	})
+}
+
+// revokeUserAccessTokens invalidates every access token issued to the user so
+// far. Failures are logged since the refresh tokens are already revoked and
+// the access tokens expire on their own.
+func revokeUserAccessTokens(ctx context.Context, userID uuid.UUID) {
+	if err := middleware.RevokeUserAccessTokens(ctx, userID); err != nil {
+		log.Printf("Failed to revoke access tokens of user %s: %v", userID, err)
+	}
+}
+
+// revokeTokens marks the given tokens as revoked and saves them
+func revokeTokens(tokens []models.Token) error {
+	for i := range tokens {
+		tokens[i].Revoke()
+		if err := database.GetDB().Save(&tokens[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeTokenFamily revokes every active token sharing the given token's family
+// and returns how many were revoked
+func revokeTokenFamily(token *models.Token) int {
+	if token.FamilyID == uuid.Nil {
+		return 0
+	}
+
+	var tokens []models.Token
+	if err := database.GetDB().Where("family_id = ? AND is_revoked = ?", token.FamilyID, false).Find(&tokens).Error; err != nil {
+		log.Printf("Failed to load token family %s: %v", token.FamilyID, err)
+		return 0
+	}
+
+	if err := revokeTokens(tokens); err != nil {
+		log.Printf("Failed to revoke token family %s: %v", token.FamilyID, err)
+	}
+
+	return len(tokens)
+}
+
+// issueVerificationToken creates and stores a new email verification token
+func issueVerificationToken(user *models.User, cfg *config.Config) (*models.Token, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+
+	token := models.Token{
+		UserID:    user.ID,
+		Token:     hex.EncodeToString(bytes),
+		Type:      "verify",
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+	}
+
+	if err := database.GetDB().Create(&token).Error; err != nil {
+		return nil, err
+	}
+
+	email.Notify(user.Email, email.TemplateVerify, email.TemplateData{
+		Name: user.FullName(),
+		Link: fmt.Sprintf("%s/verify-email?token=%s", cfg.Email.AppURL, token.Token),
+	})
+
+	return &token, nil
+}
+
+// generateTokens generates access and refresh tokens. Access tokens carry
+// the user's token generation so revoking the user's tokens rejects them.
+// When Redis is unavailable the access token is issued in generation zero
+// rather than failing login, as revocation checks let tokens through then.
+func generateTokens(ctx context.Context, user *models.User, jwtConfig config.JWTConfig) (string, string, error) {
+	generation, err := middleware.TokenGeneration(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get token generation of user %s: %v", user.ID, err)
+	}
+
 	// Generate access token
 	accessClaims := middleware.Claims{
-		UserID:   user.ID.String(),
-		Email:    user.Email,
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
+		UserID:     user.ID.String(),
+		Email:      user.Email,
+		Username:   user.Username,
+		IsAdmin:    user.IsAdmin,
+		TokenType:  middleware.TokenTypeAccess,
+		Generation: generation,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(jwtConfig.ExpirationHours) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "open-same",
 			Subject:   user.ID.String(),
+			ID:        uuid.NewString(),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(jwtConfig.Secret))
+	accessTokenString, err := jwtkeys.Get().Sign(accessClaims)
 	if err != nil {
 		return "", "", err
 	}
 
 	// Generate refresh token
 	refreshClaims := middleware.Claims{
-		UserID:   user.ID.String(),
-		Email:    user.Email,
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		Username:  user.Username,
+		IsAdmin:   user.IsAdmin,
+		TokenType: middleware.TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(jwtConfig.RefreshHours) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "open-same",
 			Subject:   user.ID.String(),
+			ID:        uuid.NewString(),
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(jwtConfig.Secret))
+	refreshTokenString, err := jwtkeys.Get().Sign(refreshClaims)
 	if err != nil {
 		return "", "", err
 	}
 
 	return accessTokenString, refreshTokenString, nil
-}
\ No newline at end of file
+}
+
+// GetJWKS publishes the public keys that verify issued tokens in JSON Web
+// Key Set format, so other services can verify tokens without the secret.
+// The set is empty when tokens are signed with HS256.
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwtkeys.Get().JWKS())
+}
+
+// GetPasswordPolicy returns the rules new passwords must follow so clients
+// can show them before submitting
+func GetPasswordPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password policy retrieved successfully",
+		"data":    password.NewPolicy(config.Load().Password),
+	})
+}
+
+// validateNewPassword checks a password chosen by a user against the
+// password policy, responding with the rules it breaks when it's rejected
+func validateNewPassword(c *gin.Context, newPassword string) bool {
+	err := password.Validate(c.Request.Context(), config.Load().Password, newPassword)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "Password too weak",
+			"code":       "WEAK_PASSWORD",
+			"message":    policyErr.Error(),
+			"violations": policyErr.Violations,
+		})
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Password breached",
+		"code":    "BREACHED_PASSWORD",
+		"message": err.Error(),
+	})
+	return false
+}
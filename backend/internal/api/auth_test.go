@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/jwtkeys"
+	"github.com/open-same/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB migrates the database named by TEST_DATABASE_URL and points
+// the database package at it, skipping the test when it is not set
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	require.NoError(t, err)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	require.NoError(t, database.AutoMigrate())
+	return db
+}
+
+// postJSON posts request as JSON to handler
+func postJSON(t *testing.T, handler gin.HandlerFunc, request interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := gin.New()
+	router.POST("/", handler)
+
+	body, err := json.Marshal(request)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// register posts a registration for email and username
+func register(t *testing.T, email, username string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return postJSON(t, Register, RegisterRequest{
+		Email:     email,
+		Username:  username,
+		Password:  "Correct-Horse-42-Battery",
+		FirstName: "Test",
+		LastName:  "User",
+	})
+}
+
+func TestRegisterReusesDeletedAccountIdentifiers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+
+	suffix := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	email, username := "reuse-"+suffix+"@example.com", "reuse_"+suffix
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM tokens WHERE user_id IN (SELECT id FROM users WHERE email = ?)", email)
+		db.Unscoped().Where("email = ?", email).Delete(&models.User{})
+	})
+
+	w := register(t, email, username)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// A live account keeps both identifiers
+	w = register(t, email, "other_"+suffix)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = register(t, "other-"+suffix+"@example.com", username)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var first models.User
+	require.NoError(t, db.Where("email = ?", email).First(&first).Error)
+	require.NoError(t, db.Delete(&first).Error)
+
+	// Once deleted, the same email and username register a new account
+	w = register(t, email, username)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var second models.User
+	require.NoError(t, db.Where("email = ?", email).First(&second).Error)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, username, second.Username)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count).Error)
+	assert.Equal(t, int64(2), count, "the deleted account is kept alongside the new one")
+
+	// The partial indexes still reject a duplicate that skips the handler check
+	duplicate := models.User{Email: email, Username: "dup_" + suffix, PasswordHash: "x"}
+	assert.Error(t, db.Create(&duplicate).Error)
+}
+
+// bannedUserRows returns the database row of a banned user
+func bannedUserRows(user models.User) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "email", "username", "password_hash", "is_active", "is_banned"}).
+		AddRow(user.ID, user.Email, user.Username, user.PasswordHash, false, true)
+}
+
+// assertBanned asserts that a response rejects a banned user
+func assertBanned(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "USER_BANNED", body["code"])
+	assert.NotContains(t, body, "data", "no tokens are issued")
+}
+
+func TestBannedUserIsRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Load()
+	_, err := jwtkeys.Init(config.JWTConfig{Secret: "test-secret"})
+	require.NoError(t, err)
+
+	user := models.User{ID: uuid.New(), Email: "banned@example.com", Username: "banned"}
+	require.NoError(t, user.SetPassword("Correct-Horse-42-Battery"))
+
+	t.Run("login", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1`).
+			WithArgs(user.Email).
+			WillReturnRows(bannedUserRows(user))
+
+		w := postJSON(t, Login, AuthRequest{Email: user.Email, Password: "Correct-Horse-42-Battery"})
+		assertBanned(t, w)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		// A refresh token issued before the ban that escaped revocation
+		_, refreshToken, err := generateTokens(context.Background(), &user, cfg.JWT)
+		require.NoError(t, err)
+
+		mock := setupMockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "tokens" WHERE token = \$1 AND type = \$2`).
+			WithArgs(refreshToken, "refresh").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "type", "family_id", "expires_at", "is_revoked"}).
+				AddRow(uuid.New(), user.ID, refreshToken, "refresh", uuid.New(), time.Now().Add(time.Hour), false))
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+			WillReturnRows(bannedUserRows(user))
+
+		w := postJSON(t, RefreshToken, RefreshRequest{RefreshToken: refreshToken})
+		assertBanned(t, w)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/storage"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// maxAvatarSourceDimension bounds the width and height of uploaded
+	// images so decoding cannot exhaust memory
+	maxAvatarSourceDimension = 4096
+	// avatarSize is the largest width and height avatars are stored at
+	avatarSize = 256
+)
+
+// avatarFormats lists the image formats accepted as avatars
+var avatarFormats = map[string]bool{
+	"png":  true,
+	"jpeg": true,
+	"gif":  true,
+	"webp": true,
+}
+
+// UploadAvatar validates an uploaded image, scales it down to the avatar
+// size and makes it the user's avatar
+func UploadAvatar(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	maxSize := config.Load().Storage.MaxAvatarSize
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64*1024)
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "File too large",
+				"code":    "FILE_TOO_LARGE",
+				"message": fmt.Sprintf("Avatars may be at most %d bytes", maxSize),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Image required",
+			"code":    "MISSING_FILE",
+			"message": "Upload the image in the avatar field",
+		})
+		return
+	}
+
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "File too large",
+			"code":    "FILE_TOO_LARGE",
+			"message": fmt.Sprintf("Avatars may be at most %d bytes", maxSize),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read file",
+			"code":    "INVALID_FILE",
+			"message": "The uploaded file could not be read",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read file",
+			"code":    "INVALID_FILE",
+			"message": "The uploaded file could not be read",
+		})
+		return
+	}
+
+	// Check the format and dimensions before decoding the pixels
+	imageConfig, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !avatarFormats[format] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "Unsupported image type",
+			"code":    "UNSUPPORTED_MEDIA_TYPE",
+			"message": "Avatars must be PNG, JPEG, GIF or WebP images",
+		})
+		return
+	}
+	if imageConfig.Width > maxAvatarSourceDimension || imageConfig.Height > maxAvatarSourceDimension {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Image too large",
+			"code":    "IMAGE_TOO_LARGE",
+			"message": fmt.Sprintf("Avatars may be at most %dx%d pixels", maxAvatarSourceDimension, maxAvatarSourceDimension),
+		})
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid image",
+			"code":    "INVALID_IMAGE",
+			"message": "The uploaded image could not be decoded",
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, resizeAvatar(img)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to process image",
+			"code":    "IMAGE_PROCESSING_ERROR",
+			"message": "An error occurred while processing the avatar",
+		})
+		return
+	}
+
+	// A fresh key per upload keeps cached copies of the old avatar from lingering
+	avatarID := uuid.New()
+	key := fmt.Sprintf("avatars/%s/%s.png", user.ID, avatarID)
+	if err := storage.Get().Put(c.Request.Context(), key, &buf, int64(buf.Len()), "image/png"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to store avatar",
+			"code":    "STORAGE_ERROR",
+			"message": "An error occurred while storing the avatar",
+		})
+		return
+	}
+
+	url := storage.Get().URL(key)
+	if url == "" {
+		url = fmt.Sprintf("/api/v1/users/%s/avatar?v=%s", user.ID, avatarID)
+	}
+
+	previousKey := user.AvatarKey
+	if err := database.GetDB().Model(user).Updates(map[string]interface{}{
+		"avatar":     url,
+		"avatar_key": key,
+	}).Error; err != nil {
+		deleteStoredObjects([]string{key})
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update avatar",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while updating the avatar",
+		})
+		return
+	}
+	if previousKey != "" {
+		deleteStoredObjects([]string{previousKey})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar updated successfully",
+		"data":    user,
+	})
+}
+
+// DeleteAvatar removes the user's avatar so clients fall back to their
+// default avatar
+func DeleteAvatar(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	previousKey := user.AvatarKey
+	if err := database.GetDB().Model(user).Updates(map[string]interface{}{
+		"avatar":     "",
+		"avatar_key": "",
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove avatar",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while removing the avatar",
+		})
+		return
+	}
+	if previousKey != "" {
+		deleteStoredObjects([]string{previousKey})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar removed successfully",
+		"data":    user,
+	})
+}
+
+// GetAvatar serves an uploaded avatar for backends without public URLs
+func GetAvatar(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid user ID",
+			"code":    "INVALID_USER_ID",
+			"message": "User ID must be a valid UUID",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().Select("id", "avatar_key").First(&user, "id = ?", id).Error; err != nil || user.AvatarKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Avatar not found",
+			"code":    "AVATAR_NOT_FOUND",
+			"message": "The user has no uploaded avatar",
+		})
+		return
+	}
+
+	file, err := storage.Get().Open(c.Request.Context(), user.AvatarKey)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Avatar not found",
+			"code":    "AVATAR_NOT_FOUND",
+			"message": "The avatar file is missing from storage",
+		})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "image/png", file, map[string]string{
+		"Cache-Control": "public, max-age=86400",
+	})
+}
+
+// resizeAvatar scales an image down to fit within the avatar size, keeping
+// its aspect ratio. Smaller images are kept as they are.
+func resizeAvatar(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= avatarSize && height <= avatarSize {
+		return img
+	}
+
+	if width > height {
+		height = height * avatarSize / width
+		width = avatarSize
+	} else {
+		width = width * avatarSize / height
+		height = avatarSize
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
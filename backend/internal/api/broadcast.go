@@ -0,0 +1,140 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/websocket"
+)
+
+// AdminBroadcastRequest represents an announcement to connected users.
+// It goes to every client unless UserID or RoomID narrows it down.
+type AdminBroadcastRequest struct {
+	Message string  `json:"message" binding:"required,min=1,max=2000"`
+	Title   string  `json:"title" binding:"max=200"`
+	Level   string  `json:"level" binding:"omitempty,oneof=info warning critical"`
+	UserID  *string `json:"user_id"`
+	RoomID  *string `json:"room_id"`
+}
+
+// AdminBroadcast sends an announcement over WebSocket to every connected
+// client, to every connection of one user or to one room, on all replicas
+func AdminBroadcast(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AdminBroadcastRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		if req.UserID != nil && req.RoomID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid target",
+				"code":    "INVALID_TARGET",
+				"message": "Set at most one of user_id and room_id",
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		if req.Level == "" {
+			req.Level = "info"
+		}
+		announcement := websocket.Message{
+			Type:     "announcement",
+			UserID:   user.ID.String(),
+			Username: user.Username,
+			Data: map[string]interface{}{
+				"title":   req.Title,
+				"message": req.Message,
+				"level":   req.Level,
+			},
+			Timestamp: time.Now(),
+		}
+
+		target := "all"
+		details := models.JSON{"message": req.Message, "title": req.Title, "level": req.Level}
+		switch {
+		case req.UserID != nil:
+			recipientID, err := uuid.Parse(*req.UserID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid user ID",
+					"code":    "INVALID_USER_ID",
+					"message": "user_id must be a valid UUID",
+				})
+				return
+			}
+			if err := database.GetDB().Select("id").First(&models.User{}, "id = ?", recipientID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error":   "User not found",
+					"code":    "USER_NOT_FOUND",
+					"message": "The user to notify was not found",
+				})
+				return
+			}
+			target = "user"
+			details["user_id"] = recipientID
+			hub.BroadcastToUser(recipientID.String(), announcement)
+		case req.RoomID != nil:
+			// Rooms are keyed by content ID
+			roomID, err := uuid.Parse(*req.RoomID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid room ID",
+					"code":    "INVALID_ROOM_ID",
+					"message": "room_id must be a valid content ID",
+				})
+				return
+			}
+			target = "room"
+			details["room_id"] = roomID
+			announcement.RoomID = roomID.String()
+			hub.BroadcastToRoom(announcement.RoomID, announcement)
+		default:
+			hub.BroadcastToAll(announcement)
+		}
+		details["target"] = target
+
+		recordAudit(user.ID, models.AuditAdminBroadcast, details)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Announcement sent successfully",
+			"data":    announcement,
+			"target":  target,
+		})
+	}
+}
+
+// recordAudit writes an audit log entry. Failures are logged rather than
+// returned since the action itself has already succeeded.
+func recordAudit(actorID uuid.UUID, action string, details models.JSON) {
+	entry := models.AuditLog{
+		ActorID: &actorID,
+		Action:  action,
+		Details: details,
+	}
+	if err := database.GetDB().Create(&entry).Error; err != nil {
+		log.Printf("Failed to record %s audit entry by %s: %v", action, actorID, err)
+	}
+}
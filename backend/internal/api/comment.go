@@ -0,0 +1,458 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// CreateCommentRequest represents a new comment or reply
+type CreateCommentRequest struct {
+	Body     string                  `json:"body" binding:"required,min=1,max=10000"`
+	ParentID *string                 `json:"parent_id"`
+	Anchor   *map[string]interface{} `json:"anchor"`
+}
+
+// UpdateCommentRequest represents an edit of a comment
+type UpdateCommentRequest struct {
+	Body   *string                 `json:"body" binding:"omitempty,min=1,max=10000"`
+	Anchor *map[string]interface{} `json:"anchor"`
+}
+
+// CommentListResponse represents a page of comment threads
+type CommentListResponse struct {
+	Comments    []models.Comment `json:"comments"`
+	Total       int64            `json:"total"`
+	Page        int              `json:"page"`
+	PerPage     int              `json:"per_page"`
+	TotalPages  int              `json:"total_pages"`
+	HasNext     bool             `json:"has_next"`
+	HasPrevious bool             `json:"has_previous"`
+}
+
+// CreateComment adds a comment to content, or a reply to a thread when
+// parent_id is set, and broadcasts it to the content's room
+func CreateComment(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateCommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		content, user, ok := readableContent(c)
+		if !ok {
+			return
+		}
+
+		// Public readers and viewers may follow the discussion but only the
+		// owner and collaborators allowed to comment take part in it
+		if !models.Authorize(user, &content, models.PermissionComment) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Comment permission denied",
+				"code":    "COMMENT_PERMISSION_DENIED",
+				"message": "You don't have permission to comment on this content",
+			})
+			return
+		}
+
+		comment := models.Comment{
+			ContentID: content.ID,
+			UserID:    user.ID,
+			Body:      req.Body,
+		}
+		if req.Anchor != nil {
+			comment.Anchor = models.JSON(*req.Anchor)
+		}
+
+		if req.ParentID != nil {
+			parentID, err := uuid.Parse(*req.ParentID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid parent comment ID",
+					"code":    "INVALID_COMMENT_ID",
+					"message": "parent_id must be a valid UUID",
+				})
+				return
+			}
+
+			var parent models.Comment
+			if err := database.GetDB().First(&parent, "id = ? AND content_id = ?", parentID, content.ID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error":   "Comment not found",
+					"code":    "COMMENT_NOT_FOUND",
+					"message": "The comment being replied to was not found",
+				})
+				return
+			}
+
+			// Threads are one level deep, so replies to replies join the thread
+			if parent.ParentID != nil {
+				parentID = *parent.ParentID
+			}
+			comment.ParentID = &parentID
+		}
+
+		if err := database.GetDB().Create(&comment).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create comment",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while creating the comment",
+			})
+			return
+		}
+
+		// Load relationships
+		database.GetDB().Preload("User").First(&comment, comment.ID)
+
+		broadcastComment(hub, user, "comment_created", comment)
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Comment created successfully",
+			"data":    comment,
+		})
+	}
+}
+
+// GetComments lists the comment threads of content, oldest first, with
+// their replies. ?resolved=true or false filters threads by state.
+func GetComments(c *gin.Context) {
+	content, _, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := database.GetDB().Model(&models.Comment{}).Where("content_id = ? AND parent_id IS NULL", content.ID)
+	if resolved := c.Query("resolved"); resolved != "" {
+		query = query.Where("is_resolved = ?", resolved == "true")
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	var comments []models.Comment
+	if err := query.Preload("User").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Replies.User").
+		Offset(offset).Limit(perPage).Order("created_at ASC").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve comments",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving comments",
+		})
+		return
+	}
+
+	response := CommentListResponse{
+		Comments:    comments,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comments retrieved successfully",
+		"data":    response,
+	})
+}
+
+// UpdateComment edits a comment's body or anchor. Only its author or a
+// content admin may edit it.
+func UpdateComment(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateCommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		content, user, ok := readableContent(c)
+		if !ok {
+			return
+		}
+		comment, ok := findComment(c, content.ID)
+		if !ok {
+			return
+		}
+
+		if comment.UserID != user.ID && !content.CanAdmin(user.ID) {
+			writeCommentPermissionDenied(c)
+			return
+		}
+
+		if req.Body != nil {
+			comment.Body = *req.Body
+		}
+		if req.Anchor != nil {
+			comment.Anchor = models.JSON(*req.Anchor)
+		}
+
+		if err := database.GetDB().Save(&comment).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update comment",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while updating the comment",
+			})
+			return
+		}
+
+		// Load relationships
+		database.GetDB().Preload("User").First(&comment, comment.ID)
+
+		broadcastComment(hub, user, "comment_updated", comment)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Comment updated successfully",
+			"data":    comment,
+		})
+	}
+}
+
+// DeleteComment removes a comment, along with its replies when it starts a
+// thread. Only its author or a content admin may delete it.
+func DeleteComment(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		content, user, ok := readableContent(c)
+		if !ok {
+			return
+		}
+		comment, ok := findComment(c, content.ID)
+		if !ok {
+			return
+		}
+
+		if comment.UserID != user.ID && !content.CanAdmin(user.ID) {
+			writeCommentPermissionDenied(c)
+			return
+		}
+
+		if err := database.GetDB().Where("id = ? OR parent_id = ?", comment.ID, comment.ID).Delete(&models.Comment{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to delete comment",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while deleting the comment",
+			})
+			return
+		}
+
+		broadcastComment(hub, user, "comment_deleted", comment)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Comment deleted successfully",
+		})
+	}
+}
+
+// ResolveComment marks a comment thread as resolved
+func ResolveComment(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setCommentResolved(c, hub, true)
+	}
+}
+
+// UnresolveComment reopens a resolved comment thread
+func UnresolveComment(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setCommentResolved(c, hub, false)
+	}
+}
+
+// setCommentResolved resolves or reopens the thread named by :commentId.
+// The thread's author and users who can edit the content may do so.
+func setCommentResolved(c *gin.Context, hub *websocket.Hub, resolved bool) {
+	content, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+	comment, ok := findComment(c, content.ID)
+	if !ok {
+		return
+	}
+
+	if comment.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Not a thread",
+			"code":    "COMMENT_NOT_THREAD",
+			"message": "Only the first comment of a thread can be resolved",
+		})
+		return
+	}
+
+	if comment.UserID != user.ID && !content.CanEdit(user.ID) {
+		writeCommentPermissionDenied(c)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"is_resolved": resolved,
+		"resolved_by": nil,
+		"resolved_at": nil,
+	}
+	if resolved {
+		updates["resolved_by"] = user.ID
+		updates["resolved_at"] = time.Now()
+	}
+
+	if err := database.GetDB().Model(&comment).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update comment",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while updating the comment",
+		})
+		return
+	}
+
+	// Load relationships
+	database.GetDB().Preload("User").First(&comment, comment.ID)
+
+	messageType := "comment_unresolved"
+	if resolved {
+		messageType = "comment_resolved"
+	}
+	broadcastComment(hub, user, messageType, comment)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment updated successfully",
+		"data":    comment,
+	})
+}
+
+// readableContent loads the content named by the :id param and checks the
+// user may read it, writing the error response and returning false
+// otherwise
+func readableContent(c *gin.Context) (models.Content, *models.User, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return models.Content{}, nil, false
+	}
+
+	return loadReadableContent(c, id)
+}
+
+// loadReadableContent loads content by ID for the current user, writing the
+// error response and returning false when it is missing or not readable
+func loadReadableContent(c *gin.Context, id uuid.UUID) (models.Content, *models.User, bool) {
+	var content models.Content
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return content, nil, false
+	}
+
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return content, nil, false
+	}
+
+	if !models.Authorize(user, &content, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to access this content",
+		})
+		return content, nil, false
+	}
+
+	return content, user, true
+}
+
+// findComment loads the comment named by the :commentId param, writing the
+// error response and returning false when it does not exist
+func findComment(c *gin.Context, contentID uuid.UUID) (models.Comment, bool) {
+	var comment models.Comment
+
+	id, err := uuid.Parse(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid comment ID",
+			"code":    "INVALID_COMMENT_ID",
+			"message": "Comment ID must be a valid UUID",
+		})
+		return comment, false
+	}
+
+	if err := database.GetDB().First(&comment, "id = ? AND content_id = ?", id, contentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Comment not found",
+			"code":    "COMMENT_NOT_FOUND",
+			"message": "The requested comment was not found",
+		})
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// writeCommentPermissionDenied responds to a comment change the user may not make
+func writeCommentPermissionDenied(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "Comment permission denied",
+		"code":    "COMMENT_PERMISSION_DENIED",
+		"message": "You don't have permission to change this comment",
+	})
+}
+
+// broadcastComment notifies the content's room of a comment change
+func broadcastComment(hub *websocket.Hub, user *models.User, messageType string, comment models.Comment) {
+	hub.BroadcastToRoom(comment.ContentID.String(), websocket.Message{
+		Type:     messageType,
+		RoomID:   comment.ContentID.String(),
+		UserID:   user.ID.String(),
+		Username: user.Username,
+		Data: map[string]interface{}{
+			"comment": comment,
+		},
+		Timestamp: time.Now(),
+	})
+}
@@ -1,15 +1,34 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
 	"github.com/open-same/backend/internal/middleware"
 	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+	"github.com/open-same/backend/internal/webhook"
+	"github.com/open-same/backend/internal/websocket"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateContentRequest represents content creation request
@@ -18,11 +37,16 @@ type CreateContentRequest struct {
 	Description string                `json:"description"`
 	Content     string                `json:"content"`
 	Type        models.ContentType    `json:"type" binding:"required"`
+	Visibility  models.Visibility     `json:"visibility" binding:"omitempty,oneof=private link unlisted public"`
+	// IsPublic is superseded by Visibility and makes the content public when
+	// no visibility is given
 	IsPublic    bool                  `json:"is_public"`
 	IsTemplate  bool                  `json:"is_template"`
 	Tags        []string              `json:"tags"`
 	Metadata    map[string]interface{} `json:"metadata"`
 	ParentID    *string               `json:"parent_id"`
+	// PublishAt schedules the new draft to be published at a future time
+	PublishAt   *time.Time            `json:"publish_at"`
 }
 
 // UpdateContentRequest represents content update request
@@ -32,10 +56,19 @@ type UpdateContentRequest struct {
 	Content     *string                `json:"content"`
 	Type        *models.ContentType    `json:"type"`
 	Status      *models.ContentStatus  `json:"status"`
+	Visibility  *models.Visibility     `json:"visibility" binding:"omitempty,oneof=private link unlisted public"`
+	// IsPublic is superseded by Visibility. It only switches content between
+	// public and private and is ignored when a visibility is given.
 	IsPublic    *bool                  `json:"is_public"`
 	IsTemplate  *bool                  `json:"is_template"`
 	Tags        *[]string              `json:"tags"`
 	Metadata    *map[string]interface{} `json:"metadata"`
+	// PublishAt schedules a draft to be published at a future time. Setting
+	// the status without it cancels the schedule.
+	PublishAt   *time.Time             `json:"publish_at"`
+	// Version, when set, is the version the update was made against; the
+	// update is rejected if the content has changed since
+	Version     *int                   `json:"version" binding:"omitempty,min=1"`
 }
 
 // ContentListResponse represents paginated content list response
@@ -47,6 +80,58 @@ type ContentListResponse struct {
 	TotalPages  int              `json:"total_pages"`
 	HasNext     bool             `json:"has_next"`
 	HasPrevious bool             `json:"has_previous"`
+	NextCursor  string           `json:"next_cursor,omitempty"`
+}
+
+// contentCursor is the keyset position encoded in the opaque cursor token
+type contentCursor struct {
+	UpdatedAt time.Time `json:"u"`
+	ID        uuid.UUID `json:"i"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// errInvalidParentID is returned when new content names a malformed parent
+var errInvalidParentID = errors.New("invalid parent ID")
+
+// errEditPermissionDenied is returned when a user may not edit content
+var errEditPermissionDenied = errors.New("edit permission denied")
+
+// errVersionCreation is returned when content was saved but its version
+// could not be recorded
+var errVersionCreation = errors.New("failed to create content version")
+
+// errContentPreconditionFailed is returned when an update was made against
+// a stale version of content
+var errContentPreconditionFailed = errors.New("content precondition failed")
+
+// errInvalidPublishAt is returned when publishing is scheduled in the past
+// or for content that isn't a draft
+var errInvalidPublishAt = errors.New("invalid publish time")
+
+// maxTagFilters bounds the number of tags a listing can be filtered on
+const maxTagFilters = 20
+
+// TagCount represents how many content items carry a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// DiffChunk represents one run of equal, inserted or deleted text
+type DiffChunk struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// VersionDiffResponse represents the differences between two content versions
+type VersionDiffResponse struct {
+	ContentID     uuid.UUID   `json:"content_id"`
+	From          int         `json:"from"`
+	To            int         `json:"to"`
+	Granularity   string      `json:"granularity"`
+	ChangedFields []string    `json:"changed_fields"`
+	Diff          []DiffChunk `json:"diff"`
 }
 
 // CreateContent handles content creation
@@ -72,22 +157,60 @@ func CreateContent(c *gin.Context) {
 		return
 	}
 
-	// Parse parent ID if provided
-	var parentID *uuid.UUID
-	if req.ParentID != nil {
-		parsedID, err := uuid.Parse(*req.ParentID)
-		if err != nil {
+	content, err := createContent(c.Request.Context(), user, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errTierLimitReached):
+			respondTierLimitReached(c, user, features.Content)
+		case errors.Is(err, errInvalidParentID):
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "Invalid parent ID",
 				"code":    "INVALID_PARENT_ID",
 				"message": "Parent ID must be a valid UUID",
 			})
-			return
+		case errors.Is(err, errInvalidPublishAt):
+			respondInvalidPublishAt(c)
+		case errors.Is(err, errVersionCreation):
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create content version",
+				"code":    "VERSION_CREATION_ERROR",
+				"message": "Content created but version tracking failed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while creating content",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Content created successfully",
+		"data":    content,
+	})
+}
+
+// createContent stores new content of a user with its first version and
+// notifies watchers of the creation. It returns errTierLimitReached when
+// the user's tier allows no more content.
+func createContent(ctx context.Context, user *models.User, req CreateContentRequest) (models.Content, error) {
+	var parentID *uuid.UUID
+	if req.ParentID != nil {
+		parsedID, err := uuid.Parse(*req.ParentID)
+		if err != nil {
+			return models.Content{}, errInvalidParentID
 		}
 		parentID = &parsedID
 	}
+	if req.PublishAt != nil && !req.PublishAt.After(time.Now()) {
+		return models.Content{}, errInvalidPublishAt
+	}
+	if err := allowFeature(ctx, user, features.Content, 1); err != nil {
+		return models.Content{}, err
+	}
 
-	// Create content
 	content := models.Content{
 		UserID:      user.ID,
 		Title:       req.Title,
@@ -95,22 +218,19 @@ func CreateContent(c *gin.Context) {
 		Content:     req.Content,
 		Type:        req.Type,
 		Status:      models.ContentStatusDraft,
+		Visibility:  req.Visibility,
 		IsPublic:    req.IsPublic,
 		IsTemplate:  req.IsTemplate,
 		Tags:        req.Tags,
 		Metadata:    models.JSON(req.Metadata),
 		ParentID:    parentID,
+		PublishAt:   req.PublishAt,
 		Version:     1,
 	}
 
-	// Save content to database
-	if err := database.GetDB().Create(&content).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to create content",
-			"code":    "DATABASE_ERROR",
-			"message": "An error occurred while creating content",
-		})
-		return
+	db := database.GetDB().WithContext(ctx)
+	if err := db.Create(&content).Error; err != nil {
+		return models.Content{}, err
 	}
 
 	// Create initial version
@@ -122,25 +242,20 @@ func CreateContent(c *gin.Context) {
 		Description: content.Description,
 		Tags:        content.Tags,
 		Metadata:    content.Metadata,
-		CreatedBy:   user.ID,
+		CreatedBy:   &user.ID,
 	}
-
-	if err := database.GetDB().Create(&version).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to create content version",
-			"code":    "VERSION_CREATION_ERROR",
-			"message": "Content created but version tracking failed",
-		})
-		return
+	if err := db.Create(&version).Error; err != nil {
+		return content, fmt.Errorf("%w: %v", errVersionCreation, err)
 	}
 
 	// Load relationships
-	database.GetDB().Preload("User").First(&content, content.ID)
+	db.Preload("User").First(&content, content.ID)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Content created successfully",
-		"data":    content,
-	})
+	invalidateContentCache(ctx, content.ID)
+	recordActivity(content.ID, user.ID, models.ActivityContentCreated, nil)
+	webhook.Dispatch(content.UserID, models.WebhookEventContentCreated, content)
+	indexContentEmbedding(content)
+	return content, nil
 }
 
 // GetContent handles content retrieval
@@ -167,8 +282,8 @@ func GetContent(c *gin.Context) {
 	}
 
 	// Get content with relationships
-	var content models.Content
-	if err := database.GetDB().Preload("User").Preload("Versions").Preload("Collaborations.User").First(&content, "id = ?", id).Error; err != nil {
+	content, err := loadContent(c.Request.Context(), id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Content not found",
 			"code":    "CONTENT_NOT_FOUND",
@@ -177,28 +292,37 @@ func GetContent(c *gin.Context) {
 		return
 	}
 
-	// Check if user can access this content
+	// Check if user can access this content, public content can be
+	// accessed without authentication
 	user, exists := middleware.GetUserFromContext(c)
-	if !exists {
-		// Public content can be accessed without authentication
-		if !content.IsPublic {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Access denied",
-				"code":    "ACCESS_DENIED",
-				"message": "You don't have permission to access this content",
-			})
-			return
-		}
-	} else {
-		// Check if user owns the content or is a collaborator
-		if content.UserID != user.ID && !content.IsCollaborator(user.ID) && !content.IsPublic {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Access denied",
-				"code":    "ACCESS_DENIED",
-				"message": "You don't have permission to access this content",
-			})
-			return
-		}
+	if !models.Authorize(user, &content, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to access this content",
+		})
+		return
+	}
+
+	if !exists || content.UserID != user.ID {
+		recordContentView(c, content.ID)
+	}
+
+	etag := contentETag(content)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if counts, err := reactionCounts([]uuid.UUID{content.ID}); err == nil {
+		content.ReactionCounts = counts[content.ID]
+	}
+	if exists {
+		var favorites int64
+		database.GetDB().Model(&models.Favorite{}).Where("user_id = ? AND content_id = ?", user.ID, content.ID).Count(&favorites)
+		isFavorited := favorites > 0
+		content.IsFavorited = &isFavorited
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -225,7 +349,11 @@ func GetUserContent(c *gin.Context) {
 	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
 	contentType := c.Query("type")
 	status := c.Query("status")
+	tags := c.Query("tags")
+	tagMode := c.Query("tag_mode")
 	search := c.Query("search")
+	searchMode := c.Query("search_mode")
+	cursor, useCursor := c.GetQuery("cursor")
 
 	// Validate pagination
 	if page < 1 {
@@ -244,9 +372,59 @@ func GetUserContent(c *gin.Context) {
 	}
 	if status != "" {
 		query = query.Where("status = ?", status)
+	} else if c.Query("include_archived") != "true" {
+		query = query.Where("status <> ?", models.ContentStatusArchived)
+	}
+	if tags != "" {
+		tagQuery, err := applyContentTagFilter(query, tags, tagMode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid tag filter",
+				"code":    "INVALID_TAG_FILTER",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = tagQuery
 	}
 	if search != "" {
-		query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+		searchQuery, err := applyContentSearch(query, search, searchMode, !useCursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid search mode",
+				"code":    "INVALID_SEARCH_MODE",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = searchQuery
+	}
+
+	if useCursor {
+		response, err := listContentByCursor(query, cursor, perPage)
+		if err != nil {
+			if errors.Is(err, errInvalidCursor) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid cursor",
+					"code":    "INVALID_CURSOR",
+					"message": "The cursor is malformed or expired",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving content",
+			})
+			return
+		}
+		attachFavorites(c, response.Contents)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content retrieved successfully",
+			"data":    response,
+		})
+		return
 	}
 
 	// Get total count
@@ -267,6 +445,8 @@ func GetUserContent(c *gin.Context) {
 		})
 		return
 	}
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
 
 	response := ContentListResponse{
 		Contents:    contents,
@@ -285,69 +465,136 @@ func GetUserContent(c *gin.Context) {
 }
 
 // UpdateContent handles content updates
-func UpdateContent(c *gin.Context) {
-	contentID := c.Param("id")
-	if contentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Content ID required",
-			"code":    "MISSING_CONTENT_ID",
-			"message": "Content ID is required",
-		})
-		return
-	}
+func UpdateContent(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contentID := c.Param("id")
+		if contentID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Content ID required",
+				"code":    "MISSING_CONTENT_ID",
+				"message": "Content ID is required",
+			})
+			return
+		}
 
-	// Parse content ID
-	id, err := uuid.Parse(contentID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid content ID",
-			"code":    "INVALID_CONTENT_ID",
-			"message": "Content ID must be a valid UUID",
-		})
-		return
-	}
+		// Parse content ID
+		id, err := uuid.Parse(contentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid content ID",
+				"code":    "INVALID_CONTENT_ID",
+				"message": "Content ID must be a valid UUID",
+			})
+			return
+		}
 
-	var req UpdateContentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request data",
-			"code":    "INVALID_REQUEST",
-			"message": err.Error(),
+		var req UpdateContentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		content, err := updateContent(c.Request.Context(), hub, id, user.ID, req, c.GetHeader("If-Match"))
+		if err != nil {
+			switch {
+			case errors.Is(err, redis.ErrLockNotAcquired):
+				respondContentLocked(c)
+			case errors.Is(err, errContentLocked):
+				respondContentLockedBy(c, content)
+			case errors.Is(err, errContentPreconditionFailed):
+				c.Header("ETag", contentETag(content))
+				c.JSON(http.StatusPreconditionFailed, gin.H{
+					"error":   "Precondition failed",
+					"code":    "PRECONDITION_FAILED",
+					"message": fmt.Sprintf("The content has changed since it was read and is now at version %d", content.Version),
+				})
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{
+					"error":   "Content not found",
+					"code":    "CONTENT_NOT_FOUND",
+					"message": "The requested content was not found",
+				})
+			case errors.Is(err, errEditPermissionDenied):
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "Edit permission denied",
+					"code":    "EDIT_PERMISSION_DENIED",
+					"message": "You don't have permission to edit this content",
+				})
+			case errors.Is(err, errInvalidPublishAt):
+				respondInvalidPublishAt(c)
+			case errors.Is(err, errVersionCreation):
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to create content version",
+					"code":    "VERSION_CREATION_ERROR",
+					"message": "Content updated but version tracking failed",
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to update content",
+					"code":    "DATABASE_ERROR",
+					"message": "An error occurred while updating content",
+				})
+			}
+			return
+		}
+
+		c.Header("ETag", contentETag(content))
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content updated successfully",
+			"data":    content,
 		})
-		return
 	}
+}
 
-	// Get user from context
-	user, exists := middleware.GetUserFromContext(c)
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "User context not found",
-			"code":    "MISSING_USER_CONTEXT",
-			"message": "Internal server error",
-		})
-		return
+// updateContent applies the fields set in req to content on behalf of a
+// user, recording a new version and notifying watchers. It returns
+// redis.ErrLockNotAcquired while another writer saves the content,
+// gorm.ErrRecordNotFound for unknown content, errEditPermissionDenied when
+// the user may not edit it, errContentLocked, with the content, when another
+// user holds its editing lock and errInvalidPublishAt when publishing is
+// scheduled in the past or for content that stays published or archived.
+// An update whose If-Match header or version doesn't match the current
+// content fails with errContentPreconditionFailed, returning the current
+// content. A new version is pushed to the content's live room through hub.
+func updateContent(ctx context.Context, hub *websocket.Hub, id, userID uuid.UUID, req UpdateContentRequest, ifMatch string) (models.Content, error) {
+	// Only one writer commits a version of content at a time
+	unlock, err := lockContentWrites(ctx, id)
+	if err != nil {
+		return models.Content{}, err
 	}
+	defer unlock()
 
-	// Get content
+	db := database.GetDB().WithContext(ctx)
 	var content models.Content
-	if err := database.GetDB().First(&content, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Content not found",
-			"code":    "CONTENT_NOT_FOUND",
-			"message": "The requested content was not found",
-		})
-		return
+	if err := db.Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		return models.Content{}, err
 	}
-
-	// Check if user can edit this content
-	if !content.CanEdit(user.ID) {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "Edit permission denied",
-			"code":    "EDIT_PERMISSION_DENIED",
-			"message": "You don't have permission to edit this content",
-		})
-		return
+	if !content.CanEdit(userID) {
+		return models.Content{}, errEditPermissionDenied
+	}
+	if content.IsLockedFor(userID) {
+		return content, errContentLocked
 	}
+	if (ifMatch != "" && !etagMatches(ifMatch, contentETag(content))) || (req.Version != nil && *req.Version != content.Version) {
+		return content, errContentPreconditionFailed
+	}
+
+	wasPublished := content.Status == models.ContentStatusPublished
 
 	// Create new version if content changed
 	contentChanged := false
@@ -356,59 +603,92 @@ func UpdateContent(c *gin.Context) {
 	}
 
 	// Update fields
+	var updatedFields []string
 	if req.Title != nil {
+		updatedFields = append(updatedFields, "title")
 		content.Title = *req.Title
 		contentChanged = true
 	}
 	if req.Description != nil {
+		updatedFields = append(updatedFields, "description")
 		content.Description = *req.Description
 		contentChanged = true
 	}
 	if req.Content != nil {
+		updatedFields = append(updatedFields, "content")
 		content.Content = *req.Content
 		contentChanged = true
 	}
 	if req.Type != nil {
+		updatedFields = append(updatedFields, "type")
 		content.Type = *req.Type
 		contentChanged = true
 	}
 	if req.Status != nil {
+		updatedFields = append(updatedFields, "status")
 		content.Status = *req.Status
 		contentChanged = true
 	}
-	if req.IsPublic != nil {
-		content.IsPublic = *req.IsPublic
+	if req.PublishAt != nil {
+		if !req.PublishAt.After(time.Now()) || content.Status != models.ContentStatusDraft {
+			return models.Content{}, errInvalidPublishAt
+		}
+		updatedFields = append(updatedFields, "publish_at")
+		content.PublishAt = req.PublishAt
+		contentChanged = true
+	} else if req.Status != nil && content.PublishAt != nil {
+		// Setting the status by hand replaces the schedule
+		updatedFields = append(updatedFields, "publish_at")
+		content.PublishAt = nil
+	}
+	if req.Visibility != nil {
+		updatedFields = append(updatedFields, "visibility")
+		content.Visibility = *req.Visibility
+		contentChanged = true
+	} else if req.IsPublic != nil {
+		updatedFields = append(updatedFields, "visibility")
+		if *req.IsPublic {
+			content.Visibility = models.VisibilityPublic
+		} else if content.Visibility == models.VisibilityPublic {
+			content.Visibility = models.VisibilityPrivate
+		}
 		contentChanged = true
 	}
 	if req.IsTemplate != nil {
+		updatedFields = append(updatedFields, "is_template")
 		content.IsTemplate = *req.IsTemplate
 		contentChanged = true
 	}
 	if req.Tags != nil {
+		updatedFields = append(updatedFields, "tags")
 		content.Tags = *req.Tags
 		contentChanged = true
 	}
 	if req.Metadata != nil {
+		updatedFields = append(updatedFields, "metadata")
 		content.Metadata = models.JSON(*req.Metadata)
 		contentChanged = true
 	}
 
 	// Update timestamp
 	content.UpdatedAt = time.Now()
+	if contentChanged {
+		content.Version++
+	}
 
-	// Save content
-	if err := database.GetDB().Save(&content).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update content",
-			"code":    "DATABASE_ERROR",
-			"message": "An error occurred while updating content",
-		})
-		return
+	if err := db.Omit(append([]string{clause.Associations}, contentLockColumns...)...).Save(&content).Error; err != nil {
+		return models.Content{}, err
+	}
+
+	// Editing keeps the editor's lock from going idle
+	if content.IsLocked() {
+		if _, err := renewContentLock(ctx, content.ID, userID); err != nil {
+			log.Printf("Failed to renew lock of content %s: %v", content.ID, err)
+		}
 	}
 
 	// Create new version if content changed
 	if contentChanged {
-		content.Version++
 		version := models.ContentVersion{
 			ContentID:   content.ID,
 			Version:     content.Version,
@@ -417,71 +697,462 @@ func UpdateContent(c *gin.Context) {
 			Description: content.Description,
 			Tags:        content.Tags,
 			Metadata:    content.Metadata,
-			CreatedBy:   user.ID,
+			CreatedBy:   &userID,
 		}
-
-		if err := database.GetDB().Create(&version).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to create content version",
-				"code":    "VERSION_CREATION_ERROR",
-				"message": "Content updated but version tracking failed",
-			})
-			return
+		if err := db.Create(&version).Error; err != nil {
+			return content, fmt.Errorf("%w: %v", errVersionCreation, err)
 		}
+
+		// Keep live editors from saving over the update
+		hub.SyncRoomContent(content.ID.String(), content.Content, userID.String(), int64(content.Version))
 	}
 
 	// Load relationships
-	database.GetDB().Preload("User").First(&content, content.ID)
+	db.Preload("User").First(&content, content.ID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Content updated successfully",
-		"data":    content,
+	invalidateContentCache(ctx, content.ID)
+	recordActivity(content.ID, userID, models.ActivityContentUpdated, models.JSON{
+		"fields":  updatedFields,
+		"version": content.Version,
 	})
+	webhook.Dispatch(content.UserID, models.WebhookEventContentUpdated, content)
+	if !wasPublished && content.Status == models.ContentStatusPublished {
+		webhook.Dispatch(content.UserID, models.WebhookEventContentPublished, content)
+	}
+	indexContentEmbedding(content)
+	return content, nil
 }
 
-// DeleteContent handles content deletion
-func DeleteContent(c *gin.Context) {
-	contentID := c.Param("id")
-	if contentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Content ID required",
-			"code":    "MISSING_CONTENT_ID",
-			"message": "Content ID is required",
-		})
-		return
-	}
+// RestoreContentVersion restores content to a previous version. The restore
+// is recorded as a new version so history is never rewritten.
+func RestoreContentVersion(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid content ID",
+				"code":    "INVALID_CONTENT_ID",
+				"message": "Content ID must be a valid UUID",
+			})
+			return
+		}
 
-	// Parse content ID
-	id, err := uuid.Parse(contentID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid content ID",
-			"code":    "INVALID_CONTENT_ID",
-			"message": "Content ID must be a valid UUID",
-		})
-		return
-	}
+		versionNumber, err := strconv.Atoi(c.Param("version"))
+		if err != nil || versionNumber < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid version",
+				"code":    "INVALID_VERSION",
+				"message": "Version must be a positive integer",
+			})
+			return
+		}
 
-	// Get user from context
-	user, exists := middleware.GetUserFromContext(c)
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "User context not found",
-			"code":    "MISSING_USER_CONTEXT",
-			"message": "Internal server error",
-		})
-		return
-	}
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
 
-	// Get content
-	var content models.Content
-	if err := database.GetDB().First(&content, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Content not found",
-			"code":    "CONTENT_NOT_FOUND",
-			"message": "The requested content was not found",
-		})
-		return
+		// Only one writer commits a version of content at a time
+		unlock, err := lockContentWrites(c.Request.Context(), id)
+		if err != nil {
+			respondContentLocked(c)
+			return
+		}
+		defer unlock()
+
+		// Get content with collaborators for the permission check
+		var content models.Content
+		if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Content not found",
+				"code":    "CONTENT_NOT_FOUND",
+				"message": "The requested content was not found",
+			})
+			return
+		}
+
+		if !content.CanEdit(user.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Edit permission denied",
+				"code":    "EDIT_PERMISSION_DENIED",
+				"message": "You don't have permission to edit this content",
+			})
+			return
+		}
+		if content.IsLockedFor(user.ID) {
+			respondContentLockedBy(c, content)
+			return
+		}
+
+		var version models.ContentVersion
+		if err := database.GetDB().Where("content_id = ? AND version = ?", content.ID, versionNumber).First(&version).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Version not found",
+				"code":    "VERSION_NOT_FOUND",
+				"message": "The requested version was not found",
+			})
+			return
+		}
+
+		// Copy the old revision into the live content and record it as a new version
+		content.Title = version.Title
+		content.Description = version.Description
+		content.Content = version.Content
+		content.Tags = version.Tags
+		content.Metadata = version.Metadata
+		content.Version++
+		content.UpdatedAt = time.Now()
+
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit(append([]string{clause.Associations}, contentLockColumns...)...).Save(&content).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.ContentVersion{
+				ContentID:   content.ID,
+				Version:     content.Version,
+				Content:     content.Content,
+				Title:       content.Title,
+				Description: content.Description,
+				Tags:        content.Tags,
+				Metadata:    content.Metadata,
+				CreatedBy:   &user.ID,
+			}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to restore version",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while restoring the version",
+			})
+			return
+		}
+
+		// Keep live editors from saving over the restored version
+		hub.SyncRoomContent(content.ID.String(), content.Content, user.ID.String(), int64(content.Version))
+
+		// Load relationships
+		database.GetDB().Preload("User").First(&content, content.ID)
+
+		invalidateContentCache(c.Request.Context(), content.ID)
+		recordActivity(content.ID, user.ID, models.ActivityVersionRestored, models.JSON{
+			"restored_from": versionNumber,
+			"version":       content.Version,
+		})
+		webhook.Dispatch(content.UserID, models.WebhookEventContentUpdated, content)
+		indexContentEmbedding(content)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Content version restored successfully",
+			"restored_from": versionNumber,
+			"data":          content,
+		})
+	}
+}
+
+// ForkContent copies content the user can read into a new draft owned by the
+// user, linked to its source through ParentID
+func ForkContent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var source models.Content
+	if err := database.GetDB().Preload("Collaborations").First(&source, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if !models.Authorize(user, &source, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to fork this content",
+		})
+		return
+	}
+
+	fork, err := forkContent(c.Request.Context(), source, user)
+	if errors.Is(err, errTierLimitReached) {
+		respondTierLimitReached(c, user, features.Content)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fork content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while forking content",
+		})
+		return
+	}
+
+	recordActivity(fork.ID, user.ID, models.ActivityContentCreated, models.JSON{
+		"forked_from": source.ID,
+	})
+	webhook.Dispatch(fork.UserID, models.WebhookEventContentCreated, fork)
+	indexContentEmbedding(fork)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Content forked successfully",
+		"data":    fork,
+	})
+}
+
+// forkContent copies source into a new private draft owned by user, linked
+// to source through ParentID. It returns errTierLimitReached when the
+// user's tier allows no more content.
+func forkContent(ctx context.Context, source models.Content, user *models.User) (models.Content, error) {
+	if err := allowFeature(ctx, user, features.Content, 1); err != nil {
+		return models.Content{}, err
+	}
+
+	// Copy tags and metadata so the fork never shares them with its source
+	var tags []string
+	if source.Tags != nil {
+		tags = append([]string{}, source.Tags...)
+	}
+	var metadata models.JSON
+	if source.Metadata != nil {
+		metadata = make(models.JSON, len(source.Metadata))
+		for key, value := range source.Metadata {
+			metadata[key] = value
+		}
+	}
+
+	// Forks start as private drafts and a forked template becomes regular content
+	fork := models.Content{
+		UserID:      user.ID,
+		Title:       source.Title,
+		Description: source.Description,
+		Content:     source.Content,
+		Type:        source.Type,
+		Status:      models.ContentStatusDraft,
+		Visibility:  models.VisibilityPrivate,
+		IsTemplate:  false,
+		Tags:        tags,
+		Metadata:    metadata,
+		AIGenerated: source.AIGenerated,
+		AIModel:     source.AIModel,
+		AIPrompt:    source.AIPrompt,
+		ParentID:    &source.ID,
+		Version:     1,
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fork).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ContentVersion{
+			ContentID:   fork.ID,
+			Version:     1,
+			Content:     fork.Content,
+			Title:       fork.Title,
+			Description: fork.Description,
+			Tags:        fork.Tags,
+			Metadata:    fork.Metadata,
+			CreatedBy:   &user.ID,
+		}).Error
+	})
+	if err != nil {
+		return fork, err
+	}
+
+	// Load relationships
+	database.GetDB().Preload("User").First(&fork, fork.ID)
+
+	return fork, nil
+}
+
+// DiffContentVersions returns the differences between two versions of content
+func DiffContentVersions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil || from < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid version",
+			"code":    "INVALID_VERSION",
+			"message": "Both from and to must be positive integers",
+		})
+		return
+	}
+	if from >= to {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid version range",
+			"code":    "INVALID_VERSION_RANGE",
+			"message": "The from version must be lower than the to version",
+		})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "line")
+	if granularity != "line" && granularity != "word" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid granularity",
+			"code":    "INVALID_GRANULARITY",
+			"message": "Granularity must be line or word",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if !models.Authorize(user, &content, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to access this content",
+		})
+		return
+	}
+
+	// Both versions must belong to this content
+	var versions []models.ContentVersion
+	if err := database.GetDB().Where("content_id = ? AND version IN ?", content.ID, []int{from, to}).Order("version ASC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve versions",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving versions",
+		})
+		return
+	}
+	if len(versions) != 2 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Version not found",
+			"code":    "VERSION_NOT_FOUND",
+			"message": "One or both of the requested versions were not found",
+		})
+		return
+	}
+	oldVersion, newVersion := versions[0], versions[1]
+
+	changedFields := []string{}
+	if oldVersion.Title != newVersion.Title {
+		changedFields = append(changedFields, "title")
+	}
+	if oldVersion.Description != newVersion.Description {
+		changedFields = append(changedFields, "description")
+	}
+	if oldVersion.Content != newVersion.Content {
+		changedFields = append(changedFields, "content")
+	}
+	if strings.Join(oldVersion.Tags, ",") != strings.Join(newVersion.Tags, ",") {
+		changedFields = append(changedFields, "tags")
+	}
+	if !reflect.DeepEqual(oldVersion.Metadata, newVersion.Metadata) {
+		changedFields = append(changedFields, "metadata")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Version diff generated successfully",
+		"data": VersionDiffResponse{
+			ContentID:     content.ID,
+			From:          from,
+			To:            to,
+			Granularity:   granularity,
+			ChangedFields: changedFields,
+			Diff:          diffText(oldVersion.Content, newVersion.Content, granularity),
+		},
+	})
+}
+
+// DeleteContent handles content deletion
+func DeleteContent(c *gin.Context) {
+	contentID := c.Param("id")
+	if contentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Content ID required",
+			"code":    "MISSING_CONTENT_ID",
+			"message": "Content ID is required",
+		})
+		return
+	}
+
+	// Parse content ID
+	id, err := uuid.Parse(contentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Get content
+	var content models.Content
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
 	}
 
 	// Check if user can delete this content
@@ -504,6 +1175,9 @@ func DeleteContent(c *gin.Context) {
 		return
 	}
 
+	invalidateContentCache(c.Request.Context(), content.ID)
+	recordActivity(content.ID, user.ID, models.ActivityContentDeleted, nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Content deleted successfully",
 	})
@@ -515,7 +1189,11 @@ func GetPublicContent(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
 	contentType := c.Query("type")
+	tags := c.Query("tags")
+	tagMode := c.Query("tag_mode")
 	search := c.Query("search")
+	searchMode := c.Query("search_mode")
+	cursor, useCursor := c.GetQuery("cursor")
 
 	// Validate pagination
 	if page < 1 {
@@ -526,14 +1204,77 @@ func GetPublicContent(c *gin.Context) {
 	}
 
 	// Build query for public content
-	query := database.GetDB().Model(&models.Content{}).Where("is_public = ? AND status = ?", true, models.ContentStatusPublished)
+	query := publiclyListed(database.GetDB().Model(&models.Content{}))
 
 	// Apply filters
 	if contentType != "" {
 		query = query.Where("type = ?", contentType)
 	}
+	if tags != "" {
+		tagQuery, err := applyContentTagFilter(query, tags, tagMode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid tag filter",
+				"code":    "INVALID_TAG_FILTER",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = tagQuery
+	}
 	if search != "" {
-		query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+		searchQuery, err := applyContentSearch(query, search, searchMode, !useCursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid search mode",
+				"code":    "INVALID_SEARCH_MODE",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = searchQuery
+	}
+
+	if useCursor {
+		response, err := listContentByCursor(query, cursor, perPage)
+		if err != nil {
+			if errors.Is(err, errInvalidCursor) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid cursor",
+					"code":    "INVALID_CURSOR",
+					"message": "The cursor is malformed or expired",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving content",
+			})
+			return
+		}
+		attachFavorites(c, response.Contents)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Public content retrieved successfully",
+			"data":    response,
+		})
+		return
+	}
+
+	// Serve the page from the cache when it is there
+	cacheKey, cached := publicContentCacheKey(c)
+	if cached {
+		if response, ok := getCachedPublicContent(c.Request.Context(), cacheKey); ok {
+			attachReactionCounts(response.Contents)
+			attachFavorites(c, response.Contents)
+
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Public content retrieved successfully",
+				"data":    response,
+			})
+			return
+		}
 	}
 
 	// Get total count
@@ -564,9 +1305,522 @@ func GetPublicContent(c *gin.Context) {
 		HasNext:     page < totalPages,
 		HasPrevious: page > 1,
 	}
+	if cached {
+		cachePublicContent(c.Request.Context(), cacheKey, response)
+	}
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Public content retrieved successfully",
 		"data":    response,
 	})
-}
\ No newline at end of file
+}
+
+// GetContentPresence returns the users currently connected to a content's
+// real-time room, on every instance when Redis presence is enabled, with
+// typing state and last activity, and how many connections the room holds
+// on this instance out of how many it admits
+func GetContentPresence(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid content ID",
+				"code":    "INVALID_CONTENT_ID",
+				"message": "Content ID must be a valid UUID",
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		allowed, err := CanAccessContentRoom(user.ID.String(), id.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to check access",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while checking content access",
+			})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Access denied",
+				"code":    "ACCESS_DENIED",
+				"message": "You don't have permission to access this content",
+			})
+			return
+		}
+
+		// Presence of other instances is best effort; fall back to this one
+		presence, err := hub.GetClusterPresence(c.Request.Context(), id.String())
+		if err != nil {
+			log.Printf("Failed to read presence of room %s: %v", id, err)
+			presence = hub.GetRoomPresence(id.String())
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Presence retrieved successfully",
+			"data":      presence,
+			"occupancy": hub.GetRoomOccupancy(id.String()),
+		})
+	}
+}
+
+// CanAccessContentRoom reports whether a user may join the real-time room of
+// a content item. Rooms are keyed by content ID and admit the owner,
+// collaborators and, for public content, any authenticated user.
+func CanAccessContentRoom(userID, roomID string) (bool, error) {
+	return authorizeContentRoom(userID, roomID, models.PermissionView)
+}
+
+// CanEditContentRoom reports whether a user may change the content of the
+// real-time room of a content item, which takes the edit permission
+func CanEditContentRoom(userID, roomID string) (bool, error) {
+	return authorizeContentRoom(userID, roomID, models.PermissionEdit)
+}
+
+// authorizeContentRoom reports whether a user may take action on the
+// content a room is keyed by
+func authorizeContentRoom(userID, roomID string, action models.Permission) (bool, error) {
+	contentID, err := uuid.Parse(roomID)
+	if err != nil {
+		return false, nil
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return false, nil
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", contentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return models.Authorize(&models.User{ID: uid}, &content, action), nil
+}
+
+// ContentRoomStore persists the live content of real-time collaboration rooms
+type ContentRoomStore struct{}
+
+// LoadRoomContent returns the saved body and version of the content a room
+// edits
+func (ContentRoomStore) LoadRoomContent(roomID string) (string, int64, error) {
+	var content models.Content
+	if err := database.GetDB().Select("content", "version").First(&content, "id = ?", roomID).Error; err != nil {
+		return "", 0, err
+	}
+	return content.Content, int64(content.Version), nil
+}
+
+// SaveRoomContent saves the live body of the content a room edits and
+// records it as a new version attributed to the last editor. The version is
+// the room's, which is ahead of the stored one by the changes made since.
+// The body is refused with websocket.ErrStaleRoomContent when the content
+// was changed outside the room since the room's base version, so a room
+// never saves over a REST write it has not seen.
+func (ContentRoomStore) SaveRoomContent(roomID, body, userID string, baseVersion, version int64) (int64, error) {
+	editorID, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid editor ID: %w", err)
+	}
+
+	// Only one writer commits a version of content at a time; a locked
+	// room stays dirty and is saved on the next flush
+	contentID, err := uuid.Parse(roomID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid room ID: %w", err)
+	}
+	unlock, err := lockContentWrites(context.Background(), contentID)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var content models.Content
+	changed := false
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&content, "id = ?", roomID).Error; err != nil {
+			return err
+		}
+		if content.Content == body {
+			return nil
+		}
+		if int64(content.Version) != baseVersion {
+			return websocket.ErrStaleRoomContent
+		}
+		changed = true
+
+		content.Content = body
+		if int(version) > content.Version {
+			content.Version = int(version)
+		} else {
+			content.Version++
+		}
+		content.UpdatedAt = time.Now()
+		if err := tx.Omit(clause.Associations).Save(&content).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.ContentVersion{
+			ContentID:   content.ID,
+			Version:     content.Version,
+			Content:     content.Content,
+			Title:       content.Title,
+			Description: content.Description,
+			Tags:        content.Tags,
+			Metadata:    content.Metadata,
+			CreatedBy:   &editorID,
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	if changed {
+		invalidateContentCache(context.Background(), content.ID)
+		indexContentEmbedding(content)
+	}
+	return int64(content.Version), nil
+}
+
+// contentWriteLockTTL bounds how long a writer holds the lock of content, so
+// a crashed writer cannot block others
+const contentWriteLockTTL = 15 * time.Second
+
+// contentWriteLockWait is how long a writer queues for the lock of content
+const contentWriteLockWait = 2 * time.Second
+
+// lockContentWrites takes the single-writer lock of content, queuing briefly
+// when another writer holds it. It returns redis.ErrLockNotAcquired when the
+// lock stays held. Redis failures are logged and the write proceeds unlocked
+// so an outage does not block editing.
+func lockContentWrites(ctx context.Context, contentID uuid.UUID) (func(), error) {
+	lock, err := redis.AcquireLock(ctx, "lock:content:"+contentID.String(), contentWriteLockTTL, contentWriteLockWait)
+	if errors.Is(err, redis.ErrLockNotAcquired) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, redis.ErrLockNotAcquired
+	}
+	if err != nil {
+		log.Printf("Failed to lock content %s, writing unlocked: %v", contentID, err)
+		return func() {}, nil
+	}
+
+	return func() {
+		if err := redis.ReleaseLock(context.Background(), lock); err != nil {
+			log.Printf("Failed to release lock of content %s: %v", contentID, err)
+		}
+	}, nil
+}
+
+// contentETag identifies a revision of content for conditional requests
+func contentETag(content models.Content) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", content.ID, content.Version, content.UpdatedAt.UnixNano())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists
+// etag. Weak validators compare by their opaque tag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// publiclyListed narrows a content query to content anyone may find:
+// public, published and past its scheduled publish time. Unlisted and link
+// content is never listed.
+func publiclyListed(query *gorm.DB) *gorm.DB {
+	return query.Where("contents.visibility = ? AND contents.status = ?", models.VisibilityPublic, models.ContentStatusPublished).
+		Where("contents.publish_at IS NULL OR contents.publish_at <= ?", time.Now())
+}
+
+// respondContentLocked writes the response for content another writer is saving
+func respondContentLocked(c *gin.Context) {
+	c.JSON(http.StatusLocked, gin.H{
+		"error":   "Content locked",
+		"code":    "CONTENT_LOCKED",
+		"message": "The content is being saved by another writer, please try again",
+	})
+}
+
+// applyContentSearch filters a content query by a search term. The default
+// "fulltext" mode matches plain words against the search vector, "advanced"
+// accepts tsquery syntax (e.g. "go & !java"), and both rank results with
+// ts_rank unless rank is false. The "simple" mode keeps the ILIKE match on
+// title and description.
+func applyContentSearch(query *gorm.DB, search, mode string, rank bool) (*gorm.DB, error) {
+	var tsquery string
+	switch mode {
+	case "", "fulltext":
+		tsquery = "plainto_tsquery('english', ?)"
+	case "advanced":
+		tsquery = "to_tsquery('english', ?)"
+	case "simple":
+		return query.Where("title ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%"), nil
+	default:
+		return nil, fmt.Errorf("unsupported search mode %q, use fulltext, advanced or simple", mode)
+	}
+
+	query = query.Where("search_vector @@ "+tsquery, search)
+	if !rank {
+		return query, nil
+	}
+
+	return query.
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(search_vector, " + tsquery + ") DESC",
+			Vars:               []interface{}{search},
+			WithoutParentheses: true,
+		}}), nil
+}
+
+// GetTagCloud lists the most used tags with their counts. Tags come from the
+// caller's own content by default, or from published public content with
+// ?scope=public.
+func GetTagCloud(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := database.GetDB().Table("contents, unnest(contents.tags) AS tag").
+		Where("contents.deleted_at IS NULL")
+	switch scope := c.DefaultQuery("scope", "mine"); scope {
+	case "mine":
+		query = query.Where("contents.user_id = ?", user.ID)
+	case "public":
+		query = query.Where("contents.visibility = ? AND contents.status = ?", models.VisibilityPublic, models.ContentStatusPublished)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid scope",
+			"code":    "INVALID_SCOPE",
+			"message": "Scope must be mine or public",
+		})
+		return
+	}
+
+	tags := []TagCount{}
+	if err := query.Select("tag, COUNT(*) AS count").
+		Group("tag").
+		Order("count DESC, tag ASC").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve tags",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags retrieved successfully",
+		"data":    tags,
+	})
+}
+
+// applyContentTagFilter narrows query to content carrying the comma separated
+// tags. The "all" mode, the default, requires every tag while "any" accepts
+// content with at least one of them.
+func applyContentTagFilter(query *gorm.DB, tags, mode string) (*gorm.DB, error) {
+	var values []string
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsString(values, tag) {
+			values = append(values, tag)
+		}
+	}
+	if len(values) == 0 {
+		return query, nil
+	}
+	if len(values) > maxTagFilters {
+		return nil, fmt.Errorf("at most %d tags can be filtered on", maxTagFilters)
+	}
+
+	switch mode {
+	case "", "all":
+		return query.Where("tags @> ?::text[]", pq.Array(values)), nil
+	case "any":
+		return query.Where("tags && ?::text[]", pq.Array(values)), nil
+	default:
+		return nil, fmt.Errorf("unsupported tag mode %q, use all or any", mode)
+	}
+}
+
+// listContentByCursor returns one page of content using keyset pagination on
+// (updated_at, id). Unlike page based pagination it stays fast on deep lists
+// and does not skip or repeat rows while content is being updated, so it is
+// the preferred way to walk large result sets. An empty cursor starts at the
+// most recently updated content.
+func listContentByCursor(query *gorm.DB, cursor string, perPage int) (ContentListResponse, error) {
+	var position contentCursor
+	if cursor != "" {
+		var err error
+		if position, err = decodeContentCursor(cursor); err != nil {
+			return ContentListResponse{}, err
+		}
+	}
+
+	// Total counts every matching row, not just those after the cursor
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return ContentListResponse{}, err
+	}
+
+	if cursor != "" {
+		// The id tiebreaker keeps rows sharing an updated_at from being skipped
+		query = query.Where("(updated_at, id) < (?, ?)", position.UpdatedAt, position.ID)
+	}
+
+	// Fetch one extra row to know whether another page follows
+	var contents []models.Content
+	if err := query.Preload("User").Order("updated_at DESC").Order("id DESC").Limit(perPage + 1).Find(&contents).Error; err != nil {
+		return ContentListResponse{}, err
+	}
+
+	response := ContentListResponse{
+		Total:       total,
+		PerPage:     perPage,
+		HasPrevious: cursor != "",
+	}
+	if len(contents) > perPage {
+		contents = contents[:perPage]
+		last := contents[len(contents)-1]
+		response.HasNext = true
+		response.NextCursor = encodeContentCursor(contentCursor{UpdatedAt: last.UpdatedAt, ID: last.ID})
+	}
+	attachReactionCounts(contents)
+	response.Contents = contents
+
+	return response, nil
+}
+
+// encodeContentCursor encodes a keyset position as an opaque token
+func encodeContentCursor(position contentCursor) string {
+	data, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeContentCursor decodes a token produced by encodeContentCursor
+func decodeContentCursor(cursor string) (contentCursor, error) {
+	var position contentCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return position, errInvalidCursor
+	}
+	if err := json.Unmarshal(data, &position); err != nil || position.ID == uuid.Nil {
+		return position, errInvalidCursor
+	}
+	return position, nil
+}
+
+// diffText computes a line or word level diff between two texts
+func diffText(oldText, newText, granularity string) []DiffChunk {
+	dmp := diffmatchpatch.New()
+
+	var diffs []diffmatchpatch.Diff
+	if granularity == "word" {
+		oldRunes, newRunes, tokens := wordsToRunes(oldText, newText)
+		diffs = runesToWords(dmp.DiffMainRunes(oldRunes, newRunes, false), tokens)
+	} else {
+		oldChars, newChars, lines := dmp.DiffLinesToChars(oldText, newText)
+		diffs = dmp.DiffCharsToLines(dmp.DiffMain(oldChars, newChars, false), lines)
+	}
+
+	chunks := make([]DiffChunk, 0, len(diffs))
+	for _, d := range diffs {
+		chunk := DiffChunk{Text: d.Text}
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			chunk.Type = "insert"
+		case diffmatchpatch.DiffDelete:
+			chunk.Type = "delete"
+		default:
+			chunk.Type = "equal"
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// wordsToRunes splits both texts into words and whitespace runs and encodes
+// each distinct token as a single rune so the diff works on whole words
+func wordsToRunes(oldText, newText string) ([]rune, []rune, []string) {
+	tokens := []string{}
+	index := map[string]rune{}
+
+	encode := func(text string) []rune {
+		var encoded []rune
+		start, inSpace := 0, false
+		for i, r := range text {
+			if i > start && unicode.IsSpace(r) != inSpace {
+				encoded = append(encoded, tokenRune(text[start:i], &tokens, index))
+				start = i
+			}
+			inSpace = unicode.IsSpace(r)
+		}
+		if start < len(text) {
+			encoded = append(encoded, tokenRune(text[start:], &tokens, index))
+		}
+		return encoded
+	}
+
+	return encode(oldText), encode(newText), tokens
+}
+
+// tokenRune returns the rune encoding a token, assigning a new one if needed
+func tokenRune(token string, tokens *[]string, index map[string]rune) rune {
+	if r, ok := index[token]; ok {
+		return r
+	}
+	r := rune(len(*tokens))
+	// Skip the surrogate range, which cannot be represented in a string
+	if r >= 0xD800 {
+		r += 0x800
+	}
+	index[token] = r
+	*tokens = append(*tokens, token)
+	return r
+}
+
+// runesToWords expands rune encoded diffs back into their words
+func runesToWords(diffs []diffmatchpatch.Diff, tokens []string) []diffmatchpatch.Diff {
+	for i, d := range diffs {
+		var text strings.Builder
+		for _, r := range d.Text {
+			if r >= 0xD800+0x800 {
+				r -= 0x800
+			}
+			text.WriteString(tokens[r])
+		}
+		diffs[i].Text = text.String()
+	}
+	return diffs
+}
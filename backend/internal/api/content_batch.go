@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+)
+
+// BatchContentRequest lists the content to retrieve at once, at most 100 IDs
+type BatchContentRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// BatchContentResponse holds the requested content the user may view, in
+// the requested order, and the IDs of content that doesn't exist or that
+// the user may not view. The two are not told apart so the response doesn't
+// reveal which private content exists.
+type BatchContentResponse struct {
+	Contents []models.Content `json:"contents"`
+	Missing  []uuid.UUID      `json:"missing"`
+}
+
+// GetContentBatch retrieves several content items in one query, saving
+// dashboards a GetContent call per item
+func GetContentBatch(c *gin.Context) {
+	var req BatchContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Duplicates are returned once
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	for _, raw := range req.IDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid content ID",
+				"code":    "INVALID_CONTENT_ID",
+				"message": "Content ID " + raw + " must be a valid UUID",
+			})
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var found []models.Content
+	if err := database.GetDB().Preload("User").Preload("Collaborations").
+		Where("id IN ?", ids).Find(&found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving content",
+		})
+		return
+	}
+
+	byID := make(map[uuid.UUID]models.Content, len(found))
+	for _, content := range found {
+		if models.Authorize(user, &content, models.PermissionView) {
+			byID[content.ID] = content
+		}
+	}
+
+	response := BatchContentResponse{
+		Contents: make([]models.Content, 0, len(byID)),
+		Missing:  []uuid.UUID{},
+	}
+	for _, id := range ids {
+		content, ok := byID[id]
+		if !ok {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+		response.Contents = append(response.Contents, content)
+	}
+	attachReactionCounts(response.Contents)
+	attachFavorites(c, response.Contents)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content retrieved successfully",
+		"data":    response,
+	})
+}
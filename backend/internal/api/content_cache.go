@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/metrics"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+)
+
+// contentCacheVersion is part of every content cache key. Bump it when the
+// cached shape of content changes so entries written by older builds are
+// ignored.
+const contentCacheVersion = "v1"
+
+// publicContentGenerationKey holds a counter bumped on every content write.
+// Pages of the public listing are keyed by it, so a write retires them all
+// at once.
+const publicContentGenerationKey = "content:cache:" + contentCacheVersion + ":public:generation"
+
+// contentCacheKey is the cache key of a single content
+func contentCacheKey(id uuid.UUID) string {
+	return fmt.Sprintf("content:cache:%s:%s", contentCacheVersion, id)
+}
+
+// contentCacheEnabled reports whether entries with the given TTL are cached
+func contentCacheEnabled(ttl time.Duration) bool {
+	return config.Load().Content.CacheEnabled && ttl > 0 && redis.GetClient() != nil
+}
+
+// loadContent returns content with the relations shown by GetContent, reading
+// through the cache. Per-viewer fields such as reaction counts and favorites
+// are not cached and must be attached by the caller.
+func loadContent(ctx context.Context, id uuid.UUID) (models.Content, error) {
+	ttl := config.Load().Content.CacheTTL
+	cached := contentCacheEnabled(ttl)
+
+	if cached {
+		var content models.Content
+		if getCachedJSON(ctx, "content", contentCacheKey(id), &content) {
+			// Content without collaborators is cached without the field,
+			// which must still read as loaded
+			if content.Collaborations == nil {
+				content.Collaborations = []models.Collaboration{}
+			}
+			return content, nil
+		}
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("User").Preload("Versions").Preload("Collaborations.User").First(&content, "id = ?", id).Error; err != nil {
+		return content, err
+	}
+
+	if cached {
+		setCachedJSON(ctx, contentCacheKey(id), content, ttl)
+	}
+	return content, nil
+}
+
+// publicContentCacheKey keys a page of the public listing by the listing
+// generation and the query string. It reports false when the cache is
+// disabled or Redis cannot be reached.
+func publicContentCacheKey(c *gin.Context) (string, bool) {
+	if !contentCacheEnabled(config.Load().Content.PublicCacheTTL) {
+		return "", false
+	}
+
+	generation, err := redis.Get(c.Request.Context(), publicContentGenerationKey)
+	if errors.Is(err, redis.Nil) {
+		generation = "0"
+	} else if err != nil {
+		return "", false
+	}
+
+	// Encode sorts the parameters, so their order does not split the cache
+	hash := sha256.Sum256([]byte(c.Request.URL.Query().Encode()))
+	return fmt.Sprintf("content:cache:%s:public:%s:%s", contentCacheVersion, generation, hex.EncodeToString(hash[:])), true
+}
+
+// getCachedPublicContent returns a cached page of the public listing
+func getCachedPublicContent(ctx context.Context, key string) (ContentListResponse, bool) {
+	var response ContentListResponse
+	ok := getCachedJSON(ctx, "public_content", key, &response)
+	return response, ok
+}
+
+// cachePublicContent stores a page of the public listing
+func cachePublicContent(ctx context.Context, key string, response ContentListResponse) {
+	setCachedJSON(ctx, key, response, config.Load().Content.PublicCacheTTL)
+}
+
+// invalidateContentCache drops the cached copies of content and retires the
+// cached pages of the public listing. Call it after every write that changes
+// content or the relations GetContent returns.
+func invalidateContentCache(ctx context.Context, ids ...uuid.UUID) {
+	if redis.GetClient() == nil {
+		return
+	}
+
+	pipe := redis.Pipeline()
+	for _, id := range ids {
+		pipe.Del(ctx, contentCacheKey(id))
+	}
+	pipe.Incr(ctx, publicContentGenerationKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to invalidate content cache: %v", err)
+	}
+}
+
+// contentIDsOfUser returns the content a user owns or collaborates on, whose
+// cached copies embed the user
+func contentIDsOfUser(userID uuid.UUID) []uuid.UUID {
+	db := database.GetDB()
+	collaborated := db.Model(&models.Collaboration{}).Select("content_id").Where("user_id = ?", userID)
+
+	var ids []uuid.UUID
+	if err := db.Unscoped().Model(&models.Content{}).
+		Where("user_id = ? OR id IN (?)", userID, collaborated).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("Failed to list content of user %s: %v", userID, err)
+	}
+	return ids
+}
+
+// getCachedJSON decodes a cached entry into dest and records the lookup
+func getCachedJSON(ctx context.Context, cache, key string, dest interface{}) bool {
+	data, err := redis.GetBytes(ctx, key)
+	if err == nil && json.Unmarshal(data, dest) == nil {
+		metrics.CacheRequests.WithLabelValues(cache, "hit").Inc()
+		return true
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to read cache entry %s: %v", key, err)
+	}
+	metrics.CacheRequests.WithLabelValues(cache, "miss").Inc()
+	return false
+}
+
+// setCachedJSON stores value as a cache entry expiring after ttl
+func setCachedJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := redis.Set(ctx, key, data, ttl); err != nil {
+		log.Printf("Failed to write cache entry %s: %v", key, err)
+	}
+}
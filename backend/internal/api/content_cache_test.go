@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupMockDB points the database package at a sqlmock connection
+func setupMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+	return mock
+}
+
+// setupRedis points the redis package at an in-memory server
+func setupRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = nil
+	})
+	return server
+}
+
+func TestLoadContentSecondReadSkipsDatabase(t *testing.T) {
+	t.Setenv("CONTENT_CACHE_ENABLED", "true")
+	t.Setenv("CONTENT_CACHE_TTL", "5m")
+	mock := setupMockDB(t)
+	server := setupRedis(t)
+	ctx := context.Background()
+
+	contentID, ownerID := uuid.New(), uuid.New()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(`SELECT \* FROM "contents" WHERE id = \$1`).
+		WithArgs(contentID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "content", "version"}).
+			AddRow(contentID, ownerID, "Cached", "body", 3))
+	mock.ExpectQuery(`SELECT \* FROM "collaborations"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_id", "user_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(ownerID, "owner"))
+	mock.ExpectQuery(`SELECT \* FROM "content_versions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_id", "version"}))
+
+	first, err := loadContent(ctx, contentID)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, server.Exists(contentCacheKey(contentID)))
+
+	// No queries are expected anymore, so any query fails the read
+	second, err := loadContent(ctx, contentID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, "Cached", second.Title)
+	assert.Equal(t, 3, second.Version)
+	assert.Equal(t, "owner", second.User.Username)
+	assert.NotNil(t, second.Collaborations, "cached collaborations read as loaded")
+
+	// Invalidation sends the next read back to the database
+	invalidateContentCache(ctx, contentID)
+	assert.False(t, server.Exists(contentCacheKey(contentID)))
+	_, err = loadContent(ctx, contentID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,540 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/ai"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/openapi"
+	"github.com/open-same/backend/internal/password"
+	"github.com/open-same/backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// apiBasePath is the prefix of the routes described by the OpenAPI spec
+const apiBasePath = "/api/v1"
+
+// undocumentedRoutes are API routes left out of the spec: the docs
+// themselves and the WebSocket upgrade
+var undocumentedRoutes = map[string]bool{
+	apiBasePath + "/docs":         true,
+	apiBasePath + "/openapi.json": true,
+	apiBasePath + "/ws":           true,
+}
+
+// routeDoc describes a route in the OpenAPI spec. Routes without one are
+// still listed, with an untyped response.
+type routeDoc struct {
+	Summary string
+	// Tag groups the route, defaulting to its first path segment
+	Tag string
+	// Public routes don't require a bearer token
+	Public bool
+	// Params are the query and header parameters
+	Params []openapi.Parameter
+	// Request is the JSON body, Upload the field of a multipart file upload
+	Request interface{}
+	Upload  string
+	// Response is the data of the success response, either a value of the
+	// response type or a *openapi.Schema
+	Response interface{}
+	// Status of the success response, 200 when zero
+	Status int
+	// File is the media type of a download served instead of JSON
+	File string
+}
+
+// queryParam describes a query parameter of type string, integer or boolean
+func queryParam(name, typ, description string, values ...interface{}) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      &openapi.Schema{Type: typ, Enum: values},
+	}
+}
+
+// headerParam describes a request header
+func headerParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{
+		Name:        name,
+		In:          "header",
+		Description: description,
+		Schema:      openapi.String(""),
+	}
+}
+
+// pageParams returns the page-based pagination parameters followed by extra
+func pageParams(extra ...openapi.Parameter) []openapi.Parameter {
+	return append([]openapi.Parameter{
+		queryParam("page", "integer", "Page number, starting at 1"),
+		queryParam("per_page", "integer", "Items per page, at most 100"),
+	}, extra...)
+}
+
+// contentListParams returns the filters of content listings followed by extra
+func contentListParams(extra ...openapi.Parameter) []openapi.Parameter {
+	return pageParams(append([]openapi.Parameter{
+		queryParam("type", "string", "Content type"),
+		queryParam("tags", "string", "Comma-separated tags"),
+		queryParam("tag_mode", "string", "Whether content needs all or any of the tags", "all", "any"),
+		queryParam("search", "string", "Search term"),
+		queryParam("search_mode", "string", "How the search term is matched", "fulltext", "advanced", "simple"),
+		queryParam("cursor", "string", "Opaque cursor from next_cursor; switches to keyset pagination"),
+	}, extra...)...)
+}
+
+// collaborationListParams returns the filters of collaboration listings
+// followed by extra
+func collaborationListParams(extra ...openapi.Parameter) []openapi.Parameter {
+	return pageParams(append([]openapi.Parameter{
+		queryParam("status", "string", "Invitation status", "pending", "accepted", "declined"),
+		queryParam("role", "string", "Collaborator role", "viewer", "commenter", "editor", "admin"),
+	}, extra...)...)
+}
+
+// limitParam describes a limit on the number of results
+func limitParam(defaultLimit string) openapi.Parameter {
+	return queryParam("limit", "integer", "Maximum number of results, "+defaultLimit+" by default")
+}
+
+// routeDocs documents the API routes, keyed by method and route path
+var routeDocs = map[string]routeDoc{
+	// Authentication
+	"POST /api/v1/auth/register":             {Summary: "Register a user", Public: true, Request: RegisterRequest{}, Response: AuthResponse{}, Status: http.StatusCreated},
+	"POST /api/v1/auth/login":                {Summary: "Log in with email and password", Public: true, Request: AuthRequest{}, Response: AuthResponse{}},
+	"POST /api/v1/auth/refresh":              {Summary: "Exchange a refresh token for new tokens", Public: true, Request: RefreshRequest{}, Response: AuthResponse{}},
+	"POST /api/v1/auth/verify-email":         {Summary: "Verify an email address", Public: true, Request: VerifyEmailRequest{}, Response: models.User{}},
+	"GET /api/v1/auth/password-policy":       {Summary: "Get the rules new passwords must follow", Public: true, Response: password.Policy{}},
+	"POST /api/v1/auth/2fa/validate":         {Summary: "Complete a login with a two-factor code", Public: true, Request: TwoFactorValidateRequest{}, Response: AuthResponse{}},
+	"GET /api/v1/auth/oauth/:provider/start": {Summary: "Start a social login", Public: true},
+	"GET /api/v1/auth/oauth/:provider/callback": {Summary: "Complete a social login", Public: true, Response: AuthResponse{},
+		Params: []openapi.Parameter{queryParam("state", "string", "State from the start redirect"), queryParam("code", "string", "Authorization code")}},
+	"POST /api/v1/auth/logout":              {Summary: "Revoke a refresh token and the current access token", Request: LogoutRequest{}},
+	"POST /api/v1/auth/logout_all":          {Summary: "Revoke every token of the user", Response: openapi.Object(map[string]*openapi.Schema{"revoked": {Type: "integer"}})},
+	"POST /api/v1/auth/resend-verification": {Summary: "Send the verification email again"},
+	"POST /api/v1/auth/2fa/enable": {Summary: "Start enabling two-factor authentication", Response: openapi.Object(map[string]*openapi.Schema{
+		"secret":           openapi.String(""),
+		"provisioning_uri": openapi.String("uri"),
+	})},
+	"POST /api/v1/auth/2fa/verify":  {Summary: "Confirm two-factor authentication with a code", Request: TwoFactorCodeRequest{}},
+	"POST /api/v1/auth/2fa/disable": {Summary: "Disable two-factor authentication", Request: TwoFactorCodeRequest{}},
+
+	// User
+	"GET /api/v1/user/profile":          {Summary: "Get the user's profile", Response: models.User{}},
+	"PUT /api/v1/user/profile":          {Summary: "Update the user's profile", Request: UpdateProfileRequest{}, Response: models.User{}},
+	"POST /api/v1/user/change-password": {Summary: "Change the user's password, logging out other sessions", Request: ChangePasswordRequest{}, Response: AuthResponse{}},
+	"GET /api/v1/user/sessions":         {Summary: "List the user's signed in sessions", Response: []SessionResponse{}},
+	"DELETE /api/v1/user/sessions/:id":  {Summary: "Sign out of a session"},
+	"POST /api/v1/user/avatar":          {Summary: "Upload an avatar", Upload: "avatar", Response: models.User{}},
+	"DELETE /api/v1/user/avatar":        {Summary: "Remove the avatar", Response: models.User{}},
+	"GET /api/v1/user/favorites":        {Summary: "List favorited content", Params: pageParams(), Response: ContentListResponse{}},
+	"GET /api/v1/user/limits":           {Summary: "Get the limits of your plan and their use", Response: UserLimitsResponse{}},
+	"DELETE /api/v1/user/account":       {Summary: "Delete the user's account", Request: DeleteAccountRequest{}},
+	"GET /api/v1/user/export": {Summary: "Export all of the user's data", File: "application/json",
+		Params: []openapi.Parameter{queryParam("async", "boolean", "Build the export in the background and email a download link")}},
+
+	// Content
+	"GET /api/v1/content/public": {Summary: "List public content", Public: true, Params: contentListParams(), Response: ContentListResponse{}},
+	"GET /api/v1/content/trending": {Summary: "List trending public content", Public: true,
+		Params: []openapi.Parameter{queryParam("window", "string", "Trending window", "daily", "weekly", "all-time"), limitParam("20")},
+		Response: openapi.Object(map[string]*openapi.Schema{
+			"window":   openapi.String(""),
+			"contents": {Type: "array", Items: &openapi.Schema{Ref: "#/components/schemas/Content"}},
+		})},
+	"POST /api/v1/content": {Summary: "Create content", Request: CreateContentRequest{}, Response: models.Content{}, Status: http.StatusCreated},
+	"POST /api/v1/content/import": {Summary: "Import a file as content", Upload: "file", Response: models.Content{}, Status: http.StatusCreated,
+		Params: []openapi.Parameter{queryParam("dry_run", "boolean", "Parse the file without saving it")}},
+	"GET /api/v1/content": {Summary: "List the user's content", Response: ContentListResponse{},
+		Params: contentListParams(
+			queryParam("status", "string", "Content status"),
+			queryParam("include_archived", "boolean", "Include archived content when no status is given"),
+		)},
+	"POST /api/v1/content/batch": {Summary: "Get several content items at once", Request: BatchContentRequest{}, Response: BatchContentResponse{}},
+	"GET /api/v1/content/trash":  {Summary: "List deleted content", Params: pageParams(), Response: ContentListResponse{}},
+	"GET /api/v1/content/tags": {Summary: "Count content per tag", Response: []TagCount{},
+		Params: []openapi.Parameter{queryParam("scope", "string", "Whose content is counted", "mine", "public"), limitParam("50")}},
+	"GET /api/v1/content/search/semantic": {Summary: "Search content by meaning", Response: []SemanticSearchResult{},
+		Params: []openapi.Parameter{queryParam("q", "string", "Search text"), limitParam("20")}},
+	"GET /api/v1/content/:id": {Summary: "Get content", Response: models.Content{},
+		Params: []openapi.Parameter{headerParam("If-None-Match", "ETag of a cached copy; answered with 304 while it is current")}},
+	"PUT /api/v1/content/:id": {Summary: "Update content", Request: UpdateContentRequest{}, Response: models.Content{},
+		Params: []openapi.Parameter{headerParam("If-Match", "ETag the update was made against; answered with 412 when stale")}},
+	"DELETE /api/v1/content/:id":           {Summary: "Move content to the trash"},
+	"POST /api/v1/content/:id/restore":     {Summary: "Restore content from the trash", Response: models.Content{}},
+	"POST /api/v1/content/:id/archive":     {Summary: "Archive content", Response: models.Content{}},
+	"POST /api/v1/content/:id/unarchive":   {Summary: "Return archived content to draft", Response: models.Content{}},
+	"DELETE /api/v1/content/:id/permanent": {Summary: "Delete content permanently"},
+	"GET /api/v1/content/:id/versions/diff": {Summary: "Diff two versions of content", Response: VersionDiffResponse{},
+		Params: []openapi.Parameter{
+			queryParam("from", "integer", "Older version"),
+			queryParam("to", "integer", "Newer version"),
+			queryParam("granularity", "string", "Diff granularity", "line", "word"),
+		}},
+	"POST /api/v1/content/:id/versions/:version/restore": {Summary: "Restore a version of content", Response: models.Content{}},
+	"POST /api/v1/content/:id/fork":                      {Summary: "Fork content", Response: models.Content{}, Status: http.StatusCreated},
+	"POST /api/v1/content/:id/lock":                      {Summary: "Lock content for exclusive editing", Response: models.Content{}},
+	"POST /api/v1/content/:id/unlock":                    {Summary: "Release or force open the editing lock of content", Response: models.Content{}},
+	"GET /api/v1/content/:id/export": {Summary: "Export content", File: "application/octet-stream",
+		Params: []openapi.Parameter{
+			queryParam("format", "string", "Export format", "markdown", "html", "pdf"),
+			queryParam("async", "boolean", "Render in the background and return an export job"),
+		}},
+	"GET /api/v1/exports/:exportId":          {Summary: "Get an export job", Tag: "Content", Response: ExportJob{}},
+	"GET /api/v1/exports/:exportId/download": {Summary: "Download a finished export", Tag: "Content", File: "application/octet-stream"},
+	"GET /api/v1/content/:id/activity":       {Summary: "List the activity on content", Tag: "Activity", Params: pageParams(), Response: ActivityListResponse{}},
+	"GET /api/v1/content/:id/similar":        {Summary: "List similar content", Params: []openapi.Parameter{limitParam("10")}, Response: []models.Content{}},
+	"GET /api/v1/content/:id/stats":          {Summary: "Get content statistics", Response: models.ContentStats{}},
+	"GET /api/v1/content/:id/presence":       {Summary: "List the users in the collaboration room and its occupancy", Tag: "Collaborations", Response: []websocket.Presence{}},
+	"POST /api/v1/content/:id/favorite":      {Summary: "Favorite content"},
+	"POST /api/v1/content/:id/pin":           {Summary: "Pin public content to your profile"},
+	"DELETE /api/v1/content/:id/pin":         {Summary: "Unpin content from your profile"},
+	"DELETE /api/v1/content/:id/favorite":    {Summary: "Unfavorite content"},
+
+	// Comments
+	"POST /api/v1/content/:id/comments": {Summary: "Comment on content", Tag: "Comments", Request: CreateCommentRequest{}, Response: models.Comment{}, Status: http.StatusCreated},
+	"GET /api/v1/content/:id/comments": {Summary: "List comments on content", Tag: "Comments", Response: CommentListResponse{},
+		Params: pageParams(queryParam("resolved", "boolean", "Only resolved or unresolved threads"))},
+	"PUT /api/v1/content/:id/comments/:commentId":            {Summary: "Update a comment", Tag: "Comments", Request: UpdateCommentRequest{}, Response: models.Comment{}},
+	"DELETE /api/v1/content/:id/comments/:commentId":         {Summary: "Delete a comment", Tag: "Comments"},
+	"POST /api/v1/content/:id/comments/:commentId/resolve":   {Summary: "Resolve a comment thread", Tag: "Comments", Response: models.Comment{}},
+	"POST /api/v1/content/:id/comments/:commentId/unresolve": {Summary: "Reopen a comment thread", Tag: "Comments", Response: models.Comment{}},
+
+	// Reactions and attachments
+	"POST /api/v1/content/:id/react":                       {Summary: "React to content", Tag: "Reactions", Request: ReactRequest{}},
+	"DELETE /api/v1/content/:id/react":                     {Summary: "Remove a reaction", Tag: "Reactions", Params: []openapi.Parameter{queryParam("type", "string", "Reaction type")}},
+	"GET /api/v1/content/:id/reactions":                    {Summary: "Summarize reactions to content", Tag: "Reactions", Response: ReactionSummary{}},
+	"POST /api/v1/content/:id/attachments":                 {Summary: "Upload an attachment", Tag: "Attachments", Upload: "file", Response: models.Attachment{}, Status: http.StatusCreated},
+	"GET /api/v1/content/:id/attachments":                  {Summary: "List attachments", Tag: "Attachments", Response: []models.Attachment{}},
+	"GET /api/v1/content/:id/attachments/:attachmentId":    {Summary: "Download an attachment", Tag: "Attachments", File: "application/octet-stream"},
+	"DELETE /api/v1/content/:id/attachments/:attachmentId": {Summary: "Delete an attachment", Tag: "Attachments"},
+
+	// AI
+	"POST /api/v1/content/:id/summarize": {Summary: "Summarize content", Tag: "AI", Request: SummarizeRequest{}, Response: ai.ContentSummary{}},
+	"GET /api/v1/content/:id/suggestions": {Summary: "Suggest completions and improvements", Tag: "AI", Response: openapi.Object(map[string]*openapi.Schema{
+		"suggestions":  {Type: "array", Items: &openapi.Schema{}},
+		"base_version": {Type: "integer"},
+	})},
+	"POST /api/v1/content/:id/suggestions/apply": {Summary: "Apply a suggestion", Tag: "AI", Request: ApplySuggestionRequest{}, Response: models.Content{}},
+	"GET /api/v1/templates/ai": {Summary: "Generate a template", Tag: "AI", Response: ai.AITemplate{},
+		Params: []openapi.Parameter{queryParam("type", "string", "Template type"), queryParam("category", "string", "Template category")}},
+	"GET /api/v1/ai/usage":      {Summary: "Get the user's AI usage", Params: []openapi.Parameter{queryParam("month", "string", "Month as YYYY-MM")}, Response: AIUsageResponse{}},
+	"POST /api/v1/ai/moderate":  {Summary: "Check text against the moderation policy", Request: ModerateRequest{}, Response: ai.ModerationResult{}},
+	"POST /api/v1/ai/translate": {Summary: "Translate text or content", Request: TranslateRequest{}, Response: ai.TranslationResult{}},
+
+	// Templates and sharing
+	"GET /api/v1/templates": {Summary: "List templates", Public: true, Response: ContentListResponse{},
+		Params: contentListParams(
+			queryParam("category", "string", "Template category"),
+			queryParam("sort", "string", "Ordering", "popular", "recent"),
+		)},
+	"POST /api/v1/templates/:id/use":            {Summary: "Create content from a template", Response: models.Content{}, Status: http.StatusCreated},
+	"GET /api/v1/users/:username/profile":       {Summary: "Get a user's public profile with pinned and published content", Tag: "User", Public: true, Params: pageParams(), Response: PublicProfileResponse{}},
+	"GET /api/v1/share/:token":                  {Summary: "Get content shared by link", Tag: "Sharing", Public: true, Response: models.Content{}},
+	"POST /api/v1/content/:id/share":            {Summary: "Share content", Tag: "Sharing", Request: ShareContentRequest{}, Response: models.SharedContent{}, Status: http.StatusCreated},
+	"DELETE /api/v1/content/:id/share/:shareId": {Summary: "Revoke a share", Tag: "Sharing"},
+
+	// Folders
+	"POST /api/v1/folders":             {Summary: "Create a folder", Request: CreateFolderRequest{}, Response: FolderResponse{}, Status: http.StatusCreated},
+	"GET /api/v1/folders":              {Summary: "List the folders and content at the root", Params: pageParams(), Response: FolderChildrenResponse{}},
+	"GET /api/v1/folders/:id":          {Summary: "Get a folder with its breadcrumbs", Response: FolderResponse{}},
+	"GET /api/v1/folders/:id/children": {Summary: "List the subfolders and content of a folder", Params: pageParams(), Response: FolderChildrenResponse{}},
+	"PUT /api/v1/folders/:id":          {Summary: "Rename a folder", Request: RenameFolderRequest{}, Response: FolderResponse{}},
+	"POST /api/v1/folders/:id/move":    {Summary: "Move a folder into another folder or to the root", Request: MoveFolderRequest{}, Response: FolderResponse{}},
+	"DELETE /api/v1/folders/:id":       {Summary: "Delete a folder and its subfolders, unfiling their content"},
+	"POST /api/v1/content/:id/move":    {Summary: "Move content into a folder or to the root", Tag: "Folders", Request: MoveContentRequest{}},
+
+	// Collaborations
+	"POST /api/v1/content/:id/collaborate": {Summary: "Invite a collaborator", Tag: "Collaborations", Request: AddCollaboratorRequest{}, Response: models.Collaboration{}, Status: http.StatusCreated},
+	"GET /api/v1/collaborations": {Summary: "List the user's collaborations", Response: CollaborationListResponse{},
+		Params: collaborationListParams(queryParam("content_id", "string", "Content the collaborations are on"))},
+	"GET /api/v1/content/:id/collaborators": {Summary: "List the collaborators of content", Tag: "Collaborations", Response: CollaborationListResponse{},
+		Params: collaborationListParams()},
+	"PUT /api/v1/collaborations/:id":          {Summary: "Change a collaborator's role", Request: UpdateCollaborationRequest{}, Response: models.Collaboration{}},
+	"DELETE /api/v1/collaborations/:id":       {Summary: "Remove a collaborator"},
+	"POST /api/v1/collaborations/:id/accept":  {Summary: "Accept an invitation", Response: models.Collaboration{}},
+	"POST /api/v1/collaborations/:id/decline": {Summary: "Decline an invitation", Response: models.Collaboration{}},
+
+	// Webhooks
+	"POST /api/v1/webhooks":               {Summary: "Register a webhook", Request: CreateWebhookRequest{}, Response: models.Webhook{}, Status: http.StatusCreated},
+	"GET /api/v1/webhooks":                {Summary: "List webhooks", Response: []models.Webhook{}},
+	"PUT /api/v1/webhooks/:id":            {Summary: "Update a webhook", Request: UpdateWebhookRequest{}, Response: models.Webhook{}},
+	"DELETE /api/v1/webhooks/:id":         {Summary: "Delete a webhook"},
+	"GET /api/v1/webhooks/:id/deliveries": {Summary: "List recent deliveries of a webhook", Response: []models.WebhookDelivery{}},
+
+	// Administration
+	"GET /api/v1/admin/users": {Summary: "List users", Params: pageParams(queryParam("search", "string", "Username or email")), Response: UserListResponse{}},
+	"GET /api/v1/admin/content": {Summary: "List all content", Response: ContentListResponse{},
+		Params: pageParams(
+			queryParam("type", "string", "Content type"),
+			queryParam("status", "string", "Content status"),
+			queryParam("search", "string", "Title or description"),
+			queryParam("user_id", "string", "Owner"),
+			queryParam("visibility", "string", "private, link, unlisted or public"),
+			queryParam("is_public", "boolean", "Public content only"),
+		)},
+	"GET /api/v1/admin/stats":                {Summary: "Get platform statistics", Response: AdminStats{}},
+	"POST /api/v1/admin/users/:id/ban":       {Summary: "Ban a user", Request: BanUserRequest{}},
+	"PUT /api/v1/admin/users/:id/tier":       {Summary: "Change a user's tier", Request: SetUserTierRequest{}, Response: models.User{}},
+	"DELETE /api/v1/admin/users/:id":         {Summary: "Delete a user"},
+	"GET /api/v1/admin/ai/usage":             {Summary: "Get AI usage per user", Params: []openapi.Parameter{queryParam("month", "string", "Month as YYYY-MM")}},
+	"GET /api/v1/admin/activity":             {Summary: "List activity across content", Params: pageParams(queryParam("action", "string", "Activity action")), Response: ActivityListResponse{}},
+	"POST /api/v1/admin/embeddings/backfill": {Summary: "Queue embeddings for content without one"},
+	"POST /api/v1/admin/broadcast":           {Summary: "Send an announcement to connected users", Request: AdminBroadcastRequest{}},
+}
+
+// routeTagNames spells out tags derived from path segments
+var routeTagNames = map[string]string{
+	"ai":             "AI",
+	"auth":           "Auth",
+	"user":           "User",
+	"content":        "Content",
+	"exports":        "Content",
+	"templates":      "Templates",
+	"collaborations": "Collaborations",
+	"webhooks":       "Webhooks",
+	"admin":          "Admin",
+}
+
+// errorResponseRef refers to the shared error response schema
+var errorResponseRef = &openapi.Schema{Ref: "#/components/schemas/ErrorResponse"}
+
+// BuildOpenAPISpec describes the API routes among routes, using routeDocs
+// for their request and response shapes
+func BuildOpenAPISpec(routes gin.RoutesInfo, version string) *openapi.Document {
+	generator := openapi.NewGenerator()
+	generator.Define(models.ContentType(""), &openapi.Schema{Type: "string", Enum: []interface{}{
+		models.ContentTypeText, models.ContentTypeCode, models.ContentTypeDiagram,
+		models.ContentTypeImage, models.ContentTypeDocument, models.ContentTypeTemplate,
+	}})
+	generator.Define(models.ContentStatus(""), &openapi.Schema{Type: "string", Enum: []interface{}{
+		models.ContentStatusDraft, models.ContentStatusPublished, models.ContentStatusArchived, models.ContentStatusDeleted,
+	}})
+	generator.Define(gorm.DeletedAt{}, &openapi.Schema{Type: "string", Format: "date-time", Nullable: true})
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Open Same API",
+			Description: "Successful responses wrap their payload as {message, data}; errors are {error, code, message}.",
+			Version:     version,
+		},
+		Paths: make(map[string]openapi.PathItem),
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	// Sort routes so operation IDs and tags are stable between builds
+	sorted := append(gin.RoutesInfo{}, routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	operationIDs := make(map[string]bool)
+	tags := make(map[string]bool)
+	for _, route := range sorted {
+		if !strings.HasPrefix(route.Path, apiBasePath+"/") || undocumentedRoutes[route.Path] {
+			continue
+		}
+
+		spec := routeDocs[route.Method+" "+route.Path]
+		operation := buildOperation(generator, route, spec)
+
+		operation.OperationID = handlerOperationID(route.Handler)
+		if operation.OperationID == "" || operationIDs[operation.OperationID] {
+			operation.OperationID = strings.ToLower(route.Method) + strings.NewReplacer("/", "_", ":", "", "-", "_").Replace(strings.TrimPrefix(route.Path, apiBasePath))
+		}
+		operationIDs[operation.OperationID] = true
+		tags[operation.Tags[0]] = true
+
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(openapi.PathItem)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, openapi.Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	doc.Components.Schemas = generator.Schemas()
+	doc.Components.Schemas["ErrorResponse"] = openapi.Object(map[string]*openapi.Schema{
+		"error":   openapi.String(""),
+		"code":    openapi.String(""),
+		"message": openapi.String(""),
+	}, "error", "code")
+	return doc
+}
+
+// buildOperation describes a route from its documentation
+func buildOperation(generator *openapi.Generator, route gin.RouteInfo, spec routeDoc) *openapi.Operation {
+	operation := &openapi.Operation{
+		Summary:   spec.Summary,
+		Tags:      []string{spec.Tag},
+		Responses: make(map[string]*openapi.Response),
+	}
+	if spec.Tag == "" {
+		segment := strings.SplitN(strings.TrimPrefix(route.Path, apiBasePath+"/"), "/", 2)[0]
+		operation.Tags[0] = routeTagNames[segment]
+		if operation.Tags[0] == "" {
+			operation.Tags[0] = strings.ToUpper(segment[:1]) + segment[1:]
+		}
+	}
+	if spec.Public {
+		operation.Security = &[]openapi.SecurityRequirement{}
+	}
+
+	hasPathParams := false
+	for _, segment := range strings.Split(route.Path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			hasPathParams = true
+			operation.Parameters = append(operation.Parameters, openapi.Parameter{
+				Name:     segment[1:],
+				In:       "path",
+				Required: true,
+				Schema:   openapi.String(""),
+			})
+		}
+	}
+	operation.Parameters = append(operation.Parameters, spec.Params...)
+
+	switch {
+	case spec.Request != nil:
+		operation.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: generator.Schema(spec.Request)}},
+		}
+	case spec.Upload != "":
+		operation.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{"multipart/form-data": {Schema: openapi.Object(map[string]*openapi.Schema{
+				spec.Upload: openapi.String("binary"),
+			}, spec.Upload)}},
+		}
+	}
+
+	status := spec.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &openapi.Response{Description: http.StatusText(status)}
+	if spec.File != "" {
+		success.Content = map[string]openapi.MediaType{spec.File: {Schema: openapi.String("binary")}}
+	} else {
+		data := &openapi.Schema{}
+		if schema, ok := spec.Response.(*openapi.Schema); ok {
+			data = schema
+		} else if spec.Response != nil {
+			data = generator.Schema(spec.Response)
+		}
+		envelope := openapi.Object(map[string]*openapi.Schema{
+			"message": openapi.String(""),
+			"data":    data,
+		}, "message")
+		success.Content = map[string]openapi.MediaType{"application/json": {Schema: envelope}}
+	}
+	operation.Responses[statusKey(status)] = success
+
+	errorResponse := func(status int) {
+		operation.Responses[statusKey(status)] = &openapi.Response{
+			Description: http.StatusText(status),
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: errorResponseRef}},
+		}
+	}
+	if operation.RequestBody != nil || len(operation.Parameters) > 0 {
+		errorResponse(http.StatusBadRequest)
+	}
+	if !spec.Public {
+		errorResponse(http.StatusUnauthorized)
+	}
+	if strings.HasPrefix(route.Path, apiBasePath+"/admin/") {
+		errorResponse(http.StatusForbidden)
+	}
+	if hasPathParams {
+		errorResponse(http.StatusNotFound)
+	}
+	errorResponse(http.StatusInternalServerError)
+	return operation
+}
+
+// openAPIPath converts gin path parameters to OpenAPI templates
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// handlerOperationID derives an operation ID from a handler's function name,
+// e.g. "getContent" for github.com/open-same/backend/internal/api.GetContent
+// and for the closure returned by a handler factory
+func handlerOperationID(handler string) string {
+	name := handler[strings.LastIndex(handler, "/")+1:]
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 || parts[0] != "api" || parts[1] == "" {
+		return ""
+	}
+	return strings.ToLower(parts[1][:1]) + parts[1][1:]
+}
+
+// statusKey is the key of a status in an operation's responses
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
+
+// OpenAPISpec serves the OpenAPI spec of the routes returned by routes,
+// built on the first request once every route is registered
+func OpenAPISpec(routes func() gin.RoutesInfo) gin.HandlerFunc {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			doc = BuildOpenAPISpec(routes(), config.Load().Version)
+		})
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at the spec
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8" />
+	<title>Open Same API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body style="margin: 0">
+	<div id="swagger-ui"></div>
+	<script crossorigin src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+	<script>
+		window.ui = SwaggerUIBundle({ url: '` + apiBasePath + `/openapi.json', dom_id: '#swagger-ui' });
+	</script>
+</body>
+</html>
+`
+
+// ServeDocs serves Swagger UI for the OpenAPI spec
+func ServeDocs(c *gin.Context) {
+	// Swagger UI's scripts and styles come from the CDN
+	c.Header("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https:; font-src 'self' data: https://unpkg.com; connect-src 'self'; frame-ancestors 'none';")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/yuin/goldmark"
+)
+
+// exportFormats maps each export format to its file extension and MIME type
+var exportFormats = map[string]struct {
+	extension   string
+	contentType string
+}{
+	"markdown": {"md", "text/markdown; charset=utf-8"},
+	"html":     {"html", "text/html; charset=utf-8"},
+	"pdf":      {"pdf", "application/pdf"},
+}
+
+// exportHTMLTemplate renders content as a standalone HTML document
+var exportHTMLTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Content.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; line-height: 1.6; }
+pre { background: #f5f5f5; padding: 1rem; overflow-x: auto; }
+footer { margin-top: 3rem; color: #666; font-size: 0.85rem; border-top: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>{{.Content.Title}}</h1>
+{{if .Content.Description}}<p><em>{{.Content.Description}}</em></p>{{end}}
+{{if .Content.Tags}}<p>Tags: {{range $i, $tag := .Content.Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}</p>{{end}}
+<article>
+{{.Body}}
+</article>
+<footer><p>{{.Footer}}</p></footer>
+</body>
+</html>
+`))
+
+// unsafeFilenameChars matches characters not kept in export file names
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExportContent downloads content as Markdown, HTML or PDF. With ?async=true
+// the export is rendered by a background job instead and fetched from
+// GetExport once ready.
+func ExportContent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	formatName := c.DefaultQuery("format", "markdown")
+	format, ok := exportFormats[formatName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid export format",
+			"code":    "INVALID_EXPORT_FORMAT",
+			"message": "format must be one of markdown, html or pdf",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("User").Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if !models.Authorize(user, &content, models.PermissionView) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "You don't have permission to export this content",
+		})
+		return
+	}
+
+	if c.Query("async") == "true" {
+		queueContentExport(c, content, user.ID, formatName)
+		return
+	}
+
+	data, err := renderExport(&content, formatName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export content",
+			"code":    "EXPORT_ERROR",
+			"message": "An error occurred while rendering the export",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, exportFilename(&content), format.extension))
+	c.Data(http.StatusOK, format.contentType, data)
+}
+
+// renderExport renders content in one of the export formats
+func renderExport(content *models.Content, formatName string) ([]byte, error) {
+	switch formatName {
+	case "markdown":
+		return exportMarkdown(content), nil
+	case "html":
+		return exportHTML(content)
+	case "pdf":
+		return exportPDF(content)
+	}
+	return nil, fmt.Errorf("unknown export format %q", formatName)
+}
+
+// exportMarkdown serializes content as Markdown with its metadata as a
+// front matter list
+func exportMarkdown(content *models.Content) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# %s\n\n", content.Title)
+	if content.Description != "" {
+		fmt.Fprintf(&buf, "_%s_\n\n", content.Description)
+	}
+	if len(content.Tags) > 0 {
+		fmt.Fprintf(&buf, "**Tags:** %s\n\n", strings.Join(content.Tags, ", "))
+	}
+	if len(content.Metadata) > 0 {
+		keys := make([]string, 0, len(content.Metadata))
+		for key := range content.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&buf, "- **%s:** %v\n", key, content.Metadata[key])
+		}
+		buf.WriteString("\n")
+	}
+
+	if content.Type == models.ContentTypeCode {
+		fmt.Fprintf(&buf, "```\n%s\n```\n", content.Content)
+	} else {
+		buf.WriteString(content.Content)
+		buf.WriteString("\n")
+	}
+
+	fmt.Fprintf(&buf, "\n---\n\n_%s_\n", exportFooter(content))
+	return buf.Bytes()
+}
+
+// exportHTML renders content as an HTML document, treating text bodies as
+// Markdown
+func exportHTML(content *models.Content) ([]byte, error) {
+	var body bytes.Buffer
+	if content.Type == models.ContentTypeCode {
+		body.WriteString("<pre><code>")
+		body.WriteString(template.HTMLEscapeString(content.Content))
+		body.WriteString("</code></pre>")
+	} else if err := goldmark.Convert([]byte(content.Content), &body); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err := exportHTMLTemplate.Execute(&buf, struct {
+		Content *models.Content
+		Body    template.HTML
+		Footer  string
+	}{
+		Content: content,
+		// goldmark omits raw HTML from its output, so the body is safe to embed
+		Body:   template.HTML(body.String()),
+		Footer: exportFooter(content),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportPDF renders content as a PDF document
+func exportPDF(content *models.Content) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// The core fonts only cover cp1252
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	footer := tr(exportFooter(content))
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(102, 102, 102)
+		pdf.CellFormat(0, 10, footer, "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.MultiCell(0, 9, tr(content.Title), "", "L", false)
+	pdf.Ln(2)
+
+	if content.Description != "" {
+		pdf.SetFont("Helvetica", "I", 11)
+		pdf.MultiCell(0, 6, tr(content.Description), "", "L", false)
+		pdf.Ln(2)
+	}
+	if len(content.Tags) > 0 {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 5, tr("Tags: "+strings.Join(content.Tags, ", ")), "", "L", false)
+		pdf.Ln(2)
+	}
+
+	if content.Type == models.ContentTypeCode {
+		pdf.SetFont("Courier", "", 9)
+	} else {
+		pdf.SetFont("Helvetica", "", 11)
+	}
+	pdf.MultiCell(0, 5.5, tr(content.Content), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportFooter describes the exported version of content
+func exportFooter(content *models.Content) string {
+	footer := fmt.Sprintf("Version %d, last updated %s, exported %s",
+		content.Version,
+		content.UpdatedAt.UTC().Format(time.RFC3339),
+		time.Now().UTC().Format(time.RFC3339))
+	if content.User.Username != "" {
+		footer = fmt.Sprintf("By %s. %s", content.User.Username, footer)
+	}
+	return footer
+}
+
+// exportFilename derives a download file name from the content title
+func exportFilename(content *models.Content) string {
+	name := strings.Trim(unsafeFilenameChars.ReplaceAllString(content.Title, "-"), "-.")
+	if name == "" {
+		return content.ID.String()
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name
+}
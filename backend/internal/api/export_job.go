@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/queue"
+	"github.com/open-same/backend/internal/redis"
+	"github.com/open-same/backend/internal/storage"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// exportRetention is how long a background export stays downloadable
+const exportRetention = 24 * time.Hour
+
+// exportsExpiringKey is the Redis sorted set of stored exports scored by
+// the Unix time they expire
+const exportsExpiringKey = "exports:expiring"
+
+// Export job statuses
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// Export job kinds
+const (
+	// ExportKindContent renders one content in an export format
+	ExportKindContent = "content"
+	// ExportKindUserData archives everything stored about a user as JSON
+	ExportKindUserData = "user_data"
+)
+
+// ExportJob is an export rendered in the background
+type ExportJob struct {
+	ID uuid.UUID `json:"id"`
+	// Kind is empty for content exports queued before kinds existed
+	Kind      string    `json:"kind"`
+	ContentID uuid.UUID `json:"content_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Format    string    `json:"format"`
+	Status    string    `json:"status"`
+	Filename  string    `json:"filename,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportJobKey is the Redis key of an export job's state
+func exportJobKey(id uuid.UUID) string {
+	return "export:" + id.String()
+}
+
+// fileType returns the file extension and MIME type of the export
+func (e ExportJob) fileType() (extension, contentType string) {
+	if e.Kind == ExportKindUserData {
+		return "json", "application/json"
+	}
+	format := exportFormats[e.Format]
+	return format.extension, format.contentType
+}
+
+// storageKey is where the rendered export is stored
+func (e ExportJob) storageKey() string {
+	extension, _ := e.fileType()
+	return fmt.Sprintf("exports/%s/%s.%s", e.UserID, e.ID, extension)
+}
+
+// saveExportJob stores the state of an export job until it expires
+func saveExportJob(ctx context.Context, export ExportJob) error {
+	data, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+	return redis.Set(ctx, exportJobKey(export.ID), data, exportRetention)
+}
+
+// loadExportJob returns the state of an export job
+func loadExportJob(ctx context.Context, id uuid.UUID) (ExportJob, error) {
+	var export ExportJob
+	data, err := redis.GetBytes(ctx, exportJobKey(id))
+	if err != nil {
+		return export, err
+	}
+	err = json.Unmarshal(data, &export)
+	return export, err
+}
+
+// queueContentExport queues the rendering of content and responds with the
+// pending export job
+func queueContentExport(c *gin.Context, content models.Content, userID uuid.UUID, formatName string) {
+	export := ExportJob{
+		ID:        uuid.New(),
+		Kind:      ExportKindContent,
+		ContentID: content.ID,
+		UserID:    userID,
+		Format:    formatName,
+		Status:    ExportStatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := saveExportJob(c.Request.Context(), export)
+	if err == nil {
+		err = queue.Publish(c.Request.Context(), queue.JobExport, export)
+	}
+	if err != nil {
+		log.Printf("Failed to queue export of content %s: %v", content.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to queue export",
+			"code":    "EXPORT_ERROR",
+			"message": "An error occurred while queuing the export",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Export queued successfully",
+		"data":    export,
+	})
+}
+
+// HandleExportJob renders a queued export and stores it for download
+func HandleExportJob(ctx context.Context, job queue.Job) error {
+	var export ExportJob
+	if err := job.Decode(&export); err != nil {
+		return err
+	}
+	if export.Kind == ExportKindUserData {
+		return handleUserDataExport(ctx, export)
+	}
+
+	var content models.Content
+	if err := database.GetDB().WithContext(ctx).Preload("User").First(&content, "id = ?", export.ContentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			export.Status = ExportStatusFailed
+			export.Error = "The content was deleted"
+			return saveExportJob(ctx, export)
+		}
+		return err
+	}
+
+	data, err := renderExport(&content, export.Format)
+	if err == nil {
+		_, contentType := export.fileType()
+		err = storage.Get().Put(ctx, export.storageKey(), bytes.NewReader(data), int64(len(data)), contentType)
+	}
+	if err != nil {
+		export.Status = ExportStatusFailed
+		export.Error = "An error occurred while rendering the export"
+		if saveErr := saveExportJob(ctx, export); saveErr != nil {
+			log.Printf("Failed to record failure of export %s: %v", export.ID, saveErr)
+		}
+		return err
+	}
+
+	scheduleExportExpiry(ctx, export)
+
+	extension, _ := export.fileType()
+	export.Status = ExportStatusReady
+	export.Filename = exportFilename(&content) + "." + extension
+	export.Size = int64(len(data))
+	return saveExportJob(ctx, export)
+}
+
+// scheduleExportExpiry records when a stored export is purged
+func scheduleExportExpiry(ctx context.Context, export ExportJob) {
+	expiresAt := time.Now().Add(exportRetention)
+	if err := redis.ZAdd(ctx, exportsExpiringKey, goredis.Z{Score: float64(expiresAt.Unix()), Member: export.storageKey()}); err != nil {
+		log.Printf("Failed to schedule expiry of export %s: %v", export.ID, err)
+	}
+}
+
+// exportForUser loads the export named by the :exportId path parameter,
+// writing an error response unless it belongs to the authenticated user
+func exportForUser(c *gin.Context) (ExportJob, bool) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return ExportJob{}, false
+	}
+
+	id, err := uuid.Parse(c.Param("exportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid export ID",
+			"code":    "INVALID_EXPORT_ID",
+			"message": "Export ID must be a valid UUID",
+		})
+		return ExportJob{}, false
+	}
+
+	export, err := loadExportJob(c.Request.Context(), id)
+	if err != nil || export.UserID != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Export not found",
+			"code":    "EXPORT_NOT_FOUND",
+			"message": "The requested export was not found or has expired",
+		})
+		return ExportJob{}, false
+	}
+	return export, true
+}
+
+// GetExport returns the status of a background export
+func GetExport(c *gin.Context) {
+	export, ok := exportForUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Export retrieved successfully",
+		"data":    export,
+	})
+}
+
+// DownloadExport downloads a background export once it is ready
+func DownloadExport(c *gin.Context) {
+	export, ok := exportForUser(c)
+	if !ok {
+		return
+	}
+
+	if export.Status != ExportStatusReady {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Export not ready",
+			"code":    "EXPORT_NOT_READY",
+			"message": "The export is " + export.Status,
+		})
+		return
+	}
+
+	file, err := storage.Get().Open(c.Request.Context(), export.storageKey())
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Export not found",
+			"code":    "EXPORT_NOT_FOUND",
+			"message": "The requested export was not found or has expired",
+		})
+		return
+	}
+	defer file.Close()
+
+	_, contentType := export.fileType()
+	c.DataFromReader(http.StatusOK, export.Size, contentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// PurgeExpiredExports deletes stored exports past their retention and
+// returns how many were removed
+func PurgeExpiredExports(ctx context.Context) (int, error) {
+	keys, err := redis.ZRangeByScore(ctx, exportsExpiringKey, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, key := range keys {
+		if err := storage.Get().Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to delete export %s from storage: %v", key, err)
+			continue
+		}
+		if err := redis.ZRem(ctx, exportsExpiringKey, key); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
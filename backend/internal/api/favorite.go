@@ -0,0 +1,184 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// AddFavorite saves content the user can read to their favorites.
+// Favoriting content twice has no further effect.
+func AddFavorite(c *gin.Context) {
+	content, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	favorite := models.Favorite{
+		UserID:    user.ID,
+		ContentID: content.ID,
+	}
+	if err := database.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add favorite",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while adding the favorite",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content added to favorites",
+		"data":    gin.H{"content_id": content.ID, "is_favorited": true},
+	})
+}
+
+// RemoveFavorite removes content from the user's favorites. It works even
+// when the user has lost access to the content.
+func RemoveFavorite(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if err := database.GetDB().Where("user_id = ? AND content_id = ?", user.ID, id).Delete(&models.Favorite{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove favorite",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while removing the favorite",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content removed from favorites",
+		"data":    gin.H{"content_id": id, "is_favorited": false},
+	})
+}
+
+// GetFavorites lists the user's favorited content, most recently favorited
+// first. Content the user can no longer read is left out.
+func GetFavorites(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := database.GetDB().Model(&models.Content{}).
+		Joins("JOIN favorites ON favorites.content_id = contents.id AND favorites.user_id = ?", user.ID).
+		Where(`(contents.user_id = ? OR contents.visibility IN ? OR EXISTS (
+			SELECT 1 FROM collaborations
+			WHERE collaborations.content_id = contents.id AND collaborations.user_id = ?
+			AND collaborations.is_active = ? AND collaborations.status = ?))`,
+			user.ID, models.ViewableByIDVisibilities, user.ID, true, models.CollaborationStatusAccepted)
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	var contents []models.Content
+	if err := query.Preload("User").Offset(offset).Limit(perPage).Order("favorites.created_at DESC").Find(&contents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve favorites",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving favorites",
+		})
+		return
+	}
+	attachReactionCounts(contents)
+	isFavorited := true
+	for i := range contents {
+		contents[i].IsFavorited = &isFavorited
+	}
+
+	response := ContentListResponse{
+		Contents:    contents,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Favorites retrieved successfully",
+		"data":    response,
+	})
+}
+
+// attachFavorites marks which of the listed content the authenticated user
+// has favorited. Anonymous requests are left unchanged.
+func attachFavorites(c *gin.Context, contents []models.Content) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists || len(contents) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(contents))
+	for i, content := range contents {
+		ids[i] = content.ID
+	}
+
+	var favorited []uuid.UUID
+	if err := database.GetDB().Model(&models.Favorite{}).
+		Where("user_id = ? AND content_id IN ?", user.ID, ids).
+		Pluck("content_id", &favorited).Error; err != nil {
+		log.Printf("Failed to load favorites for user %s: %v", user.ID, err)
+		return
+	}
+
+	set := make(map[uuid.UUID]bool, len(favorited))
+	for _, id := range favorited {
+		set[id] = true
+	}
+	for i := range contents {
+		isFavorited := set[contents[i].ID]
+		contents[i].IsFavorited = &isFavorited
+	}
+}
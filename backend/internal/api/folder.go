@@ -0,0 +1,539 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxFolderDepth bounds the ancestor walk of a folder
+const maxFolderDepth = 100
+
+// errFolderCycle is returned when a folder would be moved into itself or
+// one of its subfolders
+var errFolderCycle = errors.New("folder cycle")
+
+// CreateFolderRequest represents a new folder, at the root unless ParentID
+// is set
+type CreateFolderRequest struct {
+	Name     string  `json:"name" binding:"required,max=255"`
+	ParentID *string `json:"parent_id"`
+}
+
+// RenameFolderRequest represents a folder's new name
+type RenameFolderRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// MoveFolderRequest names the folder to move a folder into, the root when
+// ParentID is null or omitted
+type MoveFolderRequest struct {
+	ParentID *string `json:"parent_id"`
+}
+
+// MoveContentRequest names the folder to file content in, the root when
+// FolderID is null or omitted
+type MoveContentRequest struct {
+	FolderID *string `json:"folder_id"`
+}
+
+// FolderBreadcrumb is one folder on the path from the root to a folder
+type FolderBreadcrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// FolderResponse is a folder with the path leading to it, root first and
+// ending with the folder itself
+type FolderResponse struct {
+	models.Folder
+	Breadcrumbs []FolderBreadcrumb `json:"breadcrumbs"`
+}
+
+// FolderChildrenResponse lists what a folder, or the root, holds. Folders
+// are listed in full, content a page at a time.
+type FolderChildrenResponse struct {
+	Folder      *models.Folder      `json:"folder"`
+	Breadcrumbs []FolderBreadcrumb  `json:"breadcrumbs"`
+	Folders     []models.Folder     `json:"folders"`
+	Contents    ContentListResponse `json:"contents"`
+}
+
+// CreateFolder creates a folder for the authenticated user
+func CreateFolder(c *gin.Context) {
+	var req CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	parentID, ok := targetFolderID(c, user.ID, req.ParentID)
+	if !ok {
+		return
+	}
+
+	folder := models.Folder{
+		UserID:   user.ID,
+		ParentID: parentID,
+		Name:     req.Name,
+	}
+	if err := database.GetDB().Create(&folder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create folder",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while creating the folder",
+		})
+		return
+	}
+
+	respondFolder(c, http.StatusCreated, "Folder created successfully", folder)
+}
+
+// GetFolder retrieves one of the user's folders with its breadcrumbs
+func GetFolder(c *gin.Context) {
+	folder, _, ok := ownFolder(c)
+	if !ok {
+		return
+	}
+
+	respondFolder(c, http.StatusOK, "Folder retrieved successfully", folder)
+}
+
+// GetFolderChildren lists the subfolders and content of one of the user's
+// folders, or of the root when no folder ID is given. Content is paginated
+// with page and per_page and listed most recently updated first.
+func GetFolderChildren(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	response := FolderChildrenResponse{Breadcrumbs: []FolderBreadcrumb{}}
+	db := database.GetDB()
+	folders := db.Where("user_id = ? AND parent_id IS NULL", user.ID)
+	contents := db.Model(&models.Content{}).Where("user_id = ? AND folder_id IS NULL", user.ID)
+	if c.Param("id") != "" {
+		folder, _, ok := ownFolder(c)
+		if !ok {
+			return
+		}
+		breadcrumbs, err := folderBreadcrumbs(db, folder.ID)
+		if err != nil {
+			respondFolderError(c)
+			return
+		}
+		response.Folder = &folder
+		response.Breadcrumbs = breadcrumbs
+		folders = db.Where("user_id = ? AND parent_id = ?", user.ID, folder.ID)
+		contents = db.Model(&models.Content{}).Where("user_id = ? AND folder_id = ?", user.ID, folder.ID)
+	}
+
+	response.Folders = []models.Folder{}
+	if err := folders.Order("name ASC").Find(&response.Folders).Error; err != nil {
+		respondFolderError(c)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	var total int64
+	if err := contents.Count(&total).Error; err != nil {
+		respondFolderError(c)
+		return
+	}
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	items := []models.Content{}
+	if err := contents.Preload("User").Offset((page - 1) * perPage).Limit(perPage).Order("updated_at DESC").Find(&items).Error; err != nil {
+		respondFolderError(c)
+		return
+	}
+	attachReactionCounts(items)
+	attachFavorites(c, items)
+
+	response.Contents = ContentListResponse{
+		Contents:    items,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder contents retrieved successfully",
+		"data":    response,
+	})
+}
+
+// RenameFolder renames one of the user's folders
+func RenameFolder(c *gin.Context) {
+	var req RenameFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	folder, _, ok := ownFolder(c)
+	if !ok {
+		return
+	}
+
+	if err := database.GetDB().Model(&folder).Update("name", req.Name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to rename folder",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while renaming the folder",
+		})
+		return
+	}
+	folder.Name = req.Name
+
+	respondFolder(c, http.StatusOK, "Folder renamed successfully", folder)
+}
+
+// MoveFolder moves one of the user's folders, with everything in it, into
+// another of their folders or to the root. A folder can't be moved into
+// itself or its subfolders.
+func MoveFolder(c *gin.Context) {
+	var req MoveFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	folder, user, ok := ownFolder(c)
+	if !ok {
+		return
+	}
+
+	parentID, ok := targetFolderID(c, user.ID, req.ParentID)
+	if !ok {
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Moves of the user's folders take turns so two concurrent moves
+		// can't each pass the cycle check and form a loop together
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			ancestors, err := folderAncestors(tx, *parentID)
+			if err != nil {
+				return err
+			}
+			for _, ancestor := range ancestors {
+				if ancestor.ID == folder.ID {
+					return errFolderCycle
+				}
+			}
+		}
+
+		return tx.Model(&folder).Update("parent_id", parentID).Error
+	})
+	if errors.Is(err, errFolderCycle) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid folder move",
+			"code":    "FOLDER_CYCLE",
+			"message": "A folder can't be moved into itself or one of its subfolders",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to move folder",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while moving the folder",
+		})
+		return
+	}
+	folder.ParentID = parentID
+
+	respondFolder(c, http.StatusOK, "Folder moved successfully", folder)
+}
+
+// DeleteFolder deletes one of the user's folders and its subfolders. The
+// content filed in them moves back to the root; none of it is deleted.
+func DeleteFolder(c *gin.Context) {
+	folder, _, ok := ownFolder(c)
+	if !ok {
+		return
+	}
+
+	// The foreign keys delete the subfolders and unfile their content
+	if err := database.GetDB().Delete(&folder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete folder",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while deleting the folder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder deleted successfully",
+	})
+}
+
+// MoveContent files content the user owns in one of their folders, or at
+// the root. Only the owner organizes content, as it has a single place in
+// the owner's tree.
+func MoveContent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req MoveContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var content models.Content
+	if err := db.First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if content.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "Only the owner can move this content",
+		})
+		return
+	}
+
+	folderID, ok := targetFolderID(c, user.ID, req.FolderID)
+	if !ok {
+		return
+	}
+
+	// Filing content doesn't change it, so updated_at is left alone
+	if err := db.Model(&content).UpdateColumn("folder_id", folderID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to move content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while moving the content",
+		})
+		return
+	}
+	content.FolderID = folderID
+	invalidateContentCache(c.Request.Context(), content.ID)
+
+	breadcrumbs := []FolderBreadcrumb{}
+	if folderID != nil {
+		if breadcrumbs, err = folderBreadcrumbs(db, *folderID); err != nil {
+			respondFolderError(c)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content moved successfully",
+		"data": gin.H{
+			"content_id":  content.ID,
+			"folder_id":   content.FolderID,
+			"breadcrumbs": breadcrumbs,
+		},
+	})
+}
+
+// ownFolder loads the folder named in the path, writing the error response
+// and returning false when it isn't one of the user's folders
+func ownFolder(c *gin.Context) (models.Folder, *models.User, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid folder ID",
+			"code":    "INVALID_FOLDER_ID",
+			"message": "Folder ID must be a valid UUID",
+		})
+		return models.Folder{}, nil, false
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return models.Folder{}, nil, false
+	}
+
+	// Other users' folders are reported missing so they aren't revealed
+	var folder models.Folder
+	if err := database.GetDB().Where("id = ? AND user_id = ?", id, user.ID).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Folder not found",
+				"code":    "FOLDER_NOT_FOUND",
+				"message": "The requested folder was not found",
+			})
+			return models.Folder{}, nil, false
+		}
+		respondFolderError(c)
+		return models.Folder{}, nil, false
+	}
+	return folder, user, true
+}
+
+// targetFolderID resolves the folder something is created or moved into,
+// nil for the root. It writes the error response and returns false when
+// raw doesn't name one of the user's folders.
+func targetFolderID(c *gin.Context, userID uuid.UUID, raw *string) (*uuid.UUID, bool) {
+	if raw == nil || *raw == "" {
+		return nil, true
+	}
+
+	id, err := uuid.Parse(*raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid folder ID",
+			"code":    "INVALID_FOLDER_ID",
+			"message": "Folder ID must be a valid UUID",
+		})
+		return nil, false
+	}
+
+	var count int64
+	if err := database.GetDB().Model(&models.Folder{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		respondFolderError(c)
+		return nil, false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Folder not found",
+			"code":    "FOLDER_NOT_FOUND",
+			"message": "The target folder was not found",
+		})
+		return nil, false
+	}
+	return &id, true
+}
+
+// folderAncestors returns a folder and the folders above it, root first
+func folderAncestors(db *gorm.DB, id uuid.UUID) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := db.Raw(`WITH RECURSIVE ancestors AS (
+		SELECT id, user_id, parent_id, name, created_at, updated_at, 0 AS depth
+		FROM folders WHERE id = ?
+		UNION ALL
+		SELECT f.id, f.user_id, f.parent_id, f.name, f.created_at, f.updated_at, a.depth + 1
+		FROM folders f JOIN ancestors a ON f.id = a.parent_id
+		WHERE a.depth < ?
+	) SELECT id, user_id, parent_id, name, created_at, updated_at FROM ancestors ORDER BY depth DESC`, id, maxFolderDepth).
+		Scan(&folders).Error
+	return folders, err
+}
+
+// folderBreadcrumbs returns the path from the root to a folder
+func folderBreadcrumbs(db *gorm.DB, id uuid.UUID) ([]FolderBreadcrumb, error) {
+	ancestors, err := folderAncestors(db, id)
+	if err != nil {
+		return nil, err
+	}
+	breadcrumbs := make([]FolderBreadcrumb, len(ancestors))
+	for i, folder := range ancestors {
+		breadcrumbs[i] = FolderBreadcrumb{ID: folder.ID, Name: folder.Name}
+	}
+	return breadcrumbs, nil
+}
+
+// respondFolder writes a folder with its breadcrumbs
+func respondFolder(c *gin.Context, status int, message string, folder models.Folder) {
+	breadcrumbs, err := folderBreadcrumbs(database.GetDB(), folder.ID)
+	if err != nil {
+		respondFolderError(c)
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"message": message,
+		"data":    FolderResponse{Folder: folder, Breadcrumbs: breadcrumbs},
+	})
+}
+
+// respondFolderError writes the response for a failed folder query
+func respondFolderError(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Failed to retrieve folder",
+		"code":    "DATABASE_ERROR",
+		"message": "An error occurred while retrieving the folder",
+	})
+}
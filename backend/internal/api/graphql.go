@@ -0,0 +1,1008 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+	"github.com/open-same/backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// graphQLContextKey carries the gin context of a GraphQL request to the
+// resolvers, which share the REST API's authentication through it
+type graphQLContextKey struct{}
+
+// graphQLRequest is a GraphQL request sent over HTTP
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLError is an error returned to GraphQL clients. Its code, the one
+// the REST API uses for the same failure, is reported in the extensions.
+type graphQLError struct {
+	code    string
+	message string
+}
+
+var _ gqlerrors.ExtendedError = (*graphQLError)(nil)
+
+func (e *graphQLError) Error() string {
+	return e.message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *graphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// newGraphQLError creates an error with a code for GraphQL clients
+func newGraphQLError(code, message string) error {
+	return &graphQLError{code: code, message: message}
+}
+
+var (
+	errGraphQLUnauthenticated = newGraphQLError("UNAUTHENTICATED", "Authentication is required")
+	errGraphQLContentNotFound = newGraphQLError("CONTENT_NOT_FOUND", "The requested content was not found")
+	errGraphQLAccessDenied    = newGraphQLError("ACCESS_DENIED", "You don't have permission to access this content")
+	errGraphQLUserNotFound    = newGraphQLError("USER_NOT_FOUND", "The requested user was not found")
+	errGraphQLDatabase        = newGraphQLError("DATABASE_ERROR", "An error occurred while accessing the database")
+)
+
+// graphQLContentError converts an error of the content service to the
+// error returned to GraphQL clients
+func graphQLContentError(err error) error {
+	switch {
+	case errors.Is(err, redis.ErrLockNotAcquired):
+		return newGraphQLError("CONTENT_LOCKED", "The content is being saved by another writer, please try again")
+	case errors.Is(err, errContentLocked):
+		return newGraphQLError("CONTENT_LOCKED", "The content is locked for editing by another user")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errGraphQLContentNotFound
+	case errors.Is(err, errEditPermissionDenied):
+		return newGraphQLError("EDIT_PERMISSION_DENIED", "You don't have permission to edit this content")
+	case errors.Is(err, errInvalidParentID):
+		return newGraphQLError("INVALID_PARENT_ID", "Parent ID must be a valid UUID")
+	case errors.Is(err, errVersionCreation):
+		return newGraphQLError("VERSION_CREATION_ERROR", "Content saved but version tracking failed")
+	case errors.Is(err, errContentPreconditionFailed):
+		return newGraphQLError("PRECONDITION_FAILED", "The content has changed since the given version")
+	case errors.Is(err, errInvalidPublishAt):
+		return newGraphQLError("INVALID_PUBLISH_AT", "publishAt must be in the future and only drafts can be scheduled")
+	case errors.Is(err, errTierLimitReached):
+		return newGraphQLError("TIER_LIMIT_REACHED", "Your plan doesn't allow more content")
+	}
+	log.Printf("GraphQL content operation failed: %v", err)
+	return errGraphQLDatabase
+}
+
+// graphQLGinContext returns the gin context of the request a resolver runs in
+func graphQLGinContext(p graphql.ResolveParams) *gin.Context {
+	c, _ := p.Context.Value(graphQLContextKey{}).(*gin.Context)
+	return c
+}
+
+// graphQLViewer returns the authenticated user of the request a resolver
+// runs in, if any
+func graphQLViewer(p graphql.ResolveParams) (*models.User, bool) {
+	c := graphQLGinContext(p)
+	if c == nil {
+		return nil, false
+	}
+	return middleware.GetUserFromContext(c)
+}
+
+// graphQLRequireViewer returns the authenticated user or an UNAUTHENTICATED
+// error
+func graphQLRequireViewer(p graphql.ResolveParams) (*models.User, error) {
+	user, exists := graphQLViewer(p)
+	if !exists {
+		return nil, errGraphQLUnauthenticated
+	}
+	return user, nil
+}
+
+// graphQLID parses a UUID argument, reporting code when it is malformed
+func graphQLID(p graphql.ResolveParams, name, code string) (uuid.UUID, error) {
+	raw, _ := p.Args[name].(string)
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, newGraphQLError(code, fmt.Sprintf("%s must be a valid UUID", name))
+	}
+	return id, nil
+}
+
+// graphQLPage returns the page and page size arguments of a list with the
+// defaults and bounds of the REST API
+func graphQLPage(args map[string]interface{}) (int, int) {
+	page, _ := args["page"].(int)
+	perPage, _ := args["perPage"].(int)
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	return page, perPage
+}
+
+// graphQLStrings converts a list argument to strings
+func graphQLStrings(value interface{}) []string {
+	values, _ := value.([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// jsonScalar passes arbitrary JSON, such as content metadata, through as is
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "An arbitrary JSON value",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: parseJSONLiteral,
+})
+
+// parseJSONLiteral converts a JSON value written inline in a query
+func parseJSONLiteral(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseInt(value.Value, 10, 64)
+		return n
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(value.Value, 64)
+		return f
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.ListValue:
+		list := make([]interface{}, len(value.Values))
+		for i, v := range value.Values {
+			list[i] = parseJSONLiteral(v)
+		}
+		return list
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			object[field.Name.Value] = parseJSONLiteral(field.Value)
+		}
+		return object
+	}
+	return nil
+}
+
+// userField resolves a field of a user with get
+func userField(typ graphql.Output, get func(p graphql.ResolveParams, user *models.User) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			switch user := p.Source.(type) {
+			case *models.User:
+				return get(p, user), nil
+			case models.User:
+				return get(p, &user), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// contentField resolves a field of content with get
+func contentField(typ graphql.Output, get func(content *models.Content) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			switch content := p.Source.(type) {
+			case *models.Content:
+				return get(content), nil
+			case models.Content:
+				return get(&content), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// collaborationField resolves a field of a collaboration with get
+func collaborationField(typ graphql.Output, get func(collaboration *models.Collaboration) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			switch collaboration := p.Source.(type) {
+			case *models.Collaboration:
+				return get(collaboration), nil
+			case models.Collaboration:
+				return get(&collaboration), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// pageField resolves a field of a content page with get
+func pageField(typ graphql.Output, get func(page *ContentListResponse) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if page, ok := p.Source.(*ContentListResponse); ok {
+				return get(page), nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// NewGraphQLSchema builds the GraphQL schema: queries for content, user
+// profiles and collaborations, mutations creating and updating content, and
+// subscriptions to the collaboration rooms of hub
+func NewGraphQLSchema(hub *websocket.Hub) (graphql.Schema, error) {
+	nonNullString := graphql.NewNonNull(graphql.String)
+	nonNullBoolean := graphql.NewNonNull(graphql.Boolean)
+	nonNullInt := graphql.NewNonNull(graphql.Int)
+	nonNullID := graphql.NewNonNull(graphql.ID)
+	nonNullDateTime := graphql.NewNonNull(graphql.DateTime)
+
+	contentTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ContentType",
+		Values: graphql.EnumValueConfigMap{
+			"TEXT":     &graphql.EnumValueConfig{Value: models.ContentTypeText},
+			"CODE":     &graphql.EnumValueConfig{Value: models.ContentTypeCode},
+			"DIAGRAM":  &graphql.EnumValueConfig{Value: models.ContentTypeDiagram},
+			"IMAGE":    &graphql.EnumValueConfig{Value: models.ContentTypeImage},
+			"DOCUMENT": &graphql.EnumValueConfig{Value: models.ContentTypeDocument},
+			"TEMPLATE": &graphql.EnumValueConfig{Value: models.ContentTypeTemplate},
+		},
+	})
+
+	contentStatusEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ContentStatus",
+		Values: graphql.EnumValueConfigMap{
+			"DRAFT":     &graphql.EnumValueConfig{Value: models.ContentStatusDraft},
+			"PUBLISHED": &graphql.EnumValueConfig{Value: models.ContentStatusPublished},
+			"ARCHIVED":  &graphql.EnumValueConfig{Value: models.ContentStatusArchived},
+		},
+	})
+
+	contentVisibilityEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ContentVisibility",
+		Values: graphql.EnumValueConfigMap{
+			"PRIVATE":  &graphql.EnumValueConfig{Value: models.VisibilityPrivate},
+			"LINK":     &graphql.EnumValueConfig{Value: models.VisibilityLink},
+			"UNLISTED": &graphql.EnumValueConfig{Value: models.VisibilityUnlisted},
+			"PUBLIC":   &graphql.EnumValueConfig{Value: models.VisibilityPublic},
+		},
+	})
+
+	collaborationStatusEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "CollaborationStatus",
+		Values: graphql.EnumValueConfigMap{
+			"PENDING":  &graphql.EnumValueConfig{Value: models.CollaborationStatusPending},
+			"ACCEPTED": &graphql.EnumValueConfig{Value: models.CollaborationStatusAccepted},
+			"DECLINED": &graphql.EnumValueConfig{Value: models.CollaborationStatusDeclined},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": userField(nonNullID, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.ID.String() }),
+			// The email address is private to its owner
+			"email": userField(graphql.String, func(p graphql.ResolveParams, u *models.User) interface{} {
+				if viewer, exists := graphQLViewer(p); exists && viewer.ID == u.ID {
+					return u.Email
+				}
+				return nil
+			}),
+			"username":   userField(nonNullString, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.Username }),
+			"firstName":  userField(graphql.String, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.FirstName }),
+			"lastName":   userField(graphql.String, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.LastName }),
+			"avatar":     userField(graphql.String, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.Avatar }),
+			"bio":        userField(graphql.String, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.Bio }),
+			"isVerified": userField(nonNullBoolean, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.IsVerified }),
+			"createdAt":  userField(nonNullDateTime, func(_ graphql.ResolveParams, u *models.User) interface{} { return u.CreatedAt }),
+		},
+	})
+
+	contentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Content",
+		Fields: graphql.Fields{
+			"id":          contentField(nonNullID, func(c *models.Content) interface{} { return c.ID.String() }),
+			"title":       contentField(nonNullString, func(c *models.Content) interface{} { return c.Title }),
+			"description": contentField(graphql.String, func(c *models.Content) interface{} { return c.Description }),
+			"content":     contentField(graphql.String, func(c *models.Content) interface{} { return c.Content }),
+			"type":        contentField(graphql.NewNonNull(contentTypeEnum), func(c *models.Content) interface{} { return c.Type }),
+			"status":      contentField(graphql.NewNonNull(contentStatusEnum), func(c *models.Content) interface{} { return c.Status }),
+			"visibility":  contentField(graphql.NewNonNull(contentVisibilityEnum), func(c *models.Content) interface{} { return c.Visibility }),
+			"isPublic":    contentField(nonNullBoolean, func(c *models.Content) interface{} { return c.IsPublic }),
+			"isTemplate":  contentField(nonNullBoolean, func(c *models.Content) interface{} { return c.IsTemplate }),
+			"tags": contentField(graphql.NewNonNull(graphql.NewList(nonNullString)), func(c *models.Content) interface{} {
+				if c.Tags == nil {
+					return []string{}
+				}
+				return c.Tags
+			}),
+			"metadata":    contentField(jsonScalar, func(c *models.Content) interface{} { return c.Metadata }),
+			"aiGenerated": contentField(nonNullBoolean, func(c *models.Content) interface{} { return c.AIGenerated }),
+			"version":     contentField(nonNullInt, func(c *models.Content) interface{} { return c.Version }),
+			"parentId": contentField(graphql.ID, func(c *models.Content) interface{} {
+				if c.ParentID == nil {
+					return nil
+				}
+				return c.ParentID.String()
+			}),
+			"user": contentField(userType, func(c *models.Content) interface{} {
+				if c.User.ID == uuid.Nil {
+					return nil
+				}
+				return &c.User
+			}),
+			"reactionCounts": contentField(jsonScalar, func(c *models.Content) interface{} { return c.ReactionCounts }),
+			"isFavorited": contentField(graphql.Boolean, func(c *models.Content) interface{} {
+				if c.IsFavorited == nil {
+					return nil
+				}
+				return *c.IsFavorited
+			}),
+			"publishAt": contentField(graphql.DateTime, func(c *models.Content) interface{} {
+				if c.PublishAt == nil {
+					return nil
+				}
+				return *c.PublishAt
+			}),
+			"createdAt": contentField(nonNullDateTime, func(c *models.Content) interface{} { return c.CreatedAt }),
+			"updatedAt": contentField(nonNullDateTime, func(c *models.Content) interface{} { return c.UpdatedAt }),
+		},
+	})
+
+	contentPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ContentPage",
+		Fields: graphql.Fields{
+			"contents":    pageField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contentType))), func(p *ContentListResponse) interface{} { return p.Contents }),
+			"total":       pageField(nonNullInt, func(p *ContentListResponse) interface{} { return int(p.Total) }),
+			"page":        pageField(nonNullInt, func(p *ContentListResponse) interface{} { return p.Page }),
+			"perPage":     pageField(nonNullInt, func(p *ContentListResponse) interface{} { return p.PerPage }),
+			"totalPages":  pageField(nonNullInt, func(p *ContentListResponse) interface{} { return p.TotalPages }),
+			"hasNext":     pageField(nonNullBoolean, func(p *ContentListResponse) interface{} { return p.HasNext }),
+			"hasPrevious": pageField(nonNullBoolean, func(p *ContentListResponse) interface{} { return p.HasPrevious }),
+		},
+	})
+
+	collaborationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Collaboration",
+		Fields: graphql.Fields{
+			"id":     collaborationField(nonNullID, func(c *models.Collaboration) interface{} { return c.ID.String() }),
+			"role":   collaborationField(nonNullString, func(c *models.Collaboration) interface{} { return c.Role }),
+			"status": collaborationField(graphql.NewNonNull(collaborationStatusEnum), func(c *models.Collaboration) interface{} { return c.Status }),
+			"content": collaborationField(contentType, func(c *models.Collaboration) interface{} {
+				if c.Content.ID == uuid.Nil {
+					return nil
+				}
+				return &c.Content
+			}),
+			"joinedAt": collaborationField(graphql.DateTime, func(c *models.Collaboration) interface{} {
+				if c.JoinedAt.IsZero() {
+					return nil
+				}
+				return c.JoinedAt
+			}),
+			"lastActive": collaborationField(graphql.DateTime, func(c *models.Collaboration) interface{} {
+				if c.LastActive == nil {
+					return nil
+				}
+				return *c.LastActive
+			}),
+			"createdAt": collaborationField(nonNullDateTime, func(c *models.Collaboration) interface{} { return c.CreatedAt }),
+		},
+	})
+
+	createContentInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateContentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: nonNullString},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"content":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"type":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(contentTypeEnum)},
+			"visibility":  &graphql.InputObjectFieldConfig{Type: contentVisibilityEnum},
+			"isPublic":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"isTemplate":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(nonNullString)},
+			"metadata":    &graphql.InputObjectFieldConfig{Type: jsonScalar},
+			"parentId":    &graphql.InputObjectFieldConfig{Type: graphql.ID},
+			"publishAt":   &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	updateContentInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateContentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"content":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"type":        &graphql.InputObjectFieldConfig{Type: contentTypeEnum},
+			"status":      &graphql.InputObjectFieldConfig{Type: contentStatusEnum},
+			"visibility":  &graphql.InputObjectFieldConfig{Type: contentVisibilityEnum},
+			"isPublic":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"isTemplate":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(nonNullString)},
+			"metadata":    &graphql.InputObjectFieldConfig{Type: jsonScalar},
+			"publishAt":   &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"version": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "The version the update was made against; stale updates fail with PRECONDITION_FAILED",
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:        graphql.NewNonNull(userType),
+				Description: "The authenticated user",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphQLRequireViewer(p)
+				},
+			},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "A user by ID or username",
+				Args: graphql.FieldConfigArgument{
+					"id":       &graphql.ArgumentConfig{Type: graphql.ID},
+					"username": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolveGraphQLUser,
+			},
+			"content": &graphql.Field{
+				Type:        contentType,
+				Description: "Content the caller may access, by ID",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: nonNullID},
+				},
+				Resolve: resolveGraphQLContent,
+			},
+			"myContent": &graphql.Field{
+				Type:        graphql.NewNonNull(contentPageType),
+				Description: "A page of the authenticated user's content, most recently updated first",
+				Args: graphql.FieldConfigArgument{
+					"page":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"perPage": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+					"type":    &graphql.ArgumentConfig{Type: contentTypeEnum},
+					"status":  &graphql.ArgumentConfig{Type: contentStatusEnum},
+					"search":  &graphql.ArgumentConfig{Type: graphql.String},
+					"includeArchived": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+						Description:  "Include archived content when no status is given",
+					},
+				},
+				Resolve: resolveGraphQLMyContent,
+			},
+			"collaborations": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(collaborationType))),
+				Description: "The authenticated user's collaborations, newest first",
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{Type: collaborationStatusEnum},
+				},
+				Resolve: resolveGraphQLCollaborations,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createContent": &graphql.Field{
+				Type:        graphql.NewNonNull(contentType),
+				Description: "Creates draft content owned by the authenticated user",
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createContentInput)},
+				},
+				Resolve: resolveGraphQLCreateContent,
+			},
+			"updateContent": &graphql.Field{
+				Type:        graphql.NewNonNull(contentType),
+				Description: "Updates the given fields of content, recording a new version",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: nonNullID},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateContentInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveGraphQLUpdateContent(p, hub)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: graphQLSubscriptionType(hub, userType),
+	})
+}
+
+// resolveGraphQLUser looks a user up by exactly one of ID or username
+func resolveGraphQLUser(p graphql.ResolveParams) (interface{}, error) {
+	_, byID := p.Args["id"]
+	username, byUsername := p.Args["username"].(string)
+	if byID == byUsername {
+		return nil, newGraphQLError("INVALID_REQUEST", "Exactly one of id or username is required")
+	}
+
+	query := database.GetDB().WithContext(p.Context)
+	if byID {
+		id, err := graphQLID(p, "id", "INVALID_USER_ID")
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("username = ?", username)
+	}
+
+	var user models.User
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errGraphQLUserNotFound
+		}
+		return nil, errGraphQLDatabase
+	}
+	return &user, nil
+}
+
+// resolveGraphQLContent returns content the caller owns, collaborates on or
+// that is public, counting the view like GetContent
+func resolveGraphQLContent(p graphql.ResolveParams) (interface{}, error) {
+	id, err := graphQLID(p, "id", "INVALID_CONTENT_ID")
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := loadContent(p.Context, id)
+	if err != nil {
+		return nil, errGraphQLContentNotFound
+	}
+
+	user, exists := graphQLViewer(p)
+	if !models.Authorize(user, &content, models.PermissionView) {
+		return nil, errGraphQLAccessDenied
+	}
+
+	c := graphQLGinContext(p)
+	if !exists || content.UserID != user.ID {
+		recordContentView(c, content.ID)
+	}
+
+	contents := []models.Content{content}
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
+	return &contents[0], nil
+}
+
+// resolveGraphQLMyContent returns a page of the authenticated user's content
+func resolveGraphQLMyContent(p graphql.ResolveParams) (interface{}, error) {
+	user, err := graphQLRequireViewer(p)
+	if err != nil {
+		return nil, err
+	}
+	page, perPage := graphQLPage(p.Args)
+
+	query := database.GetDB().WithContext(p.Context).Model(&models.Content{}).Where("user_id = ?", user.ID)
+	if contentType, ok := p.Args["type"].(models.ContentType); ok {
+		query = query.Where("type = ?", contentType)
+	}
+	if status, ok := p.Args["status"].(models.ContentStatus); ok {
+		query = query.Where("status = ?", status)
+	} else if includeArchived, _ := p.Args["includeArchived"].(bool); !includeArchived {
+		query = query.Where("status <> ?", models.ContentStatusArchived)
+	}
+	if search, _ := p.Args["search"].(string); search != "" {
+		if query, err = applyContentSearch(query, search, "", true); err != nil {
+			return nil, newGraphQLError("INVALID_SEARCH_MODE", err.Error())
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errGraphQLDatabase
+	}
+
+	var contents []models.Content
+	if err := query.Preload("User").Offset((page - 1) * perPage).Limit(perPage).Order("updated_at DESC").Find(&contents).Error; err != nil {
+		return nil, errGraphQLDatabase
+	}
+	attachReactionCounts(contents)
+	attachFavorites(graphQLGinContext(p), contents)
+
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+	return &ContentListResponse{
+		Contents:    contents,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}, nil
+}
+
+// resolveGraphQLCollaborations lists the authenticated user's
+// collaborations, optionally filtered by status
+func resolveGraphQLCollaborations(p graphql.ResolveParams) (interface{}, error) {
+	user, err := graphQLRequireViewer(p)
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.GetDB().WithContext(p.Context).Preload("Content").Where("user_id = ?", user.ID)
+	if status, ok := p.Args["status"].(string); ok {
+		query = query.Where("status = ?", status)
+	}
+
+	var collaborations []models.Collaboration
+	if err := query.Order("created_at DESC").Find(&collaborations).Error; err != nil {
+		return nil, errGraphQLDatabase
+	}
+	return collaborations, nil
+}
+
+// resolveGraphQLCreateContent creates content through the same path as
+// CreateContent, validating the input with its binding rules
+func resolveGraphQLCreateContent(p graphql.ResolveParams) (interface{}, error) {
+	user, err := graphQLRequireViewer(p)
+	if err != nil {
+		return nil, err
+	}
+
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := CreateContentRequest{}
+	req.Title, _ = input["title"].(string)
+	req.Description, _ = input["description"].(string)
+	req.Content, _ = input["content"].(string)
+	req.Type, _ = input["type"].(models.ContentType)
+	req.Visibility, _ = input["visibility"].(models.Visibility)
+	req.IsPublic, _ = input["isPublic"].(bool)
+	req.IsTemplate, _ = input["isTemplate"].(bool)
+	if tags, ok := input["tags"]; ok {
+		req.Tags = graphQLStrings(tags)
+	}
+	if metadata, ok := input["metadata"]; ok && metadata != nil {
+		if req.Metadata, ok = metadata.(map[string]interface{}); !ok {
+			return nil, newGraphQLError("INVALID_REQUEST", "metadata must be an object")
+		}
+	}
+	if parentID, ok := input["parentId"].(string); ok {
+		req.ParentID = &parentID
+	}
+	if publishAt, ok := input["publishAt"].(time.Time); ok {
+		req.PublishAt = &publishAt
+	}
+
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, newGraphQLError("INVALID_REQUEST", err.Error())
+	}
+
+	content, err := createContent(p.Context, user, req)
+	if err != nil {
+		return nil, graphQLContentError(err)
+	}
+	return &content, nil
+}
+
+// resolveGraphQLUpdateContent updates the fields present in the input
+// through the same path as UpdateContent
+func resolveGraphQLUpdateContent(p graphql.ResolveParams, hub *websocket.Hub) (interface{}, error) {
+	user, err := graphQLRequireViewer(p)
+	if err != nil {
+		return nil, err
+	}
+	id, err := graphQLID(p, "id", "INVALID_CONTENT_ID")
+	if err != nil {
+		return nil, err
+	}
+
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := UpdateContentRequest{}
+	if title, ok := input["title"].(string); ok {
+		req.Title = &title
+	}
+	if description, ok := input["description"].(string); ok {
+		req.Description = &description
+	}
+	if body, ok := input["content"].(string); ok {
+		req.Content = &body
+	}
+	if contentType, ok := input["type"].(models.ContentType); ok {
+		req.Type = &contentType
+	}
+	if status, ok := input["status"].(models.ContentStatus); ok {
+		req.Status = &status
+	}
+	if publishAt, ok := input["publishAt"].(time.Time); ok {
+		req.PublishAt = &publishAt
+	}
+	if visibility, ok := input["visibility"].(models.Visibility); ok {
+		req.Visibility = &visibility
+	}
+	if isPublic, ok := input["isPublic"].(bool); ok {
+		req.IsPublic = &isPublic
+	}
+	if isTemplate, ok := input["isTemplate"].(bool); ok {
+		req.IsTemplate = &isTemplate
+	}
+	if value, ok := input["tags"]; ok && value != nil {
+		tags := graphQLStrings(value)
+		req.Tags = &tags
+	}
+	if value, ok := input["metadata"]; ok && value != nil {
+		metadata, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, newGraphQLError("INVALID_REQUEST", "metadata must be an object")
+		}
+		req.Metadata = &metadata
+	}
+
+	if version, ok := input["version"].(int); ok {
+		req.Version = &version
+	}
+
+	content, err := updateContent(p.Context, hub, id, user.ID, req, "")
+	if err != nil {
+		return nil, graphQLContentError(err)
+	}
+	return &content, nil
+}
+
+// graphQLPaginatedFields are the fields returning a page of results,
+// whose selections are multiplied by the page size when costing a query
+var graphQLPaginatedFields = map[string]bool{
+	"myContent": true,
+}
+
+// graphQLCostMeter measures the depth and complexity of an operation
+type graphQLCostMeter struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// visiting guards against fragment cycles, which validation rejects
+	// later but would otherwise recurse forever here
+	visiting map[string]bool
+}
+
+// graphQLCost returns the depth and complexity of the operation a request
+// executes. Each field costs one, and the selections of a paginated field
+// cost once per item of the requested page.
+func graphQLCost(doc *ast.Document, operationName string, variables map[string]interface{}) (int, int) {
+	meter := graphQLCostMeter{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		visiting:  make(map[string]bool),
+	}
+
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			meter.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	operation := graphQLOperation(doc, operationName)
+	if operation == nil {
+		return 0, 0
+	}
+	return meter.selectionSet(operation.SelectionSet)
+}
+
+// graphQLOperation returns the operation of a document a request executes:
+// the one named operationName, or the first when no name is given
+func graphQLOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation
+		}
+	}
+	return nil
+}
+
+// selectionSet returns the depth and complexity of a selection set
+func (m *graphQLCostMeter) selectionSet(set *ast.SelectionSet) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	depth, complexity := 0, 0
+	for _, selection := range set.Selections {
+		var d, cost int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			childDepth, childCost := m.selectionSet(selection.SelectionSet)
+			// Introspection is bounded by the schema but nests type
+			// references deeper than any data query
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				childDepth = 0
+			}
+			d = childDepth + 1
+			cost = 1 + childCost*m.multiplier(selection)
+		case *ast.InlineFragment:
+			d, cost = m.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			d, cost = m.selectionSet(fragment.SelectionSet)
+			delete(m.visiting, name)
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += cost
+	}
+	return depth, complexity
+}
+
+// multiplier returns how many times the selections of a field are resolved
+func (m *graphQLCostMeter) multiplier(field *ast.Field) int {
+	if !graphQLPaginatedFields[field.Name.Value] {
+		return 1
+	}
+
+	args := make(map[string]interface{})
+	for _, argument := range field.Arguments {
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			n, _ := strconv.Atoi(value.Value)
+			args[argument.Name.Value] = n
+		case *ast.Variable:
+			// JSON numbers decode as float64
+			if n, ok := m.variables[value.Name.Value].(float64); ok {
+				args[argument.Name.Value] = int(n)
+			}
+		}
+	}
+	_, perPage := graphQLPage(args)
+	return perPage
+}
+
+// parseGraphQLRequest parses the query of a request, rejecting it when the
+// operation is nested deeper or costs more than configured
+func parseGraphQLRequest(req graphQLRequest) (*ast.Document, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return nil, newGraphQLError("GRAPHQL_PARSE_FAILED", err.Error())
+	}
+
+	cfg := config.Load().GraphQL
+	depth, complexity := graphQLCost(doc, req.OperationName, req.Variables)
+	if cfg.MaxDepth > 0 && depth > cfg.MaxDepth {
+		return nil, newGraphQLError("QUERY_TOO_DEEP",
+			fmt.Sprintf("Query depth %d exceeds the limit of %d", depth, cfg.MaxDepth))
+	}
+	if cfg.MaxComplexity > 0 && complexity > cfg.MaxComplexity {
+		return nil, newGraphQLError("QUERY_TOO_COMPLEX",
+			fmt.Sprintf("Query complexity %d exceeds the limit of %d", complexity, cfg.MaxComplexity))
+	}
+	return doc, nil
+}
+
+// graphQLErrors lists an error rejecting a whole request in the format of
+// GraphQL response errors
+func graphQLErrors(err error) []gin.H {
+	formatted := gin.H{"message": err.Error()}
+	var gqlErr *graphQLError
+	if errors.As(err, &gqlErr) {
+		formatted["extensions"] = gqlErr.Extensions()
+	}
+	return []gin.H{formatted}
+}
+
+// GraphQLHandler executes GraphQL queries and mutations against schema. It
+// runs as the authenticated user when the request carries a token and
+// rejects queries nested deeper or costing more than configured before
+// executing them.
+func GraphQLHandler(schema graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req graphQLRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": graphQLErrors(newGraphQLError("INVALID_REQUEST", err.Error()))})
+			return
+		}
+
+		doc, err := parseGraphQLRequest(req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": graphQLErrors(err)})
+			return
+		}
+		// Subscriptions stream over the GraphQL WebSocket
+		if operation := graphQLOperation(doc, req.OperationName); operation != nil && operation.Operation == ast.OperationTypeSubscription {
+			c.JSON(http.StatusBadRequest, gin.H{"errors": graphQLErrors(newGraphQLError("SUBSCRIPTION_REQUIRES_WEBSOCKET", "Subscriptions are served over WebSocket at /graphql/ws"))})
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), graphQLContextKey{}, c)
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        ctx,
+		})
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// graphQLPlaygroundPage loads GraphiQL from a CDN and points it at /graphql,
+// running subscriptions over /graphql/ws with the headers from its headers tab
+const graphQLPlaygroundPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8" />
+	<title>Open Same GraphQL</title>
+	<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css" />
+</head>
+<body style="margin: 0">
+	<div id="graphiql" style="height: 100vh"></div>
+	<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/graphql-ws@5/umd/graphql-ws.min.js"></script>
+	<script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+	<script>
+		const wsClient = graphqlWs.createClient({
+			url: (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/graphql/ws',
+			connectionParams: () => {
+				try {
+					return JSON.parse(localStorage.getItem('graphiql:headers') || '{}');
+				} catch (e) {
+					return {};
+				}
+			},
+		});
+		const fetcher = GraphiQL.createFetcher({ url: '/graphql', wsClient });
+		ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher }));
+	</script>
+</body>
+</html>
+`
+
+// GraphQLPlayground serves the GraphiQL explorer. It is only routed outside
+// production; send an Authorization header from its headers tab to run as
+// a user.
+func GraphQLPlayground(c *gin.Context) {
+	// The explorer's scripts and styles come from the CDN
+	c.Header("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https:; font-src 'self' data: https://unpkg.com; connect-src 'self' ws: wss:; frame-ancestors 'none';")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(graphQLPlaygroundPage))
+}
@@ -0,0 +1,632 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/open-same/backend/internal/jwtkeys"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/websocket"
+)
+
+// graphQLWSProtocol is the graphql-ws subprotocol spoken on the GraphQL
+// WebSocket
+const graphQLWSProtocol = "graphql-transport-ws"
+
+const (
+	// graphQLWSInitTimeout is how long a socket may stay open before
+	// authenticating with connection_init
+	graphQLWSInitTimeout = 10 * time.Second
+	// graphQLWSWriteWait bounds a single write to the socket
+	graphQLWSWriteWait = 10 * time.Second
+	// graphQLWSPongWait is how long the socket may stay silent; pings are
+	// sent well within it
+	graphQLWSPongWait   = 60 * time.Second
+	graphQLWSPingPeriod = graphQLWSPongWait * 9 / 10
+	// graphQLWSMaxMessageSize is the largest message a client may send
+	graphQLWSMaxMessageSize = 64 << 10
+	// graphQLWSMaxOperations is how many operations a socket may run at once
+	graphQLWSMaxOperations = 20
+)
+
+// Close codes of the graphql-ws protocol
+const (
+	graphQLWSCloseBadRequest         = 4400
+	graphQLWSCloseUnauthorized       = 4401
+	graphQLWSCloseForbidden          = 4403
+	graphQLWSCloseSubprotocol        = 4406
+	graphQLWSCloseInitTimeout        = 4408
+	graphQLWSCloseSubscriberExists   = 4409
+	graphQLWSCloseTooManyInitRequest = 4429
+)
+
+// graphQLWSMessage is a message of the graphql-ws protocol
+type graphQLWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphQLSubscriptionType is the root of the subscriptions, which stream the
+// messages the hub broadcasts to a content room
+func graphQLSubscriptionType(hub *websocket.Hub, userType *graphql.Object) *graphql.Object {
+	contentChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ContentChange",
+		Fields: graphql.Fields{
+			"contentId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"content":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"userId":    &graphql.Field{Type: graphql.ID},
+			"username":  &graphql.Field{Type: graphql.String},
+			"timestamp": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	presenceUserType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PresenceUser",
+		Fields: graphql.Fields{
+			"userId":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"username":   &graphql.Field{Type: graphql.String},
+			"typing":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"lastActive": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	presenceType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Presence",
+		Fields: graphql.Fields{
+			"contentId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"users":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(presenceUserType)))},
+		},
+	})
+
+	commentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Comment",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"contentId":  &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"parentId":   &graphql.Field{Type: graphql.ID},
+			"body":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"anchor":     &graphql.Field{Type: jsonScalar},
+			"isResolved": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"user":       &graphql.Field{Type: userType},
+			"createdAt":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	contentIDArgs := graphql.FieldConfigArgument{
+		"contentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+	}
+	resolveEvent := func(p graphql.ResolveParams) (interface{}, error) {
+		return p.Source, nil
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"contentChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(contentChangeType),
+				Description: "Live edits of content accepted in its collaboration room",
+				Args:        contentIDArgs,
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					return subscribeToContentRoom(p, hub, false, func(message websocket.Message) interface{} {
+						return contentChangeEvent(message)
+					})
+				},
+				Resolve: resolveEvent,
+			},
+			"presenceChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(presenceType),
+				Description: "The users in the collaboration room of content, sent on subscribing and whenever someone joins, leaves or types",
+				Args:        contentIDArgs,
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					return subscribeToContentRoom(p, hub, true, func(message websocket.Message) interface{} {
+						switch message.Type {
+						case "user_joined", "user_left", "typing_start", "typing_stop":
+							return presenceEvent(hub, message.RoomID)
+						}
+						return nil
+					})
+				},
+				Resolve: resolveEvent,
+			},
+			"commentAdded": &graphql.Field{
+				Type:        graphql.NewNonNull(commentType),
+				Description: "Comments added to content",
+				Args:        contentIDArgs,
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					return subscribeToContentRoom(p, hub, false, func(message websocket.Message) interface{} {
+						return commentAddedEvent(message)
+					})
+				},
+				Resolve: resolveEvent,
+			},
+		},
+	})
+}
+
+// subscribeToContentRoom streams the events toEvent makes of the messages
+// broadcast to the room of the contentId argument until the subscription
+// ends. Only users who may join the room can subscribe. With sendPresence
+// the room's presence is sent first.
+func subscribeToContentRoom(p graphql.ResolveParams, hub *websocket.Hub, sendPresence bool, toEvent func(websocket.Message) interface{}) (interface{}, error) {
+	user, err := graphQLRequireViewer(p)
+	if err != nil {
+		return nil, err
+	}
+	contentID, err := graphQLID(p, "contentId", "INVALID_CONTENT_ID")
+	if err != nil {
+		return nil, err
+	}
+	roomID := contentID.String()
+
+	allowed, err := CanAccessContentRoom(user.ID.String(), roomID)
+	if err != nil {
+		log.Printf("Room authorization failed for user %s in room %s: %v", user.ID, roomID, err)
+		return nil, errGraphQLDatabase
+	}
+	if !allowed {
+		return nil, errGraphQLAccessDenied
+	}
+
+	messages, stop := hub.ListenToRoom(roomID)
+	events := make(chan interface{})
+	go func() {
+		defer close(events)
+		defer stop()
+
+		if sendPresence {
+			select {
+			case events <- presenceEvent(hub, roomID):
+			case <-p.Context.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				event := toEvent(message)
+				if event == nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// messageInt reads a number from message data, which is decoded as float64
+// when relayed from another replica
+func messageInt(value interface{}) int {
+	switch n := value.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// contentChangeEvent converts an accepted live edit
+func contentChangeEvent(message websocket.Message) interface{} {
+	if message.Type != "content_change" {
+		return nil
+	}
+	content, _ := message.Data["content"].(string)
+	return map[string]interface{}{
+		"contentId": message.RoomID,
+		"version":   messageInt(message.Data["version"]),
+		"content":   content,
+		"userId":    message.UserID,
+		"username":  message.Username,
+		"timestamp": message.Timestamp,
+	}
+}
+
+// presenceEvent describes the users currently in a room
+func presenceEvent(hub *websocket.Hub, roomID string) interface{} {
+	presence := hub.GetRoomPresence(roomID)
+	users := make([]interface{}, len(presence))
+	for i, entry := range presence {
+		users[i] = map[string]interface{}{
+			"userId":     entry.UserID,
+			"username":   entry.Username,
+			"typing":     entry.Typing,
+			"lastActive": entry.LastActive,
+		}
+	}
+	return map[string]interface{}{
+		"contentId": roomID,
+		"users":     users,
+	}
+}
+
+// commentAddedEvent converts a comment creation
+func commentAddedEvent(message websocket.Message) interface{} {
+	if message.Type != "comment_created" {
+		return nil
+	}
+
+	// Comments relayed from other replicas arrive decoded as JSON objects
+	var comment models.Comment
+	data, err := json.Marshal(message.Data["comment"])
+	if err == nil {
+		err = json.Unmarshal(data, &comment)
+	}
+	if err != nil {
+		log.Printf("Failed to decode comment in room %s: %v", message.RoomID, err)
+		return nil
+	}
+
+	event := map[string]interface{}{
+		"id":         comment.ID.String(),
+		"contentId":  comment.ContentID.String(),
+		"parentId":   nil,
+		"body":       comment.Body,
+		"anchor":     comment.Anchor,
+		"isResolved": comment.IsResolved,
+		"user":       nil,
+		"createdAt":  comment.CreatedAt,
+	}
+	if comment.ParentID != nil {
+		event["parentId"] = comment.ParentID.String()
+	}
+	if comment.User.ID != uuid.Nil {
+		event["user"] = &comment.User
+	}
+	return event
+}
+
+// graphQLWSConn is a socket speaking the graphql-ws protocol
+type graphQLWSConn struct {
+	conn   *gorillaws.Conn
+	schema graphql.Schema
+	keys   *jwtkeys.KeySet
+	// c is the upgrade request, carrying the user once authenticated
+	c *gin.Context
+	// ctx is cancelled when the socket closes, ending its operations
+	ctx context.Context
+
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	authenticated bool
+	initReceived  bool
+	operations    map[string]context.CancelFunc
+	running       sync.WaitGroup
+}
+
+// GraphQLSubscriptions serves GraphQL over WebSocket with the graphql-ws
+// protocol, streaming subscriptions bridged to the collaboration hub.
+// Queries and mutations may run on the socket too. Clients authenticate
+// with a JWT in the connection_init payload's Authorization field, or on
+// the upgrade request like /ws.
+func GraphQLSubscriptions(schema graphql.Schema, hub *websocket.Hub, keys *jwtkeys.KeySet) gin.HandlerFunc {
+	upgrader := gorillaws.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     hub.CheckOrigin,
+		Subprotocols:    []string{graphQLWSProtocol},
+	}
+
+	return func(c *gin.Context) {
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("GraphQL WebSocket upgrade failed: %v", err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.WithValue(c.Request.Context(), graphQLContextKey{}, c))
+		ws := &graphQLWSConn{
+			conn:       conn,
+			schema:     schema,
+			keys:       keys,
+			c:          c,
+			ctx:        ctx,
+			operations: make(map[string]context.CancelFunc),
+		}
+
+		if conn.Subprotocol() != graphQLWSProtocol {
+			ws.close(graphQLWSCloseSubprotocol, "Subprotocol not acceptable")
+			cancel()
+			return
+		}
+
+		ws.serve()
+
+		// Operations use the request, so they must end before it is reused
+		cancel()
+		ws.running.Wait()
+		conn.Close()
+	}
+}
+
+// serve reads messages until the socket closes
+func (ws *graphQLWSConn) serve() {
+	ws.conn.SetReadLimit(graphQLWSMaxMessageSize)
+	ws.conn.SetReadDeadline(time.Now().Add(graphQLWSPongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		ws.conn.SetReadDeadline(time.Now().Add(graphQLWSPongWait))
+		return nil
+	})
+
+	initTimer := time.AfterFunc(graphQLWSInitTimeout, func() {
+		ws.mu.Lock()
+		authenticated := ws.authenticated
+		ws.mu.Unlock()
+		if !authenticated {
+			ws.close(graphQLWSCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	done := make(chan struct{})
+	defer close(done)
+	go ws.keepAlive(done)
+
+	for {
+		_, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		ws.conn.SetReadDeadline(time.Now().Add(graphQLWSPongWait))
+
+		var message graphQLWSMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			ws.close(graphQLWSCloseBadRequest, "Invalid message")
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if !ws.handleInit(message) {
+				return
+			}
+		case "ping":
+			ws.send(graphQLWSMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !ws.handleSubscribe(message) {
+				return
+			}
+		case "complete":
+			ws.stopOperation(message.ID)
+		default:
+			ws.close(graphQLWSCloseBadRequest, "Unknown message type "+message.Type)
+			return
+		}
+	}
+}
+
+// keepAlive pings the client until done is closed
+func (ws *graphQLWSConn) keepAlive(done chan struct{}) {
+	ticker := time.NewTicker(graphQLWSPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ws.writeMu.Lock()
+			err := ws.conn.WriteControl(gorillaws.PingMessage, nil, time.Now().Add(graphQLWSWriteWait))
+			ws.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleInit authenticates the socket, reporting whether it stays open
+func (ws *graphQLWSConn) handleInit(message graphQLWSMessage) bool {
+	ws.mu.Lock()
+	initReceived := ws.initReceived
+	ws.initReceived = true
+	ws.mu.Unlock()
+	if initReceived {
+		ws.close(graphQLWSCloseTooManyInitRequest, "Too many initialisation requests")
+		return false
+	}
+
+	var params map[string]interface{}
+	if len(message.Payload) > 0 {
+		json.Unmarshal(message.Payload, &params)
+	}
+
+	user, err := middleware.UserFromToken(ws.ctx, ws.keys, graphQLWSToken(ws.c, params))
+	if err != nil {
+		ws.close(graphQLWSCloseForbidden, "Forbidden")
+		return false
+	}
+	middleware.SetUserContext(ws.c, user)
+
+	ws.mu.Lock()
+	ws.authenticated = true
+	ws.mu.Unlock()
+
+	ws.send(graphQLWSMessage{Type: "connection_ack"})
+	return true
+}
+
+// graphQLWSToken returns the JWT of a socket from the connection_init
+// payload, falling back to the upgrade request
+func graphQLWSToken(c *gin.Context, params map[string]interface{}) string {
+	for _, key := range []string{"Authorization", "authorization"} {
+		if value, ok := params[key].(string); ok && value != "" {
+			return strings.TrimPrefix(value, "Bearer ")
+		}
+	}
+	if token, ok := params["token"].(string); ok && token != "" {
+		return token
+	}
+
+	if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	return c.Query("token")
+}
+
+// handleSubscribe starts an operation, reporting whether the socket stays
+// open
+func (ws *graphQLWSConn) handleSubscribe(message graphQLWSMessage) bool {
+	var req graphQLRequest
+	if err := json.Unmarshal(message.Payload, &req); err != nil || message.ID == "" || req.Query == "" {
+		ws.close(graphQLWSCloseBadRequest, "Invalid subscribe message")
+		return false
+	}
+
+	ws.mu.Lock()
+	if !ws.authenticated {
+		ws.mu.Unlock()
+		ws.close(graphQLWSCloseUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, exists := ws.operations[message.ID]; exists {
+		ws.mu.Unlock()
+		ws.close(graphQLWSCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", message.ID))
+		return false
+	}
+	if len(ws.operations) >= graphQLWSMaxOperations {
+		ws.mu.Unlock()
+		ws.sendErrors(message.ID, graphQLErrors(newGraphQLError("TOO_MANY_OPERATIONS",
+			fmt.Sprintf("At most %d operations may run on a connection", graphQLWSMaxOperations))))
+		return true
+	}
+	ctx, cancel := context.WithCancel(ws.ctx)
+	ws.operations[message.ID] = cancel
+	ws.running.Add(1)
+	ws.mu.Unlock()
+
+	go ws.run(ctx, message.ID, req)
+	return true
+}
+
+// run executes an operation and sends its results, ending with complete
+// unless the client completed it first
+func (ws *graphQLWSConn) run(ctx context.Context, id string, req graphQLRequest) {
+	defer ws.running.Done()
+
+	doc, err := parseGraphQLRequest(req)
+	if err != nil {
+		ws.finishOperation(id)
+		ws.sendErrors(id, graphQLErrors(err))
+		return
+	}
+
+	params := graphql.Params{
+		Schema:         ws.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	}
+
+	operation := graphQLOperation(doc, req.OperationName)
+	if operation == nil || operation.Operation != ast.OperationTypeSubscription {
+		ws.sendResult(id, graphql.Do(params))
+	} else {
+		for result := range graphql.Subscribe(params) {
+			if ctx.Err() != nil {
+				continue
+			}
+			ws.sendResult(id, result)
+		}
+	}
+
+	if ws.finishOperation(id) {
+		ws.send(graphQLWSMessage{ID: id, Type: "complete"})
+	}
+}
+
+// sendResult sends a result of an operation. A result without data is an
+// operation that failed as a whole.
+func (ws *graphQLWSConn) sendResult(id string, result *graphql.Result) {
+	if result.Data == nil && result.HasErrors() {
+		payload, _ := json.Marshal(result.Errors)
+		ws.send(graphQLWSMessage{ID: id, Type: "error", Payload: payload})
+		return
+	}
+	payload, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("Failed to encode GraphQL result: %v", err)
+		return
+	}
+	ws.send(graphQLWSMessage{ID: id, Type: "next", Payload: payload})
+}
+
+// sendErrors ends an operation with errors
+func (ws *graphQLWSConn) sendErrors(id string, errs []gin.H) {
+	payload, _ := json.Marshal(errs)
+	ws.send(graphQLWSMessage{ID: id, Type: "error", Payload: payload})
+}
+
+// stopOperation ends an operation the client completed
+func (ws *graphQLWSConn) stopOperation(id string) {
+	ws.mu.Lock()
+	cancel, exists := ws.operations[id]
+	delete(ws.operations, id)
+	ws.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+// finishOperation forgets an operation that ended, reporting whether it
+// was still running rather than completed by the client
+func (ws *graphQLWSConn) finishOperation(id string) bool {
+	ws.mu.Lock()
+	cancel, exists := ws.operations[id]
+	delete(ws.operations, id)
+	ws.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+	return exists
+}
+
+// send writes a message to the socket
+func (ws *graphQLWSConn) send(message graphQLWSMessage) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	ws.conn.SetWriteDeadline(time.Now().Add(graphQLWSWriteWait))
+	if err := ws.conn.WriteJSON(message); err != nil {
+		log.Printf("Failed to write to GraphQL WebSocket: %v", err)
+	}
+}
+
+// close sends a close frame with a graphql-ws close code and closes the
+// socket, which ends serve
+func (ws *graphQLWSConn) close(code int, reason string) {
+	ws.writeMu.Lock()
+	ws.conn.WriteControl(gorillaws.CloseMessage, gorillaws.FormatCloseMessage(code, reason), time.Now().Add(graphQLWSWriteWait))
+	ws.writeMu.Unlock()
+	ws.conn.Close()
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/redis"
+)
+
+// dependencyTimeout bounds each dependency check so probes answer quickly
+const dependencyTimeout = 2 * time.Second
+
+// DependencyStatus reports the reachability of a single dependency
+type DependencyStatus struct {
+	Status    string `json:"status"` // up or down
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Health reports the status of each dependency, responding 503 when any of
+// them is unreachable
+func Health(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checks, healthy := checkDependencies(c.Request.Context())
+
+		status, code := "healthy", http.StatusOK
+		if !healthy {
+			status, code = "unhealthy", http.StatusServiceUnavailable
+		}
+
+		c.JSON(code, gin.H{
+			"status":    status,
+			"timestamp": time.Now().UTC(),
+			"version":   version,
+			"checks":    checks,
+		})
+	}
+}
+
+// Ready is the readiness probe: it fails until migrations have completed
+// and every dependency is reachable
+func Ready(c *gin.Context) {
+	if !database.Migrated() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not_ready",
+			"reason": "database migrations have not completed",
+		})
+		return
+	}
+
+	checks, healthy := checkDependencies(c.Request.Context())
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not_ready",
+			"checks": checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ready",
+		"checks": checks,
+	})
+}
+
+// Live is the liveness probe: it only shows the process is serving requests
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "alive",
+	})
+}
+
+// checkDependencies pings Postgres and Redis concurrently and reports
+// whether all of them are up. Failures are logged rather than returned so
+// probes do not expose connection details.
+func checkDependencies(ctx context.Context) (map[string]DependencyStatus, bool) {
+	checks := map[string]func(context.Context) error{
+		"database": func(ctx context.Context) error {
+			sqlDB, err := database.GetDB().DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+		"redis": func(ctx context.Context) error {
+			return redis.GetClient().Ping(ctx).Err()
+		},
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]DependencyStatus, len(checks))
+		healthy = true
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, dependencyTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+			result := DependencyStatus{Status: "up", LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				log.Printf("Health check for %s failed: %v", name, err)
+				result.Status = "down"
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			if err != nil {
+				healthy = false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return results, healthy
+}
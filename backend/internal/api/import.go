@@ -0,0 +1,317 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/webhook"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gorm.io/gorm"
+)
+
+// importFormats maps accepted upload MIME types to the import format
+var importFormats = map[string]string{
+	"text/markdown":   "markdown",
+	"text/x-markdown": "markdown",
+	"text/html":       "html",
+}
+
+// importExtensions maps accepted file extensions to the import format, used
+// when the client sends a generic MIME type
+var importExtensions = map[string]string{
+	".md":       "markdown",
+	".markdown": "markdown",
+	".html":     "html",
+	".htm":      "html",
+}
+
+// fencedCodeBlock matches a Markdown document made of a single fenced code block
+var fencedCodeBlock = regexp.MustCompile("^```[^\\n]*\\n[\\s\\S]*\\n```$")
+
+// maxTitleLength mirrors the title limit enforced when creating content
+const maxTitleLength = 200
+
+// ImportContent creates draft content from an uploaded Markdown or HTML file.
+// With dry_run=true the parsed content is returned without being saved.
+func ImportContent(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	maxSize := config.Load().Content.MaxImportSize
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "File too large",
+				"code":    "FILE_TOO_LARGE",
+				"message": fmt.Sprintf("Imported files may be at most %d bytes", maxSize),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "File required",
+			"code":    "MISSING_FILE",
+			"message": "Upload a Markdown or HTML file in the file field",
+		})
+		return
+	}
+
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "File too large",
+			"code":    "FILE_TOO_LARGE",
+			"message": fmt.Sprintf("Imported files may be at most %d bytes", maxSize),
+		})
+		return
+	}
+
+	format := importFormat(fileHeader.Header.Get("Content-Type"), fileHeader.Filename)
+	if format == "" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "Unsupported file type",
+			"code":    "UNSUPPORTED_MEDIA_TYPE",
+			"message": "Only Markdown (.md, text/markdown) and HTML (.html, text/html) files can be imported",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read file",
+			"code":    "INVALID_FILE",
+			"message": "The uploaded file could not be read",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize))
+	if err != nil || !utf8.Valid(data) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read file",
+			"code":    "INVALID_FILE",
+			"message": "The uploaded file must be UTF-8 encoded text",
+		})
+		return
+	}
+
+	var content models.Content
+	if format == "html" {
+		content, err = parseHTMLImport(data)
+	} else {
+		content = parseMarkdownImport(data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to parse file",
+			"code":    "INVALID_FILE",
+			"message": "The uploaded HTML could not be parsed",
+		})
+		return
+	}
+
+	if content.Title == "" {
+		content.Title = strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+	}
+	if content.Title == "" {
+		content.Title = "Imported content"
+	}
+	if utf8.RuneCountInString(content.Title) > maxTitleLength {
+		content.Title = string([]rune(content.Title)[:maxTitleLength])
+	}
+
+	content.UserID = user.ID
+	content.Status = models.ContentStatusDraft
+	content.Version = 1
+	content.Metadata = models.JSON{
+		"imported_from": fileHeader.Filename,
+		"import_format": format,
+	}
+
+	if c.Query("dry_run") == "true" || c.PostForm("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content parsed successfully",
+			"dry_run": true,
+			"data":    content,
+		})
+		return
+	}
+
+	if !requireFeature(c, user, features.Content, 1) {
+		return
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&content).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ContentVersion{
+			ContentID:   content.ID,
+			Version:     1,
+			Content:     content.Content,
+			Title:       content.Title,
+			Description: content.Description,
+			Tags:        content.Tags,
+			Metadata:    content.Metadata,
+			CreatedBy:   &user.ID,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while importing content",
+		})
+		return
+	}
+
+	// Load relationships
+	database.GetDB().Preload("User").First(&content, content.ID)
+
+	recordActivity(content.ID, user.ID, models.ActivityContentCreated, models.JSON{
+		"imported_from": fileHeader.Filename,
+	})
+	webhook.Dispatch(content.UserID, models.WebhookEventContentCreated, content)
+	indexContentEmbedding(content)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Content imported successfully",
+		"data":    content,
+	})
+}
+
+// importFormat resolves the import format from the upload's MIME type,
+// falling back to the file extension for generic types
+func importFormat(contentType, filename string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if format, ok := importFormats[mediaType]; ok {
+			return format
+		}
+		if mediaType != "text/plain" && mediaType != "application/octet-stream" {
+			return ""
+		}
+	}
+	return importExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// parseMarkdownImport takes the title from the first level one heading and
+// keeps the rest of the document as the body
+func parseMarkdownImport(data []byte) models.Content {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	var title string
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") {
+			title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			lines = append(lines[:i], lines[i+1:]...)
+			break
+		}
+	}
+
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+	contentType := models.ContentTypeText
+	if fencedCodeBlock.MatchString(body) {
+		contentType = models.ContentTypeCode
+	}
+
+	return models.Content{
+		Title:   title,
+		Content: body,
+		Type:    contentType,
+	}
+}
+
+// parseHTMLImport takes the title from the first heading, or the document
+// title, and keeps the markup inside <body> as the body
+func parseHTMLImport(data []byte) (models.Content, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return models.Content{}, err
+	}
+
+	var heading, docTitle string
+	var body *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.H1, atom.H2, atom.H3:
+				if heading == "" {
+					heading = strings.TrimSpace(nodeText(n))
+				}
+			case atom.Title:
+				if docTitle == "" {
+					docTitle = strings.TrimSpace(nodeText(n))
+				}
+			case atom.Body:
+				body = n
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	var buf bytes.Buffer
+	if body != nil {
+		for child := body.FirstChild; child != nil; child = child.NextSibling {
+			if err := html.Render(&buf, child); err != nil {
+				return models.Content{}, err
+			}
+		}
+	}
+
+	title := heading
+	if title == "" {
+		title = docTitle
+	}
+
+	return models.Content{
+		Title:   strings.Join(strings.Fields(title), " "),
+		Content: strings.TrimSpace(buf.String()),
+		Type:    models.ContentTypeDocument,
+	}, nil
+}
+
+// nodeText returns the concatenated text of a node and its descendants
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(nodeText(child))
+	}
+	return sb.String()
+}
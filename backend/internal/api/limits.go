@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+)
+
+// errTierLimitReached is returned when a user's tier allows no more of a
+// feature
+var errTierLimitReached = errors.New("tier limit reached")
+
+// FeatureLimit is how much of a feature a user may use and has used
+type FeatureLimit struct {
+	Limit     int64  `json:"limit"` // zero means unlimited
+	Used      int64  `json:"used"`
+	Remaining *int64 `json:"remaining,omitempty"`
+}
+
+// UserLimitsResponse represents the limits of a user's tier and their use
+// of each feature
+type UserLimitsResponse struct {
+	Tier   string                            `json:"tier"`
+	Limits map[features.Feature]FeatureLimit `json:"limits"`
+}
+
+// SetUserTierRequest represents a change of a user's tier
+type SetUserTierRequest struct {
+	Tier string `json:"tier" binding:"required"`
+}
+
+// GetUserLimits returns the limits of the current user's tier and how much
+// of each feature they have used. Collaborators are counted per content
+// item, so their use is that of the user's busiest item.
+func GetUserLimits(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	response := UserLimitsResponse{
+		Tier:   user.Tier,
+		Limits: make(map[features.Feature]FeatureLimit, len(features.All)),
+	}
+	for _, feature := range features.All {
+		used, err := features.Used(c.Request.Context(), user, feature)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve limits",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving your limits",
+			})
+			return
+		}
+
+		limit := FeatureLimit{Limit: features.Limit(user, feature), Used: used}
+		if limit.Limit > 0 {
+			remaining := limit.Limit - used
+			if remaining < 0 {
+				remaining = 0
+			}
+			limit.Remaining = &remaining
+		}
+		response.Limits[feature] = limit
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Limits retrieved successfully",
+		"data":    response,
+	})
+}
+
+// AdminSetUserTier moves a user to another tier. Content and attachments
+// over the new tier's limits are kept, but no more can be added.
+func AdminSetUserTier(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid user ID",
+			"code":    "INVALID_USER_ID",
+			"message": "User ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req SetUserTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !features.IsTier(req.Tier) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid tier",
+			"code":    "INVALID_TIER",
+			"message": "Tier must be one of the configured tiers",
+			"tiers":   features.Tiers(),
+		})
+		return
+	}
+
+	// Get user from context
+	admin, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var user models.User
+	if err := db.First(&user, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "User not found",
+			"code":    "USER_NOT_FOUND",
+			"message": "The requested user was not found",
+		})
+		return
+	}
+
+	previous := user.Tier
+	if err := db.Model(&user).Update("tier", req.Tier).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update tier",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while updating the user's tier",
+		})
+		return
+	}
+
+	recordAudit(admin.ID, models.AuditUserTierChanged, models.JSON{
+		"user_id":  user.ID,
+		"previous": previous,
+		"tier":     user.Tier,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tier updated successfully",
+		"data":    user,
+	})
+}
+
+// allowFeature returns errTierLimitReached when user may not use n more of
+// feature
+func allowFeature(ctx context.Context, user *models.User, feature features.Feature, n int64) error {
+	allowed, err := features.AllowN(ctx, user, feature, n)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errTierLimitReached
+	}
+	return nil
+}
+
+// requireFeature reports whether user may use n more of feature, writing
+// the response when they may not
+func requireFeature(c *gin.Context, user *models.User, feature features.Feature, n int64) bool {
+	err := allowFeature(c.Request.Context(), user, feature, n)
+	if errors.Is(err, errTierLimitReached) {
+		respondTierLimitReached(c, user, feature)
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to check limits",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while checking your plan's limits",
+		})
+		return false
+	}
+	return true
+}
+
+// respondTierLimitReached writes the response for a request exceeding a
+// limit of the user's tier
+func respondTierLimitReached(c *gin.Context, user *models.User, feature features.Feature) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "Tier limit reached",
+		"code":    "TIER_LIMIT_REACHED",
+		"message": "Your plan doesn't allow more " + featureDescriptions[feature],
+		"feature": feature,
+		"limit":   features.Limit(user, feature),
+	})
+}
+
+// featureDescriptions name features in error messages
+var featureDescriptions = map[features.Feature]string{
+	features.AITokens:      "AI tokens this month",
+	features.Content:       "content",
+	features.Collaborators: "collaborators on this content",
+	features.Storage:       "attachment storage",
+}
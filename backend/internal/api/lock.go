@@ -0,0 +1,297 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// errContentLocked is returned when content is locked for editing by
+// another user
+var errContentLocked = errors.New("content locked by another user")
+
+// contentLockColumns are only written by the lock endpoints and the
+// collaboration rooms, so saves of whole content rows leave them alone
+var contentLockColumns = []string{"locked_by", "locked_at", "lock_expires_at"}
+
+// LockContent claims exclusive editing of content for the authenticated
+// user until it has been idle for the configured lock timeout. Locking
+// content the user already holds renews the lock.
+func LockContent(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid content ID",
+				"code":    "INVALID_CONTENT_ID",
+				"message": "Content ID must be a valid UUID",
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		db := database.GetDB()
+		var content models.Content
+		if err := db.Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Content not found",
+				"code":    "CONTENT_NOT_FOUND",
+				"message": "The requested content was not found",
+			})
+			return
+		}
+
+		if !content.CanEdit(user.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Edit permission denied",
+				"code":    "EDIT_PERMISSION_DENIED",
+				"message": "You don't have permission to edit this content",
+			})
+			return
+		}
+
+		// Taken only when the content is unlocked, already the user's or
+		// its lock expired, so concurrent claims can't both succeed
+		now := time.Now()
+		result := db.Model(&models.Content{}).
+			Where("id = ? AND (locked_by IS NULL OR locked_by = ? OR lock_expires_at IS NULL OR lock_expires_at <= ?)", id, user.ID, now).
+			UpdateColumns(map[string]interface{}{
+				"locked_by":       user.ID,
+				"locked_at":       gorm.Expr("CASE WHEN locked_by = ? AND lock_expires_at > ? THEN locked_at ELSE ? END", user.ID, now, now),
+				"lock_expires_at": now.Add(config.Load().Content.LockTimeout),
+			})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to lock content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while locking the content",
+			})
+			return
+		}
+
+		if err := db.Preload("User").First(&content, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving content",
+			})
+			return
+		}
+		if result.RowsAffected == 0 {
+			respondContentLockedBy(c, content)
+			return
+		}
+
+		invalidateContentCache(c.Request.Context(), content.ID)
+		hub.SetRoomLock(content.ID.String(), contentRoomLock(content))
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content locked successfully",
+			"data":    content,
+		})
+	}
+}
+
+// UnlockContent releases the editing lock of content. The holder may
+// release it; owners, content admins and site admins may force it open.
+func UnlockContent(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid content ID",
+				"code":    "INVALID_CONTENT_ID",
+				"message": "Content ID must be a valid UUID",
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		db := database.GetDB()
+		var content models.Content
+		if err := db.Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Content not found",
+				"code":    "CONTENT_NOT_FOUND",
+				"message": "The requested content was not found",
+			})
+			return
+		}
+
+		if !content.IsLocked() {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Content not locked",
+				"code":    "CONTENT_NOT_LOCKED",
+				"message": "The content is not locked for editing",
+			})
+			return
+		}
+
+		if content.IsLockedFor(user.ID) && !content.CanAdmin(user.ID) && !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Access denied",
+				"code":    "ACCESS_DENIED",
+				"message": "Only the holder of the lock, the owner and admins can unlock this content",
+			})
+			return
+		}
+
+		// Cleared only if the lock wasn't taken over meanwhile
+		released, err := releaseContentLock(c.Request.Context(), id, *content.LockedBy)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to unlock content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while unlocking the content",
+			})
+			return
+		}
+		if !released {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Content lock changed",
+				"code":    "CONTENT_LOCK_CHANGED",
+				"message": "The lock of the content changed, please try again",
+			})
+			return
+		}
+
+		hub.SetRoomLock(content.ID.String(), websocket.RoomLock{})
+
+		if err := db.Preload("User").First(&content, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving content",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Content unlocked successfully",
+			"data":    content,
+		})
+	}
+}
+
+// respondContentLockedBy writes the response for content another user has
+// locked for editing
+func respondContentLockedBy(c *gin.Context, content models.Content) {
+	c.JSON(http.StatusLocked, gin.H{
+		"error":           "Content locked",
+		"code":            "CONTENT_LOCKED",
+		"message":         "The content is locked for editing by another user",
+		"locked_by":       content.LockedBy,
+		"lock_expires_at": content.LockExpiresAt,
+	})
+}
+
+// renewContentLock extends the lock of content held by userID by the lock
+// timeout, reporting whether userID still held it
+func renewContentLock(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	now := time.Now()
+	result := database.GetDB().WithContext(ctx).Model(&models.Content{}).
+		Where("id = ? AND locked_by = ? AND lock_expires_at > ?", id, userID, now).
+		UpdateColumn("lock_expires_at", now.Add(config.Load().Content.LockTimeout))
+	return result.RowsAffected > 0, result.Error
+}
+
+// releaseContentLock clears the lock of content held by userID, reporting
+// whether userID held it
+func releaseContentLock(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	result := database.GetDB().WithContext(ctx).Model(&models.Content{}).
+		Where("id = ? AND locked_by = ?", id, userID).
+		UpdateColumns(map[string]interface{}{
+			"locked_by":       nil,
+			"locked_at":       nil,
+			"lock_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		invalidateContentCache(ctx, id)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// contentRoomLock returns the lock of content as seen by its room
+func contentRoomLock(content models.Content) websocket.RoomLock {
+	if !content.IsLocked() {
+		return websocket.RoomLock{}
+	}
+	return websocket.RoomLock{UserID: content.LockedBy.String(), ExpiresAt: *content.LockExpiresAt}
+}
+
+// LoadRoomLock returns the editing lock of the content a room edits
+func (ContentRoomStore) LoadRoomLock(roomID string) (websocket.RoomLock, error) {
+	var content models.Content
+	if err := database.GetDB().Select("id", "locked_by", "locked_at", "lock_expires_at").First(&content, "id = ?", roomID).Error; err != nil {
+		return websocket.RoomLock{}, err
+	}
+	return contentRoomLock(content), nil
+}
+
+// RenewRoomLock extends the lock userID holds on the content a room edits
+// and returns the content's current lock
+func (s ContentRoomStore) RenewRoomLock(roomID, userID string) (websocket.RoomLock, error) {
+	id, err := uuid.Parse(roomID)
+	if err != nil {
+		return websocket.RoomLock{}, err
+	}
+	holder, err := uuid.Parse(userID)
+	if err != nil {
+		return websocket.RoomLock{}, err
+	}
+
+	renewed, err := renewContentLock(context.Background(), id, holder)
+	if err != nil {
+		return websocket.RoomLock{}, err
+	}
+	if renewed {
+		invalidateContentCache(context.Background(), id)
+	}
+	return s.LoadRoomLock(roomID)
+}
+
+// ReleaseRoomLock releases the lock userID holds on the content a room edits
+func (ContentRoomStore) ReleaseRoomLock(roomID, userID string) (bool, error) {
+	id, err := uuid.Parse(roomID)
+	if err != nil {
+		return false, err
+	}
+	holder, err := uuid.Parse(userID)
+	if err != nil {
+		return false, err
+	}
+	return releaseContentLock(context.Background(), id, holder)
+}
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+const (
+	// Lifetime of the OAuth state parameter stored in Redis
+	oauthStateTTL = 10 * time.Minute
+)
+
+// oauthUserInfo holds the identity returned by an OAuth provider
+type oauthUserInfo struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+	Username      string
+	FirstName     string
+	LastName      string
+	Avatar        string
+}
+
+var usernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// OAuthStart redirects the user to the provider's consent page
+func OAuthStart(c *gin.Context) {
+	provider := c.Param("provider")
+
+	cfg := config.Load()
+	oauthConfig, ok := oauthProviderConfig(provider, cfg.OAuth)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Unsupported OAuth provider",
+			"code":    "UNSUPPORTED_OAUTH_PROVIDER",
+			"message": "The requested OAuth provider is not supported or not configured",
+		})
+		return
+	}
+
+	// Generate state parameter
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate state",
+			"code":    "OAUTH_STATE_ERROR",
+			"message": "An error occurred while starting the login",
+		})
+		return
+	}
+	state := hex.EncodeToString(bytes)
+
+	// Remember the state so the callback can validate it
+	if err := redis.Set(c.Request.Context(), oauthStateKey(state), provider, oauthStateTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save state",
+			"code":    "OAUTH_STATE_ERROR",
+			"message": "An error occurred while starting the login",
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, oauthConfig.AuthCodeURL(state))
+}
+
+// OAuthCallback completes the OAuth flow and issues tokens
+func OAuthCallback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	cfg := config.Load()
+	oauthConfig, ok := oauthProviderConfig(provider, cfg.OAuth)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Unsupported OAuth provider",
+			"code":    "UNSUPPORTED_OAUTH_PROVIDER",
+			"message": "The requested OAuth provider is not supported or not configured",
+		})
+		return
+	}
+
+	// Validate state parameter
+	ctx := c.Request.Context()
+	state := c.Query("state")
+	storedProvider, err := redis.Get(ctx, oauthStateKey(state))
+	if state == "" || err != nil || storedProvider != provider {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid OAuth state",
+			"code":    "INVALID_OAUTH_STATE",
+			"message": "The login request is invalid or has expired, please try again",
+		})
+		return
+	}
+	redis.Del(ctx, oauthStateKey(state))
+
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "OAuth authorization denied",
+			"code":    "OAUTH_DENIED",
+			"message": errParam,
+		})
+		return
+	}
+
+	// Exchange authorization code
+	oauthToken, err := oauthConfig.Exchange(ctx, c.Query("code"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "OAuth code exchange failed",
+			"code":    "OAUTH_EXCHANGE_FAILED",
+			"message": "Could not complete login with the provider",
+		})
+		return
+	}
+
+	// Fetch user identity from the provider
+	info, err := fetchOAuthUserInfo(ctx, provider, oauthConfig.Client(ctx, oauthToken))
+	if err != nil || info.Email == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Failed to fetch user info",
+			"code":    "OAUTH_USERINFO_FAILED",
+			"message": "Could not retrieve your profile from the provider",
+		})
+		return
+	}
+
+	// Find user linked to this provider account
+	var user models.User
+	err = database.GetDB().Where("oauth_provider = ? AND oauth_id = ?", provider, info.ID).First(&user).Error
+	if err != nil {
+		// Fall back to matching by email
+		if err := database.GetDB().Where("email = ?", info.Email).First(&user).Error; err == nil {
+			// Only link when both sides have proven ownership of the email
+			if user.OAuthProvider != "" || !user.IsVerified || !info.EmailVerified {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":   "Account already exists",
+					"code":    "ACCOUNT_EXISTS",
+					"message": "An account with this email already exists, please log in with your password",
+				})
+				return
+			}
+
+			user.OAuthProvider = provider
+			user.OAuthID = info.ID
+		} else {
+			user = models.User{
+				Email:         info.Email,
+				Username:      uniqueUsername(info.Username),
+				FirstName:     info.FirstName,
+				LastName:      info.LastName,
+				Avatar:        info.Avatar,
+				IsActive:      true,
+				IsVerified:    info.EmailVerified,
+				OAuthProvider: provider,
+				OAuthID:       info.ID,
+			}
+			if info.EmailVerified {
+				now := time.Now()
+				user.EmailVerifiedAt = &now
+			}
+
+			if err := database.GetDB().Create(&user).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to create user",
+					"code":    "DATABASE_ERROR",
+					"message": "An error occurred while creating your account",
+				})
+				return
+			}
+		}
+	}
+
+	// Check if user is banned
+	if user.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account banned",
+			"code":    "USER_BANNED",
+			"message": "Your account has been banned",
+		})
+		return
+	}
+
+	// Check if user is active
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account deactivated",
+			"code":    "ACCOUNT_DEACTIVATED",
+			"message": "Your account has been deactivated",
+		})
+		return
+	}
+
+	// Require a second factor before issuing tokens
+	if user.TOTPEnabled {
+		database.GetDB().Save(&user)
+
+		challenge, err := issueTwoFactorChallenge(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create challenge",
+				"code":    "TOKEN_GENERATION_ERROR",
+				"message": "An error occurred while logging in",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Two-factor authentication required",
+			"data": TwoFactorChallengeResponse{
+				TwoFactorRequired: true,
+				ChallengeToken:    challenge.Token,
+				ExpiresIn:         int64(twoFactorChallengeTTL.Seconds()),
+			},
+		})
+		return
+	}
+
+	// Update last login
+	now := time.Now()
+	user.LastLoginAt = &now
+	database.GetDB().Save(&user)
+
+	// Generate tokens
+	accessToken, refreshToken, err := generateTokens(c.Request.Context(), &user, cfg.JWT)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate tokens",
+			"code":    "TOKEN_GENERATION_ERROR",
+			"message": "An error occurred while logging in",
+		})
+		return
+	}
+
+	// Save refresh token to database, starting a new token family
+	token := models.Token{
+		UserID:    user.ID,
+		Token:     refreshToken,
+		Type:      "refresh",
+		FamilyID:  uuid.New(),
+		ExpiresAt: time.Now().Add(time.Duration(cfg.JWT.RefreshHours) * time.Hour),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+
+	if err := database.GetDB().Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save token",
+			"code":    "TOKEN_SAVE_ERROR",
+			"message": "An error occurred while logging in",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"data": AuthResponse{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    int64(cfg.JWT.ExpirationHours * 3600),
+			User:         user,
+		},
+	})
+}
+
+// oauthProviderConfig builds the oauth2 configuration for a provider
+func oauthProviderConfig(provider string, cfg config.OAuthConfig) (*oauth2.Config, bool) {
+	switch provider {
+	case "google":
+		if cfg.Google.ClientID == "" {
+			return nil, false
+		}
+		return &oauth2.Config{
+			ClientID:     cfg.Google.ClientID,
+			ClientSecret: cfg.Google.ClientSecret,
+			RedirectURL:  cfg.Google.RedirectURL,
+			Endpoint:     endpoints.Google,
+			Scopes:       []string{"openid", "email", "profile"},
+		}, true
+	case "github":
+		if cfg.GitHub.ClientID == "" {
+			return nil, false
+		}
+		return &oauth2.Config{
+			ClientID:     cfg.GitHub.ClientID,
+			ClientSecret: cfg.GitHub.ClientSecret,
+			RedirectURL:  cfg.GitHub.RedirectURL,
+			Endpoint:     endpoints.GitHub,
+			Scopes:       []string{"read:user", "user:email"},
+		}, true
+	}
+	return nil, false
+}
+
+// fetchOAuthUserInfo retrieves the user's identity from the provider API
+func fetchOAuthUserInfo(ctx context.Context, provider string, client *http.Client) (*oauthUserInfo, error) {
+	switch provider {
+	case "google":
+		var profile struct {
+			Sub           string `json:"sub"`
+			Email         string `json:"email"`
+			EmailVerified bool   `json:"email_verified"`
+			GivenName     string `json:"given_name"`
+			FamilyName    string `json:"family_name"`
+			Picture       string `json:"picture"`
+		}
+		if err := getJSON(ctx, client, "https://openidconnect.googleapis.com/v1/userinfo", &profile); err != nil {
+			return nil, err
+		}
+		return &oauthUserInfo{
+			ID:            profile.Sub,
+			Email:         profile.Email,
+			EmailVerified: profile.EmailVerified,
+			Username:      strings.Split(profile.Email, "@")[0],
+			FirstName:     profile.GivenName,
+			LastName:      profile.FamilyName,
+			Avatar:        profile.Picture,
+		}, nil
+
+	case "github":
+		var profile struct {
+			ID        int64  `json:"id"`
+			Login     string `json:"login"`
+			Name      string `json:"name"`
+			AvatarURL string `json:"avatar_url"`
+		}
+		if err := getJSON(ctx, client, "https://api.github.com/user", &profile); err != nil {
+			return nil, err
+		}
+
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := getJSON(ctx, client, "https://api.github.com/user/emails", &emails); err != nil {
+			return nil, err
+		}
+
+		info := &oauthUserInfo{
+			ID:       strconv.FormatInt(profile.ID, 10),
+			Username: profile.Login,
+			Avatar:   profile.AvatarURL,
+		}
+		if first, last, found := strings.Cut(profile.Name, " "); found {
+			info.FirstName, info.LastName = first, last
+		} else {
+			info.FirstName = profile.Name
+		}
+		for _, email := range emails {
+			if email.Primary {
+				info.Email = email.Email
+				info.EmailVerified = email.Verified
+			}
+		}
+		return info, nil
+	}
+	return nil, fmt.Errorf("unsupported OAuth provider: %s", provider)
+}
+
+// getJSON performs an authenticated GET request and decodes the JSON response
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// uniqueUsername derives an unused username from the provider's suggestion
+func uniqueUsername(base string) string {
+	base = usernameSanitizer.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user" + base
+	}
+	if len(base) > 25 {
+		base = base[:25]
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		database.GetDB().Model(&models.User{}).Where("username = ?", username).Count(&count)
+		if count == 0 {
+			return username
+		}
+
+		suffix := make([]byte, 2)
+		rand.Read(suffix)
+		username = base + "-" + hex.EncodeToString(suffix)
+	}
+	return username
+}
+
+// oauthStateKey returns the Redis key holding an OAuth state parameter
+func oauthStateKey(state string) string {
+	return fmt.Sprintf("oauth_state:%s", state)
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errPinLimit is returned when a user already pinned as much content as
+// allowed
+var errPinLimit = errors.New("pin limit reached")
+
+// PublicProfile is what anyone may see of a user
+type PublicProfile struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Avatar    string    `json:"avatar"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PublicProfileResponse is a user's public page: their profile, the content
+// they pinned in their order and their published content, newest first
+type PublicProfileResponse struct {
+	User     PublicProfile       `json:"user"`
+	Pinned   []models.Content    `json:"pinned"`
+	Contents ContentListResponse `json:"contents"`
+}
+
+// PinContent pins content the user owns to their public profile. Only
+// public, published content can be pinned, up to CONTENT_MAX_PINS items;
+// pinning content twice has no further effect.
+func PinContent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	db := database.GetDB()
+	var content models.Content
+	if err := db.First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if content.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "Only the owner can pin this content",
+		})
+		return
+	}
+
+	var listed int64
+	if err := publiclyListed(db.Model(&models.Content{})).Where("id = ?", id).Count(&listed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to pin content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while pinning the content",
+		})
+		return
+	}
+	if listed == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Content not public",
+			"code":    "CONTENT_NOT_PUBLIC",
+			"message": "Only public, published content can be pinned to your profile",
+		})
+		return
+	}
+
+	maxPins := config.Load().Content.MaxPins
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Pins of a user are added one at a time so concurrent pins can't
+		// exceed the limit together
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+
+		var pins []models.PinnedContent
+		if err := tx.Where("user_id = ?", user.ID).Find(&pins).Error; err != nil {
+			return err
+		}
+		position := 0
+		for _, pin := range pins {
+			if pin.ContentID == content.ID {
+				return nil
+			}
+			if pin.Position >= position {
+				position = pin.Position + 1
+			}
+		}
+		if len(pins) >= maxPins {
+			return errPinLimit
+		}
+
+		return tx.Create(&models.PinnedContent{
+			UserID:    user.ID,
+			ContentID: content.ID,
+			Position:  position,
+		}).Error
+	})
+	if errors.Is(err, errPinLimit) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Pin limit reached",
+			"code":    "PIN_LIMIT_REACHED",
+			"message": "You can pin at most " + strconv.Itoa(maxPins) + " items, unpin one first",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to pin content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while pinning the content",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content pinned to your profile",
+		"data":    gin.H{"content_id": content.ID, "is_pinned": true},
+	})
+}
+
+// UnpinContent removes content from the user's public profile. It works
+// even when the content was deleted or made private.
+func UnpinContent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if err := database.GetDB().Where("user_id = ? AND content_id = ?", user.ID, id).Delete(&models.PinnedContent{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unpin content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while unpinning the content",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content unpinned from your profile",
+		"data":    gin.H{"content_id": id, "is_pinned": false},
+	})
+}
+
+// GetPublicProfile returns a user's public page. Pinned content that is no
+// longer public or published is left out until it is again. Published
+// content is paginated with page and per_page.
+func GetPublicProfile(c *gin.Context) {
+	db := database.GetDB()
+	var user models.User
+	if err := db.Where("username = ? AND is_active = ? AND is_banned = ?", c.Param("username"), true, false).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "User not found",
+				"code":    "USER_NOT_FOUND",
+				"message": "The requested user was not found",
+			})
+			return
+		}
+		respondProfileError(c)
+		return
+	}
+
+	response := PublicProfileResponse{
+		User: PublicProfile{
+			ID:        user.ID,
+			Username:  user.Username,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Avatar:    user.Avatar,
+			Bio:       user.Bio,
+			CreatedAt: user.CreatedAt,
+		},
+		Pinned: []models.Content{},
+	}
+
+	if err := publiclyListed(db.Model(&models.Content{})).
+		Joins("JOIN pinned_contents ON pinned_contents.content_id = contents.id AND pinned_contents.user_id = ?", user.ID).
+		Where("contents.user_id = ?", user.ID).
+		Order("pinned_contents.position ASC").
+		Find(&response.Pinned).Error; err != nil {
+		respondProfileError(c)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := publiclyListed(db.Model(&models.Content{})).Where("contents.user_id = ?", user.ID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondProfileError(c)
+		return
+	}
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	contents := []models.Content{}
+	if err := query.Offset((page - 1) * perPage).Limit(perPage).Order("created_at DESC").Find(&contents).Error; err != nil {
+		respondProfileError(c)
+		return
+	}
+	attachReactionCounts(response.Pinned)
+	attachReactionCounts(contents)
+	attachFavorites(c, response.Pinned)
+	attachFavorites(c, contents)
+
+	response.Contents = ContentListResponse{
+		Contents:    contents,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile retrieved successfully",
+		"data":    response,
+	})
+}
+
+// respondProfileError writes the response for a failed profile query
+func respondProfileError(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Failed to retrieve profile",
+		"code":    "DATABASE_ERROR",
+		"message": "An error occurred while retrieving the profile",
+	})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/webhook"
+)
+
+// respondInvalidPublishAt writes the response to content scheduled for
+// publishing at a time that isn't in the future, or to a schedule on
+// content that isn't a draft
+func respondInvalidPublishAt(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Invalid publish time",
+		"code":    "INVALID_PUBLISH_AT",
+		"message": "publish_at must be in the future and only drafts can be scheduled",
+	})
+}
+
+// PublishScheduledContent publishes drafts whose publish time has come and
+// returns how many were published. Each draft is claimed with a conditional
+// update, so replicas checking at the same time publish it only once.
+func PublishScheduledContent(ctx context.Context) (int, error) {
+	db := database.GetDB().WithContext(ctx)
+
+	var due []models.Content
+	if err := db.Where("status = ? AND publish_at <= ?", models.ContentStatusDraft, time.Now()).
+		Order("publish_at").Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, content := range due {
+		now := time.Now()
+		result := db.Model(&models.Content{}).
+			Where("id = ? AND status = ? AND publish_at IS NOT NULL", content.ID, models.ContentStatusDraft).
+			Updates(map[string]interface{}{
+				"status":     models.ContentStatusPublished,
+				"publish_at": nil,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return published, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Published, rescheduled or edited since it was loaded
+			continue
+		}
+		published++
+
+		scheduledAt := *content.PublishAt
+		content.Status = models.ContentStatusPublished
+		content.PublishAt = nil
+		content.UpdatedAt = now
+
+		invalidateContentCache(ctx, content.ID)
+		recordActivity(content.ID, content.UserID, models.ActivityContentPublished, models.JSON{
+			"scheduled_at": scheduledAt,
+		})
+		webhook.Dispatch(content.UserID, models.WebhookEventContentPublished, content)
+	}
+	return published, nil
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// ReactRequest represents a reaction to content
+type ReactRequest struct {
+	Type string `json:"type" binding:"omitempty,max=32"`
+}
+
+// ReactionSummary represents the reactions on content
+type ReactionSummary struct {
+	Counts        map[string]int64 `json:"counts"`
+	Total         int64            `json:"total"`
+	UserReactions []string         `json:"user_reactions"`
+}
+
+// maxReactionRunes bounds emoji reactions, leaving room for modifiers and
+// joined sequences
+const maxReactionRunes = 8
+
+// AddReaction reacts to content with a like or an emoji. Reacting twice
+// with the same type has no further effect.
+func AddReaction(c *gin.Context) {
+	var req ReactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	reactionType := req.Type
+	if reactionType == "" {
+		reactionType = models.ReactionLike
+	}
+	if !validReactionType(reactionType) {
+		writeInvalidReaction(c)
+		return
+	}
+
+	content, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	reaction := models.Reaction{
+		ContentID: content.ID,
+		UserID:    user.ID,
+		Type:      reactionType,
+	}
+	result := database.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add reaction",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while adding the reaction",
+		})
+		return
+	}
+	if result.RowsAffected > 0 {
+		recordTrending(c.Request.Context(), content.ID, trendingReactionWeight)
+	}
+
+	writeReactionSummary(c, content.ID, user.ID, "Reaction added successfully")
+}
+
+// RemoveReaction withdraws the user's reaction of the given ?type, a like
+// by default
+func RemoveReaction(c *gin.Context) {
+	reactionType := c.DefaultQuery("type", models.ReactionLike)
+	if !validReactionType(reactionType) {
+		writeInvalidReaction(c)
+		return
+	}
+
+	content, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	if err := database.GetDB().Where("content_id = ? AND user_id = ? AND type = ?", content.ID, user.ID, reactionType).
+		Delete(&models.Reaction{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove reaction",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while removing the reaction",
+		})
+		return
+	}
+
+	writeReactionSummary(c, content.ID, user.ID, "Reaction removed successfully")
+}
+
+// GetReactions summarizes the reactions on content
+func GetReactions(c *gin.Context) {
+	content, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	writeReactionSummary(c, content.ID, user.ID, "Reactions retrieved successfully")
+}
+
+// writeReactionSummary responds with the reaction counts of content and the
+// types the user reacted with
+func writeReactionSummary(c *gin.Context, contentID, userID uuid.UUID, message string) {
+	counts, err := reactionCounts([]uuid.UUID{contentID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve reactions",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving reactions",
+		})
+		return
+	}
+
+	summary := ReactionSummary{
+		Counts:        counts[contentID],
+		UserReactions: []string{},
+	}
+	if summary.Counts == nil {
+		summary.Counts = map[string]int64{}
+	}
+	for _, count := range summary.Counts {
+		summary.Total += count
+	}
+
+	if err := database.GetDB().Model(&models.Reaction{}).Where("content_id = ? AND user_id = ?", contentID, userID).
+		Order("created_at ASC").Pluck("type", &summary.UserReactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve reactions",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving reactions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    summary,
+	})
+}
+
+// reactionCounts counts the reactions on each content by type
+func reactionCounts(contentIDs []uuid.UUID) (map[uuid.UUID]map[string]int64, error) {
+	counts := make(map[uuid.UUID]map[string]int64, len(contentIDs))
+	if len(contentIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ContentID uuid.UUID
+		Type      string
+		Count     int64
+	}
+	if err := database.GetDB().Model(&models.Reaction{}).
+		Select("content_id, type, COUNT(*) AS count").
+		Where("content_id IN ?", contentIDs).
+		Group("content_id, type").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if counts[row.ContentID] == nil {
+			counts[row.ContentID] = make(map[string]int64)
+		}
+		counts[row.ContentID][row.Type] = row.Count
+	}
+	return counts, nil
+}
+
+// attachReactionCounts fills in the reaction counts of listed content.
+// Failures are logged and leave the counts out of the response.
+func attachReactionCounts(contents []models.Content) {
+	ids := make([]uuid.UUID, len(contents))
+	for i, content := range contents {
+		ids[i] = content.ID
+	}
+
+	counts, err := reactionCounts(ids)
+	if err != nil {
+		log.Printf("Failed to count reactions: %v", err)
+		return
+	}
+	for i := range contents {
+		contents[i].ReactionCounts = counts[contents[i].ID]
+	}
+}
+
+// validReactionType accepts likes and short emoji sequences
+func validReactionType(reactionType string) bool {
+	if reactionType == models.ReactionLike {
+		return true
+	}
+	if reactionType == "" || utf8.RuneCountInString(reactionType) > maxReactionRunes {
+		return false
+	}
+
+	hasSymbol := false
+	for _, r := range reactionType {
+		switch {
+		case r < utf8.RuneSelf:
+			return false
+		case unicode.Is(unicode.So, r) || unicode.Is(unicode.Regional_Indicator, r):
+			hasSymbol = true
+		case r == '\u200d' || unicode.Is(unicode.Variation_Selector, r) || unicode.Is(unicode.Sk, r):
+			// Joiners, presentation selectors and skin tones modify emoji
+		default:
+			return false
+		}
+	}
+	return hasSymbol
+}
+
+// writeInvalidReaction responds to an unsupported reaction type
+func writeInvalidReaction(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Invalid reaction",
+		"code":    "INVALID_REACTION",
+		"message": "Reactions must be like or an emoji",
+	})
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/embedding"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// embeddingTimeout bounds embedding one batch of content in the background
+const embeddingTimeout = 30 * time.Second
+
+// embeddingBackfillBatch is the number of content items embedded per request
+// during a backfill
+const embeddingBackfillBatch = 50
+
+// embeddingBackfillRunning prevents concurrent backfills on this instance
+var embeddingBackfillRunning atomic.Bool
+
+// SemanticSearchResult represents content matched by meaning
+type SemanticSearchResult struct {
+	models.Content
+	Score float64 `json:"score"` // cosine similarity, higher is closer
+}
+
+// semanticSearchAvailable reports whether content can be embedded and searched
+func semanticSearchAvailable() bool {
+	return embedding.Get() != nil && database.VectorSearchAvailable()
+}
+
+// embeddingText is the text of content that is embedded
+func embeddingText(content models.Content) string {
+	parts := []string{content.Title}
+	if content.Description != "" {
+		parts = append(parts, content.Description)
+	}
+	if len(content.Tags) > 0 {
+		parts = append(parts, strings.Join(content.Tags, ", "))
+	}
+	if content.Content != "" {
+		parts = append(parts, content.Content)
+	}
+	return embedding.Truncate(strings.Join(parts, "\n\n"))
+}
+
+// embeddingJob is the queued embedding of one content
+type embeddingJob struct {
+	ContentID uuid.UUID `json:"content_id"`
+}
+
+// indexContentEmbedding queues the embedding of content after it is created
+// or changed. Failures are logged and picked up by the backfill.
+func indexContentEmbedding(content models.Content) {
+	if !semanticSearchAvailable() {
+		return
+	}
+
+	if err := queue.Publish(context.Background(), queue.JobEmbedding, embeddingJob{ContentID: content.ID}); err != nil {
+		log.Printf("Failed to queue embedding of content %s: %v", content.ID, err)
+	}
+}
+
+// HandleEmbeddingJob embeds the current text of queued content. Content
+// deleted since it was queued is skipped.
+func HandleEmbeddingJob(ctx context.Context, job queue.Job) error {
+	var payload embeddingJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	if !semanticSearchAvailable() {
+		return nil
+	}
+
+	var content models.Content
+	if err := database.GetDB().WithContext(ctx).First(&content, "id = ?", payload.ContentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, embeddingTimeout)
+	defer cancel()
+	return embedContents(ctx, []models.Content{content})
+}
+
+// embedContents stores the embeddings of contents whose text changed since
+// they were last embedded
+func embedContents(ctx context.Context, contents []models.Content) error {
+	embedder := embedding.Get()
+	model := embedder.Model()
+
+	ids := make([]uuid.UUID, len(contents))
+	for i, content := range contents {
+		ids[i] = content.ID
+	}
+	var existing []models.ContentEmbedding
+	if err := database.GetDB().WithContext(ctx).Select("content_id", "checksum").
+		Where("content_id IN ?", ids).Find(&existing).Error; err != nil {
+		return err
+	}
+	checksums := make(map[uuid.UUID]string, len(existing))
+	for _, e := range existing {
+		checksums[e.ContentID] = e.Checksum
+	}
+
+	var pending []models.ContentEmbedding
+	var texts []string
+	for _, content := range contents {
+		text := embeddingText(content)
+		checksum := embedding.Checksum(model, text)
+		if checksums[content.ID] == checksum {
+			continue
+		}
+		pending = append(pending, models.ContentEmbedding{ContentID: content.ID, Model: model, Checksum: checksum})
+		texts = append(texts, text)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		pending[i].Embedding = vectors[i]
+	}
+
+	// Content purged while it was embedded is skipped
+	return database.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, e := range pending {
+			err := tx.Exec(`INSERT INTO content_embeddings (content_id, embedding, model, checksum, updated_at)
+				SELECT ?, ?::vector, ?, ?, ? WHERE EXISTS (SELECT 1 FROM contents WHERE id = ?)
+				ON CONFLICT (content_id) DO UPDATE SET embedding = EXCLUDED.embedding, model = EXCLUDED.model,
+				checksum = EXCLUDED.checksum, updated_at = EXCLUDED.updated_at`,
+				e.ContentID, e.Embedding, e.Model, e.Checksum, time.Now().UTC(), e.ContentID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SemanticSearch finds content related in meaning to the ?q= query by
+// cosine distance between embeddings. Only content the user can read is
+// returned. Without an embedding provider or pgvector it falls back to
+// full-text search.
+func SemanticSearch(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing query",
+			"code":    "MISSING_QUERY",
+			"message": "The q query parameter is required",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	if semanticSearchAvailable() {
+		results, err := semanticMatches(c.Request.Context(), q, user.ID, limit)
+		if err == nil {
+			attachSearchResultExtras(c, results)
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Content retrieved successfully",
+				"data":    results,
+				"mode":    "semantic",
+			})
+			return
+		}
+		log.Printf("Semantic search failed, falling back to full-text search: %v", err)
+	}
+
+	query, _ := applyContentSearch(database.GetDB().Model(&models.Content{}).Scopes(readableBy(user.ID)), q, "fulltext", true)
+	var contents []models.Content
+	if err := query.Preload("User").Limit(limit).Find(&contents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while searching content",
+		})
+		return
+	}
+
+	results := make([]SemanticSearchResult, len(contents))
+	for i, content := range contents {
+		results[i] = SemanticSearchResult{Content: content}
+	}
+	attachSearchResultExtras(c, results)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content retrieved successfully",
+		"data":    results,
+		"mode":    "keyword",
+	})
+}
+
+// semanticMatches embeds the query and returns the nearest readable content
+func semanticMatches(ctx context.Context, q string, userID uuid.UUID, limit int) ([]SemanticSearchResult, error) {
+	embedder := embedding.Get()
+	vectors, err := embedder.Embed(ctx, []string{q})
+	if err != nil {
+		return nil, err
+	}
+	vector := vectors[0]
+
+	var matches []struct {
+		ContentID uuid.UUID
+		Distance  float64
+	}
+	if err := database.GetDB().WithContext(ctx).Model(&models.Content{}).
+		Select("contents.id AS content_id, content_embeddings.embedding <=> ?::vector AS distance", vector).
+		Joins("JOIN content_embeddings ON content_embeddings.content_id = contents.id").
+		Where("content_embeddings.model = ?", embedder.Model()).
+		Scopes(readableBy(userID)).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "content_embeddings.embedding <=> ?::vector",
+			Vars:               []interface{}{vector},
+			WithoutParentheses: true,
+		}}).
+		Limit(limit).
+		Scan(&matches).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ContentID
+	}
+	var contents []models.Content
+	if len(ids) > 0 {
+		if err := database.GetDB().WithContext(ctx).Preload("User").Where("id IN ?", ids).Find(&contents).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[uuid.UUID]models.Content, len(contents))
+	for _, content := range contents {
+		byID[content.ID] = content
+	}
+
+	results := make([]SemanticSearchResult, 0, len(matches))
+	for _, match := range matches {
+		if content, ok := byID[match.ContentID]; ok {
+			results = append(results, SemanticSearchResult{Content: content, Score: 1 - match.Distance})
+		}
+	}
+	return results, nil
+}
+
+// attachSearchResultExtras fills in reaction counts and favorites of results
+func attachSearchResultExtras(c *gin.Context, results []SemanticSearchResult) {
+	contents := make([]models.Content, len(results))
+	for i, result := range results {
+		contents[i] = result.Content
+	}
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
+	for i := range results {
+		results[i].Content = contents[i]
+	}
+}
+
+// AdminBackfillEmbeddings starts embedding all content that has no
+// embedding from the current model. The backfill runs in the background.
+func AdminBackfillEmbeddings(c *gin.Context) {
+	if !semanticSearchAvailable() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Semantic search unavailable",
+			"code":    "SEMANTIC_SEARCH_UNAVAILABLE",
+			"message": "No embedding provider is configured or pgvector is not installed",
+		})
+		return
+	}
+
+	if !embeddingBackfillRunning.CompareAndSwap(false, true) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Backfill already running",
+			"code":    "BACKFILL_RUNNING",
+			"message": "An embedding backfill is already in progress",
+		})
+		return
+	}
+
+	var pending int64
+	if err := missingEmbeddings(database.GetDB()).Count(&pending).Error; err != nil {
+		embeddingBackfillRunning.Store(false)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to start backfill",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while counting content to embed",
+		})
+		return
+	}
+
+	go func() {
+		defer embeddingBackfillRunning.Store(false)
+		embedded, err := backfillEmbeddings()
+		if err != nil {
+			log.Printf("Embedding backfill stopped after %d items: %v", embedded, err)
+			return
+		}
+		log.Printf("Embedding backfill completed, %d items embedded", embedded)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Embedding backfill started",
+		"data":    gin.H{"pending": pending},
+	})
+}
+
+// missingEmbeddings selects content without an embedding from the current model
+func missingEmbeddings(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Content{}).
+		Joins("LEFT JOIN content_embeddings ON content_embeddings.content_id = contents.id").
+		Where("content_embeddings.content_id IS NULL OR content_embeddings.model <> ?", embedding.Get().Model())
+}
+
+// backfillEmbeddings embeds content missing embeddings in batches, walking
+// the content by ID so failing items are not retried in a loop
+func backfillEmbeddings() (int, error) {
+	embedded := 0
+	after := uuid.Nil
+	for {
+		var batch []models.Content
+		if err := missingEmbeddings(database.GetDB()).
+			Select("contents.*").
+			Where("contents.id > ?", after).
+			Order("contents.id").
+			Limit(embeddingBackfillBatch).
+			Find(&batch).Error; err != nil {
+			return embedded, err
+		}
+		if len(batch) == 0 {
+			return embedded, nil
+		}
+		after = batch[len(batch)-1].ID
+
+		ctx, cancel := context.WithTimeout(context.Background(), embeddingTimeout)
+		err := embedContents(ctx, batch)
+		cancel()
+		if err != nil {
+			return embedded, err
+		}
+		embedded += len(batch)
+	}
+}
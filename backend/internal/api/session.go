@@ -0,0 +1,171 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// SessionResponse describes a signed in device. A session is the family of
+// refresh tokens rotated since a login, so its ID stays the same across
+// refreshes.
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// GetSessions lists the authenticated user's active sessions, most recently
+// used first. The user agent and address are those of the last refresh.
+func GetSessions(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var tokens []models.Token
+	if err := activeSessionTokens(user.ID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve sessions",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving your sessions",
+		})
+		return
+	}
+
+	// A session started when the first token of its family was issued
+	familyIDs := make([]uuid.UUID, 0, len(tokens))
+	for _, token := range tokens {
+		if token.FamilyID != uuid.Nil {
+			familyIDs = append(familyIDs, token.FamilyID)
+		}
+	}
+	var starts []struct {
+		FamilyID  uuid.UUID
+		StartedAt time.Time
+	}
+	if len(familyIDs) > 0 {
+		if err := database.GetDB().Model(&models.Token{}).
+			Select("family_id, MIN(created_at) AS started_at").
+			Where("family_id IN ?", familyIDs).
+			Group("family_id").
+			Scan(&starts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve sessions",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving your sessions",
+			})
+			return
+		}
+	}
+	startedAt := make(map[uuid.UUID]time.Time, len(starts))
+	for _, start := range starts {
+		startedAt[start.FamilyID] = start.StartedAt
+	}
+
+	sessions := make([]SessionResponse, len(tokens))
+	for i, token := range tokens {
+		session := SessionResponse{
+			ID:         token.FamilyID,
+			UserAgent:  token.UserAgent,
+			IPAddress:  token.IPAddress,
+			SignedInAt: token.CreatedAt,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+		}
+		// Tokens issued before families were tracked are their own session
+		if token.FamilyID == uuid.Nil {
+			session.ID = token.ID
+		} else if started, ok := startedAt[token.FamilyID]; ok {
+			session.SignedInAt = started
+		}
+		sessions[i] = session
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions retrieved successfully",
+		"data":    sessions,
+	})
+}
+
+// RevokeSession signs the authenticated user out of one session by revoking
+// its refresh tokens. Access tokens already issued to the session stay valid
+// until they expire.
+func RevokeSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid session ID",
+			"code":    "INVALID_SESSION_ID",
+			"message": "Session ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var token models.Token
+	if err := activeSessionTokens(user.ID).Where("family_id = ? OR id = ?", id, id).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Session not found",
+				"code":    "SESSION_NOT_FOUND",
+				"message": "The requested session does not exist or has ended",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve session",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving the session",
+		})
+		return
+	}
+
+	query := database.GetDB().Model(&models.Token{}).Where("id = ?", token.ID)
+	if token.FamilyID != uuid.Nil {
+		query = database.GetDB().Model(&models.Token{}).Where("family_id = ? AND is_revoked = ?", token.FamilyID, false)
+	}
+	if err := query.Update("is_revoked", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke session",
+			"code":    "TOKEN_REVOKE_ERROR",
+			"message": "An error occurred while revoking the session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// activeSessionTokens selects the user's refresh tokens that can still be
+// used, one per session since rotation revokes the previous token
+func activeSessionTokens(userID uuid.UUID) *gorm.DB {
+	return database.GetDB().Where("user_id = ? AND type = ? AND is_revoked = ? AND expires_at > ?", userID, "refresh", false, time.Now())
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/email"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ShareContentRequest represents a request to share content with a user or by link
+type ShareContentRequest struct {
+	ShareType      string  `json:"share_type" binding:"required,oneof=user link embed"`
+	SharedWith     *string `json:"shared_with"`
+	Permission     string  `json:"permission" binding:"omitempty,oneof=view comment edit manage read write admin"`
+	ExpiresInHours int     `json:"expires_in_hours" binding:"min=0"`
+}
+
+// ShareContent shares content with another user or creates a read-only
+// share link. Sharing private content by link makes it link content, so the
+// link resolves.
+func ShareContent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req ShareContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("Collaborations").First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if !content.CanAdmin(user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Share permission denied",
+			"code":    "SHARE_PERMISSION_DENIED",
+			"message": "You don't have permission to share this content",
+		})
+		return
+	}
+
+	share := models.SharedContent{
+		ContentID:  content.ID,
+		OwnerID:    user.ID,
+		ShareType:  req.ShareType,
+		Permission: models.PermissionView,
+	}
+
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		share.ExpiresAt = &expiresAt
+	}
+
+	var recipient *models.User
+	if req.ShareType == "user" {
+		if req.SharedWith == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Recipient required",
+				"code":    "MISSING_SHARED_WITH",
+				"message": "shared_with is required when sharing with a user",
+			})
+			return
+		}
+
+		recipientID, err := uuid.Parse(*req.SharedWith)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid user ID",
+				"code":    "INVALID_USER_ID",
+				"message": "shared_with must be a valid UUID",
+			})
+			return
+		}
+
+		recipient = &models.User{}
+		if err := database.GetDB().First(recipient, "id = ?", recipientID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "User not found",
+				"code":    "USER_NOT_FOUND",
+				"message": "The user to share with was not found",
+			})
+			return
+		}
+
+		share.SharedWith = &recipient.ID
+		if req.Permission != "" {
+			// The binding accepts only known permissions
+			share.Permission, _ = models.ParsePermission(req.Permission)
+		}
+	} else {
+		// Links are read-only and identified by an unguessable token
+		token, err := generateShareToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to generate share link",
+				"code":    "TOKEN_GENERATION_ERROR",
+				"message": "An error occurred while creating the share link",
+			})
+			return
+		}
+		share.ShareToken = &token
+	}
+
+	madeLinkable := share.ShareToken != nil && !content.Visibility.ViewableByLink()
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if madeLinkable {
+			if err := tx.Model(&content).UpdateColumn("visibility", models.VisibilityLink).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&share).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to share content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while sharing content",
+		})
+		return
+	}
+
+	recordActivity(content.ID, user.ID, models.ActivityContentShared, models.JSON{
+		"share_id":    share.ID,
+		"share_type":  share.ShareType,
+		"permission":  share.Permission,
+		"shared_with": share.SharedWith,
+	})
+	recordContentShare(c.Request.Context(), content.ID)
+	if madeLinkable {
+		invalidateContentCache(c.Request.Context(), content.ID)
+	}
+
+	if recipient != nil {
+		email.Notify(recipient.Email, email.TemplateShare, email.TemplateData{
+			Name:         recipient.FullName(),
+			Actor:        user.FullName(),
+			ContentTitle: content.Title,
+			Link:         fmt.Sprintf("%s/content/%s", config.Load().Email.AppURL, content.ID),
+		})
+	}
+
+	response := gin.H{
+		"message": "Content shared successfully",
+		"data":    share,
+	}
+	if share.ShareToken != nil {
+		response["share_url"] = "/api/v1/share/" + *share.ShareToken
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetSharedContent resolves a share link to its content without authentication
+func GetSharedContent(c *gin.Context) {
+	token := c.Param("token")
+
+	var share models.SharedContent
+	if err := database.GetDB().Where("share_token = ? AND share_type IN ?", token, []string{"link", "embed"}).First(&share).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Share link not found",
+			"code":    "SHARE_NOT_FOUND",
+			"message": "The share link is invalid or has been revoked",
+		})
+		return
+	}
+
+	if share.IsExpired() {
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "Share link expired",
+			"code":    "SHARE_EXPIRED",
+			"message": "The share link has expired",
+		})
+		return
+	}
+
+	var content models.Content
+	if err := database.GetDB().Preload("User").First(&content, "id = ?", share.ContentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The shared content was not found",
+		})
+		return
+	}
+
+	// Links of content made private stop resolving until it is shared again
+	if !content.Visibility.ViewableByLink() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Share link not found",
+			"code":    "SHARE_NOT_FOUND",
+			"message": "The share link is invalid or has been revoked",
+		})
+		return
+	}
+
+	database.GetDB().Model(&share).UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+	recordContentView(c, content.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Shared content retrieved successfully",
+		"data":       content,
+		"share_type": share.ShareType,
+		"permission": models.PermissionView,
+	})
+}
+
+// RevokeShare deletes a share so its link or user access stops working
+func RevokeShare(c *gin.Context) {
+	contentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	shareID, err := uuid.Parse(c.Param("shareId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid share ID",
+			"code":    "INVALID_SHARE_ID",
+			"message": "Share ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var share models.SharedContent
+	if err := database.GetDB().Preload("Content.Collaborations").
+		First(&share, "id = ? AND content_id = ?", shareID, contentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Share not found",
+			"code":    "SHARE_NOT_FOUND",
+			"message": "The requested share was not found",
+		})
+		return
+	}
+
+	if share.OwnerID != user.ID && !share.Content.CanAdmin(user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Share permission denied",
+			"code":    "SHARE_PERMISSION_DENIED",
+			"message": "You don't have permission to revoke this share",
+		})
+		return
+	}
+
+	if err := database.GetDB().Delete(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke share",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while revoking the share",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share revoked successfully",
+	})
+}
+
+// generateShareToken generates an unguessable share link token
+func generateShareToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sharedTagBoost is added to the similarity score of content sharing at
+// least one tag with the source
+const sharedTagBoost = 0.2
+
+// GetSimilarContent suggests content related to the given content, ranked by
+// trigram similarity of title and description with a boost for shared tags.
+// Only content the user can read is suggested.
+func GetSimilarContent(c *gin.Context) {
+	content, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	tags := pq.Array(content.Tags)
+	score := clause.Expr{
+		SQL: `GREATEST(similarity(contents.title, ?), similarity(coalesce(contents.description, ''), ?))
+			+ CASE WHEN contents.tags && ?::text[] THEN ? ELSE 0 END DESC`,
+		Vars: []interface{}{content.Title, content.Description, tags, sharedTagBoost},
+	}
+
+	var contents []models.Content
+	if err := database.GetDB().Model(&models.Content{}).
+		Where("contents.id <> ?", content.ID).
+		Where("(contents.title % ? OR (? <> '' AND contents.description % ?) OR contents.tags && ?::text[])",
+			content.Title, content.Description, content.Description, tags).
+		Scopes(readableBy(user.ID)).
+		Clauses(clause.OrderBy{Expression: score}).
+		Preload("User").
+		Limit(limit).
+		Find(&contents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve similar content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving similar content",
+		})
+		return
+	}
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Similar content retrieved successfully",
+		"data":    contents,
+	})
+}
+
+// readableBy limits a content query to published public content and content
+// the user owns or collaborates on
+func readableBy(userID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`((contents.visibility = ? AND contents.status = ?) OR contents.user_id = ? OR EXISTS (
+			SELECT 1 FROM collaborations
+			WHERE collaborations.content_id = contents.id AND collaborations.user_id = ?
+			AND collaborations.is_active = ? AND collaborations.status = ?))`,
+			models.VisibilityPublic, models.ContentStatusPublished, userID, userID, true, models.CollaborationStatusAccepted)
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+)
+
+const (
+	// contentStatsDirtyKey is the Redis set of content with unflushed counters
+	contentStatsDirtyKey = "content:stats:dirty"
+	// contentStatsFlushBatch bounds how many content counters one flush pass
+	// pops at a time
+	contentStatsFlushBatch = 100
+)
+
+// recordContentView counts a view of content by the authenticated user or,
+// for anonymous requests, the client address. Repeat views by the same
+// viewer within the dedup window are ignored.
+func recordContentView(c *gin.Context, contentID uuid.UUID) {
+	ctx := c.Request.Context()
+
+	viewer := "ip:" + c.ClientIP()
+	if user, exists := middleware.GetUserFromContext(c); exists {
+		viewer = "user:" + user.ID.String()
+	}
+
+	window := config.Load().Content.ViewDedupWindow
+	if window > 0 {
+		fresh, err := redis.SetNX(ctx, fmt.Sprintf("content:view:%s:%s", contentID, viewer), 1, window)
+		if err != nil {
+			log.Printf("Failed to record view of content %s: %v", contentID, err)
+			return
+		}
+		if !fresh {
+			return
+		}
+	}
+
+	pipe := redis.Pipeline()
+	pipe.HIncrBy(ctx, contentStatsKey(contentID), "views", 1)
+	pipe.HSet(ctx, contentStatsKey(contentID), "last_viewed", time.Now().Unix())
+	pipe.PFAdd(ctx, contentViewersKey(contentID), viewer)
+	pipe.SAdd(ctx, contentStatsDirtyKey, contentID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record view of content %s: %v", contentID, err)
+		return
+	}
+	recordTrending(ctx, contentID, trendingViewWeight)
+}
+
+// recordContentShare counts a new share of content
+func recordContentShare(ctx context.Context, contentID uuid.UUID) {
+	pipe := redis.Pipeline()
+	pipe.HIncrBy(ctx, contentStatsKey(contentID), "shares", 1)
+	pipe.SAdd(ctx, contentStatsDirtyKey, contentID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record share of content %s: %v", contentID, err)
+		return
+	}
+	recordTrending(ctx, contentID, trendingShareWeight)
+}
+
+// recordTemplateUse counts a use of a template
+func recordTemplateUse(templateID uuid.UUID) {
+	if err := database.GetDB().Exec(`INSERT INTO content_stats (content_id, uses, updated_at)
+		VALUES (?, 1, NOW())
+		ON CONFLICT (content_id) DO UPDATE SET uses = content_stats.uses + 1, updated_at = EXCLUDED.updated_at`,
+		templateID).Error; err != nil {
+		log.Printf("Failed to record use of template %s: %v", templateID, err)
+	}
+}
+
+// GetContentStats returns the engagement totals of content, including
+// counters not yet flushed to the database. Only the owner and admins can
+// see them.
+func GetContentStats(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var content models.Content
+	if err := database.GetDB().First(&content, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found",
+		})
+		return
+	}
+
+	if content.UserID != user.ID && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Access denied",
+			"code":    "ACCESS_DENIED",
+			"message": "Only the owner can view content statistics",
+		})
+		return
+	}
+
+	stats := models.ContentStats{ContentID: content.ID}
+	if err := database.GetDB().Where("content_id = ?", content.ID).Limit(1).Find(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve statistics",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving statistics",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if pending, err := redis.HGetAll(ctx, contentStatsKey(content.ID)); err == nil {
+		views, shares, lastViewed := parseContentCounters(pending)
+		stats.Views += views
+		stats.Shares += shares
+		if lastViewed != nil && (stats.LastViewedAt == nil || lastViewed.After(*stats.LastViewedAt)) {
+			stats.LastViewedAt = lastViewed
+		}
+	}
+	if viewers, err := redis.PFCount(ctx, contentViewersKey(content.ID)); err == nil && viewers > stats.UniqueViewers {
+		stats.UniqueViewers = viewers
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Statistics retrieved successfully",
+		"data":    stats,
+	})
+}
+
+// FlushContentStats moves the view and share counters accumulated in Redis
+// into the content_stats table. Counters that fail to save are put back for
+// the next flush.
+func FlushContentStats(ctx context.Context) error {
+	for {
+		ids, err := redis.SPopN(ctx, contentStatsDirtyKey, contentStatsFlushBatch)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for _, rawID := range ids {
+			id, err := uuid.Parse(rawID)
+			if err != nil {
+				continue
+			}
+			if err := flushContentCounters(ctx, id); err != nil {
+				log.Printf("Failed to flush stats of content %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// flushContentCounters saves the pending counters of one content item
+func flushContentCounters(ctx context.Context, contentID uuid.UUID) error {
+	key := contentStatsKey(contentID)
+
+	pipe := redis.TxPipeline()
+	pendingCmd := pipe.HGetAll(ctx, key)
+	pipe.Del(ctx, key)
+	viewersCmd := pipe.PFCount(ctx, contentViewersKey(contentID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	views, shares, lastViewed := parseContentCounters(pendingCmd.Val())
+	if views == 0 && shares == 0 {
+		return nil
+	}
+
+	result := database.GetDB().Exec(`INSERT INTO content_stats (content_id, views, unique_viewers, shares, last_viewed_at, updated_at)
+		SELECT ?, ?, ?, ?, ?, NOW() WHERE EXISTS (SELECT 1 FROM contents WHERE id = ?)
+		ON CONFLICT (content_id) DO UPDATE SET
+			views = content_stats.views + EXCLUDED.views,
+			unique_viewers = GREATEST(content_stats.unique_viewers, EXCLUDED.unique_viewers),
+			shares = content_stats.shares + EXCLUDED.shares,
+			last_viewed_at = GREATEST(content_stats.last_viewed_at, EXCLUDED.last_viewed_at),
+			updated_at = EXCLUDED.updated_at`,
+		contentID, views, viewersCmd.Val(), shares, lastViewed, contentID)
+	if result.Error != nil {
+		// Put the counters back so they are retried on the next flush
+		restore := redis.Pipeline()
+		restore.HIncrBy(ctx, key, "views", views)
+		restore.HIncrBy(ctx, key, "shares", shares)
+		if lastViewed != nil {
+			restore.HSet(ctx, key, "last_viewed", lastViewed.Unix())
+		}
+		restore.SAdd(ctx, contentStatsDirtyKey, contentID.String())
+		if _, err := restore.Exec(ctx); err != nil {
+			log.Printf("Failed to restore stats counters of content %s: %v", contentID, err)
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// The content was deleted, so its viewers no longer need tracking
+		redis.Del(ctx, contentViewersKey(contentID))
+	}
+	return nil
+}
+
+// parseContentCounters reads the pending counters stored in a Redis hash
+func parseContentCounters(pending map[string]string) (views, shares int64, lastViewed *time.Time) {
+	views, _ = strconv.ParseInt(pending["views"], 10, 64)
+	shares, _ = strconv.ParseInt(pending["shares"], 10, 64)
+	if unix, err := strconv.ParseInt(pending["last_viewed"], 10, 64); err == nil {
+		t := time.Unix(unix, 0)
+		lastViewed = &t
+	}
+	return views, shares, lastViewed
+}
+
+// contentStatsKey returns the Redis hash holding the unflushed counters of
+// content
+func contentStatsKey(contentID uuid.UUID) string {
+	return "content:stats:" + contentID.String()
+}
+
+// contentViewersKey returns the Redis HyperLogLog estimating the unique
+// viewers of content
+func contentViewersKey(contentID uuid.UUID) string {
+	return "content:viewers:" + contentID.String()
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/ai"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/features"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/webhook"
+)
+
+// GetTemplates lists published public templates, filterable by type,
+// category (metadata.category), tags and search. Templates are ordered by
+// use count with ?sort=popular (the default) or by creation with
+// ?sort=recent.
+func GetTemplates(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	contentType := c.Query("type")
+	category := c.Query("category")
+	tags := c.Query("tags")
+	tagMode := c.Query("tag_mode")
+	search := c.Query("search")
+	searchMode := c.Query("search_mode")
+	sort := c.DefaultQuery("sort", "popular")
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	if sort != "popular" && sort != "recent" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sort",
+			"code":    "INVALID_SORT",
+			"message": "Sort must be popular or recent",
+		})
+		return
+	}
+
+	query := database.GetDB().Model(&models.Content{}).
+		Where("contents.is_template = ? AND contents.visibility = ? AND contents.status = ?", true, models.VisibilityPublic, models.ContentStatusPublished)
+
+	// Apply filters
+	if contentType != "" {
+		query = query.Where("contents.type = ?", contentType)
+	}
+	if category != "" {
+		query = query.Where("contents.metadata->>'category' = ?", category)
+	}
+	if tags != "" {
+		tagQuery, err := applyContentTagFilter(query, tags, tagMode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid tag filter",
+				"code":    "INVALID_TAG_FILTER",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = tagQuery
+	}
+	if search != "" {
+		searchQuery, err := applyContentSearch(query, search, searchMode, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid search mode",
+				"code":    "INVALID_SEARCH_MODE",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = searchQuery
+	}
+
+	// Get total count
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve templates",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving templates",
+		})
+		return
+	}
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	if sort == "popular" {
+		query = query.Joins("LEFT JOIN content_stats ON content_stats.content_id = contents.id").
+			Order("COALESCE(content_stats.uses, 0) DESC")
+	}
+
+	var contents []models.Content
+	if err := query.Select("contents.*").Preload("User").
+		Order("contents.created_at DESC").
+		Offset(offset).Limit(perPage).
+		Find(&contents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve templates",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving templates",
+		})
+		return
+	}
+	attachTemplateUses(contents)
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
+
+	response := ContentListResponse{
+		Contents:    contents,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Templates retrieved successfully",
+		"data":    response,
+	})
+}
+
+// UseTemplate forks a template the user can read into a new private draft
+// owned by the user and counts the use
+func UseTemplate(c *gin.Context) {
+	template, user, ok := readableContent(c)
+	if !ok {
+		return
+	}
+
+	if !template.IsTemplate {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Not a template",
+			"code":    "NOT_A_TEMPLATE",
+			"message": "The requested content is not a template",
+		})
+		return
+	}
+
+	content, err := forkContent(c.Request.Context(), template, user)
+	if errors.Is(err, errTierLimitReached) {
+		respondTierLimitReached(c, user, features.Content)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to use template",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while creating content from the template",
+		})
+		return
+	}
+
+	recordTemplateUse(template.ID)
+	recordActivity(content.ID, user.ID, models.ActivityContentCreated, models.JSON{
+		"template_id": template.ID,
+	})
+	webhook.Dispatch(content.UserID, models.WebhookEventContentCreated, content)
+	indexContentEmbedding(content)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Template used successfully",
+		"data":    content,
+	})
+}
+
+// GenerateAITemplate generates a fresh template of the ?type= and
+// ?category= query parameters with AI. The template is returned and not
+// saved.
+func GenerateAITemplate(aiService *ai.AIService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		templateType := c.Query("type")
+		category := c.Query("category")
+		if templateType == "" || category == "" || len(templateType) > 50 || len(category) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid template parameters",
+				"code":    "INVALID_REQUEST",
+				"message": "type and category are required and at most 50 characters",
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		template, err := aiService.GenerateTemplate(c.Request.Context(), templateType, category, user.ID.String())
+		if err != nil {
+			respondAIError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Template generated successfully",
+			"data":    template,
+		})
+	}
+}
+
+// attachTemplateUses fills in the use counts of templates
+func attachTemplateUses(contents []models.Content) {
+	if len(contents) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(contents))
+	for i, content := range contents {
+		ids[i] = content.ID
+	}
+
+	var stats []models.ContentStats
+	if err := database.GetDB().Select("content_id", "uses").Where("content_id IN ?", ids).Find(&stats).Error; err != nil {
+		log.Printf("Failed to load template uses: %v", err)
+		return
+	}
+	uses := make(map[uuid.UUID]int64, len(stats))
+	for _, s := range stats {
+		uses[s.ContentID] = s.Uses
+	}
+
+	for i := range contents {
+		count := uses[contents[i].ID]
+		contents[i].TemplateUses = &count
+	}
+}
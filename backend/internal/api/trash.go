@@ -0,0 +1,242 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// GetTrash lists the user's soft-deleted content, most recently deleted first
+func GetTrash(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	query := database.GetDB().Unscoped().Model(&models.Content{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", user.ID)
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Calculate pagination
+	offset := (page - 1) * perPage
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+
+	var contents []models.Content
+	if err := query.Offset(offset).Limit(perPage).Order("deleted_at DESC").Find(&contents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve trash",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving deleted content",
+		})
+		return
+	}
+
+	response := ContentListResponse{
+		Contents:    contents,
+		Total:       total,
+		Page:        page,
+		PerPage:     perPage,
+		TotalPages:  totalPages,
+		HasNext:     page < totalPages,
+		HasPrevious: page > 1,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trash retrieved successfully",
+		"data":    response,
+	})
+}
+
+// RestoreContent moves soft-deleted content out of the trash
+func RestoreContent(c *gin.Context) {
+	content, user, ok := trashedContentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	if err := database.GetDB().Unscoped().Model(&content).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to restore content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while restoring content",
+		})
+		return
+	}
+
+	invalidateContentCache(c.Request.Context(), content.ID)
+	recordActivity(content.ID, user.ID, models.ActivityContentRestored, nil)
+
+	// Load relationships
+	database.GetDB().Preload("User").First(&content, content.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content restored successfully",
+		"data":    content,
+	})
+}
+
+// DeleteContentPermanently hard deletes content that is already in the trash
+func DeleteContentPermanently(c *gin.Context) {
+	content, _, ok := trashedContentForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var keys []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		keys, err = purgeContent(tx, []uuid.UUID{content.ID})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete content",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while permanently deleting content",
+		})
+		return
+	}
+
+	deleteStoredObjects(keys)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content permanently deleted",
+	})
+}
+
+// PurgeTrash permanently deletes content that has been in the trash longer
+// than the retention window and returns how many items were removed
+func PurgeTrash(retention time.Duration) (int64, error) {
+	var ids []uuid.UUID
+	if err := database.GetDB().Unscoped().Model(&models.Content{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-retention)).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var keys []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		keys, err = purgeContent(tx, ids)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	deleteStoredObjects(keys)
+	return int64(len(ids)), nil
+}
+
+// trashedContentForAdmin loads the soft-deleted content named by the :id
+// param and checks the user may administer it, writing the error response
+// and returning false otherwise
+func trashedContentForAdmin(c *gin.Context) (models.Content, *models.User, bool) {
+	var content models.Content
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid content ID",
+			"code":    "INVALID_CONTENT_ID",
+			"message": "Content ID must be a valid UUID",
+		})
+		return content, nil, false
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return content, nil, false
+	}
+
+	if err := database.GetDB().Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).First(&content).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Content not found",
+			"code":    "CONTENT_NOT_FOUND",
+			"message": "The requested content was not found in the trash",
+		})
+		return content, nil, false
+	}
+
+	if err := database.GetDB().Where("content_id = ?", content.ID).Find(&content.Collaborations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to check permissions",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while checking content permissions",
+		})
+		return content, nil, false
+	}
+
+	if !content.CanAdmin(user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Delete permission denied",
+			"code":    "DELETE_PERMISSION_DENIED",
+			"message": "You don't have permission to manage this deleted content",
+		})
+		return content, nil, false
+	}
+
+	return content, user, true
+}
+
+// purgeContent hard deletes content along with its versions, collaborations,
+// shares and attachments. It returns the storage keys of the deleted
+// attachments, whose files the caller removes once the transaction commits.
+func purgeContent(tx *gorm.DB, ids []uuid.UUID) ([]string, error) {
+	var keys []string
+	if err := tx.Model(&models.Attachment{}).Where("content_id IN ?", ids).Pluck("storage_key", &keys).Error; err != nil {
+		return nil, err
+	}
+
+	for _, model := range []interface{}{
+		&models.ContentVersion{},
+		&models.Collaboration{},
+		&models.SharedContent{},
+		&models.Attachment{},
+	} {
+		if err := tx.Unscoped().Where("content_id IN ?", ids).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Content{}).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
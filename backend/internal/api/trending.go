@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+)
+
+// Trending points per event
+const (
+	trendingViewWeight     = 1
+	trendingReactionWeight = 3
+	trendingShareWeight    = 5
+)
+
+const (
+	// trendingRankTTL is how long a window's merged ranking is reused
+	trendingRankTTL = time.Minute
+	// trendingOverscan is how many ranked entries are read per requested
+	// item, leaving room for content that is no longer public
+	trendingOverscan = 3
+)
+
+// trendingWindow is a leaderboard window. Events land in a sorted set per
+// period whose points decay with the given half-life, and the window ranks
+// the current and previous period together so it rolls instead of emptying
+// when a period starts. A window without a period keeps a single set of
+// undecayed totals.
+type trendingWindow struct {
+	name     string
+	period   time.Duration
+	halfLife time.Duration
+}
+
+// trendingWindows lists the supported windows by name
+var trendingWindows = map[string]trendingWindow{
+	"daily":    {name: "daily", period: 24 * time.Hour, halfLife: 6 * time.Hour},
+	"weekly":   {name: "weekly", period: 7 * 24 * time.Hour, halfLife: 2 * 24 * time.Hour},
+	"all-time": {name: "all-time"},
+}
+
+// periodStart returns the start of the period containing t
+func (w trendingWindow) periodStart(t time.Time) time.Time {
+	return t.UTC().Truncate(w.period)
+}
+
+// key returns the sorted set of the period starting at start
+func (w trendingWindow) key(start time.Time) string {
+	if w.period == 0 {
+		return "trending:" + w.name
+	}
+	return fmt.Sprintf("trending:%s:%d", w.name, start.Unix())
+}
+
+// decay returns the factor that converts points earned at t into the scale
+// of a period starting at start. Later events weigh more, which is the same
+// as older events decaying, without rewriting stored scores.
+func (w trendingWindow) decay(t, start time.Time) float64 {
+	return math.Exp2(t.Sub(start).Hours() / w.halfLife.Hours())
+}
+
+// recordTrending adds the points of an event on content to every window
+func recordTrending(ctx context.Context, contentID uuid.UUID, points float64) {
+	now := time.Now()
+	member := contentID.String()
+
+	pipe := redis.Pipeline()
+	for _, window := range trendingWindows {
+		if window.period == 0 {
+			pipe.ZIncrBy(ctx, window.key(now), points, member)
+			continue
+		}
+		start := window.periodStart(now)
+		key := window.key(start)
+		pipe.ZIncrBy(ctx, key, points*window.decay(now, start), member)
+		// The set is still read as the previous period during the next one
+		pipe.ExpireAt(ctx, key, start.Add(2*window.period))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record trending score of content %s: %v", contentID, err)
+	}
+}
+
+// trendingRanking returns up to limit content IDs of a window with their
+// scores, highest first. Scores of decaying windows are given as of now.
+func trendingRanking(ctx context.Context, window trendingWindow, limit int) ([]uuid.UUID, []float64, error) {
+	key := window.key(time.Time{})
+	scale := 1.0
+	if window.period > 0 {
+		now := time.Now()
+		start := window.periodStart(now)
+		key = window.key(start) + ":ranked"
+		scale = 1 / window.decay(now, start)
+
+		exists, err := redis.Exists(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			// Scale the previous period's scores to the current one
+			keys := []string{window.key(start), window.key(start.Add(-window.period))}
+			weights := []float64{1, 1 / window.decay(start, start.Add(-window.period))}
+			if err := redis.ZUnionStore(ctx, key, keys, weights); err != nil {
+				return nil, nil, err
+			}
+			if err := redis.Expire(ctx, key, trendingRankTTL); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	entries, err := redis.ZRevRangeWithScores(ctx, key, 0, int64(limit)-1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(entries))
+	scores := make([]float64, 0, len(entries))
+	for _, entry := range entries {
+		id, err := uuid.Parse(fmt.Sprint(entry.Member))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		scores = append(scores, entry.Score*scale)
+	}
+	return ids, scores, nil
+}
+
+// GetTrendingContent returns the most popular public content of a ?window:
+// daily, weekly or all-time. Views, reactions and shares earn points, and
+// in the daily and weekly windows recent activity weighs more.
+func GetTrendingContent(c *gin.Context) {
+	windowName := c.DefaultQuery("window", "daily")
+	window, ok := trendingWindows[windowName]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid window",
+			"code":    "INVALID_WINDOW",
+			"message": "Window must be daily, weekly or all-time",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ids, scores, err := trendingRanking(c.Request.Context(), window, limit*trendingOverscan)
+	if err != nil {
+		log.Printf("Failed to rank trending content: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Trending unavailable",
+			"code":    "TRENDING_UNAVAILABLE",
+			"message": "Trending content is temporarily unavailable",
+		})
+		return
+	}
+
+	contents := []models.Content{}
+	if len(ids) > 0 {
+		var found []models.Content
+		if err := database.GetDB().Preload("User").
+			Where("id IN ? AND visibility = ? AND status = ?", ids, models.VisibilityPublic, models.ContentStatusPublished).
+			Find(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to retrieve content",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while retrieving content",
+			})
+			return
+		}
+
+		byID := make(map[uuid.UUID]models.Content, len(found))
+		for _, content := range found {
+			byID[content.ID] = content
+		}
+		for i, id := range ids {
+			content, ok := byID[id]
+			if !ok {
+				continue
+			}
+			score := scores[i]
+			content.TrendingScore = &score
+			contents = append(contents, content)
+			if len(contents) == limit {
+				break
+			}
+		}
+	}
+	attachReactionCounts(contents)
+	attachFavorites(c, contents)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trending content retrieved successfully",
+		"data": gin.H{
+			"window":   window.name,
+			"contents": contents,
+		},
+	})
+}
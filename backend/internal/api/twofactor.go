@@ -0,0 +1,496 @@
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	// Issuer shown in authenticator apps
+	totpIssuer = "Open-Same"
+
+	// Lifetime of the challenge token returned by Login when 2FA is enabled
+	twoFactorChallengeTTL = 5 * time.Minute
+
+	// Wrong codes accepted against one challenge before it is revoked
+	twoFactorMaxAttempts = 5
+
+	// Length of a TOTP time step
+	totpPeriod = 30
+)
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// TwoFactorValidateRequest represents a 2FA login challenge response
+type TwoFactorValidateRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// TwoFactorChallengeResponse represents the response returned by Login when 2FA is required
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// EnableTwoFactor starts 2FA setup by generating a new TOTP secret
+func EnableTwoFactor(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Two-factor authentication already enabled",
+			"code":    "2FA_ALREADY_ENABLED",
+			"message": "Disable two-factor authentication before setting it up again",
+		})
+		return
+	}
+
+	// Generate TOTP key
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate secret",
+			"code":    "2FA_SECRET_ERROR",
+			"message": "An error occurred while enabling two-factor authentication",
+		})
+		return
+	}
+
+	// Store encrypted secret until setup is confirmed
+	cfg := config.Load()
+	encrypted, err := encryptSecret(key.Secret(), cfg.Security.EncryptionKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to encrypt secret",
+			"code":    "2FA_SECRET_ERROR",
+			"message": "An error occurred while enabling two-factor authentication",
+		})
+		return
+	}
+
+	user.TOTPSecret = encrypted
+	if err := database.GetDB().Save(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save secret",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while enabling two-factor authentication",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Scan the provisioning URI and confirm with a code to finish setup",
+		"data": gin.H{
+			"secret":           key.Secret(),
+			"provisioning_uri": key.URL(),
+		},
+	})
+}
+
+// VerifyTwoFactor confirms 2FA setup with a code from the authenticator app
+func VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Two-factor authentication already enabled",
+			"code":    "2FA_ALREADY_ENABLED",
+			"message": "Two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Two-factor setup not started",
+			"code":    "2FA_NOT_STARTED",
+			"message": "Start two-factor setup before verifying a code",
+		})
+		return
+	}
+
+	if !validateTOTP(user, req.Code) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid code",
+			"code":    "INVALID_2FA_CODE",
+			"message": "The provided code is invalid or expired",
+		})
+		return
+	}
+
+	user.TOTPEnabled = true
+	if err := database.GetDB().Save(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to enable two-factor authentication",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while enabling two-factor authentication",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled successfully",
+	})
+}
+
+// DisableTwoFactor turns off 2FA after checking a current code
+func DisableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Two-factor authentication not enabled",
+			"code":    "2FA_NOT_ENABLED",
+			"message": "Two-factor authentication is not enabled",
+		})
+		return
+	}
+
+	if !validateTOTP(user, req.Code) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid code",
+			"code":    "INVALID_2FA_CODE",
+			"message": "The provided code is invalid or expired",
+		})
+		return
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	if err := database.GetDB().Save(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to disable two-factor authentication",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while disabling two-factor authentication",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled successfully",
+	})
+}
+
+// ValidateTwoFactor exchanges a login challenge and TOTP code for a token pair
+func ValidateTwoFactor(c *gin.Context) {
+	var req TwoFactorValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Find challenge token in database
+	var challenge models.Token
+	if err := database.GetDB().Where("token = ? AND type = ? AND is_revoked = ?", req.ChallengeToken, "2fa_challenge", false).First(&challenge).Error; err != nil || challenge.IsExpired() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid challenge token",
+			"code":    "INVALID_CHALLENGE_TOKEN",
+			"message": "Invalid or expired challenge token, please log in again",
+		})
+		return
+	}
+
+	// Get user
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", challenge.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "User not found",
+			"code":    "USER_NOT_FOUND",
+			"message": "User associated with token not found",
+		})
+		return
+	}
+
+	if !validateTOTP(&user, req.Code) {
+		// Count the failure, revoking the challenge once too many codes
+		// have been guessed against it
+		database.GetDB().Model(&models.Token{}).Where("id = ?", challenge.ID).Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"is_revoked": gorm.Expr("is_revoked OR attempts + 1 >= ?", twoFactorMaxAttempts),
+		})
+
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid code",
+			"code":    "INVALID_2FA_CODE",
+			"message": "The provided code is invalid or expired",
+		})
+		return
+	}
+
+	// Consume challenge token. Only one request can consume it, so a
+	// challenge raced with a second code is rejected.
+	result := database.GetDB().Model(&models.Token{}).
+		Where("id = ? AND is_revoked = ?", challenge.ID, false).
+		Updates(map[string]interface{}{"is_revoked": true, "updated_at": time.Now()})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to consume challenge",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while logging in",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid challenge token",
+			"code":    "INVALID_CHALLENGE_TOKEN",
+			"message": "Invalid or expired challenge token, please log in again",
+		})
+		return
+	}
+
+	// The account may have been banned or deactivated since the password
+	// step
+	if user.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account banned",
+			"code":    "USER_BANNED",
+			"message": "Your account has been banned",
+		})
+		return
+	}
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Account deactivated",
+			"code":    "ACCOUNT_DEACTIVATED",
+			"message": "Your account has been deactivated",
+		})
+		return
+	}
+
+	// Update last login
+	now := time.Now()
+	user.LastLoginAt = &now
+	database.GetDB().Save(&user)
+
+	// Generate tokens
+	cfg := config.Load()
+	accessToken, refreshToken, err := generateTokens(c.Request.Context(), &user, cfg.JWT)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate tokens",
+			"code":    "TOKEN_GENERATION_ERROR",
+			"message": "An error occurred while logging in",
+		})
+		return
+	}
+
+	// Save refresh token to database, starting a new token family
+	token := models.Token{
+		UserID:    user.ID,
+		Token:     refreshToken,
+		Type:      "refresh",
+		FamilyID:  uuid.New(),
+		ExpiresAt: time.Now().Add(time.Duration(cfg.JWT.RefreshHours) * time.Hour),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+
+	if err := database.GetDB().Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save token",
+			"code":    "TOKEN_SAVE_ERROR",
+			"message": "An error occurred while logging in",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"data": AuthResponse{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    int64(cfg.JWT.ExpirationHours * 3600),
+			User:         user,
+		},
+	})
+}
+
+// issueTwoFactorChallenge creates a short-lived token proving the password step succeeded
+func issueTwoFactorChallenge(user *models.User) (*models.Token, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+
+	token := models.Token{
+		UserID:    user.ID,
+		Token:     hex.EncodeToString(bytes),
+		Type:      "2fa_challenge",
+		ExpiresAt: time.Now().Add(twoFactorChallengeTTL),
+	}
+
+	if err := database.GetDB().Create(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// validateTOTP checks a code against the user's stored secret, allowing
+// one time step of clock skew. A code is accepted once: its time step is
+// recorded and codes from that step or earlier ones are rejected after.
+func validateTOTP(user *models.User, code string) bool {
+	cfg := config.Load()
+	secret, err := decryptSecret(user.TOTPSecret, cfg.Security.EncryptionKey)
+	if err != nil {
+		return false
+	}
+
+	now := time.Now().Unix() / totpPeriod
+	for _, step := range []int64{now - 1, now, now + 1} {
+		if step <= user.TOTPLastStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil || subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		// Record the step unless a concurrent request used it first
+		result := database.GetDB().Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+	return false
+}
+
+// encryptSecret encrypts a secret with AES-GCM using a key derived from the passphrase
+func encryptSecret(plaintext, passphrase string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret decrypts a secret produced by encryptSecret
+func decryptSecret(ciphertext, passphrase string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// newGCM builds an AES-256-GCM cipher from a passphrase
+func newGCM(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
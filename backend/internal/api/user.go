@@ -0,0 +1,491 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/websocket"
+	"gorm.io/gorm"
+)
+
+// UpdateProfileRequest represents a profile update. Only the fields that are
+// set are changed; account flags such as IsAdmin cannot be changed here.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty,max=50"`
+	LastName  *string `json:"last_name" binding:"omitempty,max=50"`
+	Bio       *string `json:"bio" binding:"omitempty,max=500"`
+	Username  *string `json:"username" binding:"omitempty,min=3,max=30"`
+	Email     *string `json:"email" binding:"omitempty,email"`
+}
+
+// DeleteAccountRequest represents an account deletion request. Password
+// confirms the deletion; accounts created through social login, which have
+// no password, confirm with their username instead. Content is moved to the
+// user named by TransferTo when set and deleted otherwise.
+type DeleteAccountRequest struct {
+	Password     string `json:"password"`
+	Confirmation string `json:"confirmation"`
+	TransferTo   string `json:"transfer_to"`
+}
+
+// ChangePasswordRequest represents a password change by a logged-in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// GetUserProfile returns the authenticated user's profile
+func GetUserProfile(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile retrieved successfully",
+		"data":    user,
+	})
+}
+
+// UpdateUserProfile updates the authenticated user's profile. Changing the
+// email address marks the account unverified and sends a new verification
+// email.
+func UpdateUserProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.FirstName != nil {
+		updates["first_name"] = strings.TrimSpace(*req.FirstName)
+	}
+	if req.LastName != nil {
+		updates["last_name"] = strings.TrimSpace(*req.LastName)
+	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
+	}
+
+	if req.Username != nil && *req.Username != user.Username {
+		if profileFieldTaken("username", *req.Username, user.ID) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Username already taken",
+				"code":    "USERNAME_EXISTS",
+				"message": "A user with this username already exists",
+			})
+			return
+		}
+		updates["username"] = *req.Username
+	}
+
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged {
+		if profileFieldTaken("email", *req.Email, user.ID) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Email already in use",
+				"code":    "EMAIL_EXISTS",
+				"message": "A user with this email already exists",
+			})
+			return
+		}
+		updates["email"] = *req.Email
+		updates["is_verified"] = false
+		updates["email_verified_at"] = nil
+	}
+
+	if len(updates) > 0 {
+		if err := database.GetDB().Model(user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update profile",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while updating your profile",
+			})
+			return
+		}
+	}
+
+	if emailChanged {
+		if _, err := issueVerificationToken(user, config.Load()); err != nil {
+			log.Printf("Failed to issue verification token for user %s: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// profileFieldTaken reports whether another account uses value for column.
+// Deleted accounts release their email and username.
+func profileFieldTaken(column, value string, userID uuid.UUID) bool {
+	var count int64
+	database.GetDB().Model(&models.User{}).
+		Where(column+" = ? AND id <> ?", value, userID).
+		Count(&count)
+	return count > 0
+}
+
+// ChangePassword changes the authenticated user's password. Every session
+// of the user is logged out and the current one continues with the new
+// token pair returned.
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	// Accounts created through social login have no password
+	if user.PasswordHash == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Password change not available",
+			"code":    "OAUTH_ACCOUNT",
+			"message": "This account uses " + user.OAuthProvider + " sign-in and has no password",
+		})
+		return
+	}
+
+	if !user.CheckPassword(req.CurrentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid credentials",
+			"code":    "INVALID_CURRENT_PASSWORD",
+			"message": "Current password is incorrect",
+		})
+		return
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Password unchanged",
+			"code":    "SAME_PASSWORD",
+			"message": "The new password must differ from the current one",
+		})
+		return
+	}
+
+	if !validateNewPassword(c, req.NewPassword) {
+		return
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to change password",
+			"code":    "PASSWORD_HASH_ERROR",
+			"message": "An error occurred while changing your password",
+		})
+		return
+	}
+
+	// Save the password and revoke the refresh tokens and pending two-factor
+	// challenges issued with the old one
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_hash", user.PasswordHash).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Token{}).
+			Where("user_id = ? AND type IN ? AND is_revoked = ?", user.ID, []string{"refresh", "2fa_challenge"}, false).
+			Update("is_revoked", true).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to change password",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while changing your password",
+		})
+		return
+	}
+
+	// Reject the access tokens issued with the old password; the new pair
+	// is issued in the user's next token generation
+	revokeUserAccessTokens(c.Request.Context(), user.ID)
+
+	cfg := config.Load()
+	accessToken, refreshToken, err := generateTokens(c.Request.Context(), user, cfg.JWT)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate tokens",
+			"code":    "TOKEN_GENERATION_ERROR",
+			"message": "Your password was changed, please log in again",
+		})
+		return
+	}
+
+	// Save refresh token to database, starting a new token family
+	token := models.Token{
+		UserID:    user.ID,
+		Token:     refreshToken,
+		Type:      "refresh",
+		FamilyID:  uuid.New(),
+		ExpiresAt: time.Now().Add(time.Duration(cfg.JWT.RefreshHours) * time.Hour),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+
+	if err := database.GetDB().Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save token",
+			"code":    "TOKEN_SAVE_ERROR",
+			"message": "Your password was changed, please log in again",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed successfully",
+		"data": AuthResponse{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    int64(cfg.JWT.ExpirationHours * 3600),
+			User:         *user,
+		},
+	})
+}
+
+// DeleteUserAccount deletes the authenticated user's account. The user is
+// soft-deleted, their tokens are revoked, their collaborations deactivated
+// and their content either transferred or moved to the trash, all in one
+// transaction. Open WebSocket connections are closed afterwards.
+func DeleteUserAccount(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req DeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"code":    "INVALID_REQUEST",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		// Get user from context
+		user, exists := middleware.GetUserFromContext(c)
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
+			})
+			return
+		}
+
+		confirmed := user.CheckPassword(req.Password)
+		if user.PasswordHash == "" {
+			confirmed = req.Confirmation == user.Username
+		}
+		if !confirmed {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Confirmation failed",
+				"code":    "INVALID_CONFIRMATION",
+				"message": "Enter your password, or your username for social login accounts, to delete your account",
+			})
+			return
+		}
+
+		var transferTo *models.User
+		if req.TransferTo != "" {
+			targetID, err := uuid.Parse(req.TransferTo)
+			var target models.User
+			if err == nil && targetID != user.ID {
+				err = database.GetDB().Where("is_active = ? AND is_banned = ?", true, false).First(&target, "id = ?", targetID).Error
+			}
+			if err != nil || targetID == user.ID {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid transfer target",
+					"code":    "INVALID_TRANSFER_TARGET",
+					"message": "Content can only be transferred to another active user",
+				})
+				return
+			}
+			transferTo = &target
+		}
+
+		cachedContent := contentIDsOfUser(user.ID)
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			if transferTo != nil {
+				if err := transferUserContent(tx, user.ID, transferTo.ID); err != nil {
+					return err
+				}
+			} else if err := tx.Where("user_id = ?", user.ID).Delete(&models.Content{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.Collaboration{}).Where("user_id = ?", user.ID).
+				Update("is_active", false).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Token{}).Where("user_id = ? AND is_revoked = ?", user.ID, false).
+				Updates(map[string]interface{}{"is_revoked": true, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(user).Update("is_active", false).Error; err != nil {
+				return err
+			}
+			return tx.Delete(user).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to delete account",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while deleting your account",
+			})
+			return
+		}
+
+		revokeUserAccessTokens(c.Request.Context(), user.ID)
+		invalidateContentCache(c.Request.Context(), cachedContent...)
+		disconnectDeletedUser(hub, user.ID)
+
+		data := gin.H{"user_id": user.ID}
+		if transferTo != nil {
+			data["transferred_to"] = transferTo.ID
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Account deleted successfully",
+			"data":    data,
+		})
+	}
+}
+
+// AdminDeleteUser permanently erases a user and everything they own,
+// including stored attachments and avatar, for erasure requests
+func AdminDeleteUser(hub *websocket.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid user ID",
+				"code":    "INVALID_USER_ID",
+				"message": "User ID must be a valid UUID",
+			})
+			return
+		}
+
+		var user models.User
+		if err := database.GetDB().Unscoped().First(&user, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "User not found",
+				"code":    "USER_NOT_FOUND",
+				"message": "The requested user was not found",
+			})
+			return
+		}
+
+		cachedContent := contentIDsOfUser(user.ID)
+		var keys []string
+		err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+			var contentIDs []uuid.UUID
+			if err := tx.Unscoped().Model(&models.Content{}).Where("user_id = ?", user.ID).Pluck("id", &contentIDs).Error; err != nil {
+				return err
+			}
+			if len(contentIDs) > 0 {
+				var err error
+				if keys, err = purgeContent(tx, contentIDs); err != nil {
+					return err
+				}
+			}
+
+			// Attachments the user uploaded to other people's content
+			var uploaded []string
+			if err := tx.Model(&models.Attachment{}).Where("user_id = ?", user.ID).Pluck("storage_key", &uploaded).Error; err != nil {
+				return err
+			}
+			keys = append(keys, uploaded...)
+			if user.AvatarKey != "" {
+				keys = append(keys, user.AvatarKey)
+			}
+
+			// Remaining rows referencing the user cascade through foreign keys
+			return tx.Unscoped().Delete(&user).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to delete user",
+				"code":    "DATABASE_ERROR",
+				"message": "An error occurred while deleting the user",
+			})
+			return
+		}
+		deleteStoredObjects(keys)
+		invalidateContentCache(c.Request.Context(), cachedContent...)
+
+		disconnectDeletedUser(hub, user.ID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "User permanently deleted",
+			"data":    gin.H{"user_id": user.ID},
+		})
+	}
+}
+
+// transferUserContent hands the user's content and the shares they created
+// over to another user, dropping the new owner's now redundant
+// collaborations on it
+func transferUserContent(tx *gorm.DB, fromID, toID uuid.UUID) error {
+	contentIDs := tx.Unscoped().Model(&models.Content{}).Select("id").Where("user_id = ?", fromID)
+
+	if err := tx.Where("user_id = ? AND content_id IN (?)", toID, contentIDs).Delete(&models.Collaboration{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.SharedContent{}).Where("content_id IN (?)", contentIDs).
+		Update("owner_id", toID).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.Content{}).Where("user_id = ?", fromID).Update("user_id", toID).Error
+}
+
+// disconnectDeletedUser closes the WebSocket connections of a deleted user
+func disconnectDeletedUser(hub *websocket.Hub, userID uuid.UUID) {
+	hub.DisconnectUser(userID.String(), websocket.Message{
+		Type:      "account_deleted",
+		UserID:    userID.String(),
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/email"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/queue"
+	"github.com/open-same/backend/internal/redis"
+	"github.com/open-same/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// userDataExportVersion is the version of the user data export layout
+const userDataExportVersion = 1
+
+// userDataExportBatchSize is how many records are loaded at a time while
+// writing a user data export
+const userDataExportBatchSize = 100
+
+// userDataSection is a list of records in a user data export
+type userDataSection struct {
+	name string
+	// records returns a pointer to an empty slice of the section's model
+	records func() interface{}
+	// query selects the user's records
+	query func(db *gorm.DB, userID uuid.UUID) *gorm.DB
+}
+
+// userDataSections lists what a user data export contains besides the
+// profile
+var userDataSections = []userDataSection{
+	{
+		name:    "content",
+		records: func() interface{} { return &[]models.Content{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			// Content in the trash is still the user's
+			return db.Unscoped().Where("user_id = ?", userID).
+				Preload("Versions", func(db *gorm.DB) *gorm.DB { return db.Order("version") })
+		},
+	},
+	{
+		name:    "folders",
+		records: func() interface{} { return &[]models.Folder{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+	{
+		name:    "collaborations",
+		records: func() interface{} { return &[]models.Collaboration{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+	{
+		name:    "shared_links",
+		records: func() interface{} { return &[]models.SharedContent{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("owner_id = ?", userID)
+		},
+	},
+	{
+		name:    "comments",
+		records: func() interface{} { return &[]models.Comment{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+	{
+		name:    "reactions",
+		records: func() interface{} { return &[]models.Reaction{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+	{
+		name:    "favorites",
+		records: func() interface{} { return &[]models.Favorite{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+	{
+		name:    "pinned_content",
+		records: func() interface{} { return &[]models.PinnedContent{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+	{
+		name:    "webhooks",
+		records: func() interface{} { return &[]models.Webhook{} },
+		query: func(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+			return db.Where("user_id = ?", userID)
+		},
+	},
+}
+
+// writeUserDataExport writes everything stored about a user as one JSON
+// object. Records are loaded and written in batches so large accounts are
+// never held in memory.
+func writeUserDataExport(ctx context.Context, w io.Writer, user models.User) error {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+
+	fmt.Fprintf(buffered, `{"version":%d,"exported_at":%q,"profile":`, userDataExportVersion, time.Now().UTC().Format(time.RFC3339))
+	if err := encoder.Encode(user); err != nil {
+		return err
+	}
+
+	db := database.GetDB().WithContext(ctx)
+	for _, section := range userDataSections {
+		fmt.Fprintf(buffered, ",%q:[", section.name)
+
+		first := true
+		records := section.records()
+		err := section.query(db, user.ID).FindInBatches(records, userDataExportBatchSize, func(tx *gorm.DB, batch int) error {
+			list := reflect.ValueOf(records).Elem()
+			for i := 0; i < list.Len(); i++ {
+				if !first {
+					buffered.WriteByte(',')
+				}
+				first = false
+				if err := encoder.Encode(list.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", section.name, err)
+		}
+
+		buffered.WriteByte(']')
+	}
+
+	buffered.WriteString("}\n")
+	return buffered.Flush()
+}
+
+// userDataExportFilename names the export of a user's data
+func userDataExportFilename(user models.User) string {
+	return fmt.Sprintf("open-same-%s-%s.json", unsafeFilenameChars.ReplaceAllString(user.Username, "-"), time.Now().UTC().Format("2006-01-02"))
+}
+
+// ExportUserData downloads everything stored about the authenticated user -
+// profile, content with its versions, collaborations, shared links,
+// comments, reactions, favorites and webhooks - as a JSON archive. Accounts
+// with more content than DATA_EXPORT_ASYNC_THRESHOLD, or requests with
+// ?async=true, are exported in the background and the user is emailed a
+// download link. Exports are limited to one per DATA_EXPORT_COOLDOWN.
+func ExportUserData(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	cfg := config.Load()
+	ctx := c.Request.Context()
+
+	// Exports are expensive, allow one per cooldown
+	cooldownKey := fmt.Sprintf("data_export:%s", user.ID)
+	if cfg.Content.DataExportCooldown > 0 {
+		if acquired, err := redis.SetNX(ctx, cooldownKey, 1, cfg.Content.DataExportCooldown); err == nil && !acquired {
+			retryAfter, _ := redis.TTL(ctx, cooldownKey)
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded",
+				"code":        "RATE_LIMIT_EXCEEDED",
+				"message":     "Your data was exported recently, please try again later",
+				"retry_after": time.Now().Add(retryAfter).Unix(),
+			})
+			return
+		}
+	}
+
+	async := c.Query("async") == "true"
+	if !async {
+		var contentCount int64
+		database.GetDB().WithContext(ctx).Unscoped().Model(&models.Content{}).Where("user_id = ?", user.ID).Count(&contentCount)
+		async = contentCount > int64(cfg.Content.DataExportAsyncThreshold)
+	}
+
+	if async {
+		export := ExportJob{
+			ID:        uuid.New(),
+			Kind:      ExportKindUserData,
+			UserID:    user.ID,
+			Format:    "json",
+			Status:    ExportStatusPending,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		err := saveExportJob(ctx, export)
+		if err == nil {
+			err = queue.Publish(ctx, queue.JobExport, export)
+		}
+		if err != nil {
+			log.Printf("Failed to queue data export of user %s: %v", user.ID, err)
+			redis.Del(ctx, cooldownKey)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to queue export",
+				"code":    "EXPORT_ERROR",
+				"message": "An error occurred while queuing the export",
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Export queued, you will receive an email when it is ready",
+			"data":    export,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": userDataExportFilename(*user)}))
+	c.Header("Content-Type", "application/json")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	// The response is under way, a failure can only cut it short
+	if err := writeUserDataExport(ctx, c.Writer, *user); err != nil {
+		log.Printf("Failed to stream data export of user %s: %v", user.ID, err)
+	}
+}
+
+// handleUserDataExport builds a queued user data export, stores it for
+// download and emails the user a link
+func handleUserDataExport(ctx context.Context, export ExportJob) error {
+	var user models.User
+	if err := database.GetDB().WithContext(ctx).First(&user, "id = ?", export.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			export.Status = ExportStatusFailed
+			export.Error = "The account was deleted"
+			return saveExportJob(ctx, export)
+		}
+		return err
+	}
+
+	// Stage the export on disk since storage needs its size up front
+	file, err := os.CreateTemp("", "user-export-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	err = writeUserDataExport(ctx, file, user)
+	var size int64
+	if err == nil {
+		size, err = file.Seek(0, io.SeekCurrent)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		_, contentType := export.fileType()
+		err = storage.Get().Put(ctx, export.storageKey(), file, size, contentType)
+	}
+	if err != nil {
+		export.Status = ExportStatusFailed
+		export.Error = "An error occurred while building the export"
+		if saveErr := saveExportJob(ctx, export); saveErr != nil {
+			log.Printf("Failed to record failure of export %s: %v", export.ID, saveErr)
+		}
+		return err
+	}
+
+	scheduleExportExpiry(ctx, export)
+
+	export.Status = ExportStatusReady
+	export.Filename = userDataExportFilename(user)
+	export.Size = size
+	if err := saveExportJob(ctx, export); err != nil {
+		return err
+	}
+
+	email.Notify(user.Email, email.TemplateDataExport, email.TemplateData{
+		Name: user.FullName(),
+		Link: fmt.Sprintf("%s/exports/%s", config.Load().Email.AppURL, export.ID),
+	})
+	return nil
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/middleware"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/webhook"
+)
+
+// CreateWebhookRequest represents a request to register a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=2048"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=256"`
+}
+
+// UpdateWebhookRequest represents a request to update a webhook
+type UpdateWebhookRequest struct {
+	URL      *string   `json:"url" binding:"omitempty,url,max=2048"`
+	Events   *[]string `json:"events" binding:"omitempty,min=1"`
+	IsActive *bool     `json:"is_active"`
+}
+
+// maxWebhookDeliveries caps the delivery log returned for a webhook
+const maxWebhookDeliveries = 100
+
+// CreateWebhook registers a webhook for the user's content events. The
+// signing secret is only returned in this response.
+func CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	if !validWebhookURL(req.URL) || !validWebhookEvents(req.Events) {
+		writeInvalidWebhook(c)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		bytes := make([]byte, 32)
+		if _, err := rand.Read(bytes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to generate secret",
+				"code":    "TOKEN_GENERATION_ERROR",
+				"message": "An error occurred while creating the webhook",
+			})
+			return
+		}
+		secret = hex.EncodeToString(bytes)
+	}
+
+	webhook := models.Webhook{
+		UserID:   user.ID,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   req.Events,
+		IsActive: true,
+	}
+
+	if err := database.GetDB().Create(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create webhook",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while creating the webhook",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully",
+		"data":    webhook,
+		"secret":  secret,
+	})
+}
+
+// GetWebhooks lists the user's webhooks
+func GetWebhooks(c *gin.Context) {
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := database.GetDB().Where("user_id = ?", user.ID).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve webhooks",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving webhooks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhooks retrieved successfully",
+		"data":    webhooks,
+	})
+}
+
+// UpdateWebhook changes a webhook's URL, events or active flag
+func UpdateWebhook(c *gin.Context) {
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"code":    "INVALID_REQUEST",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	webhook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if req.URL != nil {
+		if !validWebhookURL(*req.URL) {
+			writeInvalidWebhook(c)
+			return
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if !validWebhookEvents(*req.Events) {
+			writeInvalidWebhook(c)
+			return
+		}
+		webhook.Events = *req.Events
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := database.GetDB().Save(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update webhook",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while updating the webhook",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook updated successfully",
+		"data":    webhook,
+	})
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(c *gin.Context) {
+	webhook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := database.GetDB().Delete(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete webhook",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while deleting the webhook",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries lists the most recent delivery attempts of a webhook
+func GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := database.GetDB().Where("webhook_id = ?", webhook.ID).
+		Order("created_at DESC").Limit(maxWebhookDeliveries).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve deliveries",
+			"code":    "DATABASE_ERROR",
+			"message": "An error occurred while retrieving webhook deliveries",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deliveries retrieved successfully",
+		"data":    deliveries,
+	})
+}
+
+// findWebhook loads the user's webhook named by the :id param, writing the
+// error response and returning false when it does not exist
+func findWebhook(c *gin.Context) (models.Webhook, bool) {
+	var webhook models.Webhook
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook ID",
+			"code":    "INVALID_WEBHOOK_ID",
+			"message": "Webhook ID must be a valid UUID",
+		})
+		return webhook, false
+	}
+
+	// Get user from context
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "User context not found",
+			"code":    "MISSING_USER_CONTEXT",
+			"message": "Internal server error",
+		})
+		return webhook, false
+	}
+
+	// Other users' webhooks are reported as missing
+	if err := database.GetDB().First(&webhook, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Webhook not found",
+			"code":    "WEBHOOK_NOT_FOUND",
+			"message": "The requested webhook was not found",
+		})
+		return webhook, false
+	}
+
+	return webhook, true
+}
+
+// validWebhookURL accepts absolute http and https URLs that don't point at
+// internal addresses
+func validWebhookURL(raw string) bool {
+	return webhook.ValidateURL(raw) == nil
+}
+
+// validWebhookEvents reports whether every event is a known webhook event
+func validWebhookEvents(events []string) bool {
+	for _, event := range events {
+		if !containsString(models.WebhookEvents, event) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeInvalidWebhook responds to a webhook with a bad URL or unknown events
+func writeInvalidWebhook(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Invalid webhook",
+		"code":    "INVALID_WEBHOOK",
+		"message": "url must be a public http or https URL and events must be among " + strings.Join(models.WebhookEvents, ", "),
+	})
+}
@@ -8,10 +8,25 @@ import (
 type AIConfig struct {
 	OpenAI    OpenAIConfig    `json:"openai"`
 	Anthropic AnthropicConfig `json:"anthropic"`
+	Gemini    GeminiConfig    `json:"gemini"`
 	LocalLLM  LocalLLMConfig  `json:"local_llm"`
 	Fallback  FallbackConfig  `json:"fallback"`
+	Race      RaceConfig      `json:"race"`
+	Retry     AIRetryConfig   `json:"retry"`
 	RateLimit float64         `json:"rate_limit"`
 	MaxConcurrentRequests int `json:"max_concurrent_requests"`
+	CacheTTL  time.Duration   `json:"cache_ttl"` // zero disables the generation cache
+	MonthlyTokenQuota int     `json:"monthly_token_quota"` // per user, zero means unlimited
+	UserRateLimit AIRateLimitConfig `json:"user_rate_limit"`
+	Moderation ModerationConfig `json:"moderation"`
+	Embedding  EmbeddingConfig  `json:"embedding"`
+}
+
+// AIRateLimitConfig represents the budget of AI requests of each user, kept
+// apart from the HTTP rate limits
+type AIRateLimitConfig struct {
+	RequestsPerMinute int            `json:"requests_per_minute"` // zero means unlimited
+	Tiers             map[string]int `json:"tiers"`               // requests per minute by user tier, overriding RequestsPerMinute
 }
 
 // OpenAIConfig represents OpenAI API configuration
@@ -33,6 +48,14 @@ type AnthropicConfig struct {
 	Version     string        `json:"version"`
 }
 
+// GeminiConfig represents Google Gemini API configuration
+type GeminiConfig struct {
+	APIKey    string        `json:"api_key"`
+	Model     string        `json:"model"`
+	MaxTokens int           `json:"max_tokens"`
+	Timeout   time.Duration `json:"timeout"`
+}
+
 // LocalLLMConfig represents local LLM configuration
 type LocalLLMConfig struct {
 	Enabled bool   `json:"enabled"`
@@ -47,6 +70,36 @@ type FallbackConfig struct {
 	Model   string `json:"model"`
 }
 
+// AIRetryConfig represents retries of transient AI provider failures
+// (network errors, 429, 500, 502 and 503)
+type AIRetryConfig struct {
+	MaxAttempts    int           `json:"max_attempts"` // including the first, 1 disables retries
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+}
+
+// RaceConfig represents concurrent provider racing configuration
+type RaceConfig struct {
+	Enabled      bool `json:"enabled"`       // race every request, not only those asking for it
+	MaxProviders int  `json:"max_providers"` // providers raced at once, in priority order
+}
+
+// ModerationConfig represents AI prompt and output moderation configuration
+type ModerationConfig struct {
+	Enabled  bool          `json:"enabled"`
+	FailOpen bool          `json:"fail_open"` // allow content when the provider is unreachable
+	Model    string        `json:"model"`
+	Timeout  time.Duration `json:"timeout"`
+}
+
+// EmbeddingConfig represents content embedding configuration for semantic search
+type EmbeddingConfig struct {
+	Enabled  bool          `json:"enabled"`
+	Provider string        `json:"provider"` // openai
+	Model    string        `json:"model"`
+	Timeout  time.Duration `json:"timeout"`
+}
+
 // LoadAIConfig loads AI configuration from environment variables
 func LoadAIConfig() *AIConfig {
 	return &AIConfig{
@@ -65,6 +118,12 @@ func LoadAIConfig() *AIConfig {
 			Timeout:   getEnvAsDuration("ANTHROPIC_TIMEOUT", 30*time.Second),
 			Version:   getEnv("ANTHROPIC_VERSION", "2023-06-01"),
 		},
+		Gemini: GeminiConfig{
+			APIKey:    getEnv("GEMINI_API_KEY", ""),
+			Model:     getEnv("GEMINI_MODEL", "gemini-1.5-pro"),
+			MaxTokens: getEnvAsInt("GEMINI_MAX_TOKENS", 4000),
+			Timeout:   getEnvAsDuration("GEMINI_TIMEOUT", 30*time.Second),
+		},
 		LocalLLM: LocalLLMConfig{
 			Enabled: getEnv("LOCAL_LLM_ENABLED", "false") == "true",
 			URL:     getEnv("LOCAL_LLM_URL", "http://localhost:11434"),
@@ -75,7 +134,34 @@ func LoadAIConfig() *AIConfig {
 			Enabled: getEnv("AI_FALLBACK_ENABLED", "true") == "true",
 			Model:   getEnv("AI_FALLBACK_MODEL", "gpt-3.5-turbo"),
 		},
+		Retry: AIRetryConfig{
+			MaxAttempts:    getEnvAsInt("AI_RETRY_MAX_ATTEMPTS", 3),
+			InitialBackoff: getEnvAsDuration("AI_RETRY_INITIAL_BACKOFF", 500*time.Millisecond),
+			MaxBackoff:     getEnvAsDuration("AI_RETRY_MAX_BACKOFF", 10*time.Second),
+		},
+		Race: RaceConfig{
+			Enabled:      getEnv("AI_RACE_ENABLED", "false") == "true",
+			MaxProviders: getEnvAsInt("AI_RACE_MAX_PROVIDERS", 2),
+		},
 		RateLimit:             getEnvAsFloat("AI_RATE_LIMIT", 50.0),
 		MaxConcurrentRequests: getEnvAsInt("AI_MAX_CONCURRENT_REQUESTS", 10),
+		CacheTTL:              getEnvAsDuration("AI_CACHE_TTL", 24*time.Hour),
+		MonthlyTokenQuota:     getEnvAsInt("AI_MONTHLY_TOKEN_QUOTA", 0),
+		UserRateLimit: AIRateLimitConfig{
+			RequestsPerMinute: getEnvAsInt("AI_USER_RATE_LIMIT", 10),
+			Tiers:             getEnvAsIntMap("AI_USER_RATE_LIMIT_TIERS", map[string]int{"admin": 60}),
+		},
+		Moderation: ModerationConfig{
+			Enabled:  getEnv("AI_MODERATION_ENABLED", "false") == "true",
+			FailOpen: getEnv("AI_MODERATION_FAIL_OPEN", "true") == "true",
+			Model:    getEnv("AI_MODERATION_MODEL", "omni-moderation-latest"),
+			Timeout:  getEnvAsDuration("AI_MODERATION_TIMEOUT", 10*time.Second),
+		},
+		Embedding: EmbeddingConfig{
+			Enabled:  getEnv("AI_EMBEDDINGS_ENABLED", "true") == "true",
+			Provider: getEnv("AI_EMBEDDING_PROVIDER", "openai"),
+			Model:    getEnv("AI_EMBEDDING_MODEL", "text-embedding-3-small"),
+			Timeout:  getEnvAsDuration("AI_EMBEDDING_TIMEOUT", 30*time.Second),
+		},
 	}
 }
\ No newline at end of file
@@ -1,8 +1,8 @@
 package config
 
 import (
-	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,12 +11,28 @@ type Config struct {
 	Environment string
 	Version     string
 	Server      ServerConfig
+	Logging     LoggingConfig
+	Compression CompressionConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
 	Database    DatabaseConfig
 	Redis       RedisConfig
 	RabbitMQ    RabbitMQConfig
 	JWT         JWTConfig
+	Security    SecurityConfig
+	Password    PasswordConfig
+	OAuth       OAuthConfig
+	CORS        CORSConfig
+	WebSocket   WebSocketConfig
+	Content     ContentConfig
+	GraphQL     GraphQLConfig
+	Storage     StorageConfig
+	Email       EmailConfig
 	AI          AIConfig
+	Tiers       TiersConfig
 	RateLimit   float64
+	RateLimitBackend string // memory or redis
+	UserRateLimit    float64
 }
 
 // ServerConfig holds server-specific configuration
@@ -26,6 +42,46 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// RequestTimeout bounds how long a handler may run, AIRequestTimeout
+	// replaces it on AI routes. WriteTimeout must exceed both.
+	RequestTimeout   time.Duration
+	AIRequestTimeout time.Duration
+}
+
+// LoggingConfig holds request logging configuration
+type LoggingConfig struct {
+	Format string // text or json
+	Level  string // debug, info, warn or error
+}
+
+// CompressionConfig holds response compression configuration
+type CompressionConfig struct {
+	Enabled bool
+	// Level is a gzip level from 1 (fastest) to 9 (smallest), -1 for the default
+	Level int
+	// MinSize is the smallest body in bytes worth compressing
+	MinSize int
+	// ContentTypes are the media types compressed; entries like text/* match
+	// a whole family
+	ContentTypes []string
+}
+
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool
+	// Port serves /metrics on a separate admin listener; zero serves it on
+	// the API port
+	Port int
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP/HTTP collector; tracing is
+	// disabled when empty
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+	SampleRatio float64
 }
 
 // DatabaseConfig holds database connection configuration
@@ -36,6 +92,10 @@ type DatabaseConfig struct {
 	User     string
 	Password string
 	SSLMode  string
+	// ConnectAttempts and ConnectTimeout bound the retries while Postgres
+	// is not reachable at startup
+	ConnectAttempts int
+	ConnectTimeout  time.Duration
 }
 
 // RedisConfig holds Redis connection configuration
@@ -44,14 +104,26 @@ type RedisConfig struct {
 	Port     int
 	Password string
 	DB       int
+	// ConnectAttempts and ConnectTimeout bound the retries while Redis is
+	// not reachable at startup
+	ConnectAttempts int
+	ConnectTimeout  time.Duration
 }
 
 // RabbitMQConfig holds RabbitMQ connection configuration
 type RabbitMQConfig struct {
+	// Enabled sends background jobs through RabbitMQ; otherwise they run
+	// in-process
+	Enabled  bool
 	Host     string
 	Port     int
 	User     string
 	Password string
+	// Consume runs job consumers in this process, so API-only instances
+	// can publish without working the queues
+	Consume bool
+	// Prefetch is how many unacknowledged jobs a consumer holds per queue
+	Prefetch int
 }
 
 // JWTConfig holds JWT configuration
@@ -59,16 +131,188 @@ type JWTConfig struct {
 	Secret           string
 	ExpirationHours int
 	RefreshHours     int
+	// Algorithm is HS256, signing with Secret, or RS256, signing with the
+	// private key in PrivateKeyFile
+	Algorithm      string
+	PrivateKeyFile string
+	// VerifyKeyFiles are public keys of rotated out private keys whose
+	// tokens are still accepted
+	VerifyKeyFiles []string
+}
+
+// SecurityConfig holds configuration for encrypting sensitive data at rest
+type SecurityConfig struct {
+	EncryptionKey string
+}
+
+// PasswordConfig holds the policy new passwords must follow
+type PasswordConfig struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// BreachCheck rejects passwords found in data breaches by querying the
+	// Have I Been Pwned range API at BreachCheckURL
+	BreachCheck    bool
+	BreachCheckURL string
+}
+
+// OAuthConfig holds social login provider configuration
+type OAuthConfig struct {
+	Google OAuthProviderConfig
+	GitHub OAuthProviderConfig
+}
+
+// OAuthProviderConfig holds the client credentials of a single OAuth provider
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// CORSConfig holds the origins, methods and headers allowed in browser
+// requests
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+}
+
+// WebSocketConfig holds real-time collaboration configuration
+type WebSocketConfig struct {
+	// AllowQueryIdentity accepts unauthenticated user_id/username query
+	// params from legacy clients. Disable once all clients send a JWT.
+	AllowQueryIdentity bool
+	// RedisBackplane relays room messages between replicas via Redis pub/sub
+	RedisBackplane bool
+	// SaveInterval is the longest live edits go unsaved while a room is active
+	SaveInterval time.Duration
+	// MaxMessageSize is the largest message in bytes a client may send
+	MaxMessageSize int64
+	// HistorySize is how many recent content changes and chat messages of a
+	// room are replayed to clients joining it
+	HistorySize int
+	// MessageRate and MessageBurst limit how many messages of each type a
+	// client may send per second and at once
+	MessageRate  float64
+	MessageBurst int
+	// ChatRate and ChatBurst limit the chat messages a client may send
+	ChatRate  float64
+	ChatBurst int
+	// RateLimitStrikes is how many rate limited messages a client may send
+	// within a minute before it is disconnected. Zero never disconnects.
+	RateLimitStrikes int
+	// MaxRoomClients caps the connections that may join a room on each
+	// instance. Zero is unlimited.
+	MaxRoomClients int
+	// RedisPresence tracks room presence in Redis so every instance reports
+	// the users of all instances
+	RedisPresence bool
+	// PresenceInterval is how often an instance refreshes its presence in
+	// Redis; entries expire three intervals after an instance stops
+	PresenceInterval time.Duration
+}
+
+// ContentConfig holds content lifecycle configuration
+type ContentConfig struct {
+	// TrashRetention is how long deleted content stays restorable before it
+	// is purged. Zero keeps the trash forever.
+	TrashRetention time.Duration
+	// MaxImportSize is the largest file in bytes accepted by content import
+	MaxImportSize int64
+	// PublishCheckInterval is how often content scheduled for publishing is
+	// checked for being due
+	PublishCheckInterval time.Duration
+	// AutoArchiveAfter is how long a draft stays untouched before it is
+	// archived. Zero never archives drafts.
+	AutoArchiveAfter time.Duration
+	// LockTimeout is how long an editing lock lasts without edits by its
+	// holder
+	LockTimeout time.Duration
+	// MaxPins is how much content a user may pin to their public profile
+	MaxPins int
+	// StatsFlushInterval is how often view and share counters are moved from
+	// Redis to the database
+	StatsFlushInterval time.Duration
+	// ViewDedupWindow is how long repeat views by the same viewer are not
+	// counted again
+	ViewDedupWindow time.Duration
+	// CacheEnabled serves content reads from a Redis read-through cache
+	CacheEnabled bool
+	// CacheTTL is how long a single content is cached
+	CacheTTL time.Duration
+	// PublicCacheTTL is how long a page of the public content listing is cached
+	PublicCacheTTL time.Duration
+	// DataExportCooldown is how long a user waits between two exports of
+	// their account data
+	DataExportCooldown time.Duration
+	// DataExportAsyncThreshold is the number of content items above which an
+	// account data export is built in the background and emailed
+	DataExportAsyncThreshold int
+}
+
+// GraphQLConfig holds GraphQL endpoint configuration
+type GraphQLConfig struct {
+	// MaxDepth is the deepest field nesting a query may select
+	MaxDepth int
+	// MaxComplexity is the highest cost a query may have. Each field costs
+	// one, multiplied by the page size of the lists it is selected in.
+	MaxComplexity int
+	// Playground serves the GraphiQL explorer on GET /graphql outside
+	// production
+	Playground bool
+}
+
+// StorageConfig holds attachment storage configuration
+type StorageConfig struct {
+	Backend       string // local or s3
+	LocalPath     string
+	MaxUploadSize int64
+	MaxAvatarSize int64
+	S3            S3Config
+}
+
+// S3Config holds S3-compatible object storage configuration
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+	// PublicURL serves objects directly when the bucket is published;
+	// otherwise attachments are downloaded through the API
+	PublicURL string
+}
+
+// EmailConfig holds outgoing email configuration
+type EmailConfig struct {
+	Backend string // log or smtp
+	From    string
+	// AppURL is the frontend base URL used for links in emails
+	AppURL string
+	SMTP   SMTPConfig
+}
+
+// TiersConfig holds the plans users can be on and the limits of each. A
+// limit of zero, or one a tier doesn't set, means unlimited; AI tokens
+// fall back to AI_MONTHLY_TOKEN_QUOTA instead.
+type TiersConfig struct {
+	Names            []string       // tiers admins can assign besides free, which every user starts on
+	AITokens         map[string]int // AI tokens per calendar month
+	MaxContent       map[string]int // content items, not counting trashed ones
+	MaxCollaborators map[string]int // collaborators per content item
+	MaxStorage       map[string]int // bytes of attachments
 }
 
-// AIConfig holds AI service configuration
-type AIConfig struct {
-	OpenAIKey      string
-	OpenAIModel    string
-	AnthropicKey   string
-	AnthropicModel string
-	MaxTokens      int
-	Temperature    float64
+// SMTPConfig holds SMTP server configuration
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
 }
 
 // Load loads configuration from environment variables
@@ -77,59 +321,194 @@ func Load() *Config {
 		Environment: getEnv("ENVIRONMENT", "development"),
 		Version:     getEnv("VERSION", "1.0.0"),
 		Server: ServerConfig{
-			Port:         getEnvAsInt("API_PORT", 8080),
-			Host:         getEnv("API_HOST", "0.0.0.0"),
-			ReadTimeout:  getEnvAsDuration("READ_TIMEOUT", 15*time.Second),
-			WriteTimeout: getEnvAsDuration("WRITE_TIMEOUT", 15*time.Second),
-			IdleTimeout:  getEnvAsDuration("IDLE_TIMEOUT", 60*time.Second),
+			Port:             getEnvAsInt("API_PORT", 8080),
+			Host:             getEnv("API_HOST", "0.0.0.0"),
+			ReadTimeout:      getEnvAsDuration("READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:     getEnvAsDuration("WRITE_TIMEOUT", 90*time.Second),
+			IdleTimeout:      getEnvAsDuration("IDLE_TIMEOUT", 60*time.Second),
+			RequestTimeout:   getEnvAsDuration("REQUEST_TIMEOUT", 15*time.Second),
+			AIRequestTimeout: getEnvAsDuration("AI_REQUEST_TIMEOUT", 60*time.Second),
+		},
+		Logging: LoggingConfig{
+			Format: getEnv("LOG_FORMAT", "text"),
+			Level:  getEnv("LOG_LEVEL", "info"),
+		},
+		Compression: CompressionConfig{
+			Enabled: getEnv("COMPRESSION_ENABLED", "true") == "true",
+			Level:   getEnvAsInt("COMPRESSION_LEVEL", 5),
+			MinSize: getEnvAsInt("COMPRESSION_MIN_SIZE", 1024),
+			ContentTypes: getEnvAsSlice("COMPRESSION_CONTENT_TYPES", []string{
+				"application/json", "application/javascript", "application/xml",
+				"image/svg+xml", "text/*",
+			}),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnv("METRICS_ENABLED", "true") == "true",
+			Port:    getEnvAsInt("METRICS_PORT", 0),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    getEnv("TRACING_OTLP_ENDPOINT", ""),
+			Insecure:    getEnv("TRACING_INSECURE", "false") == "true",
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "open-same-backend"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1.0),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnvAsInt("DB_PORT", 5432),
-			Name:     getEnv("DB_NAME", "opensame"),
-			User:     getEnv("DB_USER", "opensame"),
-			Password: getEnv("DB_PASSWORD", "opensame_password"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:            getEnv("DB_HOST", "localhost"),
+			Port:            getEnvAsInt("DB_PORT", 5432),
+			Name:            getEnv("DB_NAME", "opensame"),
+			User:            getEnv("DB_USER", "opensame"),
+			Password:        getEnv("DB_PASSWORD", "opensame_password"),
+			SSLMode:         getEnv("DB_SSLMODE", "disable"),
+			ConnectAttempts: getEnvAsInt("DB_CONNECT_ATTEMPTS", 10),
+			ConnectTimeout:  getEnvAsDuration("DB_CONNECT_TIMEOUT", time.Minute),
 		},
 		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnvAsInt("REDIS_PORT", 6379),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvAsInt("REDIS_DB", 0),
+			Host:            getEnv("REDIS_HOST", "localhost"),
+			Port:            getEnvAsInt("REDIS_PORT", 6379),
+			Password:        getEnv("REDIS_PASSWORD", ""),
+			DB:              getEnvAsInt("REDIS_DB", 0),
+			ConnectAttempts: getEnvAsInt("REDIS_CONNECT_ATTEMPTS", 10),
+			ConnectTimeout:  getEnvAsDuration("REDIS_CONNECT_TIMEOUT", time.Minute),
 		},
 		RabbitMQ: RabbitMQConfig{
 			Host:     getEnv("RABBITMQ_HOST", "localhost"),
 			Port:     getEnvAsInt("RABBITMQ_PORT", 5672),
 			User:     getEnv("RABBITMQ_USER", "opensame"),
 			Password: getEnv("RABBITMQ_PASS", "opensame_password"),
+			Enabled:  getEnv("RABBITMQ_ENABLED", "false") == "true",
+			Consume:  getEnv("RABBITMQ_CONSUME", "true") == "true",
+			Prefetch: getEnvAsInt("RABBITMQ_PREFETCH", 10),
 		},
 		JWT: JWTConfig{
 			Secret:           getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
 			ExpirationHours: getEnvAsInt("JWT_EXPIRATION_HOURS", 24),
 			RefreshHours:     getEnvAsInt("JWT_REFRESH_HOURS", 168), // 7 days
+			Algorithm:        getEnv("JWT_ALGORITHM", "HS256"),
+			PrivateKeyFile:   getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			VerifyKeyFiles:   getEnvAsSlice("JWT_VERIFY_KEY_FILES", nil),
+		},
+		Security: SecurityConfig{
+			EncryptionKey: getEnv("ENCRYPTION_KEY", "your-super-secret-encryption-key-change-in-production"),
+		},
+		Password: PasswordConfig{
+			MinLength:        getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+			RequireUppercase: getEnv("PASSWORD_REQUIRE_UPPERCASE", "false") == "true",
+			RequireLowercase: getEnv("PASSWORD_REQUIRE_LOWERCASE", "false") == "true",
+			RequireDigit:     getEnv("PASSWORD_REQUIRE_DIGIT", "false") == "true",
+			RequireSymbol:    getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+			BreachCheck:      getEnv("PASSWORD_BREACH_CHECK", "false") == "true",
+			BreachCheckURL:   getEnv("PASSWORD_BREACH_CHECK_URL", "https://api.pwnedpasswords.com/range/"),
+		},
+		OAuth: OAuthConfig{
+			Google: OAuthProviderConfig{
+				ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+				ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+				RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/v1/auth/oauth/google/callback"),
+			},
+			GitHub: OAuthProviderConfig{
+				ClientID:     getEnv("GITHUB_CLIENT_ID", ""),
+				ClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
+				RedirectURL:  getEnv("GITHUB_REDIRECT_URL", "http://localhost:8080/api/v1/auth/oauth/github/callback"),
+			},
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: getEnvAsSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{
+				"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS",
+			}),
+			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{
+				"Origin", "Content-Length", "Content-Type", "Authorization", "Accept",
+				"Accept-Encoding", "Accept-Language", "Cache-Control", "Connection", "DNT",
+				"Host", "Pragma", "Referer", "User-Agent", "X-Requested-With",
+				"X-Forwarded-For", "X-Forwarded-Proto", "X-Real-IP", "If-Match", "If-None-Match",
+			}),
+			AllowCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "true") == "true",
+		},
+		WebSocket: WebSocketConfig{
+			AllowQueryIdentity: getEnv("WS_ALLOW_QUERY_IDENTITY", "false") == "true",
+			RedisBackplane:     getEnv("WS_REDIS_BACKPLANE", "false") == "true",
+			SaveInterval:       getEnvAsDuration("WS_SAVE_INTERVAL", 30*time.Second),
+			MaxMessageSize:     int64(getEnvAsInt("WS_MAX_MESSAGE_SIZE", 1<<20)),
+			HistorySize:        getEnvAsInt("WS_HISTORY_SIZE", 50),
+			MessageRate:        getEnvAsFloat("WS_MESSAGE_RATE", 20),
+			MessageBurst:       getEnvAsInt("WS_MESSAGE_BURST", 40),
+			ChatRate:           getEnvAsFloat("WS_CHAT_RATE", 2),
+			ChatBurst:          getEnvAsInt("WS_CHAT_BURST", 10),
+			RateLimitStrikes:   getEnvAsInt("WS_RATE_LIMIT_STRIKES", 100),
+			MaxRoomClients:     getEnvAsInt("WS_MAX_ROOM_CLIENTS", 0),
+			RedisPresence:      getEnv("WS_REDIS_PRESENCE", "false") == "true",
+			PresenceInterval:   getEnvAsDuration("WS_PRESENCE_INTERVAL", 15*time.Second),
 		},
-		AI: AIConfig{
-			OpenAIKey:      getEnv("OPENAI_API_KEY", ""),
-			OpenAIModel:    getEnv("OPENAI_MODEL", "gpt-4"),
-			AnthropicKey:   getEnv("ANTHROPIC_API_KEY", ""),
-			AnthropicModel: getEnv("ANTHROPIC_MODEL", "claude-3-sonnet-20240229"),
-			MaxTokens:      getEnvAsInt("AI_MAX_TOKENS", 4000),
-			Temperature:    getEnvAsFloat("AI_TEMPERATURE", 0.7),
+		Content: ContentConfig{
+			TrashRetention:     getEnvAsDuration("TRASH_RETENTION", 30*24*time.Hour),
+			MaxImportSize:      int64(getEnvAsInt("CONTENT_MAX_IMPORT_SIZE", 5<<20)),
+			StatsFlushInterval: getEnvAsDuration("CONTENT_STATS_FLUSH_INTERVAL", time.Minute),
+			PublishCheckInterval: getEnvAsDuration("CONTENT_PUBLISH_CHECK_INTERVAL", time.Minute),
+			AutoArchiveAfter: getEnvAsDuration("CONTENT_AUTO_ARCHIVE_AFTER", 0),
+			LockTimeout:      getEnvAsDuration("CONTENT_LOCK_TIMEOUT", 5*time.Minute),
+			MaxPins:          getEnvAsInt("CONTENT_MAX_PINS", 6),
+			ViewDedupWindow:    getEnvAsDuration("CONTENT_VIEW_DEDUP_WINDOW", 30*time.Minute),
+			CacheEnabled:       getEnv("CONTENT_CACHE_ENABLED", "true") == "true",
+			CacheTTL:           getEnvAsDuration("CONTENT_CACHE_TTL", 5*time.Minute),
+			PublicCacheTTL:     getEnvAsDuration("CONTENT_PUBLIC_CACHE_TTL", time.Minute),
+			DataExportCooldown:       getEnvAsDuration("DATA_EXPORT_COOLDOWN", 24*time.Hour),
+			DataExportAsyncThreshold: getEnvAsInt("DATA_EXPORT_ASYNC_THRESHOLD", 200),
 		},
-		RateLimit: getEnvAsFloat("RATE_LIMIT", 100.0), // requests per second
+		GraphQL: GraphQLConfig{
+			MaxDepth:      getEnvAsInt("GRAPHQL_MAX_DEPTH", 8),
+			MaxComplexity: getEnvAsInt("GRAPHQL_MAX_COMPLEXITY", 1000),
+			Playground:    getEnv("GRAPHQL_PLAYGROUND", "true") == "true",
+		},
+		Storage: StorageConfig{
+			Backend:       getEnv("STORAGE_BACKEND", "local"),
+			LocalPath:     getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+			MaxUploadSize: int64(getEnvAsInt("ATTACHMENT_MAX_SIZE", 25<<20)),
+			MaxAvatarSize: int64(getEnvAsInt("AVATAR_MAX_SIZE", 2<<20)),
+			S3: S3Config{
+				Endpoint:  getEnv("S3_ENDPOINT", "localhost:9000"),
+				Bucket:    getEnv("S3_BUCKET", "opensame-attachments"),
+				AccessKey: getEnv("S3_ACCESS_KEY", ""),
+				SecretKey: getEnv("S3_SECRET_KEY", ""),
+				Region:    getEnv("S3_REGION", "us-east-1"),
+				UseSSL:    getEnv("S3_USE_SSL", "true") == "true",
+				PublicURL: getEnv("S3_PUBLIC_URL", ""),
+			},
+		},
+		Email: EmailConfig{
+			Backend: getEnv("EMAIL_BACKEND", "log"),
+			From:    getEnv("EMAIL_FROM", "Open Same <no-reply@localhost>"),
+			AppURL:  getEnv("APP_URL", "http://localhost:3000"),
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", "localhost"),
+				Port:     getEnvAsInt("SMTP_PORT", 587),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+			},
+		},
+		AI:               *LoadAIConfig(),
+		Tiers: TiersConfig{
+			Names:            getEnvAsSlice("TIERS", []string{"pro"}),
+			AITokens:         getEnvAsIntMap("TIER_AI_TOKENS", map[string]int{}),
+			MaxContent:       getEnvAsIntMap("TIER_MAX_CONTENT", map[string]int{}),
+			MaxCollaborators: getEnvAsIntMap("TIER_MAX_COLLABORATORS", map[string]int{}),
+			MaxStorage:       getEnvAsIntMap("TIER_MAX_STORAGE", map[string]int{}),
+		},
+		RateLimit:        getEnvAsFloat("RATE_LIMIT", 100.0), // requests per second
+		RateLimitBackend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+		UserRateLimit:    getEnvAsFloat("USER_RATE_LIMIT", 20.0), // requests per second per authenticated user
 	}
 }
 
 // Helper functions
 func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
 func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
 			return intValue
 		}
@@ -138,7 +517,7 @@ func getEnvAsInt(key string, defaultValue int) int {
 }
 
 func getEnvAsFloat(key string, defaultValue float64) float64 {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
 			return floatValue
 		}
@@ -146,8 +525,40 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := lookupEnv(key); value != "" {
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
+
+// getEnvAsIntMap reads comma separated key=value pairs with integer values,
+// e.g. "free=10,pro=60". Malformed pairs are skipped.
+func getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	if value := lookupEnv(key); value != "" {
+		values := make(map[string]int)
+		for _, item := range strings.Split(value, ",") {
+			name, number, found := strings.Cut(strings.TrimSpace(item), "=")
+			if !found {
+				continue
+			}
+			if parsed, err := strconv.Atoi(strings.TrimSpace(number)); err == nil {
+				values[strings.TrimSpace(name)] = parsed
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
 			return duration
 		}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	fileValues     map[string]string
+	fileValuesOnce sync.Once
+)
+
+// lookupEnv returns the value of an environment variable, falling back to
+// the config file named by CONFIG_FILE. Environment variables always take
+// precedence over the file.
+func lookupEnv(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	fileValuesOnce.Do(func() {
+		path := os.Getenv("CONFIG_FILE")
+		if path == "" {
+			return
+		}
+		values, err := loadConfigFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load config file %s: %v", path, err)
+		}
+		fileValues = values
+	})
+	return fileValues[key]
+}
+
+// loadConfigFile reads a YAML or JSON config file into values keyed by
+// environment variable name. Nested sections are joined with underscores,
+// so these are equivalent:
+//
+//	DB_HOST: postgres
+//	db:
+//	  host: postgres
+//
+// Lists are joined with commas as in the comma separated variables.
+func loadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, use .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	flattenConfig("", raw, values)
+	return values, nil
+}
+
+// flattenConfig flattens nested config sections into values keyed by
+// upper-cased, underscore-joined paths
+func flattenConfig(prefix string, raw map[string]interface{}, values map[string]string) {
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenConfig(name, v, values)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, formatConfigValue(item))
+			}
+			values[name] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[name] = formatConfigValue(v)
+		}
+	}
+}
+
+// formatConfigValue formats a scalar as it would be written in an
+// environment variable. JSON numbers decode as floats, so whole numbers are
+// written without an exponent to stay parseable as integers.
+func formatConfigValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
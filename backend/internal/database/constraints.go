@@ -0,0 +1,123 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// foreignKey describes a constraint between a child column and the id of a
+// parent table
+type foreignKey struct {
+	name     string
+	table    string
+	column   string
+	parent   string
+	onDelete string // CASCADE or SET NULL
+}
+
+// foreignKeys lists the constraints enforced on the schema. Parents come
+// before their children so orphan cleanup cascades in a single pass.
+// Soft-deleted content keeps its row, so only hard deletes cascade and the
+// trash keeps working. The optional content_embeddings table adds its own
+// key in migrateEmbeddings.
+var foreignKeys = []foreignKey{
+	{"fk_tokens_user_id", "tokens", "user_id", "users", "CASCADE"},
+	{"fk_folders_user_id", "folders", "user_id", "users", "CASCADE"},
+	{"fk_folders_parent_id", "folders", "parent_id", "folders", "CASCADE"},
+	{"fk_contents_user_id", "contents", "user_id", "users", "CASCADE"},
+	{"fk_contents_folder_id", "contents", "folder_id", "folders", "SET NULL"},
+	{"fk_contents_parent_id", "contents", "parent_id", "contents", "SET NULL"},
+	{"fk_content_versions_content_id", "content_versions", "content_id", "contents", "CASCADE"},
+	// Versions outlive their author so the content's history stays whole
+	{"fk_content_versions_created_by", "content_versions", "created_by", "users", "SET NULL"},
+	{"fk_collaborations_content_id", "collaborations", "content_id", "contents", "CASCADE"},
+	{"fk_collaborations_user_id", "collaborations", "user_id", "users", "CASCADE"},
+	{"fk_shared_contents_content_id", "shared_contents", "content_id", "contents", "CASCADE"},
+	{"fk_shared_contents_owner_id", "shared_contents", "owner_id", "users", "CASCADE"},
+	{"fk_shared_contents_shared_with", "shared_contents", "shared_with", "users", "CASCADE"},
+	// Stored files of cascaded attachments are not removed; purgeContent
+	// collects their keys before deleting content explicitly
+	{"fk_attachments_content_id", "attachments", "content_id", "contents", "CASCADE"},
+	{"fk_attachments_user_id", "attachments", "user_id", "users", "CASCADE"},
+	{"fk_ai_usages_user_id", "ai_usages", "user_id", "users", "CASCADE"},
+	{"fk_webhooks_user_id", "webhooks", "user_id", "users", "CASCADE"},
+	{"fk_webhook_deliveries_webhook_id", "webhook_deliveries", "webhook_id", "webhooks", "CASCADE"},
+	{"fk_activity_logs_content_id", "activity_logs", "content_id", "contents", "CASCADE"},
+	{"fk_activity_logs_user_id", "activity_logs", "user_id", "users", "CASCADE"},
+	{"fk_audit_logs_actor_id", "audit_logs", "actor_id", "users", "SET NULL"},
+	{"fk_comments_content_id", "comments", "content_id", "contents", "CASCADE"},
+	{"fk_comments_user_id", "comments", "user_id", "users", "CASCADE"},
+	{"fk_comments_parent_id", "comments", "parent_id", "comments", "CASCADE"},
+	{"fk_reactions_content_id", "reactions", "content_id", "contents", "CASCADE"},
+	{"fk_reactions_user_id", "reactions", "user_id", "users", "CASCADE"},
+	{"fk_favorites_content_id", "favorites", "content_id", "contents", "CASCADE"},
+	{"fk_favorites_user_id", "favorites", "user_id", "users", "CASCADE"},
+	{"fk_pinned_contents_content_id", "pinned_contents", "content_id", "contents", "CASCADE"},
+	{"fk_pinned_contents_user_id", "pinned_contents", "user_id", "users", "CASCADE"},
+	{"fk_content_stats_content_id", "content_stats", "content_id", "contents", "CASCADE"},
+}
+
+// migrateForeignKeys removes orphaned rows and adds the foreign keys that
+// are missing. Constraints GORM created earlier without delete rules, or
+// with a delete rule that has since changed, are replaced.
+func migrateForeignKeys() error {
+	for _, fk := range foreignKeys {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var exists bool
+			if err := tx.Raw("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = ? AND conrelid = ?::regclass AND confdeltype::text = ?)",
+				fk.name, fk.table, fk.deleteType()).Scan(&exists).Error; err != nil {
+				return err
+			}
+			if exists {
+				return nil
+			}
+
+			// Drop other foreign keys on the column so the delete rule is not
+			// shadowed by a stricter constraint
+			var existing []string
+			if err := tx.Raw(`SELECT c.conname FROM pg_constraint c
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = ANY(c.conkey)
+				WHERE c.contype = 'f' AND c.conrelid = ?::regclass AND a.attname = ?`,
+				fk.table, fk.column).Scan(&existing).Error; err != nil {
+				return err
+			}
+			for _, name := range existing {
+				if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %q", fk.table, name)).Error; err != nil {
+					return err
+				}
+			}
+
+			orphans := fmt.Sprintf("%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.id = %s.%s)",
+				fk.column, fk.parent, fk.table, fk.column)
+			var cleanup *gorm.DB
+			if fk.onDelete == "SET NULL" {
+				cleanup = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s", fk.table, fk.column, orphans))
+			} else {
+				cleanup = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", fk.table, orphans))
+			}
+			if cleanup.Error != nil {
+				return cleanup.Error
+			}
+			if cleanup.RowsAffected > 0 {
+				log.Printf("Cleaned up %d orphaned rows in %s.%s", cleanup.RowsAffected, fk.table, fk.column)
+			}
+
+			return tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(id) ON DELETE %s",
+				fk.table, fk.name, fk.column, fk.parent, fk.onDelete)).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add foreign key %s: %v", fk.name, err)
+		}
+	}
+	return nil
+}
+
+// deleteType returns the pg_constraint.confdeltype code of the delete rule
+func (fk foreignKey) deleteType() string {
+	if fk.onDelete == "SET NULL" {
+		return "n"
+	}
+	return "c"
+}
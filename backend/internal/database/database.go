@@ -1,12 +1,21 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/metrics"
 	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/retry"
+	"github.com/open-same/backend/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -14,22 +23,59 @@ import (
 
 var DB *gorm.DB
 
+// migrated records whether AutoMigrate has completed
+var migrated atomic.Bool
+
+// vectorSearch records whether the pgvector extension and the
+// content_embeddings table are available
+var vectorSearch atomic.Bool
+
 // Init initializes the database connection
 func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-		NowFunc: func() time.Time {
-			return time.Now().UTC()
-		},
+	// Postgres may still be booting, so keep retrying within the budget
+	policy := retry.Policy{
+		MaxAttempts:    cfg.ConnectAttempts,
+		Timeout:        cfg.ConnectTimeout,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+	err := retry.Do(context.Background(), "database", policy, func(ctx context.Context) error {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Info),
+			// Foreign keys are managed by migrateForeignKeys so they carry
+			// delete rules
+			DisableForeignKeyConstraintWhenMigrating: true,
+			NowFunc: func() time.Time {
+				return time.Now().UTC()
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %v", err)
+		}
+
+		// Test connection
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get sql.DB: %v", err)
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			sqlDB.Close()
+			return fmt.Errorf("failed to ping database: %v", err)
+		}
+
+		DB = db
+		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
+		return nil, err
 	}
 
+	registerMetricsCallbacks(DB)
+	registerTracingCallbacks(DB)
+
 	// Get underlying sql.DB
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -41,11 +87,6 @@ func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	// Test connection
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %v", err)
-	}
-
 	log.Println("Database connection established successfully")
 
 	// Auto migrate models
@@ -56,6 +97,28 @@ func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	return DB, nil
 }
 
+// migratedModels are the models AutoMigrate creates tables for
+var migratedModels = []interface{}{
+	&models.User{},
+	&models.Token{},
+	&models.Folder{},
+	&models.Content{},
+	&models.ContentVersion{},
+	&models.SharedContent{},
+	&models.Collaboration{},
+	&models.AIUsage{},
+	&models.Attachment{},
+	&models.Webhook{},
+	&models.WebhookDelivery{},
+	&models.ActivityLog{},
+	&models.AuditLog{},
+	&models.Comment{},
+	&models.Reaction{},
+	&models.Favorite{},
+	&models.PinnedContent{},
+	&models.ContentStats{},
+}
+
 // AutoMigrate automatically migrates the database schema
 func AutoMigrate() error {
 	log.Println("Starting database migration...")
@@ -70,23 +133,107 @@ func AutoMigrate() error {
 		return fmt.Errorf("failed to create pg_trgm extension: %v", err)
 	}
 
-	// Migrate models
-	modelsToMigrate := []interface{}{
-		&models.User{},
-		&models.Token{},
-		&models.Content{},
-		&models.ContentVersion{},
-		&models.SharedContent{},
-		&models.Collaboration{},
-	}
+	// Content predating visibility levels only had is_public, so its
+	// visibility is derived once the column exists
+	migrateVisibility := DB.Migrator().HasTable(&models.Content{}) && !DB.Migrator().HasColumn(&models.Content{}, "visibility")
 
-	for _, model := range modelsToMigrate {
+	// Migrate models
+	for _, model := range migratedModels {
 		if err := DB.AutoMigrate(model); err != nil {
 			return fmt.Errorf("failed to migrate %T: %v", model, err)
 		}
 	}
 
+	// Private content with share links keeps them working as link content
+	if migrateVisibility {
+		if err := DB.Exec(`UPDATE contents SET visibility = CASE
+			WHEN is_public THEN 'public'
+			WHEN EXISTS (SELECT 1 FROM shared_contents WHERE shared_contents.content_id = contents.id AND shared_contents.share_type IN ('link', 'embed')) THEN 'link'
+			ELSE 'private' END`).Error; err != nil {
+			return fmt.Errorf("failed to migrate content visibility: %v", err)
+		}
+	}
+
+	if err := migrateForeignKeys(); err != nil {
+		return err
+	}
+
+	// Emails and usernames used to be unique across deleted accounts too,
+	// through indexes from older migrations and constraints from
+	// init-db.sql; the partial indexes replacing them let deleted accounts
+	// release both
+	if err := DB.Exec("DROP INDEX IF EXISTS idx_users_email, idx_users_username").Error; err != nil {
+		return fmt.Errorf("failed to drop user unique indexes: %v", err)
+	}
+	if err := DB.Exec("ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key, DROP CONSTRAINT IF EXISTS users_username_key").Error; err != nil {
+		return fmt.Errorf("failed to drop user unique constraints: %v", err)
+	}
+
+	// Full-text search column over title, description and body
+	if err := DB.Exec(`ALTER TABLE contents ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(content, '')), 'C')
+	) STORED`).Error; err != nil {
+		return fmt.Errorf("failed to create content search vector: %v", err)
+	}
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_content_search_vector ON contents USING GIN(search_vector)").Error; err != nil {
+		return fmt.Errorf("failed to create content search vector index: %v", err)
+	}
+
+	// Shares created before permissions were typed use read, write and admin
+	if err := DB.Exec(`UPDATE shared_contents SET permission = CASE permission
+		WHEN 'read' THEN 'view' WHEN 'write' THEN 'edit' ELSE 'manage' END
+		WHERE permission IN ('read', 'write', 'admin')`).Error; err != nil {
+		return fmt.Errorf("failed to migrate share permissions: %v", err)
+	}
+
+	// Keyset pagination index for cursor based content listing
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_content_updated_at_id ON contents (updated_at DESC, id DESC)").Error; err != nil {
+		return fmt.Errorf("failed to create content pagination index: %v", err)
+	}
+
+	// Trigram indexes for similar content suggestions
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_content_title_trgm ON contents USING GIN(title gin_trgm_ops)").Error; err != nil {
+		return fmt.Errorf("failed to create content title trigram index: %v", err)
+	}
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_content_description_trgm ON contents USING GIN(description gin_trgm_ops)").Error; err != nil {
+		return fmt.Errorf("failed to create content description trigram index: %v", err)
+	}
+
+	// Semantic search is optional, so a missing pgvector extension only
+	// disables it
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		log.Printf("pgvector extension unavailable, semantic search disabled: %v", err)
+	} else if err := migrateEmbeddings(); err != nil {
+		return err
+	}
+
 	log.Println("Database migration completed successfully")
+	migrated.Store(true)
+	return nil
+}
+
+// migrateEmbeddings creates the content_embeddings table and its
+// approximate nearest neighbour index
+func migrateEmbeddings() error {
+	if err := DB.AutoMigrate(&models.ContentEmbedding{}); err != nil {
+		return fmt.Errorf("failed to migrate %T: %v", &models.ContentEmbedding{}, err)
+	}
+	if err := DB.Exec(`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_content_embeddings_content_id') THEN
+			DELETE FROM content_embeddings WHERE content_id NOT IN (SELECT id FROM contents);
+			ALTER TABLE content_embeddings ADD CONSTRAINT fk_content_embeddings_content_id
+				FOREIGN KEY (content_id) REFERENCES contents(id) ON DELETE CASCADE;
+		END IF;
+	END $$`).Error; err != nil {
+		return fmt.Errorf("failed to add content embeddings foreign key: %v", err)
+	}
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_content_embeddings_embedding ON content_embeddings USING hnsw (embedding vector_cosine_ops)").Error; err != nil {
+		return fmt.Errorf("failed to create content embeddings index: %v", err)
+	}
+
+	vectorSearch.Store(true)
 	return nil
 }
 
@@ -139,6 +286,74 @@ func CreateIndexes() error {
 	return nil
 }
 
+// registerMetricsCallbacks counts failed database operations. Lookups that
+// find no record are expected and not counted.
+func registerMetricsCallbacks(db *gorm.DB) {
+	record := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				metrics.DBErrors.WithLabelValues(operation).Inc()
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	callbacks.Create().After("gorm:create").Register("metrics:create", record("create"))
+	callbacks.Query().After("gorm:query").Register("metrics:query", record("query"))
+	callbacks.Update().After("gorm:update").Register("metrics:update", record("update"))
+	callbacks.Delete().After("gorm:delete").Register("metrics:delete", record("delete"))
+	callbacks.Row().After("gorm:row").Register("metrics:row", record("row"))
+	callbacks.Raw().After("gorm:raw").Register("metrics:raw", record("raw"))
+}
+
+// registerTracingCallbacks wraps each database operation in a client span.
+// Spans are children of the request span when queries run on a session
+// created with WithContext(ctx).
+func registerTracingCallbacks(db *gorm.DB) {
+	const spanKey = "tracing:span"
+
+	start := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			ctx, span := tracing.Tracer().Start(tx.Statement.Context, "db."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemPostgreSQL),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(spanKey, span)
+		}
+	}
+	end := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		span.SetAttributes(
+			semconv.DBSQLTable(tx.Statement.Table),
+			semconv.DBStatement(tx.Statement.SQL.String()),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+			span.SetStatus(codes.Error, tx.Error.Error())
+		}
+		span.End()
+	}
+
+	callbacks := db.Callback()
+	callbacks.Create().Before("gorm:create").Register("tracing:before_create", start("create"))
+	callbacks.Create().After("gorm:create").Register("tracing:after_create", end)
+	callbacks.Query().Before("gorm:query").Register("tracing:before_query", start("query"))
+	callbacks.Query().After("gorm:query").Register("tracing:after_query", end)
+	callbacks.Update().Before("gorm:update").Register("tracing:before_update", start("update"))
+	callbacks.Update().After("gorm:update").Register("tracing:after_update", end)
+	callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", start("delete"))
+	callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", end)
+	callbacks.Row().Before("gorm:row").Register("tracing:before_row", start("row"))
+	callbacks.Row().After("gorm:row").Register("tracing:after_row", end)
+	callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", start("raw"))
+	callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", end)
+}
+
 // Close closes the database connection
 func Close() error {
 	if DB != nil {
@@ -151,6 +366,17 @@ func Close() error {
 	return nil
 }
 
+// Migrated reports whether the schema migrations have completed
+func Migrated() bool {
+	return migrated.Load()
+}
+
+// VectorSearchAvailable reports whether content embeddings can be stored
+// and searched
+func VectorSearchAvailable() bool {
+	return vectorSearch.Load()
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
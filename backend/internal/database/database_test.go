@@ -0,0 +1,171 @@
+package database
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// openTestDB connects to the database named by TEST_DATABASE_URL, skipping
+// the test when it is not set
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	require.NoError(t, err)
+
+	previous := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigratedModelsParse(t *testing.T) {
+	cache := &sync.Map{}
+	for _, model := range migratedModels {
+		_, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		assert.NoError(t, err, "%T", model)
+	}
+}
+
+func TestAutoMigrate(t *testing.T) {
+	db := openTestDB(t)
+
+	require.NoError(t, AutoMigrate())
+	// Migrating an up to date schema changes nothing
+	require.NoError(t, AutoMigrate())
+
+	for _, model := range migratedModels {
+		assert.True(t, db.Migrator().HasTable(model), "%T", model)
+	}
+	for _, fk := range foreignKeys {
+		var exists bool
+		require.NoError(t, db.Raw("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = ? AND confdeltype::text = ?)",
+			fk.name, fk.deleteType()).Scan(&exists).Error)
+		assert.True(t, exists, fk.name)
+	}
+}
+
+func TestAutoMigrateDropsUserUniqueConstraints(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, AutoMigrate())
+
+	// Databases created from init-db.sql have column UNIQUE constraints
+	require.NoError(t, db.Exec("ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email), ADD CONSTRAINT users_username_key UNIQUE (username)").Error)
+	require.NoError(t, AutoMigrate())
+
+	for _, name := range []string{"users_email_key", "users_username_key"} {
+		var exists bool
+		require.NoError(t, db.Raw("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = ?)", name).Scan(&exists).Error)
+		assert.False(t, exists, name)
+	}
+	for _, name := range []string{"idx_users_email_active", "idx_users_username_active"} {
+		var definition string
+		require.NoError(t, db.Raw("SELECT indexdef FROM pg_indexes WHERE indexname = ?", name).Scan(&definition).Error)
+		assert.Contains(t, definition, "UNIQUE", name)
+		assert.Contains(t, definition, "deleted_at IS NULL", name)
+	}
+}
+
+// createTestUser creates a user with a unique email and username
+func createTestUser(t *testing.T, db *gorm.DB, name string) models.User {
+	t.Helper()
+
+	suffix := uuid.NewString()[:8]
+	user := models.User{Email: name + "-" + suffix + "@example.com", Username: name + "_" + suffix, PasswordHash: "x"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&user).Error)
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&models.User{}, "id = ?", user.ID)
+	})
+	return user
+}
+
+// createTestContent creates content owned by owner with a first version
+// written by author, an accepted collaboration and a comment by author
+func createTestContent(t *testing.T, db *gorm.DB, owner, author models.User) (models.Content, models.ContentVersion) {
+	t.Helper()
+
+	content := models.Content{UserID: owner.ID, Title: "Dependents"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&content).Error)
+	version := models.ContentVersion{ContentID: content.ID, Version: 1, Content: "body", CreatedBy: &author.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&version).Error)
+	collaboration := models.Collaboration{ContentID: content.ID, UserID: author.ID}
+	require.NoError(t, db.Omit(clause.Associations).Create(&collaboration).Error)
+	comment := models.Comment{ContentID: content.ID, UserID: author.ID, Body: "comment"}
+	require.NoError(t, db.Omit(clause.Associations).Create(&comment).Error)
+	return content, version
+}
+
+// countRows counts the rows of model matching query, deleted or not
+func countRows(t *testing.T, db *gorm.DB, model interface{}, query string, args ...interface{}) int64 {
+	t.Helper()
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(model).Where(query, args...).Count(&count).Error)
+	return count
+}
+
+func TestDeletingUserHandlesDependents(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, AutoMigrate())
+
+	owner := createTestUser(t, db, "owner")
+	author := createTestUser(t, db, "author")
+	content, version := createTestContent(t, db, owner, author)
+
+	// Deleting the author keeps the content and its history
+	require.NoError(t, db.Unscoped().Delete(&models.User{}, "id = ?", author.ID).Error)
+	assert.Equal(t, int64(0), countRows(t, db, &models.Collaboration{}, "user_id = ?", author.ID))
+	assert.Equal(t, int64(0), countRows(t, db, &models.Comment{}, "user_id = ?", author.ID))
+	var kept models.ContentVersion
+	require.NoError(t, db.First(&kept, "id = ?", version.ID).Error)
+	assert.Nil(t, kept.CreatedBy, "created_by is set to NULL")
+	assert.Equal(t, int64(1), countRows(t, db, &models.Content{}, "id = ?", content.ID))
+
+	// Deleting the owner cascades to their content and its dependents
+	require.NoError(t, db.Unscoped().Delete(&models.User{}, "id = ?", owner.ID).Error)
+	assert.Equal(t, int64(0), countRows(t, db, &models.Content{}, "id = ?", content.ID))
+	assert.Equal(t, int64(0), countRows(t, db, &models.ContentVersion{}, "content_id = ?", content.ID))
+}
+
+func TestDeletingContentCascadesToDependents(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, AutoMigrate())
+
+	owner := createTestUser(t, db, "owner")
+	author := createTestUser(t, db, "author")
+	content, _ := createTestContent(t, db, owner, author)
+
+	// Soft deletes keep dependents so content can be restored from the trash
+	require.NoError(t, db.Delete(&models.Content{}, "id = ?", content.ID).Error)
+	assert.Equal(t, int64(1), countRows(t, db, &models.ContentVersion{}, "content_id = ?", content.ID))
+
+	require.NoError(t, db.Unscoped().Delete(&models.Content{}, "id = ?", content.ID).Error)
+	assert.Equal(t, int64(0), countRows(t, db, &models.ContentVersion{}, "content_id = ?", content.ID))
+	assert.Equal(t, int64(0), countRows(t, db, &models.Collaboration{}, "content_id = ?", content.ID))
+	assert.Equal(t, int64(0), countRows(t, db, &models.Comment{}, "content_id = ?", content.ID))
+	assert.Equal(t, int64(1), countRows(t, db, &models.User{}, "id = ?", author.ID), "users outlive content")
+}
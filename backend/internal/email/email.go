@@ -0,0 +1,99 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/queue"
+	"github.com/open-same/backend/internal/retry"
+)
+
+// Message is a single email with plain text and HTML bodies
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// sendPolicy bounds the retries of a single email in the background
+var sendPolicy = retry.Policy{
+	MaxAttempts:    5,
+	Timeout:        10 * time.Minute,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     2 * time.Minute,
+}
+
+// sendTimeout bounds a single delivery attempt
+const sendTimeout = 30 * time.Second
+
+var mailer Mailer
+
+// Init initializes the configured mail backend
+func Init(cfg config.EmailConfig) (Mailer, error) {
+	switch cfg.Backend {
+	case "", "log":
+		mailer = NewLogMailer()
+	case "smtp":
+		mailer = NewSMTPMailer(cfg.SMTP, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown email backend %q", cfg.Backend)
+	}
+
+	log.Printf("Email backend %q initialized successfully", cfg.Backend)
+	return mailer, nil
+}
+
+// Get returns the mailer instance
+func Get() Mailer {
+	return mailer
+}
+
+// SendAsync queues msg for delivery in the background. Errors are logged
+// since the request that triggered the email has already been answered.
+func SendAsync(msg Message) {
+	if err := queue.Publish(context.Background(), queue.JobEmail, msg); err != nil {
+		log.Printf("Failed to queue %q to %s: %v", msg.Subject, msg.To, err)
+	}
+}
+
+// HandleSendJob delivers a queued email, retrying transient failures
+func HandleSendJob(ctx context.Context, job queue.Job) error {
+	var msg Message
+	if err := job.Decode(&msg); err != nil {
+		return err
+	}
+	if mailer == nil {
+		return errors.New("email backend not initialized")
+	}
+
+	err := retry.Do(ctx, "mail server", sendPolicy, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+		return mailer.Send(ctx, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send %q to %s: %w", msg.Subject, msg.To, err)
+	}
+	return nil
+}
+
+// Notify renders the named template for a recipient and sends it in the
+// background
+func Notify(to, template string, data TemplateData) {
+	msg, err := Render(template, to, data)
+	if err != nil {
+		log.Printf("Failed to render %s email for %s: %v", template, to, err)
+		return
+	}
+	SendAsync(msg)
+}
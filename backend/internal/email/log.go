@@ -0,0 +1,21 @@
+package email
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer prints emails to the log instead of sending them, for
+// development setups without a mail server
+type LogMailer struct{}
+
+// NewLogMailer creates a mailer that logs messages
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message's recipient, subject and plain text body
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
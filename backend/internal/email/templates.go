@@ -0,0 +1,148 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// Template names
+const (
+	TemplateVerify = "verify"
+	TemplateReset  = "reset"
+	TemplateInvite = "invite"
+	TemplateShare  = "share"
+	// TemplateDataExport announces a finished user data export
+	TemplateDataExport = "data_export"
+)
+
+// TemplateData fills in the email templates
+type TemplateData struct {
+	// Name is the recipient's display name
+	Name string
+	// Actor is the name of the user who triggered the email
+	Actor string
+	// ContentTitle is the title of the content the email is about
+	ContentTitle string
+	// Role is the collaborator role offered in an invitation
+	Role string
+	// Link is the URL the email asks the recipient to open
+	Link string
+}
+
+// emailTemplate holds the parsed parts of one email
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// templateSources lists the subject, plain text and HTML body of each email
+var templateSources = map[string][3]string{
+	TemplateVerify: {
+		"Verify your email address",
+		`Hi {{.Name}},
+
+Please confirm your email address by opening the link below:
+
+{{.Link}}
+
+The link expires in 24 hours. If you did not create an account, you can ignore this email.`,
+		`<p>Hi {{.Name}},</p>
+<p>Please confirm your email address by clicking the link below:</p>
+<p><a href="{{.Link}}">Verify email address</a></p>
+<p>The link expires in 24 hours. If you did not create an account, you can ignore this email.</p>`,
+	},
+	TemplateReset: {
+		"Reset your password",
+		`Hi {{.Name}},
+
+Someone requested a password reset for your account. Open the link below to choose a new password:
+
+{{.Link}}
+
+If you did not request a reset, you can ignore this email and your password will stay the same.`,
+		`<p>Hi {{.Name}},</p>
+<p>Someone requested a password reset for your account. Click the link below to choose a new password:</p>
+<p><a href="{{.Link}}">Reset password</a></p>
+<p>If you did not request a reset, you can ignore this email and your password will stay the same.</p>`,
+	},
+	TemplateInvite: {
+		`{{.Actor}} invited you to collaborate on "{{.ContentTitle}}"`,
+		`Hi {{.Name}},
+
+{{.Actor}} invited you to collaborate on "{{.ContentTitle}}" as {{.Role}}. Open the link below to accept or decline:
+
+{{.Link}}`,
+		`<p>Hi {{.Name}},</p>
+<p>{{.Actor}} invited you to collaborate on <strong>{{.ContentTitle}}</strong> as {{.Role}}.</p>
+<p><a href="{{.Link}}">Review invitation</a></p>`,
+	},
+	TemplateShare: {
+		`{{.Actor}} shared "{{.ContentTitle}}" with you`,
+		`Hi {{.Name}},
+
+{{.Actor}} shared "{{.ContentTitle}}" with you. Open it here:
+
+{{.Link}}`,
+		`<p>Hi {{.Name}},</p>
+<p>{{.Actor}} shared <strong>{{.ContentTitle}}</strong> with you.</p>
+<p><a href="{{.Link}}">Open content</a></p>`,
+	},
+	TemplateDataExport: {
+		"Your data export is ready",
+		`Hi {{.Name}},
+
+The export of your account data you requested is ready. Download it here:
+
+{{.Link}}
+
+The download expires in 24 hours. If you did not request an export, please change your password.`,
+		`<p>Hi {{.Name}},</p>
+<p>The export of your account data you requested is ready.</p>
+<p><a href="{{.Link}}">Download your data</a></p>
+<p>The download expires in 24 hours. If you did not request an export, please change your password.</p>`,
+	},
+}
+
+// templates holds the parsed email templates
+var templates = parseTemplates()
+
+func parseTemplates() map[string]emailTemplate {
+	parsed := make(map[string]emailTemplate, len(templateSources))
+	for name, source := range templateSources {
+		parsed[name] = emailTemplate{
+			subject: texttemplate.Must(texttemplate.New(name + "_subject").Parse(source[0])),
+			text:    texttemplate.Must(texttemplate.New(name + "_text").Parse(source[1])),
+			html:    htmltemplate.Must(htmltemplate.New(name + "_html").Parse(source[2])),
+		}
+	}
+	return parsed
+}
+
+// Render builds the message for the named template
+func Render(name, to string, data TemplateData) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
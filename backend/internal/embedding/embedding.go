@@ -0,0 +1,197 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/retry"
+)
+
+// openAIEmbeddingsURL is the OpenAI embeddings endpoint
+const openAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+
+// maxInputRunes bounds the text embedded per input, keeping it within the
+// provider's token limit
+const maxInputRunes = 24000
+
+// Embedder computes vector embeddings of text
+type Embedder interface {
+	// Embed returns one embedding of models.EmbeddingDimensions per input
+	Embed(ctx context.Context, inputs []string) ([]models.Vector, error)
+	// Model names the embedding model, stored with each embedding
+	Model() string
+}
+
+var embedder Embedder
+
+// Init initializes the embedding provider. Semantic search is disabled and
+// Get returns nil when embeddings are disabled or no provider is configured.
+func Init(cfg config.AIConfig) (Embedder, error) {
+	embedder = nil
+	if !cfg.Embedding.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Embedding.Provider {
+	case "", "openai":
+		if cfg.OpenAI.APIKey == "" {
+			log.Println("No embedding provider configured, semantic search disabled")
+			return nil, nil
+		}
+		embedder = NewOpenAIEmbedder(cfg.OpenAI, cfg.Embedding, cfg.Retry)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Embedding.Provider)
+	}
+
+	log.Printf("Embedding model %q initialized successfully", embedder.Model())
+	return embedder, nil
+}
+
+// Get returns the embedder, or nil when semantic search is disabled
+func Get() Embedder {
+	return embedder
+}
+
+// Truncate shortens text to the length embedded per input
+func Truncate(text string) string {
+	if runes := []rune(text); len(runes) > maxInputRunes {
+		return string(runes[:maxInputRunes])
+	}
+	return text
+}
+
+// Checksum identifies the embedded text and model, so unchanged content is
+// not embedded again
+func Checksum(model, text string) string {
+	hash := sha256.Sum256([]byte(model + "\x00" + text))
+	return hex.EncodeToString(hash[:])
+}
+
+// OpenAIEmbedder computes embeddings with the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	openAI config.OpenAIConfig
+	model  string
+	client *http.Client
+}
+
+// openAIEmbeddingRequest represents an embeddings request
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// openAIEmbeddingResponse represents an embeddings response
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAIEmbedder creates a new OpenAI embedder
+func NewOpenAIEmbedder(openAI config.OpenAIConfig, cfg config.EmbeddingConfig, retryCfg config.AIRetryConfig) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		openAI: openAI,
+		model:  cfg.Model,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: retry.NewTransport("OpenAI embeddings API", retry.Policy{
+				MaxAttempts:    retryCfg.MaxAttempts,
+				InitialBackoff: retryCfg.InitialBackoff,
+				MaxBackoff:     retryCfg.MaxBackoff,
+				Jitter:         true,
+			}),
+		},
+	}
+}
+
+// Model names the embedding model
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+// Embed computes the embeddings of inputs in a single request
+func (e *OpenAIEmbedder) Embed(ctx context.Context, inputs []string) ([]models.Vector, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	truncated := make([]string, len(inputs))
+	for i, input := range inputs {
+		truncated[i] = Truncate(input)
+	}
+
+	body, err := json.Marshal(openAIEmbeddingRequest{
+		Model:      e.model,
+		Input:      truncated,
+		Dimensions: models.EmbeddingDimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIEmbeddingsURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+e.openAI.APIKey)
+	if e.openAI.Organization != "" {
+		httpReq.Header.Set("OpenAI-Organization", e.openAI.Organization)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var embResp openAIEmbeddingResponse
+	if err := json.Unmarshal(respBody, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if embResp.Error != nil {
+			return nil, fmt.Errorf("OpenAI embeddings API error (status %d): %s", resp.StatusCode, embResp.Error.Message)
+		}
+		return nil, fmt.Errorf("OpenAI embeddings API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	if len(embResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embResp.Data))
+	}
+
+	sort.Slice(embResp.Data, func(i, j int) bool {
+		return embResp.Data[i].Index < embResp.Data[j].Index
+	})
+
+	vectors := make([]models.Vector, len(embResp.Data))
+	for i, data := range embResp.Data {
+		if len(data.Embedding) != models.EmbeddingDimensions {
+			return nil, fmt.Errorf("expected %d dimensions, got %d", models.EmbeddingDimensions, len(data.Embedding))
+		}
+		vectors[i] = models.Vector(data.Embedding)
+	}
+	return vectors, nil
+}
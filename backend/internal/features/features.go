@@ -0,0 +1,149 @@
+// Package features gates capabilities by the tier a user is on. Limits come
+// from the TIER_* settings; a tier without a limit for a feature may use it
+// without bound, so users keep the behavior they had before tiers existed.
+package features
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+)
+
+// Feature is a capability whose use is limited by tier
+type Feature string
+
+const (
+	// AITokens are the AI tokens a user may use per calendar month
+	AITokens Feature = "ai_tokens"
+	// Content is the number of content items a user may own, not counting
+	// trashed ones
+	Content Feature = "content"
+	// Collaborators is the number of collaborators each content item of a
+	// user may have
+	Collaborators Feature = "collaborators"
+	// Storage is the number of bytes of attachments a user may upload
+	Storage Feature = "storage"
+)
+
+// All lists every feature, in the order limits are reported
+var All = []Feature{AITokens, Content, Collaborators, Storage}
+
+// Tiers returns the tiers users can be on, free first
+func Tiers() []string {
+	tiers := []string{models.TierFree}
+	for _, tier := range config.Load().Tiers.Names {
+		if tier != models.TierFree {
+			tiers = append(tiers, tier)
+		}
+	}
+	return tiers
+}
+
+// IsTier reports whether tier is a known tier
+func IsTier(tier string) bool {
+	for _, known := range Tiers() {
+		if known == tier {
+			return true
+		}
+	}
+	return false
+}
+
+// Limit returns how much of feature user may use, zero meaning unlimited.
+// Users on an unknown tier get the limits of the free tier.
+func Limit(user *models.User, feature Feature) int64 {
+	cfg := config.Load()
+	tier := user.Tier
+	if !IsTier(tier) {
+		tier = models.TierFree
+	}
+
+	var limits map[string]int
+	switch feature {
+	case AITokens:
+		limits = cfg.Tiers.AITokens
+		if _, ok := limits[tier]; !ok {
+			return int64(cfg.AI.MonthlyTokenQuota)
+		}
+	case Content:
+		limits = cfg.Tiers.MaxContent
+	case Collaborators:
+		limits = cfg.Tiers.MaxCollaborators
+	case Storage:
+		limits = cfg.Tiers.MaxStorage
+	}
+	if limit := limits[tier]; limit > 0 {
+		return int64(limit)
+	}
+	return 0
+}
+
+// Used returns how much of feature user has used. Collaborators are
+// counted per content item, so their use is reported for the user's busiest
+// item.
+func Used(ctx context.Context, user *models.User, feature Feature) (int64, error) {
+	db := database.GetDB().WithContext(ctx)
+	var used int64
+	var err error
+	switch feature {
+	case AITokens:
+		err = db.Model(&models.AIUsage{}).
+			Where("user_id = ? AND created_at >= ?", user.ID, models.StartOfMonth(time.Now())).
+			Select("COALESCE(SUM(total_tokens), 0)").
+			Scan(&used).Error
+	case Content:
+		err = db.Model(&models.Content{}).Where("user_id = ?", user.ID).Count(&used).Error
+	case Collaborators:
+		err = db.Raw(`SELECT COALESCE(MAX(count), 0) FROM (
+			SELECT COUNT(*) AS count FROM collaborations
+			JOIN contents ON contents.id = collaborations.content_id AND contents.deleted_at IS NULL
+			WHERE contents.user_id = ? AND collaborations.is_active = ? AND collaborations.status <> ?
+			GROUP BY collaborations.content_id) AS counts`, user.ID, true, models.CollaborationStatusDeclined).
+			Scan(&used).Error
+	case Storage:
+		err = db.Model(&models.Attachment{}).Where("user_id = ?", user.ID).
+			Select("COALESCE(SUM(size), 0)").
+			Scan(&used).Error
+	}
+	return used, err
+}
+
+// Allow reports whether user may use one more unit of feature
+func Allow(ctx context.Context, user *models.User, feature Feature) (bool, error) {
+	return AllowN(ctx, user, feature, 1)
+}
+
+// AllowN reports whether user may use n more units of feature. Limits are
+// checked before acting, so concurrent requests may overshoot them
+// slightly.
+func AllowN(ctx context.Context, user *models.User, feature Feature, n int64) (bool, error) {
+	limit := Limit(user, feature)
+	if limit == 0 {
+		return true, nil
+	}
+	used, err := Used(ctx, user, feature)
+	if err != nil {
+		return false, err
+	}
+	return used+n <= limit, nil
+}
+
+// AllowCollaborator reports whether content owned by owner may have one
+// more collaborator. Pending invitations count as collaborators.
+func AllowCollaborator(ctx context.Context, owner *models.User, contentID uuid.UUID) (bool, error) {
+	limit := Limit(owner, Collaborators)
+	if limit == 0 {
+		return true, nil
+	}
+	var count int64
+	if err := database.GetDB().WithContext(ctx).Model(&models.Collaboration{}).
+		Where("content_id = ? AND is_active = ? AND status <> ?", contentID, true, models.CollaborationStatusDeclined).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count < limit, nil
+}
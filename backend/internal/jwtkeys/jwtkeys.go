@@ -0,0 +1,211 @@
+package jwtkeys
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/open-same/backend/internal/config"
+)
+
+// KeySet signs and verifies JWTs. With RS256 tokens are signed by the
+// current private key and carry its key ID, while the public keys of
+// previous private keys keep verifying tokens issued before a rotation.
+// With HS256 a single shared secret signs and verifies.
+type KeySet struct {
+	method     jwt.SigningMethod
+	keyID      string
+	signingKey interface{}
+	verifyKeys map[string]*rsa.PublicKey
+	secret     []byte
+}
+
+// JWK is a public RSA key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var keys *KeySet
+
+// Init loads the signing keys for the configured algorithm
+func Init(cfg config.JWTConfig) (*KeySet, error) {
+	switch cfg.Algorithm {
+	case "", "HS256":
+		if cfg.Secret == "" {
+			return nil, errors.New("JWT_SECRET is required for HS256")
+		}
+		keys = &KeySet{method: jwt.SigningMethodHS256, secret: []byte(cfg.Secret)}
+	case "RS256":
+		set, err := loadRSAKeys(cfg)
+		if err != nil {
+			return nil, err
+		}
+		keys = set
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	log.Printf("JWT signing with %s initialized successfully", keys.method.Alg())
+	return keys, nil
+}
+
+// Get returns the key set instance
+func Get() *KeySet {
+	return keys
+}
+
+// loadRSAKeys reads the private signing key and the public keys still
+// accepted for verification
+func loadRSAKeys(cfg config.JWTConfig) (*KeySet, error) {
+	if cfg.PrivateKeyFile == "" {
+		return nil, errors.New("JWT_PRIVATE_KEY_FILE is required for RS256")
+	}
+
+	block, err := readPEM(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %v", err)
+	}
+
+	set := &KeySet{
+		method:     jwt.SigningMethodRS256,
+		keyID:      keyID(&privateKey.PublicKey),
+		signingKey: privateKey,
+		verifyKeys: make(map[string]*rsa.PublicKey),
+	}
+	set.verifyKeys[set.keyID] = &privateKey.PublicKey
+
+	for _, path := range cfg.VerifyKeyFiles {
+		block, err := readPEM(path)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := parsePublicKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT public key %s: %v", path, err)
+		}
+		set.verifyKeys[keyID(publicKey)] = publicKey
+	}
+
+	return set, nil
+}
+
+// Sign signs claims with the current key
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.secret != nil {
+		return token.SignedString(k.secret)
+	}
+	token.Header["kid"] = k.keyID
+	return token.SignedString(k.signingKey)
+}
+
+// Parse validates a token and decodes its claims. Only the configured
+// algorithm is accepted.
+func (k *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, k.verificationKey, jwt.WithValidMethods([]string{k.method.Alg()}))
+}
+
+// verificationKey picks the key a token was signed with from its kid header
+func (k *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	if k.secret != nil {
+		return k.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// JWKS returns the public keys that verify tokens. It is empty for HS256
+// since the shared secret must not be published.
+func (k *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for kid, key := range k.verifyKeys {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: k.method.Alg(),
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+// keyID derives a stable key ID from a public key so rotated keys never
+// need to be named by hand
+func keyID(key *rsa.PublicKey) string {
+	sum := sha256.Sum256(x509.MarshalPKCS1PublicKey(key))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// readPEM reads the first PEM block of a file
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %s: %v", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in JWT key %s", path)
+	}
+	return block, nil
+}
+
+// parsePrivateKey parses a PKCS#1 or PKCS#8 RSA private key
+func parsePrivateKey(block *pem.Block) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA private key")
+	}
+	return rsaKey, nil
+}
+
+// parsePublicKey parses a PKIX or PKCS#1 RSA public key
+func parsePublicKey(block *pem.Block) (*rsa.PublicKey, error) {
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA public key")
+	}
+	return rsaKey, nil
+}
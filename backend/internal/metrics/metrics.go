@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "opensame"
+
+var (
+	// HTTPRequests counts handled HTTP requests
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes HTTP request latency
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// AIGenerations counts AI generations by outcome: success, cached or error
+	AIGenerations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_generations_total",
+		Help:      "Total number of AI generations by model and outcome.",
+	}, []string{"model", "outcome"})
+
+	// AIGenerationDuration observes the latency of AI provider calls
+	AIGenerationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_generation_duration_seconds",
+		Help:      "AI generation latency by model.",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"model"})
+
+	// AITokens counts tokens consumed by AI generations
+	AITokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_tokens_total",
+		Help:      "Total number of AI tokens by model and kind (prompt or completion).",
+	}, []string{"model", "kind"})
+
+	// DBErrors counts failed database operations
+	DBErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "Total number of failed database operations by operation.",
+	}, []string{"operation"})
+
+	// CacheRequests counts cache lookups by cache and result: hit or miss
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Total number of cache lookups by cache and result.",
+	}, []string{"cache", "result"})
+
+	// WebSocketRateLimited counts WebSocket messages rejected by rate limits
+	WebSocketRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_rate_limited_total",
+		Help:      "Total number of WebSocket messages rejected by rate limits by message type.",
+	}, []string{"type"})
+
+	// Jobs counts processed background jobs by type and outcome: success or failed
+	Jobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_total",
+		Help:      "Total number of processed background jobs by type and outcome.",
+	}, []string{"type", "outcome"})
+)
+
+// HubStats reports the state of the WebSocket hub
+type HubStats interface {
+	GetTotalClients() int
+	GetTotalRooms() int
+}
+
+// RegisterHub exposes the hub's connected clients and active rooms as gauges
+func RegisterHub(hub HubStats) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_clients",
+		Help:      "Number of connected WebSocket clients.",
+	}, func() float64 { return float64(hub.GetTotalClients()) })
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_rooms",
+		Help:      "Number of active collaboration rooms.",
+	}, func() float64 { return float64(hub.GetTotalRooms()) })
+}
+
+// ObserveAIGeneration records a successful AI generation
+func ObserveAIGeneration(model string, latency time.Duration, promptTokens, completionTokens int) {
+	AIGenerations.WithLabelValues(model, "success").Inc()
+	AIGenerationDuration.WithLabelValues(model).Observe(latency.Seconds())
+	AITokens.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	AITokens.WithLabelValues(model, "completion").Add(float64(completionTokens))
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
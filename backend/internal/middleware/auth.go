@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,22 +10,33 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/open-same/backend/internal/config"
 	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/jwtkeys"
 	"github.com/open-same/backend/internal/models"
 )
 
+// Token types told apart by the token_type claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 // Claims represents JWT claims
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	IsAdmin   bool   `json:"is_admin"`
+	TokenType string `json:"token_type"`
+	// Generation is the user's token generation when the token was issued
+	Generation int64 `json:"generation,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // Auth middleware validates JWT tokens and sets user context
-func Auth(jwtSecret string) gin.HandlerFunc {
+func Auth(keys *jwtkeys.KeySet) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -52,109 +64,210 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 		// Extract token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
+		// Validate token and load user
+		if !authenticateToken(c, tokenString, keys) {
+			return
+		}
 
-		if err != nil {
-			var errorMessage string
-			var errorCode string
-
-			if strings.Contains(err.Error(), "token is expired") {
-				errorMessage = "Token has expired"
-				errorCode = "TOKEN_EXPIRED"
-			} else if strings.Contains(err.Error(), "signature is invalid") {
-				errorMessage = "Invalid token signature"
-				errorCode = "INVALID_SIGNATURE"
-			} else {
-				errorMessage = "Invalid token"
-				errorCode = "INVALID_TOKEN"
-			}
+		c.Next()
+	}
+}
 
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   errorMessage,
-				"code":    errorCode,
-				"message": "Please provide a valid authorization token",
-			})
-			c.Abort()
-			return
+// WebSocketAuth middleware authenticates WebSocket upgrade requests. Browsers
+// cannot set headers on the upgrade, so the JWT may also be passed in the
+// token query parameter. When allowQueryIdentity is set, requests without a
+// token are let through unauthenticated so legacy clients can keep sending
+// user_id and username during the migration.
+func WebSocketAuth(keys *jwtkeys.KeySet, allowQueryIdentity bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.Query("token")
+		if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		// Extract claims
-		claims, ok := token.Claims.(*Claims)
-		if !ok || !token.Valid {
+		if tokenString == "" {
+			if allowQueryIdentity {
+				c.Next()
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Invalid token claims",
-				"code":    "INVALID_CLAIMS",
-				"message": "Token contains invalid claims",
+				"error":   "Authentication token required",
+				"code":    "MISSING_AUTH_TOKEN",
+				"message": "Please provide a token in the Authorization header or token query parameter",
 			})
 			c.Abort()
 			return
 		}
 
-		// Check if token is expired
-		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Token has expired",
-				"code":    "TOKEN_EXPIRED",
-				"message": "Please refresh your token",
-			})
-			c.Abort()
+		if !authenticateToken(c, tokenString, keys) {
 			return
 		}
 
-		// Get user from database
-		var user models.User
-		userID, err := parseUUID(claims.UserID)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Invalid user ID in token",
-				"code":    "INVALID_USER_ID",
-				"message": "Token contains invalid user information",
-			})
-			c.Abort()
-			return
+		c.Next()
+	}
+}
+
+// authenticateToken validates a JWT, loads its user and sets the user context.
+// It writes the error response and aborts the request when validation fails.
+func authenticateToken(c *gin.Context, tokenString string, keys *jwtkeys.KeySet) bool {
+	// Parse and validate token
+	token, err := keys.Parse(tokenString, &Claims{})
+
+	if err != nil {
+		var errorMessage string
+		var errorCode string
+
+		if strings.Contains(err.Error(), "token is expired") {
+			errorMessage = "Token has expired"
+			errorCode = "TOKEN_EXPIRED"
+		} else if strings.Contains(err.Error(), "signature is invalid") {
+			errorMessage = "Invalid token signature"
+			errorCode = "INVALID_SIGNATURE"
+		} else {
+			errorMessage = "Invalid token"
+			errorCode = "INVALID_TOKEN"
 		}
 
-		if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "User not found",
-				"code":    "USER_NOT_FOUND",
-				"message": "User associated with token not found",
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   errorMessage,
+			"code":    errorCode,
+			"message": "Please provide a valid authorization token",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Extract claims
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid token claims",
+			"code":    "INVALID_CLAIMS",
+			"message": "Token contains invalid claims",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Refresh tokens may only be exchanged for new tokens
+	if claims.TokenType != TokenTypeAccess {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid token type",
+			"code":    "INVALID_TOKEN_TYPE",
+			"message": "Please provide an access token",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Check if token is expired
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Token has expired",
+			"code":    "TOKEN_EXPIRED",
+			"message": "Please refresh your token",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Get user from database
+	var user models.User
+	userID, err := parseUUID(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid user ID in token",
+			"code":    "INVALID_USER_ID",
+			"message": "Token contains invalid user information",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Check if token was revoked by logout, ban or account deletion
+	if isTokenRevoked(c.Request.Context(), claims, userID) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Token has been revoked",
+			"code":    "TOKEN_REVOKED",
+			"message": "Please log in again",
+		})
+		c.Abort()
+		return false
+	}
+
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "User not found",
+			"code":    "USER_NOT_FOUND",
+			"message": "User associated with token not found",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Check if user is banned
+	if user.IsBanned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "User account is banned",
+			"code":    "USER_BANNED",
+			"message": "Your account has been banned",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Check if user is active
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "User account is deactivated",
+			"code":    "USER_DEACTIVATED",
+			"message": "Your account has been deactivated",
+		})
+		c.Abort()
+		return false
+	}
+
+	// Set user context
+	c.Set("user", &user)
+	c.Set("user_id", user.ID)
+	c.Set("is_admin", user.IsAdmin)
+	c.Set("claims", claims)
+
+	return true
+}
+
+// AdminOnly middleware ensures only admin users can access
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		isAdmin, exists := c.Get("is_admin")
+		if !exists {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "User context not found",
+				"code":    "MISSING_USER_CONTEXT",
+				"message": "Internal server error",
 			})
 			c.Abort()
 			return
 		}
 
-		// Check if user is active
-		if !user.IsActive {
+		if !isAdmin.(bool) {
 			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "User account is deactivated",
-				"code":    "USER_DEACTIVATED",
-				"message": "Your account has been deactivated",
+				"error":   "Admin access required",
+				"code":    "ADMIN_ACCESS_REQUIRED",
+				"message": "You don't have permission to access this resource",
 			})
 			c.Abort()
 			return
 		}
 
-		// Set user context
-		c.Set("user", &user)
-		c.Set("user_id", user.ID)
-		c.Set("is_admin", user.IsAdmin)
-
 		c.Next()
 	}
 }
 
-// AdminOnly middleware ensures only admin users can access
-func AdminOnly() gin.HandlerFunc {
+// RequireVerified middleware ensures the authenticated user has verified their email
+func RequireVerified() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		isAdmin, exists := c.Get("is_admin")
+		user, exists := GetUserFromContext(c)
 		if !exists {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "User context not found",
@@ -165,11 +278,11 @@ func AdminOnly() gin.HandlerFunc {
 			return
 		}
 
-		if !isAdmin.(bool) {
+		if !user.IsVerified {
 			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Admin access required",
-				"code":    "ADMIN_ACCESS_REQUIRED",
-				"message": "You don't have permission to access this resource",
+				"error":   "Email verification required",
+				"code":    "EMAIL_NOT_VERIFIED",
+				"message": "Please verify your email address to access this resource",
 			})
 			c.Abort()
 			return
@@ -180,7 +293,7 @@ func AdminOnly() gin.HandlerFunc {
 }
 
 // OptionalAuth middleware provides optional authentication
-func OptionalAuth(jwtSecret string) gin.HandlerFunc {
+func OptionalAuth(keys *jwtkeys.KeySet) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -201,12 +314,7 @@ func OptionalAuth(jwtSecret string) gin.HandlerFunc {
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
+		token, err := keys.Parse(tokenString, &Claims{})
 
 		if err != nil {
 			// Invalid token, continue without authentication
@@ -216,7 +324,7 @@ func OptionalAuth(jwtSecret string) gin.HandlerFunc {
 
 		// Extract claims
 		claims, ok := token.Claims.(*Claims)
-		if !ok || !token.Valid {
+		if !ok || !token.Valid || claims.TokenType != TokenTypeAccess {
 			// Invalid claims, continue without authentication
 			c.Next()
 			return
@@ -238,15 +346,21 @@ func OptionalAuth(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
+		if isTokenRevoked(c.Request.Context(), claims, userID) {
+			// Revoked token, continue without authentication
+			c.Next()
+			return
+		}
+
 		if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
 			// User not found, continue without authentication
 			c.Next()
 			return
 		}
 
-		// Check if user is active
-		if !user.IsActive {
-			// User deactivated, continue without authentication
+		// Check if user is banned or inactive
+		if user.IsBanned || !user.IsActive {
+			// User banned or deactivated, continue without authentication
 			c.Next()
 			return
 		}
@@ -260,6 +374,49 @@ func OptionalAuth(jwtSecret string) gin.HandlerFunc {
 	}
 }
 
+// ErrInvalidToken is returned by UserFromToken when a token does not
+// authenticate an active user
+var ErrInvalidToken = errors.New("invalid token")
+
+// UserFromToken validates a JWT with the checks of Auth and returns its
+// user. It authenticates connections that present their token after the
+// HTTP request, such as GraphQL subscription sockets.
+func UserFromToken(ctx context.Context, keys *jwtkeys.KeySet, tokenString string) (*models.User, error) {
+	token, err := keys.Parse(tokenString, &Claims{})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.TokenType != TokenTypeAccess {
+		return nil, ErrInvalidToken
+	}
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := parseUUID(claims.UserID)
+	if err != nil || isTokenRevoked(ctx, claims, userID) {
+		return nil, ErrInvalidToken
+	}
+
+	var user models.User
+	if err := database.GetDB().WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+	if user.IsBanned || !user.IsActive {
+		return nil, ErrInvalidToken
+	}
+	return &user, nil
+}
+
+// SetUserContext sets the authenticated user of a request
+func SetUserContext(c *gin.Context, user *models.User) {
+	c.Set("user", user)
+	c.Set("user_id", user.ID)
+	c.Set("is_admin", user.IsAdmin)
+}
+
 // GetUserFromContext gets the authenticated user from context
 func GetUserFromContext(c *gin.Context) (*models.User, bool) {
 	user, exists := c.Get("user")
@@ -270,12 +427,13 @@ func GetUserFromContext(c *gin.Context) (*models.User, bool) {
 }
 
 // GetUserIDFromContext gets the authenticated user ID from context
-func GetUserIDFromContext(c *gin.Context) (string, bool) {
+func GetUserIDFromContext(c *gin.Context) (uuid.UUID, bool) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		return "", false
+		return uuid.Nil, false
 	}
-	return userID.(string), true
+	id, ok := userID.(uuid.UUID)
+	return id, ok
 }
 
 // IsAdmin checks if the authenticated user is an admin
@@ -287,11 +445,14 @@ func IsAdmin(c *gin.Context) bool {
 	return isAdmin.(bool)
 }
 
-// parseUUID parses a UUID string
-func parseUUID(s string) (string, error) {
-	// Simple validation - in production you might want to use a proper UUID library
-	if len(s) != 36 {
-		return "", fmt.Errorf("invalid UUID format")
+// parseUUID parses the user ID claim, rejecting the nil UUID
+func parseUUID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid UUID format: %v", err)
+	}
+	if id == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("nil UUID is not a valid user ID")
 	}
-	return s, nil
-}
\ No newline at end of file
+	return id, nil
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/jwtkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseUUID(t *testing.T) {
+	valid := uuid.New()
+
+	tests := []struct {
+		name  string
+		input string
+		want  uuid.UUID
+		valid bool
+	}{
+		{"valid", valid.String(), valid, true},
+		{"uppercase", "6BA7B810-9DAD-11D1-80B4-00C04FD430C8", uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), true},
+		{"36 characters with invalid hex", "zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz", uuid.Nil, false},
+		{"36 characters with misplaced hyphens", "6ba7b8109-dad-11d1-80b4-00c04fd430c8", uuid.Nil, false},
+		{"36 characters without hyphens", "6ba7b8109dad11d180b400c04fd430c8abcd", uuid.Nil, false},
+		{"nil UUID", uuid.Nil.String(), uuid.Nil, false},
+		{"empty", "", uuid.Nil, false},
+		{"truncated", valid.String()[:35], uuid.Nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUUID(tt.input)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// setupMockDB points the database package at a sqlmock connection
+func setupMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+	return mock
+}
+
+func TestAuthRejectsTokenIssuedBeforeBan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys, err := jwtkeys.Init(config.JWTConfig{Secret: "test-secret"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/", Auth(keys), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	userID := uuid.New()
+	token, err := keys.Sign(testClaims(userID, TokenTypeAccess, 0))
+	require.NoError(t, err)
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("banned account", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_active", "is_banned"}).AddRow(userID, false, true))
+
+		w := request()
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "USER_BANNED")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("tokens revoked by the ban", func(t *testing.T) {
+		mock := setupMockDB(t)
+		setupRedis(t)
+		require.NoError(t, RevokeUserAccessTokens(context.Background(), userID))
+
+		w := request()
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "TOKEN_REVOKED")
+		assert.NoError(t, mock.ExpectationsWereMet(), "the user is not loaded")
+	})
+}
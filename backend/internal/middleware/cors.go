@@ -1,65 +1,47 @@
 package middleware
 
 import (
+	"errors"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/config"
 )
 
-// CORS middleware handles Cross-Origin Resource Sharing
-func CORS() gin.HandlerFunc {
-	config := cors.DefaultConfig()
-	
-	// Allow all origins in development, restrict in production
-	config.AllowAllOrigins = true
-	
-	// Allow specific origins in production
-	// config.AllowedOrigins = []string{
-	//     "https://yourdomain.com",
-	//     "https://www.yourdomain.com",
-	//     "https://app.yourdomain.com",
-	// }
-	
-	// Allow specific methods
-	config.AllowMethods = []string{
-		"GET",
-		"POST",
-		"PUT",
-		"PATCH",
-		"DELETE",
-		"HEAD",
-		"OPTIONS",
-	}
-	
-	// Allow specific headers
-	config.AllowHeaders = []string{
-		"Origin",
-		"Content-Length",
-		"Content-Type",
-		"Authorization",
-		"Accept",
-		"Accept-Encoding",
-		"Accept-Language",
-		"Cache-Control",
-		"Connection",
-		"DNT",
-		"Host",
-		"Pragma",
-		"Referer",
-		"User-Agent",
-		"X-Requested-With",
-		"X-Forwarded-For",
-		"X-Forwarded-Proto",
-		"X-Real-IP",
+// CORS middleware handles Cross-Origin Resource Sharing. Every origin is
+// allowed in development; elsewhere only the configured origins are. A "*"
+// origin is refused when credentials are allowed, since it would let any
+// site make authenticated requests.
+func CORS(cfg config.CORSConfig, environment string) (gin.HandlerFunc, error) {
+	corsConfig := cors.DefaultConfig()
+
+	switch {
+	case environment == "development":
+		// Echo the request origin so credentialed requests work from anywhere
+		corsConfig.AllowOriginFunc = func(origin string) bool { return true }
+	case containsOrigin(cfg.AllowedOrigins, "*"):
+		if cfg.AllowCredentials {
+			return nil, errors.New("ALLOWED_ORIGINS cannot be * when CORS_ALLOW_CREDENTIALS is enabled, list the allowed origins instead")
+		}
+		corsConfig.AllowAllOrigins = true
+	case len(cfg.AllowedOrigins) == 0:
+		return nil, errors.New("ALLOWED_ORIGINS must list the origins allowed outside development")
+	default:
+		corsConfig.AllowOrigins = cfg.AllowedOrigins
 	}
-	
+
+	corsConfig.AllowMethods = cfg.AllowedMethods
+	corsConfig.AllowHeaders = cfg.AllowedHeaders
+
 	// Allow credentials (cookies, authorization headers)
-	config.AllowCredentials = true
-	
+	corsConfig.AllowCredentials = cfg.AllowCredentials
+
 	// Expose headers to the client
-	config.ExposeHeaders = []string{
+	corsConfig.ExposeHeaders = []string{
 		"Content-Length",
 		"Content-Type",
 		"Content-Disposition",
+		"ETag",
 		"X-Total-Count",
 		"X-Page-Count",
 		"X-Current-Page",
@@ -67,9 +49,22 @@ func CORS() gin.HandlerFunc {
 		"X-Request-ID",
 		"X-Response-Time",
 	}
-	
+
 	// Set max age for preflight requests
-	config.MaxAge = 86400 // 24 hours
-	
-	return cors.New(config)
-}
\ No newline at end of file
+	corsConfig.MaxAge = 86400 // 24 hours
+
+	if err := corsConfig.Validate(); err != nil {
+		return nil, err
+	}
+	return cors.New(corsConfig), nil
+}
+
+// containsOrigin reports whether origins contains origin
+func containsOrigin(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
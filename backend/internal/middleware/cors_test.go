@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCORSConfig allows https://app.example.com with credentials
+func testCORSConfig() config.CORSConfig {
+	return config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Origin", "Content-Type", "Authorization"},
+		AllowCredentials: true,
+	}
+}
+
+// preflight sends a CORS preflight for a PUT from origin through handler
+func preflight(handler gin.HandlerFunc, origin string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(handler)
+	router.PUT("/api/v1/content/1", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/content/1", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		environment string
+		origin      string
+		allowed     bool
+	}{
+		{"allowed origin", "production", "https://app.example.com", true},
+		{"disallowed origin", "production", "https://evil.example.com", false},
+		{"any origin in development", "development", "https://evil.example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := CORS(testCORSConfig(), tt.environment)
+			require.NoError(t, err)
+
+			w := preflight(handler, tt.origin)
+			if !tt.allowed {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+				return
+			}
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), http.MethodPut)
+			assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+		})
+	}
+}
+
+func TestCORSConfigErrors(t *testing.T) {
+	wildcardWithCredentials := testCORSConfig()
+	wildcardWithCredentials.AllowedOrigins = []string{"*"}
+	_, err := CORS(wildcardWithCredentials, "production")
+	assert.ErrorContains(t, err, "cannot be *")
+
+	// Without credentials the wildcard is allowed
+	wildcard := wildcardWithCredentials
+	wildcard.AllowCredentials = false
+	_, err = CORS(wildcard, "production")
+	assert.NoError(t, err)
+
+	noOrigins := testCORSConfig()
+	noOrigins.AllowedOrigins = nil
+	_, err = CORS(noOrigins, "production")
+	assert.Error(t, err)
+	_, err = CORS(noOrigins, "development")
+	assert.NoError(t, err)
+}
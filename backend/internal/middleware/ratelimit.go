@@ -0,0 +1,319 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/redis"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Limiters idle for longer than this are evicted
+	limiterIdleTTL = 10 * time.Minute
+
+	// How often idle limiters are swept
+	limiterCleanupInterval = time.Minute
+
+	// Upper bound on Redis calls made while rate limiting a request
+	redisRateLimitTimeout = 100 * time.Millisecond
+)
+
+// limiterEntry is a token bucket with the time it was last used
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// limiterStore keeps a token bucket per key and evicts idle ones
+type limiterStore struct {
+	mu       sync.Mutex
+	limiters map[string]*limiterEntry
+	limit    rate.Limit
+	burst    int
+	ttl      time.Duration
+}
+
+// newLimiterStore creates a limiter store allowing bursts of one second's
+// worth of requests and starts its janitor goroutine
+func newLimiterStore(limit rate.Limit, ttl time.Duration) *limiterStore {
+	return newBurstLimiterStore(limit, int(limit), ttl)
+}
+
+// newBurstLimiterStore creates a limiter store allowing bursts of up to
+// burst requests and starts its janitor goroutine
+func newBurstLimiterStore(limit rate.Limit, burst int, ttl time.Duration) *limiterStore {
+	if burst < 1 {
+		burst = 1
+	}
+
+	store := &limiterStore{
+		limiters: make(map[string]*limiterEntry),
+		limit:    limit,
+		burst:    burst,
+		ttl:      ttl,
+	}
+
+	go store.janitor(limiterCleanupInterval)
+
+	return store
+}
+
+// get returns the limiter for a key, creating it if needed
+func (s *limiterStore) get(key string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.limiters[key]
+	if !exists {
+		entry = &limiterEntry{limiter: rate.NewLimiter(s.limit, s.burst)}
+		s.limiters[key] = entry
+	}
+	entry.lastSeen = time.Now()
+
+	return entry.limiter
+}
+
+// allow reports whether a request for the key may proceed
+func (s *limiterStore) allow(key string) bool {
+	return s.get(key).Allow()
+}
+
+// cleanup removes limiters that have been idle longer than the TTL
+func (s *limiterStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.ttl)
+	for key, entry := range s.limiters {
+		if entry.lastSeen.Before(cutoff) {
+			delete(s.limiters, key)
+		}
+	}
+}
+
+// janitor periodically evicts idle limiters
+func (s *limiterStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.cleanup()
+	}
+}
+
+// RateLimitByUser applies a separate budget to authenticated requests keyed on
+// the user ID. It must run after Auth; requests without a user are left to the
+// IP limiter.
+func RateLimitByUser(limit rate.Limit) gin.HandlerFunc {
+	limiters := newLimiterStore(limit, limiterIdleTTL)
+
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			c.Next()
+			return
+		}
+
+		if !limiters.allow(user.ID.String()) {
+			abortRateLimited(c, time.Second)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RedisRateLimit limits requests with a sliding window counter shared through
+// Redis, so the budget holds across all backend instances. Requests are keyed
+// on the client IP. If Redis is unavailable the in-memory limiter is used
+// instead.
+func RedisRateLimit(requests int, window time.Duration) gin.HandlerFunc {
+	return redisRateLimit(requests, window, func(c *gin.Context) (string, bool) {
+		return "ip:" + getClientIP(c), true
+	})
+}
+
+// RedisRateLimitByUser is RateLimitByUser with the budget shared through
+// Redis like RedisRateLimit. It must run after Auth; requests without a user
+// are left to the IP limiter.
+func RedisRateLimitByUser(requests int, window time.Duration) gin.HandlerFunc {
+	return redisRateLimit(requests, window, func(c *gin.Context) (string, bool) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			return "", false
+		}
+		return "user:" + user.ID.String(), true
+	})
+}
+
+// redisRateLimit limits requests with the sliding window counter of the key
+// returned by identity. Requests without a key are not limited.
+func redisRateLimit(requests int, window time.Duration, identity func(c *gin.Context) (string, bool)) gin.HandlerFunc {
+	fallback := newLimiterStore(rate.Limit(float64(requests)/window.Seconds()), limiterIdleTTL)
+
+	return func(c *gin.Context) {
+		key, ok := identity(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := slidingWindowAllow(c.Request.Context(), key, requests, window)
+		if err != nil {
+			// Redis unavailable, fall back to the local limiter
+			allowed = fallback.allow(key)
+			retryAfter = time.Second
+		}
+
+		if !allowed {
+			abortRateLimited(c, retryAfter)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AIRateLimit limits the AI requests of each authenticated user to
+// cfg.RequestsPerMinute, or to the limit of the user's tier in cfg.Tiers.
+// The budget is separate from the HTTP limits, so it only applies to routes
+// calling an AI provider. Counters are shared through Redis when useRedis is
+// set, with a local limiter taking over while Redis is unavailable. It must
+// run after Auth.
+func AIRateLimit(cfg config.AIRateLimitConfig, useRedis bool) gin.HandlerFunc {
+	// A local limiter for each configured limit
+	fallbacks := make(map[int]*limiterStore)
+	for _, limit := range append([]int{cfg.RequestsPerMinute}, tierLimits(cfg.Tiers)...) {
+		if limit > 0 && fallbacks[limit] == nil {
+			fallbacks[limit] = newBurstLimiterStore(rate.Limit(float64(limit)/60), limit, limiterIdleTTL)
+		}
+	}
+
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			c.Next()
+			return
+		}
+
+		limit := cfg.RequestsPerMinute
+		if tierLimit, ok := cfg.Tiers[userTier(user)]; ok {
+			limit = tierLimit
+		}
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		key := "ai:user:" + user.ID.String()
+		var allowed bool
+		var retryAfter time.Duration
+		var err error
+		if useRedis {
+			allowed, retryAfter, err = slidingWindowAllow(c.Request.Context(), key, limit, time.Minute)
+		}
+		if !useRedis || err != nil {
+			// Roughly when the next request is allowed again
+			allowed = fallbacks[limit].allow(key)
+			retryAfter = time.Minute / time.Duration(limit)
+		}
+
+		if !allowed {
+			abortWithRetryAfter(c, retryAfter, "AI_RATE_LIMIT_EXCEEDED", "Too many AI requests. Please try again later.")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// tierLimits returns the limits of every tier
+func tierLimits(tiers map[string]int) []int {
+	limits := make([]int, 0, len(tiers))
+	for _, limit := range tiers {
+		limits = append(limits, limit)
+	}
+	return limits
+}
+
+// userTier returns the tier a user's limits are looked up by. Admins are
+// limited as the admin tier, whatever plan they are on.
+func userTier(user *models.User) string {
+	if user.IsAdmin {
+		return "admin"
+	}
+	return user.Tier
+}
+
+// slidingWindowAllow counts a request in the current window and estimates the
+// rate by weighting the previous window's count by how much of it still overlaps
+func slidingWindowAllow(ctx context.Context, key string, requests int, window time.Duration) (bool, time.Duration, error) {
+	if redis.GetClient() == nil {
+		return false, 0, fmt.Errorf("redis client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisRateLimitTimeout)
+	defer cancel()
+
+	now := time.Now()
+	windowStart := now.Truncate(window)
+	currentKey := fmt.Sprintf("ratelimit:%s:%d", key, windowStart.Unix())
+	previousKey := fmt.Sprintf("ratelimit:%s:%d", key, windowStart.Add(-window).Unix())
+
+	current, err := redis.Incr(ctx, currentKey)
+	if err != nil {
+		return false, 0, err
+	}
+	if current == 1 {
+		// Keep the counter around for the next window's estimate
+		if err := redis.Expire(ctx, currentKey, 2*window); err != nil {
+			return false, 0, err
+		}
+	}
+
+	var previous int64
+	if value, err := redis.Get(ctx, previousKey); err == nil {
+		previous, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - elapsed.Seconds()/window.Seconds()
+	estimated := float64(previous)*weight + float64(current)
+
+	if estimated > float64(requests) {
+		return false, window - elapsed, nil
+	}
+	return true, 0, nil
+}
+
+// abortRateLimited rejects a request that exceeded its rate limit
+func abortRateLimited(c *gin.Context, retryAfter time.Duration) {
+	abortWithRetryAfter(c, retryAfter, "RATE_LIMIT_EXCEEDED", "Too many requests. Please try again later.")
+}
+
+// abortWithRetryAfter rejects a request with 429, telling the client when
+// to retry
+func abortWithRetryAfter(c *gin.Context, retryAfter time.Duration, code, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Rate limit exceeded",
+		"code":        code,
+		"message":     message,
+		"retry_after": time.Now().Add(time.Duration(seconds) * time.Second).Unix(),
+	})
+	c.Abort()
+}
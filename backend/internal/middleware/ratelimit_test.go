@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiterStoreConcurrentAccess(t *testing.T) {
+	store := newLimiterStore(rate.Limit(1000), time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				store.allow(fmt.Sprintf("key-%d", (i+j)%16))
+				if j%20 == 0 {
+					store.cleanup()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.LessOrEqual(t, len(store.limiters), 16)
+}
+
+func TestLimiterStoreEvictsIdleLimiters(t *testing.T) {
+	store := newLimiterStore(rate.Limit(1), time.Minute)
+
+	// Exhaust the budget so a fresh limiter is distinguishable
+	assert.True(t, store.allow("idle"))
+	assert.False(t, store.allow("idle"))
+	store.allow("active")
+
+	store.mu.Lock()
+	store.limiters["idle"].lastSeen = time.Now().Add(-2 * time.Minute)
+	store.mu.Unlock()
+
+	store.cleanup()
+
+	store.mu.Lock()
+	_, idle := store.limiters["idle"]
+	_, active := store.limiters["active"]
+	store.mu.Unlock()
+	assert.False(t, idle, "idle limiter is evicted")
+	assert.True(t, active, "recently used limiter is kept")
+
+	// An evicted key starts over with a full budget
+	assert.True(t, store.allow("idle"))
+}
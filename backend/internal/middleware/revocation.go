@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/redis"
+)
+
+// Upper bound on Redis calls made while checking token revocation
+const revocationCheckTimeout = 100 * time.Millisecond
+
+// RevokeAccessToken blacklists a single access token by its jti until it
+// would have expired anyway
+func RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	return redis.Set(ctx, revokedTokenKey(tokenID), 1, ttl)
+}
+
+// RevokeUserAccessTokens rejects every access token issued to the user up
+// to now by moving the user on to their next token generation. Tokens carry
+// the generation they were issued in, so a token issued right after the
+// revocation is accepted however soon it follows.
+func RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := redis.Incr(ctx, tokenGenerationKey(userID))
+	return err
+}
+
+// TokenGeneration returns the user's current token generation, which newly
+// issued access tokens carry. Users whose tokens were never revoked are in
+// generation zero.
+func TokenGeneration(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if redis.GetClient() == nil {
+		return 0, nil
+	}
+
+	generation, err := redis.Get(ctx, tokenGenerationKey(userID))
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(generation, 10, 64)
+}
+
+// RevokeCurrentAccessToken blacklists the access token that authenticated
+// the request
+func RevokeCurrentAccessToken(c *gin.Context) error {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil
+	}
+	tokenClaims := claims.(*Claims)
+	if tokenClaims.ExpiresAt == nil {
+		return nil
+	}
+	return RevokeAccessToken(c.Request.Context(), tokenClaims.ID, tokenClaims.ExpiresAt.Time)
+}
+
+// isTokenRevoked reports whether the token was blacklisted or issued in an
+// earlier generation than its user's current one. Redis failures are logged
+// and the token is let through rather than locking every user out.
+func isTokenRevoked(ctx context.Context, claims *Claims, userID uuid.UUID) bool {
+	if redis.GetClient() == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, revocationCheckTimeout)
+	defer cancel()
+
+	if claims.ID != "" {
+		revoked, err := redis.Exists(ctx, revokedTokenKey(claims.ID))
+		if err != nil {
+			log.Printf("Failed to check token revocation: %v", err)
+			return false
+		}
+		if revoked {
+			return true
+		}
+	}
+
+	generation, err := TokenGeneration(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to check token generation: %v", err)
+		return false
+	}
+	return claims.Generation < generation
+}
+
+// revokedTokenKey returns the Redis key blacklisting an access token
+func revokedTokenKey(tokenID string) string {
+	return "auth:revoked:token:" + tokenID
+}
+
+// tokenGenerationKey returns the Redis key holding a user's current token
+// generation
+func tokenGenerationKey(userID uuid.UUID) string {
+	return "auth:generation:user:" + userID.String()
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/jwtkeys"
+	"github.com/open-same/backend/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRedis points the redis package at an in-memory server for the test
+func setupRedis(t *testing.T) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redis.Client = goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		redis.Client.Close()
+		redis.Client = nil
+	})
+}
+
+// testClaims returns claims of a token of the given type issued now
+func testClaims(userID uuid.UUID, tokenType string, generation int64) *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID:     userID.String(),
+		TokenType:  tokenType,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+	}
+}
+
+func TestRevokeAccessTokenIsImmediate(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	claims := testClaims(userID, TokenTypeAccess, 0)
+	other := testClaims(userID, TokenTypeAccess, 0)
+
+	assert.False(t, isTokenRevoked(ctx, claims, userID))
+
+	require.NoError(t, RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time))
+	assert.True(t, isTokenRevoked(ctx, claims, userID))
+	assert.False(t, isTokenRevoked(ctx, other, userID), "only the revoked token is blacklisted")
+}
+
+func TestRevokeUserAccessTokensIsImmediate(t *testing.T) {
+	setupRedis(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	generation, err := TokenGeneration(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), generation)
+	before := testClaims(userID, TokenTypeAccess, generation)
+
+	require.NoError(t, RevokeUserAccessTokens(ctx, userID))
+	assert.True(t, isTokenRevoked(ctx, before, userID))
+
+	// A token issued in the same second as the revocation is accepted
+	generation, err = TokenGeneration(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), generation)
+	after := testClaims(userID, TokenTypeAccess, generation)
+	after.IssuedAt = before.IssuedAt
+	assert.False(t, isTokenRevoked(ctx, after, userID))
+
+	// Other users are unaffected
+	otherID := uuid.New()
+	assert.False(t, isTokenRevoked(ctx, testClaims(otherID, TokenTypeAccess, 0), otherID))
+}
+
+func TestAuthRejectsRevokedAndRefreshTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedis(t)
+	keys, err := jwtkeys.Init(config.JWTConfig{Secret: "test-secret"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/", Auth(keys), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	userID := uuid.New()
+	revoked := testClaims(userID, TokenTypeAccess, 0)
+	require.NoError(t, RevokeAccessToken(context.Background(), revoked.ID, revoked.ExpiresAt.Time))
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		code   string
+	}{
+		{"revoked access token", revoked, "TOKEN_REVOKED"},
+		{"refresh token", testClaims(userID, TokenTypeRefresh, 0), "INVALID_TOKEN_TYPE"},
+		{"untyped token", testClaims(userID, "", 0), "INVALID_TOKEN_TYPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := keys.Sign(tt.claims)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Body.String(), tt.code)
+		})
+	}
+}
@@ -36,29 +36,16 @@ func SecurityHeaders() gin.HandlerFunc {
 
 // RateLimit implements rate limiting using token bucket algorithm
 func RateLimit(limit rate.Limit) gin.HandlerFunc {
-	// Create a limiter for each IP address
-	limiters := make(map[string]*rate.Limiter)
+	// Keep a limiter for each IP address, evicting idle ones
+	limiters := newLimiterStore(limit, limiterIdleTTL)
 	
 	return func(c *gin.Context) {
 		// Get client IP
 		clientIP := getClientIP(c)
 		
-		// Get or create limiter for this IP
-		limiter, exists := limiters[clientIP]
-		if !exists {
-			limiter = rate.NewLimiter(limit, int(limit))
-			limiters[clientIP] = limiter
-		}
-		
 		// Check if request is allowed
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"code":    "RATE_LIMIT_EXCEEDED",
-				"message": "Too many requests. Please try again later.",
-				"retry_after": time.Now().Add(time.Second).Unix(),
-			})
-			c.Abort()
+		if !limiters.allow(clientIP) {
+			abortRateLimited(c, time.Second)
 			return
 		}
 		
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutKey stores the deadline of a request in the gin context
+const timeoutKey = "request_timeout"
+
+// requestDeadline cancels the request context once the handler has run for
+// too long
+type requestDeadline struct {
+	timer *time.Timer
+}
+
+// Timeout cancels the request context after d and answers 503 if the
+// handler has not responded by then, so context aware calls such as
+// database queries and AI requests are abandoned. A Timeout further down
+// the chain replaces the deadline, letting a route group override the
+// default. WebSocket upgrades are not limited.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		// An outer Timeout already owns the request, move its deadline
+		if existing, ok := c.Get(timeoutKey); ok {
+			existing.(*requestDeadline).timer.Reset(d)
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithCancelCause(c.Request.Context())
+		defer cancel(nil)
+		deadline := &requestDeadline{
+			timer: time.AfterFunc(d, func() { cancel(context.DeadlineExceeded) }),
+		}
+		defer deadline.timer.Stop()
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = writer
+		c.Set(timeoutKey, deadline)
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		if context.Cause(ctx) == context.DeadlineExceeded && !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Request timed out",
+				"code":    "REQUEST_TIMEOUT",
+				"message": "The server took too long to respond, please try again",
+			})
+		}
+	}
+}
+
+// timeoutWriter discards a response the handler starts writing after the
+// request timed out, leaving room for the timeout response. Responses
+// already under way when the deadline passes are left to finish.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+	mu  sync.Mutex
+}
+
+// discard reports whether a write should be dropped
+func (w *timeoutWriter) discard() bool {
+	return w.ctx.Err() != nil && !w.ResponseWriter.Written()
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discard() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discard() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.discard() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Activity actions
+const (
+	ActivityContentCreated      = "content.created"
+	ActivityContentUpdated      = "content.updated"
+	ActivityContentDeleted      = "content.deleted"
+	ActivityContentRestored     = "content.restored"
+	ActivityContentShared       = "content.shared"
+	ActivityContentPublished    = "content.published"
+	ActivityContentArchived     = "content.archived"
+	ActivityContentUnarchived   = "content.unarchived"
+	ActivityVersionRestored     = "content.version_restored"
+	ActivityCollaboratorInvited = "collaborator.invited"
+	ActivityCollaboratorAdded   = "collaborator.added"
+	ActivityCollaboratorRemoved = "collaborator.removed"
+	ActivityCollaboratorUpdated = "collaborator.updated"
+)
+
+// ActivityLog records an action a user took on content
+type ActivityLog struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ContentID uuid.UUID `json:"content_id" gorm:"type:uuid;not null;index:idx_activity_logs_content_created"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	Details   JSON      `json:"details" gorm:"type:jsonb"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_activity_logs_content_created"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// BeforeCreate hook to set the ID
+func (a *ActivityLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
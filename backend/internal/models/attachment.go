@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Attachment represents a file uploaded to content
+type Attachment struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ContentID  uuid.UUID `json:"content_id" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	Filename   string    `json:"filename" gorm:"not null"`
+	StorageKey string    `json:"-" gorm:"not null;uniqueIndex"`
+	Size       int64     `json:"size"`
+	MimeType   string    `json:"mime_type" gorm:"not null"`
+	URL        string    `json:"url"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// BeforeCreate hook to set the ID
+func (a *Attachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Audit actions
+const (
+	AuditAdminBroadcast  = "admin.broadcast"
+	AuditUserTierChanged = "user.tier_changed"
+)
+
+// AuditLog records an administrative action. Entries outlive their actor,
+// whose ID is cleared when the account is deleted.
+type AuditLog struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ActorID   *uuid.UUID `json:"actor_id" gorm:"type:uuid;index"`
+	Action    string     `json:"action" gorm:"not null;index"`
+	Details   JSON       `json:"details" gorm:"type:jsonb"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+
+	// Relationships
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// BeforeCreate hook to set the ID
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
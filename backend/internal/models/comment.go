@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Comment is a discussion comment on content. Replies point to the first
+// comment of their thread through ParentID.
+type Comment struct {
+	ID         uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ContentID  uuid.UUID      `json:"content_id" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
+	ParentID   *uuid.UUID     `json:"parent_id,omitempty" gorm:"type:uuid;index"`
+	Body       string         `json:"body" gorm:"type:text;not null"`
+	Anchor     JSON           `json:"anchor,omitempty" gorm:"type:jsonb"` // selection the comment refers to, e.g. offsets and quoted text
+	IsResolved bool           `json:"is_resolved" gorm:"default:false"`
+	ResolvedBy *uuid.UUID     `json:"resolved_by,omitempty" gorm:"type:uuid"`
+	ResolvedAt *time.Time     `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Relationships
+	User    User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Replies []Comment `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// BeforeCreate hook to set the ID
+func (c *Comment) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
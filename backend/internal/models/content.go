@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,6 +30,51 @@ const (
 	ContentStatusDeleted   ContentStatus = "deleted"
 )
 
+// Visibility controls who can see content besides its owner, collaborators
+// and the users it is shared with
+type Visibility string
+
+const (
+	// VisibilityPrivate content is only seen by those with access to it
+	VisibilityPrivate Visibility = "private"
+	// VisibilityLink content is also seen through its share links
+	VisibilityLink Visibility = "link"
+	// VisibilityUnlisted content is also seen by anyone who knows its ID
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPublic content is also listed publicly once published
+	VisibilityPublic Visibility = "public"
+)
+
+// Valid reports whether v is a known visibility
+func (v Visibility) Valid() bool {
+	switch v {
+	case VisibilityPrivate, VisibilityLink, VisibilityUnlisted, VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+// ViewableByID reports whether anyone may view the content by its ID
+func (v Visibility) ViewableByID() bool {
+	return v == VisibilityUnlisted || v == VisibilityPublic
+}
+
+// ViewableByLink reports whether the content's share links resolve
+func (v Visibility) ViewableByLink() bool {
+	return v == VisibilityLink || v.ViewableByID()
+}
+
+// ViewableByIDVisibilities are the visibilities of content anyone may view
+// by its ID, for queries
+var ViewableByIDVisibilities = []Visibility{VisibilityUnlisted, VisibilityPublic}
+
+// Collaboration invitation statuses
+const (
+	CollaborationStatusPending  = "pending"
+	CollaborationStatusAccepted = "accepted"
+	CollaborationStatusDeclined = "declined"
+)
+
 // Content represents user-generated content
 type Content struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -38,7 +84,8 @@ type Content struct {
 	Content         string         `json:"content" gorm:"type:text"`
 	Type            ContentType    `json:"type" gorm:"not null;default:'text'"`
 	Status          ContentStatus  `json:"status" gorm:"not null;default:'draft'"`
-	IsPublic        bool           `json:"is_public" gorm:"default:false"`
+	Visibility      Visibility     `json:"visibility" gorm:"size:16;not null;default:'private';index"`
+	IsPublic        bool           `json:"is_public" gorm:"default:false"` // mirrors Visibility == public for older clients
 	IsTemplate      bool           `json:"is_template" gorm:"default:false"`
 	Tags            []string       `json:"tags" gorm:"type:text[]"`
 	Metadata        JSON           `json:"metadata" gorm:"type:jsonb"`
@@ -46,7 +93,12 @@ type Content struct {
 	AIModel         string         `json:"ai_model"`
 	AIPrompt        string         `json:"ai_prompt"`
 	Version         int            `json:"version" gorm:"default:1"`
+	PublishAt       *time.Time     `json:"publish_at,omitempty" gorm:"index"` // drafts are published at this time
+	LockedBy        *uuid.UUID     `json:"locked_by,omitempty" gorm:"type:uuid"` // only this user may edit until the lock expires
+	LockedAt        *time.Time     `json:"locked_at,omitempty"`
+	LockExpiresAt   *time.Time     `json:"lock_expires_at,omitempty"` // renewed while the holder edits
 	ParentID        *uuid.UUID     `json:"parent_id" gorm:"type:uuid"`
+	FolderID        *uuid.UUID     `json:"folder_id" gorm:"type:uuid;index"` // nil for content at the root of its owner's tree
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -57,6 +109,15 @@ type Content struct {
 	Versions        []ContentVersion `json:"versions,omitempty" gorm:"foreignKey:ContentID"`
 	Collaborations  []Collaboration `json:"collaborations,omitempty" gorm:"foreignKey:ContentID"`
 	SharedContents  []SharedContent `json:"shared_contents,omitempty" gorm:"foreignKey:ContentID"`
+
+	// ReactionCounts is filled in by handlers, keyed by reaction type
+	ReactionCounts  map[string]int64 `json:"reaction_counts,omitempty" gorm:"-"`
+	// IsFavorited is filled in for authenticated requests
+	IsFavorited     *bool          `json:"is_favorited,omitempty" gorm:"-"`
+	// TemplateUses is filled in by the template gallery
+	TemplateUses    *int64         `json:"template_uses,omitempty" gorm:"-"`
+	// TrendingScore is filled in by the trending leaderboard
+	TrendingScore   *float64       `json:"trending_score,omitempty" gorm:"-"`
 }
 
 // ContentVersion represents a version of content
@@ -69,12 +130,13 @@ type ContentVersion struct {
 	Description string         `json:"description"`
 	Tags        []string       `json:"tags" gorm:"type:text[]"`
 	Metadata    JSON           `json:"metadata" gorm:"type:jsonb"`
-	CreatedBy   uuid.UUID      `json:"created_by" gorm:"type:uuid;not null"`
+	// CreatedBy is cleared when its author is deleted so the content's
+	// history survives
+	CreatedBy   *uuid.UUID     `json:"created_by" gorm:"type:uuid"`
 	CreatedAt   time.Time      `json:"created_at"`
 	
 	// Relationships
-	Content      Content        `json:"content,omitempty" gorm:"foreignKey:ContentID"`
-	User        User           `json:"user,omitempty" gorm:"foreignKey:CreatedBy"`
+	User        *User          `json:"user,omitempty" gorm:"foreignKey:CreatedBy"`
 }
 
 // SharedContent represents content shared with other users
@@ -82,8 +144,11 @@ type SharedContent struct {
 	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	ContentID   uuid.UUID      `json:"content_id" gorm:"type:uuid;not null"`
 	OwnerID     uuid.UUID      `json:"owner_id" gorm:"type:uuid;not null"`
-	SharedWith  uuid.UUID      `json:"shared_with" gorm:"type:uuid;not null"`
-	Permission  string         `json:"permission" gorm:"not null;default:'read'"` // read, write, admin
+	SharedWith  *uuid.UUID     `json:"shared_with,omitempty" gorm:"type:uuid"` // nil for link and embed shares
+	ShareType   string         `json:"share_type" gorm:"not null;default:'user'"` // user, link, embed
+	ShareToken  *string        `json:"share_token,omitempty" gorm:"uniqueIndex"`
+	Permission  Permission     `json:"permission" gorm:"not null;default:'view'"` // view, comment, edit, manage
+	ViewCount   int            `json:"view_count" gorm:"default:0"`
 	ExpiresAt   *time.Time     `json:"expires_at"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -99,7 +164,8 @@ type Collaboration struct {
 	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	ContentID   uuid.UUID      `json:"content_id" gorm:"type:uuid;not null"`
 	UserID      uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
-	Role        string         `json:"role" gorm:"not null;default:'editor'"` // viewer, editor, admin
+	Role        string         `json:"role" gorm:"not null;default:'editor'"` // viewer, commenter, editor, admin
+	Status      string         `json:"status" gorm:"not null;default:'accepted'"` // pending, accepted, declined; invitations start pending
 	JoinedAt    time.Time      `json:"joined_at"`
 	LastActive  *time.Time     `json:"last_active"`
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
@@ -122,6 +188,22 @@ func (c *Content) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave keeps IsPublic in line with Visibility. Content created
+// without a visibility takes it from IsPublic.
+func (c *Content) BeforeSave(tx *gorm.DB) error {
+	if c.Visibility == "" {
+		c.Visibility = VisibilityPrivate
+		if c.IsPublic {
+			c.Visibility = VisibilityPublic
+		}
+	}
+	if !c.Visibility.Valid() {
+		return fmt.Errorf("invalid content visibility %q", c.Visibility)
+	}
+	c.IsPublic = c.Visibility == VisibilityPublic
+	return nil
+}
+
 func (cv *ContentVersion) BeforeCreate(tx *gorm.DB) error {
 	if cv.ID == uuid.Nil {
 		cv.ID = uuid.New()
@@ -151,7 +233,7 @@ func (c *Content) GetFullContent() string {
 // IsCollaborator checks if a user is a collaborator
 func (c *Content) IsCollaborator(userID uuid.UUID) bool {
 	for _, col := range c.Collaborations {
-		if col.UserID == userID && col.IsActive {
+		if col.UserID == userID && col.IsActive && col.Status == CollaborationStatusAccepted {
 			return true
 		}
 	}
@@ -160,28 +242,25 @@ func (c *Content) IsCollaborator(userID uuid.UUID) bool {
 
 // CanEdit checks if a user can edit the content
 func (c *Content) CanEdit(userID uuid.UUID) bool {
-	if c.UserID == userID {
-		return true
-	}
-	
-	for _, col := range c.Collaborations {
-		if col.UserID == userID && col.IsActive && (col.Role == "editor" || col.Role == "admin") {
-			return true
-		}
-	}
-	return false
+	return Authorize(&User{ID: userID}, c, PermissionEdit)
 }
 
 // CanAdmin checks if a user can admin the content
 func (c *Content) CanAdmin(userID uuid.UUID) bool {
-	if c.UserID == userID {
-		return true
-	}
-	
-	for _, col := range c.Collaborations {
-		if col.UserID == userID && col.IsActive && col.Role == "admin" {
-			return true
-		}
-	}
-	return false
-}
\ No newline at end of file
+	return Authorize(&User{ID: userID}, c, PermissionManage)
+}
+
+// IsLocked reports whether a user holds an unexpired editing lock on the content
+func (c *Content) IsLocked() bool {
+	return c.LockedBy != nil && c.LockExpiresAt != nil && time.Now().Before(*c.LockExpiresAt)
+}
+
+// IsLockedFor reports whether the content is locked by a user other than userID
+func (c *Content) IsLockedFor(userID uuid.UUID) bool {
+	return c.IsLocked() && *c.LockedBy != userID
+}
+
+// IsExpired reports whether the share has passed its expiry time
+func (sc *SharedContent) IsExpired() bool {
+	return sc.ExpiresAt != nil && sc.ExpiresAt.Before(time.Now())
+}
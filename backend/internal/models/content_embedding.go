@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmbeddingDimensions is the size of stored content embeddings. Changing it
+// requires dropping the content_embeddings table and backfilling.
+const EmbeddingDimensions = 1536
+
+// ContentEmbedding holds the vector embedding of content used for semantic
+// search. The table needs the pgvector extension and is created by
+// database.AutoMigrate only when it is available.
+type ContentEmbedding struct {
+	ContentID uuid.UUID `json:"content_id" gorm:"type:uuid;primary_key"`
+	Embedding Vector    `json:"-" gorm:"type:vector(1536);not null"`
+	Model     string    `json:"model" gorm:"not null"`
+	// Checksum of the embedded text, so unchanged content is not re-embedded
+	Checksum  string    `json:"checksum" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Vector is a pgvector value
+type Vector []float32
+
+// Value formats the vector in the pgvector text representation
+func (v Vector) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
+// Scan parses a pgvector text representation
+func (v *Vector) Scan(value interface{}) error {
+	var text string
+	switch value := value.(type) {
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	case nil:
+		*v = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Vector", value)
+	}
+
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return fmt.Errorf("invalid vector %q", text)
+	}
+	text = text[1 : len(text)-1]
+
+	vector := Vector{}
+	if text != "" {
+		for _, part := range strings.Split(text, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+			if err != nil {
+				return fmt.Errorf("invalid vector element %q: %w", part, err)
+			}
+			vector = append(vector, float32(f))
+		}
+	}
+	*v = vector
+	return nil
+}
+
+// String formats the vector as [x,y,...]
+func (v Vector) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
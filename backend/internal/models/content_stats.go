@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContentStats holds the engagement totals of content. Views and shares are
+// counted in Redis and flushed here periodically.
+type ContentStats struct {
+	ContentID     uuid.UUID  `json:"content_id" gorm:"type:uuid;primary_key"`
+	Views         int64      `json:"views" gorm:"default:0"`
+	UniqueViewers int64      `json:"unique_viewers" gorm:"default:0"`
+	Shares        int64      `json:"shares" gorm:"default:0"`
+	Uses          int64      `json:"uses" gorm:"default:0"` // template uses, written directly
+	LastViewedAt  *time.Time `json:"last_viewed_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Favorite is content a user saved to revisit
+type Favorite struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_favorites_user_content"`
+	ContentID uuid.UUID `json:"content_id" gorm:"type:uuid;not null;uniqueIndex:idx_favorites_user_content;index"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Content Content `json:"content,omitempty" gorm:"foreignKey:ContentID"`
+}
+
+// BeforeCreate hook to set the ID
+func (f *Favorite) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Folder organizes a user's content in a tree. Deleting a folder deletes
+// its subfolders and moves the content filed in them back to the root.
+type Folder struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index:idx_folders_user_parent"`
+	ParentID  *uuid.UUID `json:"parent_id" gorm:"type:uuid;index:idx_folders_user_parent"` // nil for top-level folders
+	Name      string     `json:"name" gorm:"size:255;not null"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to set the ID
+func (f *Folder) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
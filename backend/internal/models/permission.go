@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permission is an action a user may take on content. Each permission
+// includes the ones before it: view < comment < edit < manage.
+type Permission string
+
+const (
+	// PermissionView allows reading content and its comments
+	PermissionView Permission = "view"
+	// PermissionComment allows taking part in the discussion
+	PermissionComment Permission = "comment"
+	// PermissionEdit allows changing the content and resolving comments
+	PermissionEdit Permission = "edit"
+	// PermissionManage allows sharing, inviting collaborators, moderating
+	// comments and deleting the content
+	PermissionManage Permission = "manage"
+)
+
+// permissionLevels ranks the permissions, unknown permissions rank zero
+var permissionLevels = map[Permission]int{
+	PermissionView:    1,
+	PermissionComment: 2,
+	PermissionEdit:    3,
+	PermissionManage:  4,
+}
+
+// legacyPermissions maps the share permissions used before permissions were
+// typed to their permission
+var legacyPermissions = map[string]Permission{
+	"read":  PermissionView,
+	"write": PermissionEdit,
+	"admin": PermissionManage,
+}
+
+// ParsePermission parses a permission, accepting the legacy read, write and
+// admin share permissions
+func ParsePermission(value string) (Permission, error) {
+	if permission, ok := legacyPermissions[value]; ok {
+		return permission, nil
+	}
+	permission := Permission(value)
+	if !permission.Valid() {
+		return "", fmt.Errorf("invalid permission %q", value)
+	}
+	return permission, nil
+}
+
+// Valid reports whether p is a known permission
+func (p Permission) Valid() bool {
+	return permissionLevels[p] > 0
+}
+
+// Includes reports whether p grants action
+func (p Permission) Includes(action Permission) bool {
+	return p.Valid() && permissionLevels[p] >= permissionLevels[action]
+}
+
+// Collaboration roles
+const (
+	RoleViewer    = "viewer"
+	RoleCommenter = "commenter"
+	RoleEditor    = "editor"
+	RoleAdmin     = "admin"
+)
+
+// rolePermissions maps collaboration roles to the permission they grant
+var rolePermissions = map[string]Permission{
+	RoleViewer:    PermissionView,
+	RoleCommenter: PermissionComment,
+	RoleEditor:    PermissionEdit,
+	RoleAdmin:     PermissionManage,
+}
+
+// IsValidRole reports whether role is a known collaboration role
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RolePermission returns the permission a collaboration role grants, which
+// is empty for unknown roles
+func RolePermission(role string) Permission {
+	return rolePermissions[role]
+}
+
+// PermissionFor returns the highest permission the owner or an accepted
+// collaborator has on content, or an empty permission for anyone else.
+// Collaborations must be loaded; content without them grants collaborators
+// nothing, and the missing preload is logged so it gets fixed.
+func (c *Content) PermissionFor(userID uuid.UUID) Permission {
+	if c.UserID == userID {
+		return PermissionManage
+	}
+	if c.Collaborations == nil {
+		log.Printf("Permission check on content %s without its collaborations loaded", c.ID)
+		return ""
+	}
+
+	for _, col := range c.Collaborations {
+		if col.UserID == userID && col.IsActive && col.Status == CollaborationStatusAccepted {
+			return RolePermission(col.Role)
+		}
+	}
+	return ""
+}
+
+// Authorize reports whether user may take action on content. Owners may do
+// anything, collaborators what their role grants and anyone may view public
+// or unlisted content unless it is archived. user is nil for anonymous
+// requests. Collaborations must be loaded.
+func Authorize(user *User, content *Content, action Permission) bool {
+	if content.Visibility.ViewableByID() && content.Status != ContentStatusArchived && action == PermissionView {
+		return true
+	}
+	return user != nil && content.PermissionFor(user.ID).Includes(action)
+}
+
+// BeforeSave rejects collaborations with an unknown role. An empty role is
+// left to the column default, and to bulk updates of other columns.
+func (col *Collaboration) BeforeSave(tx *gorm.DB) error {
+	if col.Role != "" && !IsValidRole(col.Role) {
+		return fmt.Errorf("invalid collaboration role %q", col.Role)
+	}
+	return nil
+}
+
+// BeforeSave rejects shares with an unknown permission. An empty permission
+// is left to the column default, and to bulk updates of other columns.
+func (sc *SharedContent) BeforeSave(tx *gorm.DB) error {
+	if sc.Permission != "" && !sc.Permission.Valid() {
+		return fmt.Errorf("invalid share permission %q", sc.Permission)
+	}
+	return nil
+}
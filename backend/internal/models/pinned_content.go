@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PinnedContent is content a user features on their public profile. Pins
+// are shown by ascending Position.
+type PinnedContent struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_pinned_contents_user_content"`
+	ContentID uuid.UUID `json:"content_id" gorm:"type:uuid;not null;uniqueIndex:idx_pinned_contents_user_content;index"`
+	Position  int       `json:"position" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Content Content `json:"content,omitempty" gorm:"foreignKey:ContentID"`
+}
+
+// BeforeCreate hook to set the ID
+func (p *PinnedContent) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
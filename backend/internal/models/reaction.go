@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReactionLike is the default reaction type; other types are emoji
+const ReactionLike = "like"
+
+// Reaction is a user's like or emoji reaction to content. A user reacts at
+// most once per type.
+type Reaction struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ContentID uuid.UUID `json:"content_id" gorm:"type:uuid;not null;uniqueIndex:idx_reactions_content_user_type"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_reactions_content_user_type"`
+	Type      string    `json:"type" gorm:"size:32;not null;uniqueIndex:idx_reactions_content_user_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set the ID
+func (r *Reaction) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
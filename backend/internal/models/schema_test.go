@@ -0,0 +1,59 @@
+package models
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+// parseSchema parses the GORM schema of a model
+func parseSchema(t *testing.T, model interface{}) *schema.Schema {
+	t.Helper()
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	return s
+}
+
+func TestContentOwnerColumn(t *testing.T) {
+	content := parseSchema(t, &Content{})
+
+	owner := content.LookUpField("UserID")
+	require.NotNil(t, owner)
+	assert.Equal(t, "user_id", owner.DBName)
+	assert.Nil(t, content.LookUpField("creator_id"), "the owner column is user_id only")
+
+	user := content.Relationships.Relations["User"]
+	require.NotNil(t, user)
+	require.Len(t, user.References, 1)
+	assert.Equal(t, "user_id", user.References[0].ForeignKey.DBName)
+}
+
+func TestUserForeignKeys(t *testing.T) {
+	user := parseSchema(t, &User{})
+
+	tests := []struct {
+		relation   string
+		table      string
+		foreignKey string
+	}{
+		{"Contents", "contents", "user_id"},
+		{"Collaborations", "collaborations", "user_id"},
+		{"SharedContents", "shared_contents", "owner_id"},
+		{"Tokens", "tokens", "user_id"},
+		{"PinnedContents", "pinned_contents", "user_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.relation, func(t *testing.T) {
+			relation := user.Relationships.Relations[tt.relation]
+			require.NotNil(t, relation)
+			assert.Equal(t, schema.HasMany, relation.Type)
+			assert.Equal(t, tt.table, relation.FieldSchema.Table)
+			require.Len(t, relation.References, 1)
+			assert.Equal(t, tt.foreignKey, relation.References[0].ForeignKey.DBName)
+			assert.Equal(t, "id", relation.References[0].PrimaryKey.DBName)
+		})
+	}
+}
@@ -11,16 +11,26 @@ import (
 // User represents a user in the system
 type User struct {
 	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Email             string         `json:"email" gorm:"uniqueIndex;not null"`
-	Username          string         `json:"username" gorm:"uniqueIndex;not null"`
+	Email             string         `json:"email" gorm:"uniqueIndex:idx_users_email_active,where:deleted_at IS NULL;not null"` // unique among accounts that aren't deleted
+	Username          string         `json:"username" gorm:"uniqueIndex:idx_users_username_active,where:deleted_at IS NULL;not null"`
 	PasswordHash      string         `json:"-" gorm:"not null"`
 	FirstName         string         `json:"first_name"`
 	LastName          string         `json:"last_name"`
 	Avatar            string         `json:"avatar"`
+	AvatarKey         string         `json:"-"` // storage key of an uploaded avatar
 	Bio               string         `json:"bio"`
 	IsVerified        bool           `json:"is_verified" gorm:"default:false"`
 	IsActive          bool           `json:"is_active" gorm:"default:true"`
 	IsAdmin           bool           `json:"is_admin" gorm:"default:false"`
+	IsBanned          bool           `json:"is_banned" gorm:"default:false"`
+	Tier              string         `json:"tier" gorm:"size:32;not null;default:'free'"` // plan whose limits apply, see package features
+	BannedAt          *time.Time     `json:"banned_at,omitempty"`
+	BanReason         string         `json:"ban_reason,omitempty"`
+	TOTPSecret        string         `json:"-"`
+	TOTPEnabled       bool           `json:"totp_enabled" gorm:"default:false"`
+	TOTPLastStep      int64          `json:"-" gorm:"default:0"` // time step of the last accepted code, so codes can't be replayed
+	OAuthProvider     string         `json:"oauth_provider,omitempty" gorm:"column:oauth_provider;index:idx_users_oauth"` // google, github
+	OAuthID           string         `json:"-" gorm:"column:oauth_id;index:idx_users_oauth"`
 	LastLoginAt       *time.Time     `json:"last_login_at"`
 	EmailVerifiedAt   *time.Time     `json:"email_verified_at"`
 	CreatedAt         time.Time      `json:"created_at"`
@@ -32,16 +42,24 @@ type User struct {
 	Collaborations    []Collaboration `json:"collaborations,omitempty" gorm:"foreignKey:UserID"`
 	SharedContents    []SharedContent `json:"shared_contents,omitempty" gorm:"foreignKey:OwnerID"`
 	Tokens            []Token        `json:"tokens,omitempty" gorm:"foreignKey:UserID"`
+	PinnedContents    []PinnedContent `json:"pinned_contents,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// TierFree is the tier every user starts on
+const TierFree = "free"
+
 // Token represents user authentication tokens
 type Token struct {
 	ID           uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID       uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
 	Token        string         `json:"token" gorm:"uniqueIndex;not null"`
-	Type         string         `json:"type" gorm:"not null"` // access, refresh, reset
+	Type         string         `json:"type" gorm:"not null"` // access, refresh, reset, verify, 2fa_challenge
+	FamilyID     uuid.UUID      `json:"family_id" gorm:"type:uuid;index"` // lineage shared by rotated refresh tokens
+	UserAgent    string         `json:"user_agent,omitempty"` // client and address a refresh token was issued to
+	IPAddress    string         `json:"ip_address,omitempty"`
 	ExpiresAt    time.Time      `json:"expires_at" gorm:"not null"`
 	IsRevoked    bool           `json:"is_revoked" gorm:"default:false"`
+	Attempts     int            `json:"-" gorm:"default:0"` // failed codes entered against a 2fa_challenge
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	
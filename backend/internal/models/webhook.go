@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook events
+const (
+	WebhookEventContentCreated     = "content.created"
+	WebhookEventContentUpdated     = "content.updated"
+	WebhookEventContentPublished   = "content.published"
+	WebhookEventCollaborationAdded = "collaboration.added"
+)
+
+// WebhookEvents lists the events webhooks can subscribe to
+var WebhookEvents = []string{
+	WebhookEventContentCreated,
+	WebhookEventContentUpdated,
+	WebhookEventContentPublished,
+	WebhookEventCollaborationAdded,
+}
+
+// Webhook is a user-registered URL notified of events on the user's content
+type Webhook struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	URL       string    `json:"url" gorm:"not null"`
+	Secret    string    `json:"-" gorm:"not null"` // HMAC key for the X-Signature header
+	Events    []string  `json:"events" gorm:"type:text[]"`
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// WebhookDelivery records a single attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	WebhookID  uuid.UUID `json:"webhook_id" gorm:"type:uuid;not null;index:idx_webhook_deliveries_webhook_created"`
+	EventID    uuid.UUID `json:"event_id" gorm:"type:uuid;not null"` // shared by retries of the same event
+	Event      string    `json:"event" gorm:"not null"`
+	Payload    string    `json:"payload" gorm:"type:text"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_webhook_deliveries_webhook_created"`
+}
+
+// Subscribes reports whether the webhook receives the event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// BeforeCreate hook to set the ID
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set the ID
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Generator generates schemas from Go types. Named struct types become
+// component schemas referenced by name, so recursive types are supported.
+// Struct fields follow their json tags, and their binding tags add the
+// required list and validation constraints.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	defined map[reflect.Type]*Schema
+}
+
+// NewGenerator returns a generator knowing the schemas of time.Time and
+// uuid.UUID
+func NewGenerator() *Generator {
+	g := &Generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+		defined: make(map[reflect.Type]*Schema),
+	}
+	g.Define(time.Time{}, String("date-time"))
+	g.Define(uuid.UUID{}, String("uuid"))
+	return g
+}
+
+// Define sets the schema of the type of value, for types whose JSON encoding
+// differs from their Go structure or that have a fixed set of values
+func (g *Generator) Define(value interface{}, schema *Schema) {
+	g.defined[reflect.TypeOf(value)] = schema
+}
+
+// Schema returns the schema of the type of value
+func (g *Generator) Schema(value interface{}) *Schema {
+	return g.schema(reflect.TypeOf(value))
+}
+
+// Schemas returns the component schemas generated so far, keyed by name
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if schema, ok := g.defined[t]; ok {
+		copied := *schema
+		return &copied
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return String("byte")
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.structName(t)}
+	}
+	// Interfaces and anything else may hold any value
+	return &Schema{}
+}
+
+// structName registers a named struct as a component schema, qualifying
+// its name with its package when another type has the same name
+func (g *Generator) structName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+
+	// Reserve the name before generating fields that may refer back to it
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the fields of a struct to an object schema, flattening
+// embedded structs as encoding/json does
+func (g *Generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(schema, embedded)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schema(field.Type)
+		if applyBinding(property, field.Tag.Get("binding")) && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// applyBinding adds the constraints of a binding tag to a schema, reporting
+// whether the field is required
+func applyBinding(schema *Schema, binding string) bool {
+	if binding == "" || schema.Ref != "" {
+		return strings.Contains(","+binding+",", ",required,")
+	}
+
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid":
+			schema.Format = "uuid"
+		case "numeric":
+			if schema.Type == "string" {
+				schema.Format = "numeric"
+			}
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "min", "max", "len":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			if name != "max" {
+				setBound(schema, n, true)
+			}
+			if name != "min" {
+				setBound(schema, n, false)
+			}
+		}
+	}
+	return required
+}
+
+// setBound sets the lower or upper bound of a schema, which is a length for
+// strings and arrays and a value for numbers
+func setBound(schema *Schema, n int, lower bool) {
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &n
+		} else {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &n
+		} else {
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		value := float64(n)
+		if lower {
+			schema.Minimum = &value
+		} else {
+			schema.Maximum = &value
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/open-same/backend/internal/config"
+)
+
+// MaxLength is the longest password in bytes, as bcrypt ignores the rest
+const MaxLength = 72
+
+// ErrBreached is returned for passwords found in known data breaches
+var ErrBreached = errors.New("this password has appeared in a data breach, choose a different one")
+
+// Policy lists the rules new passwords must follow
+type Policy struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	BreachCheck      bool `json:"breach_check"`
+}
+
+// PolicyError lists the rules a password breaks
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password " + strings.Join(e.Violations, ", ")
+}
+
+// NewPolicy returns the policy configured in cfg
+func NewPolicy(cfg config.PasswordConfig) Policy {
+	return Policy{
+		MinLength:        cfg.MinLength,
+		MaxLength:        MaxLength,
+		RequireUppercase: cfg.RequireUppercase,
+		RequireLowercase: cfg.RequireLowercase,
+		RequireDigit:     cfg.RequireDigit,
+		RequireSymbol:    cfg.RequireSymbol,
+		BreachCheck:      cfg.BreachCheck,
+	}
+}
+
+// Check returns a *PolicyError when password breaks any rule of p. It does
+// not check for breaches.
+func (p Policy) Check(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	var violations []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// client queries the breach check API
+var client = &http.Client{Timeout: 5 * time.Second}
+
+// Breached reports whether password appears in the breach corpus served at
+// rangeURL, which follows the Have I Been Pwned range API. Only the first
+// five characters of the password's SHA-1 hash are sent.
+func Breached(ctx context.Context, rangeURL, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rangeURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the real number of matches from observers
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach check returned status %d", resp.StatusCode)
+	}
+
+	// Each line is a hash suffix and how often it was seen; padding
+	// entries are seen zero times
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if found && candidate == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Validate checks password against the policy configured in cfg and, when
+// enabled, the breach corpus. A breach check that fails is logged and the
+// password accepted, so an outage of the API doesn't block sign ups.
+func Validate(ctx context.Context, cfg config.PasswordConfig, password string) error {
+	if err := NewPolicy(cfg).Check(password); err != nil {
+		return err
+	}
+	if !cfg.BreachCheck {
+		return nil
+	}
+
+	breached, err := Breached(ctx, cfg.BreachCheckURL, password)
+	if err != nil {
+		log.Printf("Password breach check failed: %v", err)
+		return nil
+	}
+	if breached {
+		return ErrBreached
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/metrics"
+)
+
+// Job types
+const (
+	JobEmail     = "email"
+	JobWebhook   = "webhook"
+	JobEmbedding = "embedding"
+	JobExport    = "export"
+)
+
+// jobTypes lists every job type, so publishers declare all queues even
+// when they do not consume them
+var jobTypes = []string{JobEmail, JobWebhook, JobEmbedding, JobExport}
+
+// jobTimeout bounds a single job run
+const jobTimeout = time.Hour
+
+// Job is a unit of background work
+type Job struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Decode unmarshals the job payload into v
+func (j Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler runs a job. Handlers retry transient failures themselves; a
+// returned error is final and moves the job to the dead-letter queue.
+type Handler func(ctx context.Context, job Job) error
+
+// Publisher enqueues background jobs without exposing the transport
+type Publisher interface {
+	// Publish enqueues a job of the given type with payload encoded as JSON
+	Publish(ctx context.Context, jobType string, payload interface{}) error
+	// Close stops consuming and releases the transport
+	Close() error
+}
+
+// publisher runs jobs in-process until Init configures the transport
+var publisher Publisher = NewLocalPublisher(nil)
+
+// Init initializes the job transport with the handler of each job type.
+// Jobs go through RabbitMQ when it is enabled and otherwise run in-process.
+func Init(cfg config.RabbitMQConfig, handlers map[string]Handler) (Publisher, error) {
+	if !cfg.Enabled {
+		publisher = NewLocalPublisher(handlers)
+		log.Println("RabbitMQ disabled, running background jobs in-process")
+		return publisher, nil
+	}
+
+	broker, err := NewBroker(cfg, handlers)
+	if err != nil {
+		return nil, err
+	}
+	publisher = broker
+
+	log.Println("RabbitMQ connection established successfully")
+	return publisher, nil
+}
+
+// Get returns the job publisher
+func Get() Publisher {
+	return publisher
+}
+
+// Publish enqueues a job with the configured publisher
+func Publish(ctx context.Context, jobType string, payload interface{}) error {
+	return publisher.Publish(ctx, jobType, payload)
+}
+
+// Close stops the configured publisher
+func Close() error {
+	return publisher.Close()
+}
+
+// newJob wraps payload in a job of the given type
+func newJob(jobType string, payload interface{}) (Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to encode %s job: %w", jobType, err)
+	}
+	return Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		Payload:   data,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// run executes a job with its handler, turning a panic into an error
+func run(handlers map[string]Handler, job Job) (err error) {
+	handler, ok := handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for %s jobs", job.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s job panicked: %v", job.Type, r)
+		}
+		outcome := "success"
+		if err != nil {
+			outcome = "failed"
+		}
+		metrics.Jobs.WithLabelValues(job.Type, outcome).Inc()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// LocalPublisher runs jobs in background goroutines of this process. Jobs
+// are lost if the process exits before they finish.
+type LocalPublisher struct {
+	handlers map[string]Handler
+}
+
+// NewLocalPublisher creates an in-process publisher
+func NewLocalPublisher(handlers map[string]Handler) *LocalPublisher {
+	return &LocalPublisher{handlers: handlers}
+}
+
+// Publish runs the job in the background
+func (p *LocalPublisher) Publish(ctx context.Context, jobType string, payload interface{}) error {
+	job, err := newJob(jobType, payload)
+	if err != nil {
+		return err
+	}
+	p.start(job)
+	return nil
+}
+
+// start runs a job in a background goroutine
+func (p *LocalPublisher) start(job Job) {
+	go func() {
+		if err := run(p.handlers, job); err != nil {
+			log.Printf("Job %s (%s) failed: %v", job.ID, job.Type, err)
+		}
+	}()
+}
+
+// Close implements Publisher
+func (p *LocalPublisher) Close() error {
+	return nil
+}
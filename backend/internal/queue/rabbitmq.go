@@ -0,0 +1,290 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/open-same/backend/internal/config"
+	"github.com/streadway/amqp"
+)
+
+const (
+	// jobsExchange routes jobs to the queue of their type
+	jobsExchange = "opensame.jobs"
+	// deadExchange routes failed jobs to the dead-letter queue of their type
+	deadExchange = "opensame.jobs.dead"
+)
+
+// errNotConnected is returned when publishing while RabbitMQ is unreachable
+var errNotConnected = errors.New("not connected to RabbitMQ")
+
+const (
+	reconnectInitialDelay = time.Second
+	reconnectMaxDelay     = 30 * time.Second
+)
+
+// queueName returns the durable queue of a job type
+func queueName(jobType string) string {
+	return "opensame.jobs." + jobType
+}
+
+// deadQueueName returns the dead-letter queue of a job type, holding jobs
+// that failed for inspection or replay
+func deadQueueName(jobType string) string {
+	return queueName(jobType) + ".dead"
+}
+
+// Broker publishes jobs to durable RabbitMQ queues and consumes them with
+// manual acknowledgments. Failed jobs are dead-lettered. The connection is
+// re-established when it drops, and jobs published while disconnected run
+// in-process so they are not lost.
+type Broker struct {
+	url      string
+	cfg      config.RabbitMQConfig
+	handlers map[string]Handler
+	local    *LocalPublisher
+
+	// mu guards the connection and serializes publishes on its channel
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBroker connects to RabbitMQ, declares the queues and, unless consuming
+// is disabled, starts a consumer per handled job type
+func NewBroker(cfg config.RabbitMQConfig, handlers map[string]Handler) (*Broker, error) {
+	uri := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(cfg.User, cfg.Password),
+		Host:   cfg.Host + ":" + strconv.Itoa(cfg.Port),
+	}
+	b := &Broker{
+		url:      uri.String(),
+		cfg:      cfg,
+		handlers: handlers,
+		local:    NewLocalPublisher(handlers),
+		done:     make(chan struct{}),
+	}
+
+	if err := b.connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	return b, nil
+}
+
+// connect dials RabbitMQ, declares the topology and starts the consumers
+func (b *Broker) connect() error {
+	conn, err := amqp.Dial(b.url)
+	if err != nil {
+		return err
+	}
+
+	channel, err := conn.Channel()
+	if err == nil {
+		err = declareTopology(channel)
+	}
+	if err == nil && b.cfg.Consume {
+		for jobType := range b.handlers {
+			if err = b.consume(conn, jobType); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	b.mu.Lock()
+	b.conn = conn
+	b.channel = channel
+	b.mu.Unlock()
+
+	// A failed publishing channel closes the connection, so every failure
+	// ends in a single reconnect
+	channelClosed := channel.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		if err, ok := <-channelClosed; ok && err != nil {
+			conn.Close()
+		}
+	}()
+	go b.watch(conn.NotifyClose(make(chan *amqp.Error, 1)))
+	return nil
+}
+
+// declareTopology declares the exchanges and, per job type, the durable
+// queue and its dead-letter queue
+func declareTopology(channel *amqp.Channel) error {
+	if err := channel.ExchangeDeclare(jobsExchange, "direct", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := channel.ExchangeDeclare(deadExchange, "direct", true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	for _, jobType := range jobTypes {
+		if _, err := channel.QueueDeclare(deadQueueName(jobType), true, false, false, false, nil); err != nil {
+			return err
+		}
+		if err := channel.QueueBind(deadQueueName(jobType), jobType, deadExchange, false, nil); err != nil {
+			return err
+		}
+
+		// Rejected jobs keep their routing key, the job type, when dead-lettered
+		args := amqp.Table{"x-dead-letter-exchange": deadExchange}
+		if _, err := channel.QueueDeclare(queueName(jobType), true, false, false, false, args); err != nil {
+			return err
+		}
+		if err := channel.QueueBind(queueName(jobType), jobType, jobsExchange, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consume starts delivering the jobs of a type to their handler. Up to the
+// prefetch count of jobs run concurrently.
+func (b *Broker) consume(conn *amqp.Connection, jobType string) error {
+	channel, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := channel.Qos(b.cfg.Prefetch, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := channel.Consume(queueName(jobType), "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for delivery := range deliveries {
+			go b.handle(delivery)
+		}
+		// Deliveries stop when the channel closes; close the connection so
+		// the consumer is restarted along with everything else
+		if !conn.IsClosed() {
+			conn.Close()
+		}
+	}()
+	return nil
+}
+
+// handle runs a delivered job, acknowledging it on success and
+// dead-lettering it on failure
+func (b *Broker) handle(delivery amqp.Delivery) {
+	var job Job
+	err := json.Unmarshal(delivery.Body, &job)
+	if err == nil {
+		err = run(b.handlers, job)
+	}
+
+	if err != nil {
+		log.Printf("Job %s (%s) failed, moving it to the dead-letter queue: %v", delivery.MessageId, delivery.RoutingKey, err)
+		if err := delivery.Nack(false, false); err != nil {
+			log.Printf("Failed to reject job %s: %v", delivery.MessageId, err)
+		}
+		return
+	}
+	if err := delivery.Ack(false); err != nil {
+		log.Printf("Failed to acknowledge job %s: %v", delivery.MessageId, err)
+	}
+}
+
+// watch waits for the connection to drop and reconnects with backoff until
+// it succeeds or the broker is closed
+func (b *Broker) watch(closed chan *amqp.Error) {
+	reason := <-closed
+
+	select {
+	case <-b.done:
+		return
+	default:
+	}
+
+	log.Printf("RabbitMQ connection lost, reconnecting: %v", reason)
+	b.mu.Lock()
+	b.conn = nil
+	b.channel = nil
+	b.mu.Unlock()
+
+	delay := reconnectInitialDelay
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-time.After(delay):
+		}
+
+		if err := b.connect(); err != nil {
+			log.Printf("Failed to reconnect to RabbitMQ, retrying in %s: %v", delay, err)
+			if delay *= 2; delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+			continue
+		}
+		log.Println("RabbitMQ connection re-established")
+		return
+	}
+}
+
+// Publish sends a persistent job to the queue of its type. When RabbitMQ
+// cannot be reached the job runs in-process instead.
+func (b *Broker) Publish(ctx context.Context, jobType string, payload interface{}) error {
+	job, err := newJob(jobType, payload)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	err = errNotConnected
+	if b.channel != nil {
+		err = b.channel.Publish(jobsExchange, jobType, false, false, amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    job.ID,
+			Timestamp:    job.CreatedAt,
+			Type:         jobType,
+			Body:         body,
+		})
+	}
+	b.mu.Unlock()
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("Failed to publish %s job %s, running it in-process: %v", jobType, job.ID, err)
+	b.local.start(job)
+	return nil
+}
+
+// Close stops consuming and closes the connection. Unacknowledged jobs are
+// redelivered to another consumer.
+func (b *Broker) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+
+	b.mu.Lock()
+	conn := b.conn
+	b.conn = nil
+	b.channel = nil
+	b.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
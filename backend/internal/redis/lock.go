@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLockNotAcquired is returned when a lock is held by someone else
+var ErrLockNotAcquired = errors.New("lock held by another owner")
+
+// ErrLockNotHeld is returned when releasing a lock that expired or was
+// taken over after expiring
+var ErrLockNotHeld = errors.New("lock not held")
+
+// lockRetryInterval is how often a waiting AcquireLock polls the lock
+const lockRetryInterval = 50 * time.Millisecond
+
+// releaseScript deletes the lock only if it still carries the owner's token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Lock is a held distributed lock. It expires after its TTL so a crashed
+// holder cannot block others forever.
+type Lock struct {
+	Key   string
+	token string
+}
+
+// AcquireLock takes the lock stored under key for ttl. When the lock is
+// held, it waits up to wait for it to be released before returning
+// ErrLockNotAcquired; a zero wait makes a single attempt.
+func AcquireLock(ctx context.Context, key string, ttl, wait time.Duration) (*Lock, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	lock := &Lock{Key: key, token: hex.EncodeToString(buf)}
+
+	deadline := time.Now().Add(wait)
+	for {
+		acquired, err := Client.SetNX(ctx, key, lock.token, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return lock, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, ErrLockNotAcquired
+		}
+
+		select {
+		case <-time.After(lockRetryInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ReleaseLock releases a lock taken with AcquireLock. Releasing a lock that
+// expired returns ErrLockNotHeld and leaves a newer owner's lock in place.
+func ReleaseLock(ctx context.Context, lock *Lock) error {
+	deleted, err := releaseScript.Run(ctx, Client, []string{lock.Key}, lock.token).Int()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
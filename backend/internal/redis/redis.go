@@ -7,11 +7,15 @@ import (
 	"time"
 
 	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/retry"
 	"github.com/redis/go-redis/v9"
 )
 
 var Client *redis.Client
 
+// Nil is the error returned when a key does not exist
+const Nil = redis.Nil
+
 // Init initializes the Redis connection
 func Init(cfg config.RedisConfig) (*redis.Client, error) {
 	Client = redis.NewClient(&redis.Options{
@@ -21,12 +25,24 @@ func Init(cfg config.RedisConfig) (*redis.Client, error) {
 		PoolSize: 20,
 	})
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := Client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	// Test connection, retrying while Redis may still be booting
+	policy := retry.Policy{
+		MaxAttempts:    cfg.ConnectAttempts,
+		Timeout:        cfg.ConnectTimeout,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+	err := retry.Do(context.Background(), "Redis", policy, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		if err := Client.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("failed to connect to Redis: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	log.Println("Redis connection established successfully")
@@ -51,6 +67,11 @@ func Set(ctx context.Context, key string, value interface{}, expiration time.Dur
 	return Client.Set(ctx, key, value, expiration).Err()
 }
 
+// SetNX sets a key-value pair with expiration only if the key does not exist
+func SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return Client.SetNX(ctx, key, value, expiration).Result()
+}
+
 // Get gets a value by key
 func Get(ctx context.Context, key string) (string, error) {
 	return Client.Get(ctx, key).Result()
@@ -97,6 +118,11 @@ func HSet(ctx context.Context, key string, values ...interface{}) error {
 	return Client.HSet(ctx, key, values...).Err()
 }
 
+// HIncrBy increments a hash field by a specific amount
+func HIncrBy(ctx context.Context, key, field string, amount int64) error {
+	return Client.HIncrBy(ctx, key, field, amount).Err()
+}
+
 // HGet gets a hash field
 func HGet(ctx context.Context, key, field string) (string, error) {
 	return Client.HGet(ctx, key, field).Result()
@@ -127,6 +153,11 @@ func SRem(ctx context.Context, key string, members ...interface{}) error {
 	return Client.SRem(ctx, key, members...).Err()
 }
 
+// SPopN removes and returns up to count random members of a set
+func SPopN(ctx context.Context, key string, count int64) ([]string, error) {
+	return Client.SPopN(ctx, key, count).Result()
+}
+
 // SIsMember checks if a member is in a set
 func SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
 	return Client.SIsMember(ctx, key, member).Result()
@@ -142,11 +173,39 @@ func ZRange(ctx context.Context, key string, start, stop int64) ([]string, error
 	return Client.ZRange(ctx, key, start, stop).Result()
 }
 
+// ZRangeByScore gets members of a sorted set with scores between min and
+// max, which may be "-inf", "+inf" or exclusive like "(5"
+func ZRangeByScore(ctx context.Context, key, min, max string) ([]string, error) {
+	return Client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+}
+
+// ZRevRangeWithScores gets members with their scores from a sorted set by
+// rank, highest score first
+func ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return Client.ZRevRangeWithScores(ctx, key, start, stop).Result()
+}
+
+// ZUnionStore stores the union of sorted sets in dest, multiplying the scores
+// of each set by its weight
+func ZUnionStore(ctx context.Context, dest string, keys []string, weights []float64) error {
+	return Client.ZUnionStore(ctx, dest, &redis.ZStore{Keys: keys, Weights: weights}).Err()
+}
+
 // ZRem removes members from a sorted set
 func ZRem(ctx context.Context, key string, members ...interface{}) error {
 	return Client.ZRem(ctx, key, members...).Err()
 }
 
+// PFAdd adds elements to a HyperLogLog
+func PFAdd(ctx context.Context, key string, elements ...interface{}) error {
+	return Client.PFAdd(ctx, key, elements...).Err()
+}
+
+// PFCount gets the approximate cardinality of a HyperLogLog
+func PFCount(ctx context.Context, keys ...string) (int64, error) {
+	return Client.PFCount(ctx, keys...).Result()
+}
+
 // Pipeline returns a new pipeline
 func Pipeline() redis.Pipeliner {
 	return Client.Pipeline()
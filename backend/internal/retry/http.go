@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryableStatus lists the HTTP statuses worth retrying
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+}
+
+// Transport retries requests that fail with a network error or a retryable
+// status, backing off per the policy and honoring Retry-After. Other
+// responses are returned as is. When every attempt got a retryable status
+// the last response is returned so callers can report the provider error.
+type Transport struct {
+	// Base performs each attempt; nil means http.DefaultTransport
+	Base   http.RoundTripper
+	Name   string
+	Policy Policy
+}
+
+// NewTransport creates a retrying transport over http.DefaultTransport
+func NewTransport(name string, policy Policy) *Transport {
+	return &Transport{Name: name, Policy: policy}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// Requests whose body cannot be replayed get a single attempt
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return base.RoundTrip(req)
+	}
+
+	var last *http.Response
+	err := Do(req.Context(), t.Name, t.Policy, func(ctx context.Context) error {
+		last = nil
+		attempt := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return Permanent(err)
+			}
+			attempt.Body = body
+		}
+
+		resp, err := base.RoundTrip(attempt)
+		if err != nil {
+			// Cancellation and deadlines are not transient
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return Permanent(err)
+			}
+			return err
+		}
+		if !retryableStatus[resp.StatusCode] {
+			last = resp
+			return nil
+		}
+
+		// Keep the body so the response can be returned after the last attempt
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return readErr
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		last = resp
+
+		statusErr := fmt.Errorf("status %d", resp.StatusCode)
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return After(statusErr, wait)
+		}
+		return statusErr
+	})
+
+	if last != nil {
+		return last, nil
+	}
+	return nil, err
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package retry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicy retries quickly so tests only wait when told to by Retry-After
+var testPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+	Jitter:         true,
+}
+
+// newStatusServer answers each request with the next status, repeating
+// the last one, and counts the requests. A 429 asks to retry after
+// retryAfter.
+func newStatusServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *int32, *[]string) {
+	t.Helper()
+
+	var requests int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		status := statuses[len(statuses)-1]
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		if status == http.StatusTooManyRequests && retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &bodies
+}
+
+func TestTransportRetriesAfterRetryAfter(t *testing.T) {
+	server, requests, bodies := newStatusServer(t, "1", http.StatusTooManyRequests, http.StatusOK)
+	client := &http.Client{Transport: &Transport{Name: "test", Policy: testPolicy}}
+
+	start := time.Now()
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"prompt":"hi"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the Retry-After wait is honored")
+	assert.Equal(t, []string{`{"prompt":"hi"}`, `{"prompt":"hi"}`}, *bodies, "the body is replayed")
+}
+
+func TestTransportDoesNotRetryClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized} {
+		server, requests, _ := newStatusServer(t, "", status, http.StatusOK)
+		client := &http.Client{Transport: &Transport{Name: "test", Policy: testPolicy}}
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, status, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	}
+}
+
+func TestTransportReturnsLastResponseWhenAttemptsRunOut(t *testing.T) {
+	server, requests, _ := newStatusServer(t, "", http.StatusServiceUnavailable)
+	client := &http.Client{Transport: &Transport{Name: "test", Policy: testPolicy}}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(testPolicy.MaxAttempts), atomic.LoadInt32(requests))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusText(http.StatusServiceUnavailable), string(body))
+}
+
+func TestTransportGivesUpWhenRetryAfterPassesDeadline(t *testing.T) {
+	server, requests, _ := newStatusServer(t, "30", http.StatusTooManyRequests, http.StatusOK)
+	policy := testPolicy
+	policy.Timeout = time.Second
+	client := &http.Client{Transport: &Transport{Name: "test", Policy: policy}}
+
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	wait, ok := retryAfter("2")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+
+	wait, ok = retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), wait.Seconds(), 2)
+
+	_, ok = retryAfter("soon")
+	assert.False(t, ok)
+	_, ok = retryAfter("")
+	assert.False(t, ok)
+}
@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Policy bounds how often and for how long an operation is retried
+type Policy struct {
+	// MaxAttempts is the number of attempts, including the first one
+	MaxAttempts int
+	// Timeout bounds the total time spent across all attempts; zero means
+	// only MaxAttempts applies
+	Timeout time.Duration
+	// InitialBackoff is the wait after the first failure, doubled after
+	// each further failure up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter waits a random duration between half and all of the backoff,
+	// so clients failing together do not retry together
+	Jitter bool
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Do returns it without retrying
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// afterError carries the wait requested by the failed operation
+type afterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
+
+// After wraps err so Do waits d before the next attempt instead of the
+// backoff, e.g. for a Retry-After header
+func After(err error, d time.Duration) error {
+	return &afterError{err: err, after: d}
+}
+
+// Do calls fn until it succeeds or the policy's budget is exhausted, waiting
+// with exponential backoff between attempts and logging each failure. It
+// returns the last error once no attempts remain.
+func Do(ctx context.Context, name string, policy Policy, fn func(ctx context.Context) error) error {
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	backoff := policy.InitialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt >= policy.MaxAttempts {
+			return fmt.Errorf("%s unavailable after %d attempts: %w", name, attempt, err)
+		}
+
+		wait := backoff
+		if policy.Jitter && wait > 0 {
+			wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		}
+		var after *afterError
+		if errors.As(err, &after) && after.after > 0 {
+			wait = after.after
+		}
+		// Give up now rather than wait past the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("%s unavailable after %d attempts: %w", name, attempt, err)
+		}
+
+		log.Printf("%s not available (attempt %d/%d): %v; retrying in %s", name, attempt, policy.MaxAttempts, err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("%s unavailable after %d attempts: %w", name, attempt, err)
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/open-same/backend/internal/config"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores binary objects such as content attachments
+type Storage interface {
+	// Put streams r into the object stored under key. size may be -1 when
+	// the length is not known up front.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns a reader over the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key
+	Delete(ctx context.Context, key string) error
+	// URL returns a URL serving the object directly, or "" when objects are
+	// only reachable through the API
+	URL(key string) string
+}
+
+var store Storage
+
+// Init initializes the configured storage backend
+func Init(cfg config.StorageConfig) (Storage, error) {
+	var err error
+	switch cfg.Backend {
+	case "", "local":
+		store, err = NewLocalStorage(cfg.LocalPath)
+	case "s3":
+		store, err = NewS3Storage(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s storage: %v", cfg.Backend, err)
+	}
+
+	log.Printf("Storage backend %q initialized successfully", cfg.Backend)
+	return store, nil
+}
+
+// Get returns the storage instance
+func Get() Storage {
+	return store
+}
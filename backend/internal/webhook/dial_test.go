@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://93.184.216.34:8080/hook", true},
+		{"ftp://example.com/hook", false},
+		{"/relative/hook", false},
+		{"http://localhost/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.0.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.100.100.200/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[fd00:ec2::254]/hook", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := ValidateURL(tt.url)
+			assert.Equal(t, tt.valid, err == nil, "ValidateURL(%q) = %v", tt.url, err)
+		})
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the internal server")
+	}))
+	defer server.Close()
+
+	// Delivery bypasses ValidateURL here, as a hostname rebound to
+	// loopback after validation would
+	resp, err := client.Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
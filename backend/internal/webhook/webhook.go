@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/database"
+	"github.com/open-same/backend/internal/models"
+	"github.com/open-same/backend/internal/queue"
+	"github.com/open-same/backend/internal/retry"
+	"gorm.io/gorm"
+)
+
+// Payload is the JSON body POSTed to webhooks
+type Payload struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// deliveryPolicy bounds the retries of a single event delivery
+var deliveryPolicy = retry.Policy{
+	MaxAttempts:    5,
+	Timeout:        30 * time.Minute,
+	InitialBackoff: 10 * time.Second,
+	MaxBackoff:     5 * time.Minute,
+}
+
+// deliveryJob is the queued delivery of an event to one webhook. The body
+// is kept as sent so its signature matches on every attempt.
+type deliveryJob struct {
+	WebhookID uuid.UUID `json:"webhook_id"`
+	Body      []byte    `json:"body"`
+}
+
+// maxErrorBody caps how much of a failed response is kept in the delivery log
+const maxErrorBody = 1024
+
+// Dispatch delivers event to the active webhooks of userID subscribed to it.
+// Deliveries are queued as background jobs; failures are retried and
+// recorded but never reported to the caller.
+func Dispatch(userID uuid.UUID, event string, data interface{}) {
+	var webhooks []models.Webhook
+	if err := database.GetDB().Where("user_id = ? AND is_active = ? AND ? = ANY(events)", userID, true, event).
+		Find(&webhooks).Error; err != nil {
+		log.Printf("Failed to load webhooks for user %s: %v", userID, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload := Payload{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode %s webhook payload: %v", event, err)
+		return
+	}
+
+	for _, hook := range webhooks {
+		if err := queue.Publish(context.Background(), queue.JobWebhook, deliveryJob{WebhookID: hook.ID, Body: body}); err != nil {
+			log.Printf("Failed to queue delivery of %s to webhook %s: %v", event, hook.ID, err)
+		}
+	}
+}
+
+// HandleDeliveryJob delivers a queued event to its webhook. Deliveries to
+// webhooks deleted or deactivated since the event are dropped.
+func HandleDeliveryJob(ctx context.Context, job queue.Job) error {
+	var delivery deliveryJob
+	if err := job.Decode(&delivery); err != nil {
+		return err
+	}
+	var payload Payload
+	if err := json.Unmarshal(delivery.Body, &payload); err != nil {
+		return err
+	}
+
+	var hook models.Webhook
+	if err := database.GetDB().WithContext(ctx).Where("is_active = ?", true).First(&hook, "id = ?", delivery.WebhookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return deliver(ctx, hook, payload, delivery.Body)
+}
+
+// Sign returns the X-Signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver POSTs the payload to a webhook, retrying until it is accepted or
+// the delivery policy gives up, and records every attempt
+func deliver(ctx context.Context, hook models.Webhook, payload Payload, body []byte) error {
+	attempt := 0
+	err := retry.Do(ctx, "webhook "+hook.ID.String(), deliveryPolicy, func(ctx context.Context) error {
+		attempt++
+		delivery := models.WebhookDelivery{
+			WebhookID: hook.ID,
+			EventID:   payload.ID,
+			Event:     payload.Event,
+			Payload:   string(body),
+			Attempt:   attempt,
+		}
+
+		start := time.Now()
+		err := post(ctx, hook, payload, body, &delivery)
+		delivery.DurationMs = time.Since(start).Milliseconds()
+		delivery.Success = err == nil
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		if dbErr := database.GetDB().Create(&delivery).Error; dbErr != nil {
+			log.Printf("Failed to record delivery of %s to webhook %s: %v", payload.Event, hook.ID, dbErr)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("giving up delivering %s to webhook %s: %w", payload.Event, hook.ID, err)
+	}
+	return nil
+}
+
+// post sends one delivery attempt, failing on transport errors and non-2xx
+// responses
+func post(ctx context.Context, hook models.Webhook, payload Payload, body []byte, delivery *models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OpenSame-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", payload.Event)
+	req.Header.Set("X-Webhook-Delivery", payload.ID.String())
+	req.Header.Set("X-Signature", Sign(hook.Secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package websocket
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,20 +22,8 @@ const (
 
 	// Send pings to peer with this period. Must be less than pongWait
 	pingPeriod = (pongWait * 9) / 10
-
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// In production, you should implement proper origin checking
-		return true
-	},
-}
-
 // Client represents a connected WebSocket client
 type Client struct {
 	// Unique identifier for the client
@@ -53,11 +44,25 @@ type Client struct {
 
 	// Current room
 	currentRoom string
+
+	// Presence, read concurrently by the hub
+	lastActive atomic.Int64 // unix nanoseconds
+	typing     atomic.Bool
+
+	// Whether the client may change the content of its current room
+	canEdit atomic.Bool
+
+	// Rate limits of the messages the client sends
+	limiter *messageLimiter
 }
 
-// Message represents a WebSocket message
+// Message represents a WebSocket message. Clients may tag a content_change
+// with a msg_id of their choosing; the hub answers it with an ack carrying
+// the same msg_id once the change is applied, or a nack with a reason when
+// it is rejected. Changes without a msg_id get no ack or nack.
 type Message struct {
 	Type      string                 `json:"type"`
+	MsgID     string                 `json:"msg_id,omitempty"`
 	RoomID    string                 `json:"room_id,omitempty"`
 	UserID    string                 `json:"user_id,omitempty"`
 	Username  string                 `json:"username,omitempty"`
@@ -66,9 +71,16 @@ type Message struct {
 	Timestamp time.Time              `json:"timestamp"`
 }
 
-// HandleWebSocket handles the WebSocket connection upgrade and client registration
-func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// HandleWebSocket handles the WebSocket connection upgrade and client registration.
+// The caller is responsible for authenticating the request and passing the
+// verified user identity.
+func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request, userID, username string) {
 	// Upgrade HTTP connection to WebSocket
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     hub.CheckOrigin,
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade failed: %v", err)
@@ -81,14 +93,23 @@ func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		hub:      hub,
 		conn:     conn,
 		send:     make(chan []byte, 256),
-		UserID:   r.URL.Query().Get("user_id"),
-		Username: r.URL.Query().Get("username"),
+		UserID:   userID,
+		Username: username,
+		limiter:  newMessageLimiter(hub),
 	}
+	client.lastActive.Store(time.Now().UnixNano())
 
-	// Register client with hub
-	hub.register <- client
+	// Register client with hub unless it is shutting down
+	select {
+	case hub.register <- client:
+	case <-hub.quit:
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+		conn.Close()
+		return
+	}
 
 	// Start goroutines for reading and writing
+	hub.writers.Add(1)
 	go client.writePump()
 	go client.readPump()
 }
@@ -96,11 +117,16 @@ func HandleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
-		c.conn.Close()
+		select {
+		case c.hub.unregister <- c:
+			c.conn.Close()
+		case <-c.hub.quit:
+			// The hub closes the send channel as it shuts down and the
+			// write pump closes the connection once the queue is drained
+		}
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
+	maxSize := c.hub.maxMessageSize()
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -108,7 +134,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		_, reader, err := c.conn.NextReader()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket read error: %v", err)
@@ -116,6 +142,21 @@ func (c *Client) readPump() {
 			break
 		}
 
+		// Read at most maxSize bytes so oversized messages are rejected
+		// without buffering them or dropping the connection
+		message, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+		if err != nil {
+			log.Printf("WebSocket read error: %v", err)
+			break
+		}
+		if int64(len(message)) > maxSize {
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				break
+			}
+			c.sendMessageTooLarge(maxSize, leadingMsgID(message))
+			continue
+		}
+
 		// Parse message
 		var msg Message
 		if err := json.Unmarshal(message, &msg); err != nil {
@@ -123,7 +164,17 @@ func (c *Client) readPump() {
 			continue
 		}
 
+		allowed, disconnect := c.allowMessage(msg)
+		if disconnect {
+			log.Printf("Disconnecting client %s of user %s for exceeding message rate limits", c.ID, c.UserID)
+			break
+		}
+		if !allowed {
+			continue
+		}
+
 		// Handle message based on type
+		c.lastActive.Store(time.Now().UnixNano())
 		c.handleMessage(msg)
 	}
 }
@@ -134,6 +185,7 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.writers.Done()
 	}()
 
 	for {
@@ -142,7 +194,7 @@ func (c *Client) writePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
 				return
 			}
 
@@ -187,6 +239,12 @@ func (c *Client) handleMessage(msg Message) {
 		c.handleSelectionChange(msg)
 	case "chat_message":
 		c.handleChatMessage(msg)
+	case "typing_start":
+		c.handleTyping(true)
+	case "typing_stop":
+		c.handleTyping(false)
+	case "presence":
+		c.handlePresence()
 	case "ping":
 		c.handlePing()
 	default:
@@ -201,26 +259,79 @@ func (c *Client) handleJoinRoom(msg Message) {
 		return
 	}
 
+	// Only owners, collaborators and readers of public content may join
+	if !c.hub.CanJoinRoom(c, roomID) {
+		response := Message{
+			Type:      "room_access_denied",
+			RoomID:    roomID,
+			Timestamp: time.Now(),
+		}
+
+		responseBytes, _ := json.Marshal(response)
+		c.send <- responseBytes
+		return
+	}
+
+	// Join new room, staying in the current one if it is full
+	if !c.hub.JoinRoom(c, roomID) {
+		response := Message{
+			Type:   "room_full",
+			RoomID: roomID,
+			Data: map[string]interface{}{
+				"max_clients": c.hub.config.WebSocket.MaxRoomClients,
+			},
+			Timestamp: time.Now(),
+		}
+
+		responseBytes, _ := json.Marshal(response)
+		c.send <- responseBytes
+		return
+	}
+
 	// Leave current room if any
-	if c.currentRoom != "" {
+	if c.currentRoom != "" && c.currentRoom != roomID {
 		c.hub.LeaveRoom(c, c.currentRoom)
 	}
 
-	// Join new room
 	c.currentRoom = roomID
-	c.hub.JoinRoom(c, roomID)
+	c.canEdit.Store(c.hub.CanEditRoom(c, roomID))
 
-	// Send confirmation
+	// Send confirmation with the state new changes must be based on
+	version, content := c.hub.RoomSnapshot(roomID)
 	response := Message{
-		Type:      "room_joined",
-		RoomID:    roomID,
-		UserID:    c.UserID,
-		Username:  c.Username,
+		Type:     "room_joined",
+		RoomID:   roomID,
+		UserID:   c.UserID,
+		Username: c.Username,
+		Data: map[string]interface{}{
+			"version": version,
+			"content": content,
+		},
 		Timestamp: time.Now(),
 	}
+	if lock := c.hub.RoomLock(roomID); lock.Held() {
+		response.Data["locked_by"] = lock.UserID
+		response.Data["lock_expires_at"] = lock.ExpiresAt
+	}
 
 	responseBytes, _ := json.Marshal(response)
 	c.send <- responseBytes
+
+	// Replay what happened in the room recently so the client catches up
+	// without waiting for the next edit
+	history := Message{
+		Type:   "room_history",
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"version": version,
+			"content": content,
+			"events":  c.hub.RoomHistory(roomID),
+		},
+		Timestamp: time.Now(),
+	}
+
+	historyBytes, _ := json.Marshal(history)
+	c.send <- historyBytes
 }
 
 // handleLeaveRoom handles room leaving
@@ -228,6 +339,7 @@ func (c *Client) handleLeaveRoom(msg Message) {
 	if c.currentRoom != "" {
 		c.hub.LeaveRoom(c, c.currentRoom)
 		c.currentRoom = ""
+		c.typing.Store(false)
 	}
 
 	// Send confirmation
@@ -242,23 +354,87 @@ func (c *Client) handleLeaveRoom(msg Message) {
 	c.send <- responseBytes
 }
 
-// handleContentChange handles content changes
+// handleContentChange handles content changes. Each change carries the
+// room version it was made against in base_version and the full resulting
+// content; changes based on an outdated version are rejected.
 func (c *Client) handleContentChange(msg Message) {
 	if c.currentRoom == "" {
+		c.sendNack(msg.MsgID, msg.RoomID, NackNotInRoom, nil)
+		return
+	}
+
+	if !c.canEdit.Load() {
+		c.sendNack(msg.MsgID, c.currentRoom, NackPermission, nil)
+		return
+	}
+
+	// Only the holder of the room's editing lock may change it
+	if lock, allowed := c.hub.checkRoomLock(c.currentRoom, c.UserID); !allowed {
+		responseBytes, _ := json.Marshal(lockMessage(c.currentRoom, lock))
+		c.send <- responseBytes
+
+		c.sendNack(msg.MsgID, c.currentRoom, NackLocked, map[string]interface{}{
+			"code":            "CONTENT_LOCKED",
+			"locked_by":       lock.UserID,
+			"lock_expires_at": lock.ExpiresAt,
+		})
+		return
+	}
+
+	baseVersion, hasVersion := msg.Data["base_version"].(float64)
+	content, hasContent := msg.Data["content"].(string)
+	if !hasVersion || !hasContent {
+		log.Printf("Invalid content_change from client %s: base_version and content are required", c.ID)
+		c.sendNack(msg.MsgID, c.currentRoom, NackInvalid, map[string]interface{}{
+			"message": "base_version and content are required",
+		})
 		return
 	}
 
+	accepted, version, current := c.hub.ApplyContentChange(c.currentRoom, int64(baseVersion), content, c.UserID)
+	if !accepted {
+		// Let the client rebase onto the current state
+		conflictMessage := Message{
+			Type:   "content_conflict",
+			RoomID: c.currentRoom,
+			Data: map[string]interface{}{
+				"base_version": int64(baseVersion),
+				"version":      version,
+				"content":      current,
+			},
+			Timestamp: time.Now(),
+		}
+
+		responseBytes, _ := json.Marshal(conflictMessage)
+		c.send <- responseBytes
+
+		c.sendNack(msg.MsgID, c.currentRoom, NackConflict, map[string]interface{}{
+			"version": version,
+		})
+		return
+	}
+
+	data := make(map[string]interface{}, len(msg.Data)+1)
+	for key, value := range msg.Data {
+		data[key] = value
+	}
+	data["version"] = version
+
 	// Broadcast change to other clients in the room
 	changeMessage := Message{
 		Type:      "content_change",
 		RoomID:    c.currentRoom,
 		UserID:    c.UserID,
 		Username:  c.Username,
-		Data:      msg.Data,
+		Data:      data,
 		Timestamp: time.Now(),
 	}
 
 	c.hub.BroadcastToRoom(c.currentRoom, changeMessage)
+
+	c.sendAck(msg.MsgID, c.currentRoom, map[string]interface{}{
+		"version": version,
+	})
 }
 
 // handleCursorMove handles cursor movement
@@ -318,6 +494,145 @@ func (c *Client) handleChatMessage(msg Message) {
 	c.hub.BroadcastToRoom(c.currentRoom, chatMessage)
 }
 
+// handleTyping broadcasts typing indicators to the room
+func (c *Client) handleTyping(typing bool) {
+	if c.currentRoom == "" {
+		return
+	}
+	c.typing.Store(typing)
+	c.hub.markPresence(c.currentRoom)
+
+	messageType := "typing_stop"
+	if typing {
+		messageType = "typing_start"
+	}
+
+	typingMessage := Message{
+		Type:      messageType,
+		RoomID:    c.currentRoom,
+		UserID:    c.UserID,
+		Username:  c.Username,
+		Timestamp: time.Now(),
+	}
+
+	c.hub.BroadcastToRoom(c.currentRoom, typingMessage)
+}
+
+// handlePresence sends the users currently in the room
+func (c *Client) handlePresence() {
+	if c.currentRoom == "" {
+		return
+	}
+
+	response := Message{
+		Type:   "presence",
+		RoomID: c.currentRoom,
+		Data: map[string]interface{}{
+			"users": c.hub.GetRoomPresence(c.currentRoom),
+		},
+		Timestamp: time.Now(),
+	}
+
+	responseBytes, _ := json.Marshal(response)
+	c.send <- responseBytes
+}
+
+// sendMessageTooLarge tells the client its last message was discarded
+func (c *Client) sendMessageTooLarge(maxSize int64, msgID string) {
+	response := Message{
+		Type: "message_too_large",
+		Data: map[string]interface{}{
+			"max_size": maxSize,
+		},
+		Timestamp: time.Now(),
+	}
+
+	responseBytes, _ := json.Marshal(response)
+	c.send <- responseBytes
+
+	c.sendNack(msgID, "", NackTooLarge, map[string]interface{}{"max_size": maxSize})
+}
+
+// Reasons a message is rejected with
+const (
+	NackConflict    = "conflict"
+	NackPermission  = "permission"
+	NackTooLarge    = "too_large"
+	NackInvalid     = "invalid"
+	NackNotInRoom   = "not_in_room"
+	NackRateLimited = "rate_limited"
+	NackLocked      = "locked"
+)
+
+// sendAck confirms the message tagged msgID was applied. Untagged messages
+// are not acknowledged.
+func (c *Client) sendAck(msgID, roomID string, data map[string]interface{}) {
+	if msgID == "" {
+		return
+	}
+
+	response := Message{
+		Type:      "ack",
+		MsgID:     msgID,
+		RoomID:    roomID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	responseBytes, _ := json.Marshal(response)
+	c.send <- responseBytes
+}
+
+// sendNack reports why the message tagged msgID was rejected. Untagged
+// messages are not answered.
+func (c *Client) sendNack(msgID, roomID, reason string, data map[string]interface{}) {
+	if msgID == "" {
+		return
+	}
+
+	payload := map[string]interface{}{"reason": reason}
+	for key, value := range data {
+		payload[key] = value
+	}
+	response := Message{
+		Type:      "nack",
+		MsgID:     msgID,
+		RoomID:    roomID,
+		Data:      payload,
+		Timestamp: time.Now(),
+	}
+
+	responseBytes, _ := json.Marshal(response)
+	c.send <- responseBytes
+}
+
+// leadingMsgID returns the msg_id of a message cut short, as long as it
+// comes before the cut, so oversized messages can still be rejected by id
+func leadingMsgID(message []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return ""
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if key == "msg_id" {
+			msgID, _ := decoder.Token()
+			value, _ := msgID.(string)
+			return value
+		}
+
+		var skipped json.RawMessage
+		if err := decoder.Decode(&skipped); err != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
 // handlePing handles ping messages
 func (c *Client) handlePing() {
 	response := Message{
@@ -340,8 +655,7 @@ func (c *Client) SendMessage(message Message) {
 	select {
 	case c.send <- messageBytes:
 	default:
-		close(c.send)
-		delete(c.hub.clients, c)
+		c.hub.dropClient(c)
 	}
 }
 
@@ -350,6 +664,26 @@ func (c *Client) GetCurrentRoom() string {
 	return c.currentRoom
 }
 
+// LastActive returns when the client last sent a message
+func (c *Client) LastActive() time.Time {
+	return time.Unix(0, c.lastActive.Load())
+}
+
+// presence describes the client as a presence entry
+func (c *Client) presence() Presence {
+	return Presence{
+		UserID:     c.UserID,
+		Username:   c.Username,
+		Typing:     c.IsTyping(),
+		LastActive: c.LastActive(),
+	}
+}
+
+// IsTyping reports whether the client is currently typing
+func (c *Client) IsTyping() bool {
+	return c.typing.Load()
+}
+
 // IsInRoom checks if the client is in a specific room
 func (c *Client) IsInRoom(roomID string) bool {
 	return c.currentRoom == roomID
@@ -0,0 +1,191 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/open-same/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMaxMessageSize = 64 << 10
+
+// newTestServer runs a hub admitting every client and serves its
+// WebSocket endpoint, identifying clients by the user query parameter
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server, _ := newTestHubServer(t)
+	return server
+}
+
+// newTestHubServer is newTestServer also returning the hub
+func newTestHubServer(t *testing.T) (*httptest.Server, *Hub) {
+	t.Helper()
+
+	hub := NewHub(nil, nil, nil, &config.Config{
+		Environment: "development",
+		WebSocket:   config.WebSocketConfig{MaxMessageSize: testMaxMessageSize},
+	})
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		HandleWebSocket(hub, w, r, user, user)
+	}))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+		server.Close()
+	})
+	return server, hub
+}
+
+// testConn is a client connection keeping the messages of a frame that
+// were not read yet, since frames can batch several messages on separate
+// lines
+type testConn struct {
+	*websocket.Conn
+	pending [][]byte
+}
+
+// dialRoom connects as user and joins room
+func dialRoom(t *testing.T, server *httptest.Server, user, room string) *testConn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?user=" + user
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	client := &testConn{Conn: conn}
+	client.send(t, Message{Type: "join_room", RoomID: room})
+	client.readUntil(t, "room_history", "")
+	return client
+}
+
+// send writes a message to the connection
+func (c *testConn) send(t *testing.T, message Message) {
+	t.Helper()
+	require.NoError(t, c.WriteJSON(message))
+}
+
+// readUntil reads messages until one of the given type, and with the given
+// msg_id when set, arrives
+func (c *testConn) readUntil(t *testing.T, messageType, msgID string) Message {
+	t.Helper()
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if len(c.pending) == 0 {
+			_, frame, err := c.ReadMessage()
+			require.NoError(t, err, "waiting for %s", messageType)
+			c.pending = bytes.Split(frame, []byte{'\n'})
+		}
+
+		var message Message
+		require.NoError(t, json.Unmarshal(c.pending[0], &message))
+		c.pending = c.pending[1:]
+		if message.Type == messageType && (msgID == "" || message.MsgID == msgID) {
+			return message
+		}
+	}
+}
+
+func TestLargeContentChangeRoundTrips(t *testing.T) {
+	server := newTestServer(t)
+	editor := dialRoom(t, server, "editor", "room-1")
+	viewer := dialRoom(t, server, "viewer", "room-1")
+
+	// Well above the old 512 byte limit, below the configured one
+	content := strings.Repeat("collaborative paste ", 2500)
+	editor.send(t, Message{
+		Type:  "content_change",
+		MsgID: "m1",
+		Data:  map[string]interface{}{"base_version": 0, "content": content},
+	})
+
+	ack := editor.readUntil(t, "ack", "m1")
+	assert.Equal(t, float64(1), ack.Data["version"])
+
+	change := viewer.readUntil(t, "content_change", "")
+	assert.Equal(t, "editor", change.UserID)
+	assert.Equal(t, content, change.Data["content"])
+	assert.Equal(t, float64(1), change.Data["version"])
+}
+
+func TestOversizedMessageIsRejectedWithoutClosing(t *testing.T) {
+	server := newTestServer(t)
+	editor := dialRoom(t, server, "editor", "room-1")
+
+	editor.send(t, Message{
+		Type:  "content_change",
+		MsgID: "too-big",
+		Data: map[string]interface{}{
+			"base_version": 0,
+			"content":      strings.Repeat("x", testMaxMessageSize+1),
+		},
+	})
+
+	tooLarge := editor.readUntil(t, "message_too_large", "")
+	assert.Equal(t, float64(testMaxMessageSize), tooLarge.Data["max_size"])
+
+	nack := editor.readUntil(t, "nack", "too-big")
+	assert.Equal(t, NackTooLarge, nack.Data["reason"])
+	assert.Equal(t, float64(testMaxMessageSize), nack.Data["max_size"])
+
+	// The connection stays usable and the room unchanged
+	editor.send(t, Message{
+		Type:  "content_change",
+		MsgID: "small",
+		Data:  map[string]interface{}{"base_version": 0, "content": "small edit"},
+	})
+	ack := editor.readUntil(t, "ack", "small")
+	assert.Equal(t, float64(1), ack.Data["version"])
+}
+
+func TestSlowClientIsDroppedFromRoom(t *testing.T) {
+	server, hub := newTestHubServer(t)
+	dialRoom(t, server, "slow", "room-1") // never reads again
+	viewer := dialRoom(t, server, "viewer", "room-1")
+	go func() {
+		for {
+			if _, _, err := viewer.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Concurrent broadcasts keep hitting the slow client's full buffer
+	// until it is unregistered, which must neither panic nor drop others
+	payload := strings.Repeat("x", 32<<10)
+	stop := make(chan struct{})
+	defer close(stop)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					hub.BroadcastToRoom("room-1", Message{Type: "notice", Data: map[string]interface{}{"text": payload}})
+				}
+			}
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		return hub.GetRoomCount("room-1") == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	presence := hub.GetRoomPresence("room-1")
+	require.Len(t, presence, 1)
+	assert.Equal(t, "viewer", presence[0].UserID)
+}
@@ -1,14 +1,45 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/open-same/backend/internal/config"
+	"github.com/open-same/backend/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
 )
 
+// roomChannelPrefix prefixes the Redis pub/sub channel of each room
+const roomChannelPrefix = "room:"
+
+// broadcastChannel is the Redis pub/sub channel of messages to every client
+// or to every connection of a user
+const broadcastChannel = "broadcast"
+
+// Defaults used when the corresponding setting is not configured
+const (
+	defaultSaveInterval     = 30 * time.Second
+	defaultMaxMessageSize   = 1 << 20
+	defaultPresenceInterval = 15 * time.Second
+)
+
+// listenerBuffer is how many room messages a listener may lag behind by
+// before further messages are dropped for it
+const listenerBuffer = 64
+
+
+// RoomAuthorizer reports whether a user may join, or change the content of,
+// a content room
+type RoomAuthorizer func(userID, roomID string) (bool, error)
+
 // Hub maintains the set of active clients and broadcasts messages to the clients
 type Hub struct {
 	// Registered clients
@@ -26,25 +57,95 @@ type Hub struct {
 	// Content-specific rooms
 	rooms map[string]map[*Client]bool
 
+	// Listeners receiving a room's messages without joining it
+	listeners map[string]map[chan Message]bool
+
 	// Mutex for thread-safe operations
 	mutex sync.RWMutex
+
+	// Permission checks for room joins and content changes
+	authorizeRoom RoomAuthorizer
+	authorizeEdit RoomAuthorizer
+
+	// Versioned live content of each room
+	states *roomStates
+
+	// Recent messages of each room, replayed to joining clients
+	history *roomHistory
+
+	// Editing locks of active rooms
+	locks *roomLocks
+
+	// Connection, origin and autosave settings
+	config *config.Config
+
+	// Shutdown signalling: quit stops the run loop, done reports it stopped
+	// and writers tracks client write pumps still flushing
+	quit         chan struct{}
+	done         chan struct{}
+	shutdownOnce sync.Once
+	writers      sync.WaitGroup
+
+	// Redis pub/sub backplane shared by all replicas, nil when disabled
+	nodeID string
+	pubsub *goredis.PubSub
+
+	// Presence mirrored to Redis for other replicas, nil when disabled
+	presence *presenceStore
+}
+
+// backplaneMessage wraps a message relayed between replicas. Messages
+// without a room go to every client, or only to UserID's when it is set.
+type backplaneMessage struct {
+	Origin  string  `json:"origin"`
+	RoomID  string  `json:"room_id"`
+	UserID  string  `json:"user_id,omitempty"`
+	Message Message `json:"message"`
 }
 
-// NewHub creates a new hub instance
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		rooms:      make(map[string]map[*Client]bool),
+// NewHub creates a new hub instance. authorizeRoom gates room joins and
+// authorizeEdit content changes; a nil authorizer admits every client. store persists live room content every
+// cfg.WebSocket.SaveInterval and when a room empties; it may be nil.
+func NewHub(authorizeRoom, authorizeEdit RoomAuthorizer, store RoomStore, cfg *config.Config) *Hub {
+	hub := &Hub{
+		clients:       make(map[*Client]bool),
+		broadcast:     make(chan []byte),
+		register:      make(chan *Client),
+		unregister:    make(chan *Client),
+		rooms:         make(map[string]map[*Client]bool),
+		listeners:     make(map[string]map[chan Message]bool),
+		authorizeRoom: authorizeRoom,
+		authorizeEdit: authorizeEdit,
+		states:        newRoomStates(store),
+		history:       newRoomHistory(cfg.WebSocket.HistorySize),
+		locks:         newRoomLocks(store),
+		config:        cfg,
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
 	}
+	hub.states.onStale = hub.reloadRoomContent
+	return hub
 }
 
 // Run starts the hub
 func (h *Hub) Run() {
+	saveInterval := h.config.WebSocket.SaveInterval
+	if saveInterval <= 0 {
+		saveInterval = defaultSaveInterval
+	}
+	saveTicker := time.NewTicker(saveInterval)
+	defer saveTicker.Stop()
+	defer close(h.done)
+
 	for {
 		select {
+		case <-h.quit:
+			h.closeClients()
+			return
+
+		case <-saveTicker.C:
+			go h.states.flush()
+
 		case client := <-h.register:
 			h.mutex.Lock()
 			h.clients[client] = true
@@ -56,14 +157,31 @@ func (h *Hub) Run() {
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
 				close(client.send)
-				
-				// Remove client from all rooms
-				for roomID, clients := range h.rooms {
-					if clients[client] {
-						delete(clients, client)
-						if len(clients) == 0 {
-							delete(h.rooms, roomID)
+			}
+
+			// Remove client from all rooms, clearing its presence
+			for roomID, clients := range h.rooms {
+				if clients[client] {
+					delete(clients, client)
+					h.markPresence(roomID)
+					leaveMessage := Message{
+						Type:      "user_left",
+						RoomID:    roomID,
+						UserID:    client.UserID,
+						Username:  client.Username,
+						Timestamp: time.Now(),
+					}
+					h.broadcastToRoom(roomID, leaveMessage)
+					h.publish(roomID, leaveMessage)
+					h.releaseLeftLock(roomID, client, clients)
+					if len(clients) == 0 {
+						delete(h.rooms, roomID)
+						if len(h.listeners[roomID]) == 0 {
+							h.unsubscribeRoom(roomID)
 						}
+						go h.states.release(roomID)
+						h.history.release(roomID)
+						h.locks.release(roomID)
 					}
 				}
 			}
@@ -76,8 +194,7 @@ func (h *Hub) Run() {
 				select {
 				case client.send <- message:
 				default:
-					close(client.send)
-					delete(h.clients, client)
+					h.dropClient(client)
 				}
 			}
 			h.mutex.RUnlock()
@@ -85,15 +202,204 @@ func (h *Hub) Run() {
 	}
 }
 
-// JoinRoom adds a client to a specific content room
-func (h *Hub) JoinRoom(client *Client, roomID string) {
+// dropClient disconnects a client whose send buffer is full. Stopping its
+// read pump hands it to Run to unregister, which closes the send channel
+// under the write lock once nothing else can send on it; until then the
+// client keeps its seat and further messages to it are dropped.
+func (h *Hub) dropClient(client *Client) {
+	client.conn.SetReadDeadline(time.Now())
+}
+
+// maxMessageSize returns the largest message a client may send
+func (h *Hub) maxMessageSize() int64 {
+	if h.config.WebSocket.MaxMessageSize <= 0 {
+		return defaultMaxMessageSize
+	}
+	return h.config.WebSocket.MaxMessageSize
+}
+
+// CheckOrigin allows upgrades from the same origin and the configured CORS
+// origins. Every origin is allowed in development.
+func (h *Hub) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || h.config.Environment == "development" {
+		return true
+	}
+
+	// Same origin
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range h.config.CORS.AllowedOrigins {
+		if strings.EqualFold(origin, strings.TrimSuffix(allowed, "/")) {
+			return true
+		}
+	}
+
+	log.Printf("WebSocket upgrade rejected for origin %s", origin)
+	return false
+}
+
+// RoomSnapshot returns the current version and content of a room
+func (h *Hub) RoomSnapshot(roomID string) (int64, string) {
+	return h.states.snapshot(roomID)
+}
+
+// RoomHistory returns the recent content changes and chat messages of a
+// room, oldest first
+func (h *Hub) RoomHistory(roomID string) []Message {
+	return h.history.recent(roomID)
+}
+
+// ApplyContentChange accepts a content change made against baseVersion and
+// returns the new room version. A stale change is rejected and the current
+// version and content are returned instead.
+func (h *Hub) ApplyContentChange(roomID string, baseVersion int64, content, userID string) (bool, int64, string) {
+	return h.states.apply(roomID, baseVersion, content, userID)
+}
+
+// SyncRoomContent pushes content stored at version outside the room, such
+// as through the REST API, to the room's clients as a content_change. Their
+// next changes build on it, and room changes made before it are not saved
+// over it.
+func (h *Hub) SyncRoomContent(roomID, content, userID string, version int64) {
+	roomVersion, active := h.states.reset(roomID, content, userID, version)
+	if !active {
+		roomVersion = version
+	}
+
+	changeMessage := Message{
+		Type:   "content_change",
+		RoomID: roomID,
+		UserID: userID,
+		Data: map[string]interface{}{
+			"version": roomVersion,
+			"content": content,
+		},
+		Timestamp: time.Now(),
+	}
+
+	if h.GetRoomCount(roomID) > 0 {
+		h.BroadcastToRoom(roomID, changeMessage)
+	} else {
+		// Replicas the room is active on still need it
+		h.publish(roomID, changeMessage)
+	}
+}
+
+// reloadRoomContent pushes the stored content of a room whose pending
+// changes were refused because the content changed outside the room
+func (h *Hub) reloadRoomContent(roomID, content string, version int64) {
+	select {
+	case <-h.quit:
+		return
+	default:
+	}
+	h.SyncRoomContent(roomID, content, "", version)
+}
+
+// CanJoinRoom checks whether a client is allowed to join a content room
+func (h *Hub) CanJoinRoom(client *Client, roomID string) bool {
+	if h.authorizeRoom == nil {
+		return true
+	}
+
+	allowed, err := h.authorizeRoom(client.UserID, roomID)
+	if err != nil {
+		log.Printf("Room authorization failed for user %s in room %s: %v", client.UserID, roomID, err)
+		return false
+	}
+	return allowed
+}
+
+// CanEditRoom checks whether a client may change the content of a room.
+// Rights are checked when the client joins the room.
+func (h *Hub) CanEditRoom(client *Client, roomID string) bool {
+	if h.authorizeEdit == nil {
+		return true
+	}
+
+	allowed, err := h.authorizeEdit(client.UserID, roomID)
+	if err != nil {
+		log.Printf("Room edit authorization failed for user %s in room %s: %v", client.UserID, roomID, err)
+		return false
+	}
+	return allowed
+}
+
+// Shutdown stops the hub, sends close frames to every client after their
+// queued messages and saves pending room content. It returns when all
+// clients are closed or ctx expires.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.shutdownOnce.Do(func() { close(h.quit) })
+
+	stopped := make(chan struct{})
+	go func() {
+		<-h.done
+		h.writers.Wait()
+		h.states.flush()
+		if h.presence != nil {
+			h.presence.close()
+		}
+		if h.pubsub != nil {
+			h.pubsub.Close()
+		}
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeClients closes every client's send channel so its write pump drains
+// the queued messages and sends a close frame
+func (h *Hub) closeClients() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.clients {
+		// Stop reading so no new messages are handled while closing; the
+		// read pump leaves closing the connection to the write pump
+		client.conn.SetReadDeadline(time.Now())
+		close(client.send)
+		delete(h.clients, client)
+	}
+	h.rooms = make(map[string]map[*Client]bool)
+
+	for _, listeners := range h.listeners {
+		for listener := range listeners {
+			close(listener)
+		}
+	}
+	h.listeners = make(map[string]map[chan Message]bool)
+}
+
+// JoinRoom adds a client to a specific content room, reporting false when
+// the room is full
+func (h *Hub) JoinRoom(client *Client, roomID string) bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	// Checked under the same lock as the join and unregister so concurrent
+	// joins can't overfill the room. Clients already in it keep their seat.
+	if max := h.config.WebSocket.MaxRoomClients; max > 0 && !h.rooms[roomID][client] && len(h.rooms[roomID]) >= max {
+		return false
+	}
+
 	if h.rooms[roomID] == nil {
 		h.rooms[roomID] = make(map[*Client]bool)
+		if len(h.listeners[roomID]) == 0 {
+			h.subscribeRoom(roomID)
+		}
 	}
 	h.rooms[roomID][client] = true
+	h.markPresence(roomID)
 
 	// Notify other clients in the room
 	joinMessage := Message{
@@ -105,6 +411,8 @@ func (h *Hub) JoinRoom(client *Client, roomID string) {
 	}
 
 	h.broadcastToRoom(roomID, joinMessage)
+	h.publish(roomID, joinMessage)
+	return true
 }
 
 // LeaveRoom removes a client from a specific content room
@@ -115,6 +423,7 @@ func (h *Hub) LeaveRoom(client *Client, roomID string) {
 	if clients, exists := h.rooms[roomID]; exists {
 		if clients[client] {
 			delete(clients, client)
+			h.markPresence(roomID)
 			
 			// Notify other clients in the room
 			leaveMessage := Message{
@@ -126,20 +435,33 @@ func (h *Hub) LeaveRoom(client *Client, roomID string) {
 			}
 
 			h.broadcastToRoom(roomID, leaveMessage)
+			h.publish(roomID, leaveMessage)
+			h.releaseLeftLock(roomID, client, clients)
 
 			// Remove room if empty
 			if len(clients) == 0 {
 				delete(h.rooms, roomID)
+				if len(h.listeners[roomID]) == 0 {
+					h.unsubscribeRoom(roomID)
+				}
+				go h.states.release(roomID)
+				h.history.release(roomID)
+				h.locks.release(roomID)
 			}
 		}
 	}
 }
 
-// BroadcastToRoom sends a message to all clients in a specific room
+// BroadcastToRoom sends a message to all clients in a specific room,
+// including clients connected to other replicas when the backplane is enabled
 func (h *Hub) BroadcastToRoom(roomID string, message Message) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
+	h.publish(roomID, message)
+	h.notifyListeners(roomID, message)
+	h.history.record(roomID, message)
+
 	if clients, exists := h.rooms[roomID]; exists {
 		messageBytes, err := json.Marshal(message)
 		if err != nil {
@@ -151,8 +473,7 @@ func (h *Hub) BroadcastToRoom(roomID string, message Message) {
 			select {
 			case client.send <- messageBytes:
 			default:
-				close(client.send)
-				delete(h.clients, client)
+				h.dropClient(client)
 			}
 		}
 	}
@@ -160,6 +481,9 @@ func (h *Hub) BroadcastToRoom(roomID string, message Message) {
 
 // broadcastToRoom is an internal method for broadcasting to a room
 func (h *Hub) broadcastToRoom(roomID string, message Message) {
+	h.notifyListeners(roomID, message)
+	h.history.record(roomID, message)
+
 	if clients, exists := h.rooms[roomID]; exists {
 		messageBytes, err := json.Marshal(message)
 		if err != nil {
@@ -171,13 +495,188 @@ func (h *Hub) broadcastToRoom(roomID string, message Message) {
 			select {
 			case client.send <- messageBytes:
 			default:
-				close(client.send)
-				delete(h.clients, client)
+				h.dropClient(client)
 			}
 		}
 	}
 }
 
+// ListenToRoom returns a channel receiving every message delivered to a
+// room, including messages relayed from other replicas, without joining it
+// or appearing in its presence. Messages are dropped while the listener lags
+// behind. Call the returned function to stop listening; the channel is also
+// closed when the hub shuts down.
+func (h *Hub) ListenToRoom(roomID string) (<-chan Message, func()) {
+	listener := make(chan Message, listenerBuffer)
+
+	h.mutex.Lock()
+	if h.listeners[roomID] == nil {
+		h.listeners[roomID] = make(map[chan Message]bool)
+		if h.rooms[roomID] == nil {
+			h.subscribeRoom(roomID)
+		}
+	}
+	h.listeners[roomID][listener] = true
+	h.mutex.Unlock()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			// Shutdown already closed the listener
+			listeners := h.listeners[roomID]
+			if !listeners[listener] {
+				return
+			}
+			delete(listeners, listener)
+			close(listener)
+			if len(listeners) == 0 {
+				delete(h.listeners, roomID)
+				if h.rooms[roomID] == nil {
+					h.unsubscribeRoom(roomID)
+				}
+			}
+		})
+	}
+	return listener, stop
+}
+
+// notifyListeners passes a room message to the room's listeners. The caller
+// holds the hub mutex.
+func (h *Hub) notifyListeners(roomID string, message Message) {
+	for listener := range h.listeners[roomID] {
+		select {
+		case listener <- message:
+		default:
+		}
+	}
+}
+
+// UseRedisBackplane relays room messages through Redis pub/sub so clients
+// connected to different replicas share rooms. Call it before Run.
+func (h *Hub) UseRedisBackplane(ctx context.Context) {
+	h.nodeID = uuid.New().String()
+	h.pubsub = redis.Subscribe(ctx, broadcastChannel)
+	h.states.shared = true
+
+	go h.receiveBackplane()
+}
+
+// receiveBackplane delivers messages published by other replicas to local clients
+func (h *Hub) receiveBackplane() {
+	for msg := range h.pubsub.Channel() {
+		var envelope backplaneMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+			log.Printf("Error parsing backplane message: %v", err)
+			continue
+		}
+
+		// This replica already delivered its own messages locally
+		if envelope.Origin == h.nodeID {
+			continue
+		}
+
+		if envelope.RoomID == "" {
+			messageBytes, err := json.Marshal(envelope.Message)
+			if err != nil {
+				log.Printf("Error marshaling message: %v", err)
+				continue
+			}
+			if envelope.UserID == "" {
+				h.broadcast <- messageBytes
+			} else {
+				h.sendToUser(envelope.UserID, messageBytes)
+			}
+			continue
+		}
+
+		// Keep the room version in step with changes accepted elsewhere
+		if envelope.Message.Type == "content_change" {
+			version, _ := envelope.Message.Data["version"].(float64)
+			content, _ := envelope.Message.Data["content"].(string)
+			h.states.sync(envelope.RoomID, int64(version), content, envelope.Message.UserID)
+		}
+
+		// Keep the room lock in step with locks taken or released elsewhere
+		if (envelope.Message.Type == "content_locked" || envelope.Message.Type == "content_unlocked") && h.GetRoomCount(envelope.RoomID) > 0 {
+			h.locks.set(envelope.RoomID, lockFromMessage(envelope.Message))
+		}
+
+		h.mutex.RLock()
+		if envelope.Message.Type == "room_access_revoked" {
+			h.disconnectFromRoom(envelope.RoomID, envelope.Message.UserID, envelope.Message)
+		} else {
+			h.broadcastToRoom(envelope.RoomID, envelope.Message)
+		}
+		h.mutex.RUnlock()
+	}
+}
+
+// publish relays a room message to the other replicas
+func (h *Hub) publish(roomID string, message Message) {
+	if h.pubsub == nil {
+		return
+	}
+
+	payload, err := json.Marshal(backplaneMessage{
+		Origin:  h.nodeID,
+		RoomID:  roomID,
+		Message: message,
+	})
+	if err != nil {
+		log.Printf("Error marshaling backplane message: %v", err)
+		return
+	}
+
+	if err := redis.Publish(context.Background(), roomChannelPrefix+roomID, payload); err != nil {
+		log.Printf("Error publishing to room %s: %v", roomID, err)
+	}
+}
+
+// publishBroadcast relays a message for every client, or every connection
+// of userID when set, to the other replicas
+func (h *Hub) publishBroadcast(userID string, message Message) {
+	if h.pubsub == nil {
+		return
+	}
+
+	payload, err := json.Marshal(backplaneMessage{
+		Origin:  h.nodeID,
+		UserID:  userID,
+		Message: message,
+	})
+	if err != nil {
+		log.Printf("Error marshaling backplane message: %v", err)
+		return
+	}
+
+	if err := redis.Publish(context.Background(), broadcastChannel, payload); err != nil {
+		log.Printf("Error publishing broadcast: %v", err)
+	}
+}
+
+// subscribeRoom starts receiving a room's messages from other replicas
+func (h *Hub) subscribeRoom(roomID string) {
+	if h.pubsub == nil {
+		return
+	}
+	if err := h.pubsub.Subscribe(context.Background(), roomChannelPrefix+roomID); err != nil {
+		log.Printf("Error subscribing to room %s: %v", roomID, err)
+	}
+}
+
+// unsubscribeRoom stops receiving a room's messages once no local client is in it
+func (h *Hub) unsubscribeRoom(roomID string) {
+	if h.pubsub == nil {
+		return
+	}
+	if err := h.pubsub.Unsubscribe(context.Background(), roomChannelPrefix+roomID); err != nil {
+		log.Printf("Error unsubscribing from room %s: %v", roomID, err)
+	}
+}
+
 // GetRoomClients returns all clients in a specific room
 func (h *Hub) GetRoomClients(roomID string) []*Client {
 	h.mutex.RLock()
@@ -193,6 +692,64 @@ func (h *Hub) GetRoomClients(roomID string) []*Client {
 	return []*Client{}
 }
 
+// Presence describes a user connected to a room
+type Presence struct {
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Typing     bool      `json:"typing"`
+	LastActive time.Time `json:"last_active"`
+}
+
+// GetRoomPresence returns the users connected to a room on this instance,
+// merging multiple connections of the same user
+func (h *Hub) GetRoomPresence(roomID string) []Presence {
+	clients := h.GetRoomClients(roomID)
+	entries := make([]Presence, len(clients))
+	for i, client := range clients {
+		entries[i] = client.presence()
+	}
+	return mergePresence(entries)
+}
+
+// mergePresence merges the presence entries of a user's connections into
+// one entry per user, typing if any connection is
+func mergePresence(entries []Presence) []Presence {
+	presence := []Presence{}
+	index := map[string]int{}
+
+	for _, entry := range entries {
+		i, seen := index[entry.UserID]
+		if !seen {
+			index[entry.UserID] = len(presence)
+			presence = append(presence, entry)
+			continue
+		}
+
+		presence[i].Typing = presence[i].Typing || entry.Typing
+		if entry.LastActive.After(presence[i].LastActive) {
+			presence[i].LastActive = entry.LastActive
+		}
+	}
+
+	return presence
+}
+
+// RoomOccupancy is how many connections are in a room on this instance and
+// how many may join it
+type RoomOccupancy struct {
+	Clients    int `json:"clients"`
+	MaxClients int `json:"max_clients"`
+}
+
+// GetRoomOccupancy returns the occupancy of a specific room. MaxClients is
+// zero when rooms are unlimited.
+func (h *Hub) GetRoomOccupancy(roomID string) RoomOccupancy {
+	return RoomOccupancy{
+		Clients:    h.GetRoomCount(roomID),
+		MaxClients: h.config.WebSocket.MaxRoomClients,
+	}
+}
+
 // GetRoomCount returns the number of clients in a specific room
 func (h *Hub) GetRoomCount(roomID string) int {
 	h.mutex.RLock()
@@ -211,6 +768,18 @@ func (h *Hub) GetTotalClients() int {
 	return len(h.clients)
 }
 
+// roomIDs returns the rooms with clients on this instance
+func (h *Hub) roomIDs() []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	ids := make([]string, 0, len(h.rooms))
+	for roomID := range h.rooms {
+		ids = append(ids, roomID)
+	}
+	return ids
+}
+
 // GetTotalRooms returns the total number of active rooms
 func (h *Hub) GetTotalRooms() int {
 	h.mutex.RLock()
@@ -218,30 +787,103 @@ func (h *Hub) GetTotalRooms() int {
 	return len(h.rooms)
 }
 
-// BroadcastToUser sends a message to a specific user across all their connections
+// BroadcastToUser sends a message to a specific user across all their
+// connections, including those to other replicas when the backplane is enabled
 func (h *Hub) BroadcastToUser(userID string, message Message) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
 	messageBytes, err := json.Marshal(message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
 		return
 	}
 
+	h.publishBroadcast(userID, message)
+	h.sendToUser(userID, messageBytes)
+}
+
+// sendToUser delivers a message to a user's connections to this replica
+func (h *Hub) sendToUser(userID string, messageBytes []byte) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
 	for client := range h.clients {
 		if client.UserID == userID {
 			select {
 			case client.send <- messageBytes:
 			default:
-				close(client.send)
-				delete(h.clients, client)
+				h.dropClient(client)
 			}
 		}
 	}
 }
 
-// BroadcastToAll sends a message to all connected clients
+// DisconnectUser sends a final message to every connection of a user and
+// then closes them. Delivery of the message is best effort since the
+// connection is torn down right after it is queued.
+func (h *Hub) DisconnectUser(userID string, message Message) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	for client := range h.clients {
+		if client.UserID != userID {
+			continue
+		}
+		select {
+		case client.send <- messageBytes:
+		default:
+		}
+		// Stopping the read pump unregisters the client and leaves its rooms
+		client.conn.SetReadDeadline(time.Now())
+	}
+}
+
+// RevokeRoomAccess disconnects a user's connections to a room, on this and,
+// through the backplane, every other replica, after telling them why. The
+// user has to join again, which checks their access anew.
+func (h *Hub) RevokeRoomAccess(roomID, userID string) {
+	message := Message{
+		Type:      "room_access_revoked",
+		RoomID:    roomID,
+		UserID:    userID,
+		Timestamp: time.Now(),
+	}
+
+	h.mutex.RLock()
+	h.disconnectFromRoom(roomID, userID, message)
+	h.mutex.RUnlock()
+
+	h.publish(roomID, message)
+}
+
+// disconnectFromRoom sends a final message to a user's clients in a room
+// and closes their connections. The caller must hold the mutex.
+func (h *Hub) disconnectFromRoom(roomID, userID string, message Message) {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	for client := range h.rooms[roomID] {
+		if client.UserID != userID {
+			continue
+		}
+		select {
+		case client.send <- messageBytes:
+		default:
+		}
+		// Stopping the read pump unregisters the client and leaves the room
+		client.conn.SetReadDeadline(time.Now())
+	}
+}
+
+// BroadcastToAll sends a message to all connected clients, including those
+// connected to other replicas when the backplane is enabled
 func (h *Hub) BroadcastToAll(message Message) {
 	messageBytes, err := json.Marshal(message)
 	if err != nil {
@@ -249,5 +891,6 @@ func (h *Hub) BroadcastToAll(message Message) {
 		return
 	}
 
+	h.publishBroadcast("", message)
 	h.broadcast <- messageBytes
 }
\ No newline at end of file
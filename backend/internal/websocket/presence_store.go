@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-same/backend/internal/redis"
+)
+
+// presenceKeyPrefix prefixes the Redis keys holding room presence
+const presenceKeyPrefix = "presence:"
+
+// presenceUpdateBuffer is how many rooms may wait for their presence to be
+// written before further updates are left to the next heartbeat
+const presenceUpdateBuffer = 256
+
+// presenceStore mirrors the presence of this instance's rooms to Redis so
+// every instance can list who is in a room. Each instance keeps one hash
+// per room, keyed by client, that expires unless the instance refreshes it,
+// so entries of crashed instances disappear on their own.
+type presenceStore struct {
+	nodeID   string
+	interval time.Duration
+	updates  chan string
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	// Rooms this instance has entries in, owned by run
+	written map[string]bool
+}
+
+// newPresenceStore creates a store refreshing its entries every interval
+func newPresenceStore(nodeID string, interval time.Duration) *presenceStore {
+	return &presenceStore{
+		nodeID:   nodeID,
+		interval: interval,
+		updates:  make(chan string, presenceUpdateBuffer),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		written:  make(map[string]bool),
+	}
+}
+
+// nodesKey is the set of instances with entries in a room
+func nodesKey(roomID string) string {
+	return presenceKeyPrefix + roomID + ":nodes"
+}
+
+// nodeKey is the hash of an instance's entries in a room
+func nodeKey(roomID, nodeID string) string {
+	return presenceKeyPrefix + roomID + ":node:" + nodeID
+}
+
+// ttl is how long entries outlive an instance that stopped refreshing them
+func (p *presenceStore) ttl() time.Duration {
+	return 3 * p.interval
+}
+
+// mark queues a room whose presence changed. Updates are dropped while the
+// queue is full; the next heartbeat writes them.
+func (p *presenceStore) mark(roomID string) {
+	select {
+	case p.updates <- roomID:
+	default:
+	}
+}
+
+// run writes queued rooms and refreshes every room each interval until
+// close, then removes this instance's entries
+func (p *presenceStore) run(h *Hub) {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case roomID := <-p.updates:
+			p.write(h, roomID)
+
+		case <-ticker.C:
+			rooms := map[string]bool{}
+			for roomID := range p.written {
+				rooms[roomID] = true
+			}
+			for _, roomID := range h.roomIDs() {
+				rooms[roomID] = true
+			}
+			for roomID := range rooms {
+				p.write(h, roomID)
+				p.prune(roomID)
+			}
+
+		case <-p.stop:
+			for roomID := range p.written {
+				p.remove(roomID)
+			}
+			return
+		}
+	}
+}
+
+// write replaces this instance's entries in a room with its current clients
+func (p *presenceStore) write(h *Hub, roomID string) {
+	clients := h.GetRoomClients(roomID)
+	if len(clients) == 0 {
+		p.remove(roomID)
+		return
+	}
+
+	values := make([]interface{}, 0, 2*len(clients))
+	for _, client := range clients {
+		entry, err := json.Marshal(client.presence())
+		if err != nil {
+			log.Printf("Error marshaling presence: %v", err)
+			continue
+		}
+		values = append(values, client.ID, entry)
+	}
+
+	ctx := context.Background()
+	key := nodeKey(roomID, p.nodeID)
+	pipe := redis.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, values...)
+	pipe.Expire(ctx, key, p.ttl())
+	pipe.SAdd(ctx, nodesKey(roomID), p.nodeID)
+	pipe.Expire(ctx, nodesKey(roomID), p.ttl())
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error writing presence of room %s: %v", roomID, err)
+		return
+	}
+	p.written[roomID] = true
+}
+
+// remove deletes this instance's entries in a room
+func (p *presenceStore) remove(roomID string) {
+	if !p.written[roomID] {
+		return
+	}
+
+	ctx := context.Background()
+	pipe := redis.TxPipeline()
+	pipe.Del(ctx, nodeKey(roomID, p.nodeID))
+	pipe.SRem(ctx, nodesKey(roomID), p.nodeID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error removing presence of room %s: %v", roomID, err)
+		return
+	}
+	delete(p.written, roomID)
+}
+
+// prune drops instances whose entries in a room expired, such as crashed
+// instances, from the room's set of instances
+func (p *presenceStore) prune(roomID string) {
+	ctx := context.Background()
+	nodes, err := redis.SMembers(ctx, nodesKey(roomID))
+	if err != nil {
+		log.Printf("Error listing presence of room %s: %v", roomID, err)
+		return
+	}
+
+	for _, nodeID := range nodes {
+		exists, err := redis.Exists(ctx, nodeKey(roomID, nodeID))
+		if err != nil {
+			log.Printf("Error checking presence of room %s: %v", roomID, err)
+			return
+		}
+		if !exists {
+			if err := redis.SRem(ctx, nodesKey(roomID), nodeID); err != nil {
+				log.Printf("Error pruning presence of room %s: %v", roomID, err)
+			}
+		}
+	}
+}
+
+// read returns the entries of every instance in a room, one per connection
+func (p *presenceStore) read(ctx context.Context, roomID string) ([]Presence, error) {
+	nodes, err := redis.SMembers(ctx, nodesKey(roomID))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Presence{}
+	for _, nodeID := range nodes {
+		fields, err := redis.HGetAll(ctx, nodeKey(roomID, nodeID))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range fields {
+			var entry Presence
+			if err := json.Unmarshal([]byte(value), &entry); err != nil {
+				log.Printf("Error parsing presence of room %s: %v", roomID, err)
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// close stops run and waits for it to remove this instance's entries
+func (p *presenceStore) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.stopped
+}
+
+// UseRedisPresence tracks the presence of this instance's rooms in Redis so
+// GetClusterPresence lists the users of every instance. Entries are
+// refreshed every cfg.WebSocket.PresenceInterval. Call it before Run.
+func (h *Hub) UseRedisPresence() {
+	interval := h.config.WebSocket.PresenceInterval
+	if interval <= 0 {
+		interval = defaultPresenceInterval
+	}
+
+	h.presence = newPresenceStore(uuid.New().String(), interval)
+	go h.presence.run(h)
+}
+
+// markPresence records that the presence of a room changed
+func (h *Hub) markPresence(roomID string) {
+	if h.presence != nil {
+		h.presence.mark(roomID)
+	}
+}
+
+// GetClusterPresence returns the users connected to a room on any instance,
+// merging multiple connections of the same user. Without Redis presence it
+// returns the users on this instance.
+func (h *Hub) GetClusterPresence(ctx context.Context, roomID string) ([]Presence, error) {
+	if h.presence == nil {
+		return h.GetRoomPresence(roomID), nil
+	}
+
+	entries, err := h.presence.read(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	return mergePresence(entries), nil
+}
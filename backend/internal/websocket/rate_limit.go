@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/open-same/backend/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Default messages per second and burst of each message type
+	defaultMessageRate  = 20
+	defaultMessageBurst = 40
+
+	// Default messages per second and burst of chat messages
+	defaultChatRate  = 2
+	defaultChatBurst = 10
+
+	// strikeWindow is the window rate limited messages are counted in to
+	// decide whether to disconnect a client
+	strikeWindow = time.Minute
+)
+
+// limitedTypes are the message types with a bucket of their own; any other
+// type shares one bucket so unknown types can't grow the limiter map
+var limitedTypes = map[string]bool{
+	"join_room":        true,
+	"leave_room":       true,
+	"content_change":   true,
+	"cursor_move":      true,
+	"selection_change": true,
+	"chat_message":     true,
+	"typing_start":     true,
+	"typing_stop":      true,
+	"presence":         true,
+	"ping":             true,
+}
+
+// messageLimiter rate limits the messages of one client with a token bucket
+// per message type. It is only used by the client's read pump.
+type messageLimiter struct {
+	hub      *Hub
+	limiters map[string]*rate.Limiter
+
+	// Rate limited messages in the current strike window
+	strikes     int
+	windowStart time.Time
+}
+
+// newMessageLimiter creates the limiter of a client of hub
+func newMessageLimiter(hub *Hub) *messageLimiter {
+	return &messageLimiter{
+		hub:      hub,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// allow reports whether a message of the given type may be handled now,
+// and otherwise how long until it could be
+func (l *messageLimiter) allow(messageType string) (bool, time.Duration) {
+	key := limitKey(messageType)
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = l.hub.newTypeLimiter(key)
+		l.limiters[key] = limiter
+	}
+
+	reservation := limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		return true, 0
+	}
+	reservation.Cancel()
+	return false, delay
+}
+
+// strike counts a rate limited message and reports whether the client has
+// exceeded its limits so often it should be disconnected
+func (l *messageLimiter) strike() bool {
+	now := time.Now()
+	if now.Sub(l.windowStart) > strikeWindow {
+		l.windowStart = now
+		l.strikes = 0
+	}
+	l.strikes++
+
+	maxStrikes := l.hub.config.WebSocket.RateLimitStrikes
+	return maxStrikes > 0 && l.strikes > maxStrikes
+}
+
+// limitKey returns the bucket of a message type
+func limitKey(messageType string) string {
+	if limitedTypes[messageType] {
+		return messageType
+	}
+	return "other"
+}
+
+// newTypeLimiter creates the token bucket of a message type
+func (h *Hub) newTypeLimiter(messageType string) *rate.Limiter {
+	cfg := h.config.WebSocket
+	if messageType == "chat_message" {
+		return newLimiter(cfg.ChatRate, cfg.ChatBurst, defaultChatRate, defaultChatBurst)
+	}
+	return newLimiter(cfg.MessageRate, cfg.MessageBurst, defaultMessageRate, defaultMessageBurst)
+}
+
+// newLimiter creates a token bucket, using the defaults for unset settings
+func newLimiter(perSecond float64, burst int, defaultRate float64, defaultBurst int) *rate.Limiter {
+	if perSecond <= 0 {
+		perSecond = defaultRate
+	}
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// allowMessage applies the rate limits to a message read from the client.
+// Excess content changes are nacked and other excess messages dropped. It
+// returns false for messages that must not be handled, and disconnect when
+// the client keeps flooding and its connection should be closed.
+func (c *Client) allowMessage(msg Message) (allowed, disconnect bool) {
+	ok, retryAfter := c.limiter.allow(msg.Type)
+	if ok {
+		return true, false
+	}
+
+	metrics.WebSocketRateLimited.WithLabelValues(limitKey(msg.Type)).Inc()
+	if msg.Type == "content_change" {
+		c.sendNack(msg.MsgID, c.currentRoom, NackRateLimited, map[string]interface{}{
+			"retry_after_ms": retryAfter.Milliseconds(),
+		})
+	}
+
+	if c.limiter.strike() {
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded"),
+			time.Now().Add(writeWait))
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"sync"
+)
+
+// historyTypes are the message types replayed to clients joining a room
+var historyTypes = map[string]bool{
+	"content_change": true,
+	"chat_message":   true,
+}
+
+// roomHistory keeps the most recent content changes and chat messages of
+// every active room so clients joining mid-session can catch up
+type roomHistory struct {
+	mutex  sync.Mutex
+	size   int
+	events map[string][]Message
+}
+
+// newRoomHistory creates a history keeping up to size messages per room.
+// A size of zero or less keeps nothing.
+func newRoomHistory(size int) *roomHistory {
+	return &roomHistory{
+		size:   size,
+		events: make(map[string][]Message),
+	}
+}
+
+// record adds a message delivered to a room, evicting the oldest message
+// once the room's history is full
+func (r *roomHistory) record(roomID string, message Message) {
+	if r.size <= 0 || !historyTypes[message.Type] {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	events := r.events[roomID]
+	if len(events) < r.size {
+		r.events[roomID] = append(events, message)
+		return
+	}
+	copy(events, events[1:])
+	events[len(events)-1] = message
+}
+
+// recent returns the recorded messages of a room, oldest first
+func (r *roomHistory) recent(roomID string) []Message {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Message(nil), r.events[roomID]...)
+}
+
+// release forgets the history of a room nobody is in anymore
+func (r *roomHistory) release(roomID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.events, roomID)
+}
@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// RoomLock is the editing lock of a room: only UserID may change the
+// room's content until ExpiresAt. The zero value is unlocked.
+type RoomLock struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// Held reports whether the lock is taken and hasn't expired
+func (l RoomLock) Held() bool {
+	return l.UserID != "" && time.Now().Before(l.ExpiresAt)
+}
+
+// RoomLockStore persists the editing locks of rooms. A RoomStore that
+// implements it has the hub enforce locks on content changes, renew them
+// while their holder edits and release them when the holder leaves.
+type RoomLockStore interface {
+	LoadRoomLock(roomID string) (RoomLock, error)
+	// RenewRoomLock extends the lock of userID and returns the room's
+	// lock, which is someone else's or none when userID lost it
+	RenewRoomLock(roomID, userID string) (RoomLock, error)
+	// ReleaseRoomLock releases the lock if userID holds it, reporting
+	// whether it did
+	ReleaseRoomLock(roomID, userID string) (bool, error)
+}
+
+// roomLocks caches the locks of active rooms
+type roomLocks struct {
+	mutex sync.Mutex
+	locks map[string]RoomLock
+	store RoomLockStore
+}
+
+// newRoomLocks creates a lock cache backed by store when it persists locks
+func newRoomLocks(store RoomStore) *roomLocks {
+	locks := &roomLocks{locks: make(map[string]RoomLock)}
+	locks.store, _ = store.(RoomLockStore)
+	return locks
+}
+
+// get returns a room's lock, loading it the first time the room is used.
+// A cached lock that expired is loaded again, as its holder may have
+// renewed it through the REST API. Rooms are unlocked when locks aren't
+// stored or can't be loaded.
+func (r *roomLocks) get(roomID string) RoomLock {
+	if r.store == nil {
+		return RoomLock{}
+	}
+
+	r.mutex.Lock()
+	previous, cached := r.locks[roomID]
+	r.mutex.Unlock()
+	if cached && (previous.UserID == "" || previous.Held()) {
+		return previous
+	}
+
+	lock, err := r.store.LoadRoomLock(roomID)
+	if err != nil {
+		log.Printf("Failed to load lock of room %s: %v", roomID, err)
+		return RoomLock{}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// A lock change may have arrived while loading
+	if current, stillCached := r.locks[roomID]; stillCached != cached || current != previous {
+		return current
+	}
+	r.locks[roomID] = lock
+	return lock
+}
+
+// cached returns a room's lock without loading it
+func (r *roomLocks) cached(roomID string) RoomLock {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.locks[roomID]
+}
+
+// set records a change of a room's lock
+func (r *roomLocks) set(roomID string, lock RoomLock) {
+	if r.store == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.locks[roomID] = lock
+}
+
+// release forgets a room's lock once the last local client has left
+func (r *roomLocks) release(roomID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.locks, roomID)
+}
+
+// lockMessage announces a room's lock to its clients
+func lockMessage(roomID string, lock RoomLock) Message {
+	if !lock.Held() {
+		return Message{
+			Type:      "content_unlocked",
+			RoomID:    roomID,
+			Timestamp: time.Now(),
+		}
+	}
+	return Message{
+		Type:   "content_locked",
+		RoomID: roomID,
+		Data: map[string]interface{}{
+			"locked_by":       lock.UserID,
+			"lock_expires_at": lock.ExpiresAt,
+		},
+		Timestamp: time.Now(),
+	}
+}
+
+// lockFromMessage reads the lock announced by a lock message relayed from
+// another replica
+func lockFromMessage(message Message) RoomLock {
+	if message.Type != "content_locked" {
+		return RoomLock{}
+	}
+	userID, _ := message.Data["locked_by"].(string)
+	expires, _ := message.Data["lock_expires_at"].(string)
+	expiresAt, err := time.Parse(time.RFC3339Nano, expires)
+	if err != nil {
+		return RoomLock{}
+	}
+	return RoomLock{UserID: userID, ExpiresAt: expiresAt}
+}
+
+// RoomLock returns the editing lock of a room
+func (h *Hub) RoomLock(roomID string) RoomLock {
+	return h.locks.get(roomID)
+}
+
+// SetRoomLock records a lock change made outside the hub, such as through
+// the lock endpoints, and announces it to the room on every replica
+func (h *Hub) SetRoomLock(roomID string, lock RoomLock) {
+	if h.GetRoomCount(roomID) > 0 {
+		h.locks.set(roomID, lock)
+	}
+	h.BroadcastToRoom(roomID, lockMessage(roomID, lock))
+}
+
+// checkRoomLock reports whether userID may change a room's content under
+// its lock, returning the lock. The holder's lock is renewed once less than
+// half of the idle timeout is left.
+func (h *Hub) checkRoomLock(roomID, userID string) (RoomLock, bool) {
+	lock := h.locks.get(roomID)
+	if !lock.Held() {
+		return lock, true
+	}
+	if lock.UserID != userID {
+		return lock, false
+	}
+
+	if time.Until(lock.ExpiresAt) < h.config.Content.LockTimeout/2 {
+		renewed, err := h.locks.store.RenewRoomLock(roomID, userID)
+		if err != nil {
+			log.Printf("Failed to renew lock of room %s: %v", roomID, err)
+			return lock, true
+		}
+		h.SetRoomLock(roomID, renewed)
+		if renewed.Held() && renewed.UserID != userID {
+			return renewed, false
+		}
+		lock = renewed
+	}
+	return lock, true
+}
+
+// releaseLeftLock releases a room's lock once its holder's last local
+// connection has left the room. Must be called with the mutex held.
+func (h *Hub) releaseLeftLock(roomID string, left *Client, clients map[*Client]bool) {
+	if h.locks.store == nil {
+		return
+	}
+	lock := h.locks.cached(roomID)
+	if !lock.Held() || lock.UserID != left.UserID {
+		return
+	}
+	for client := range clients {
+		if client.UserID == left.UserID {
+			return
+		}
+	}
+
+	go func() {
+		released, err := h.locks.store.ReleaseRoomLock(roomID, left.UserID)
+		if err != nil {
+			log.Printf("Failed to release lock of room %s: %v", roomID, err)
+			return
+		}
+		if released {
+			h.SetRoomLock(roomID, RoomLock{})
+		}
+	}()
+}
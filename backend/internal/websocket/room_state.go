@@ -0,0 +1,335 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"sync"
+)
+
+// RoomStore loads and saves the shared content edited in a room. Room
+// versions continue from the version of the stored content, so a room
+// recreated after it emptied never reuses a version clients have seen, and
+// SaveRoomContent stores content at the room version it was taken at.
+//
+// SaveRoomContent returns the stored version. It returns
+// ErrStaleRoomContent, saving nothing, when the stored content is no longer
+// at baseVersion because it was changed outside the room.
+type RoomStore interface {
+	LoadRoomContent(roomID string) (content string, version int64, err error)
+	SaveRoomContent(roomID, content, userID string, baseVersion, version int64) (int64, error)
+}
+
+// ErrStaleRoomContent is returned by RoomStore.SaveRoomContent when the
+// stored content changed since the room loaded or last saved it
+var ErrStaleRoomContent = errors.New("stored content changed since the room last saved it")
+
+// roomState is the live content of a room. Every accepted content change
+// increments version; changes made against an older version are rejected so
+// concurrent edits cannot silently overwrite each other. With the Redis
+// backplane the version is decided in Redis, see applyShared.
+type roomState struct {
+	// ready is closed once the stored content is loaded; mutex guards the
+	// fields below from then on
+	ready chan struct{}
+	mutex sync.Mutex
+
+	// released is set once the room emptied and its state was dropped, so
+	// holders of the state load the room again
+	released bool
+
+	version    int64
+	content    string
+	lastEditor string
+	dirty      bool
+
+	// saved is the stored version the room's content builds on
+	saved int64
+}
+
+// roomStates tracks the live content of every active room. The registry
+// mutex only guards the maps; rooms are loaded and saved without it so a
+// slow store holds up only the room being loaded or saved.
+type roomStates struct {
+	mutex  sync.Mutex
+	states map[string]*roomState
+	store  RoomStore
+
+	// Rooms being saved after they emptied, closed once saved so the room
+	// is not loaded again before its content is stored
+	releasing map[string]chan struct{}
+
+	// shared is set when replicas share room versions through Redis
+	shared bool
+
+	// onStale is called with the stored content of a room whose save was
+	// rejected because the content changed outside the room
+	onStale func(roomID, content string, version int64)
+}
+
+// newRoomStates creates an empty room state registry
+func newRoomStates(store RoomStore) *roomStates {
+	return &roomStates{
+		states:    make(map[string]*roomState),
+		store:     store,
+		releasing: make(map[string]chan struct{}),
+	}
+}
+
+// snapshot returns the current version and content of a room, loading the
+// persisted content the first time the room is used
+func (r *roomStates) snapshot(roomID string) (version int64, content string) {
+	r.with(roomID, func(state *roomState) {
+		if r.shared {
+			r.refreshShared(roomID, state)
+		}
+		version, content = state.version, state.content
+	})
+	return version, content
+}
+
+// apply accepts content based on the current version and returns the new
+// version. Stale changes are rejected with the current version and content.
+func (r *roomStates) apply(roomID string, baseVersion int64, content, userID string) (accepted bool, version int64, current string) {
+	r.with(roomID, func(state *roomState) {
+		if r.shared {
+			accepted, version, current = r.applyShared(roomID, state, baseVersion, content, userID)
+			return
+		}
+		if baseVersion != state.version {
+			accepted, version, current = false, state.version, state.content
+			return
+		}
+
+		state.version++
+		state.content = content
+		state.lastEditor = userID
+		state.dirty = true
+		accepted, version, current = true, state.version, state.content
+	})
+	return accepted, version, current
+}
+
+// sync adopts a change already accepted by another replica. Rooms not
+// active on this replica are left to load it when they are joined.
+func (r *roomStates) sync(roomID string, version int64, content, userID string) {
+	state := r.lookup(roomID)
+	if state == nil {
+		return
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if !state.released && version > state.version {
+		state.version = version
+		state.content = content
+		state.lastEditor = userID
+	}
+}
+
+// reset replaces the content of a room with content stored at version
+// stored outside the room, such as through the REST API. Unsaved changes
+// are dropped since the stored write supersedes them. It returns the room's
+// new version, and false when the room is not active on any replica.
+func (r *roomStates) reset(roomID, content, userID string, stored int64) (int64, bool) {
+	state := r.lookup(roomID)
+	if state == nil {
+		if r.shared {
+			return r.resetShared(roomID, stored, content, userID, stored)
+		}
+		return 0, false
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.released {
+		return 0, false
+	}
+
+	version := state.version + 1
+	if stored > version {
+		version = stored
+	}
+	if r.shared {
+		if shared, ok := r.resetShared(roomID, version, content, userID, stored); ok {
+			version = shared
+		}
+	}
+	state.version = version
+	state.content = content
+	state.lastEditor = userID
+	state.dirty = false
+	if stored > state.saved {
+		state.saved = stored
+	}
+	return version, true
+}
+
+// flush saves the content of every room changed since the last flush
+func (r *roomStates) flush() {
+	if r.store == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	states := make(map[string]*roomState, len(r.states))
+	for roomID, state := range r.states {
+		states[roomID] = state
+	}
+	r.mutex.Unlock()
+
+	for roomID, state := range states {
+		if !r.save(roomID, state) {
+			r.reload(roomID)
+		}
+	}
+}
+
+// release saves a room's pending content and forgets its state once the
+// last local client has left
+func (r *roomStates) release(roomID string) {
+	r.mutex.Lock()
+	state, exists := r.states[roomID]
+	if !exists {
+		r.mutex.Unlock()
+		return
+	}
+	delete(r.states, roomID)
+	saved := make(chan struct{})
+	r.releasing[roomID] = saved
+	r.mutex.Unlock()
+
+	defer func() {
+		r.mutex.Lock()
+		delete(r.releasing, roomID)
+		r.mutex.Unlock()
+		close(saved)
+	}()
+
+	<-state.ready
+	state.mutex.Lock()
+	state.released = true
+	state.mutex.Unlock()
+
+	// Nobody is left to see a stale room reloaded
+	if r.store != nil && !r.save(roomID, state) {
+		log.Printf("Dropped changes to room %s made before its content changed outside it", roomID)
+	}
+}
+
+// save stores a room's content if it changed since it was last saved,
+// marking it dirty again when the store fails. It reports false when the
+// save was rejected because the content changed outside the room.
+func (r *roomStates) save(roomID string, state *roomState) bool {
+	<-state.ready
+	state.mutex.Lock()
+	if !state.dirty {
+		state.mutex.Unlock()
+		return true
+	}
+	// Save what every replica agrees on, in case a change was missed
+	if r.shared {
+		r.refreshShared(roomID, state)
+	}
+	content, userID, base, version := state.content, state.lastEditor, state.saved, state.version
+	state.dirty = false
+	state.mutex.Unlock()
+
+	stored, err := r.store.SaveRoomContent(roomID, content, userID, base, version)
+	if errors.Is(err, ErrStaleRoomContent) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to save room %s: %v", roomID, err)
+		state.mutex.Lock()
+		state.dirty = true
+		state.mutex.Unlock()
+		return true
+	}
+
+	state.mutex.Lock()
+	if stored > state.saved {
+		state.saved = stored
+	}
+	state.mutex.Unlock()
+	if r.shared {
+		r.recordSavedShared(roomID, stored)
+	}
+	return true
+}
+
+// reload replaces the content of a room whose save was rejected with the
+// stored content, which supersedes the room's unsaved changes
+func (r *roomStates) reload(roomID string) {
+	content, version, err := r.store.LoadRoomContent(roomID)
+	if err != nil {
+		log.Printf("Failed to reload room %s: %v", roomID, err)
+		return
+	}
+	if r.onStale != nil {
+		r.onStale(roomID, content, version)
+	}
+}
+
+// with runs fn on the loaded state of a room while holding the state's
+// mutex, loading the room first if needed
+func (r *roomStates) with(roomID string, fn func(state *roomState)) {
+	for {
+		state := r.acquire(roomID)
+		state.mutex.Lock()
+		if !state.released {
+			fn(state)
+			state.mutex.Unlock()
+			return
+		}
+		// The room emptied meanwhile; load it again once it is saved
+		state.mutex.Unlock()
+	}
+}
+
+// acquire returns the loaded state of a room, loading the stored content
+// when the room is not active
+func (r *roomStates) acquire(roomID string) *roomState {
+	for {
+		r.mutex.Lock()
+		if state, exists := r.states[roomID]; exists {
+			r.mutex.Unlock()
+			<-state.ready
+			return state
+		}
+		if saved, releasing := r.releasing[roomID]; releasing {
+			r.mutex.Unlock()
+			<-saved
+			continue
+		}
+
+		state := &roomState{ready: make(chan struct{})}
+		r.states[roomID] = state
+		r.mutex.Unlock()
+
+		if r.store != nil {
+			content, version, err := r.store.LoadRoomContent(roomID)
+			if err != nil {
+				log.Printf("Failed to load room %s: %v", roomID, err)
+			}
+			state.content = content
+			state.version = version
+			state.saved = version
+		}
+		close(state.ready)
+		return state
+	}
+}
+
+// lookup returns the loaded state of a room active on this replica, or nil
+func (r *roomStates) lookup(roomID string) *roomState {
+	r.mutex.Lock()
+	state, exists := r.states[roomID]
+	r.mutex.Unlock()
+	if !exists {
+		return nil
+	}
+	<-state.ready
+	return state
+}
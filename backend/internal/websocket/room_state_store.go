@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/open-same/backend/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// roomStateKeyPrefix prefixes the Redis hash holding the shared version
+// and content of each room
+const roomStateKeyPrefix = "room_state:"
+
+// roomStateTTL is how long the shared state of a room outlives its last
+// change. An expired room is seeded again from a replica's state.
+const roomStateTTL = 24 * time.Hour
+
+// roomStateTimeout bounds each Redis call made for a room's state
+const roomStateTimeout = time.Second
+
+// applyScript accepts content made against the version in ARGV[3] and
+// returns {accepted, version, content}. The shared state is seeded from
+// the caller's version and content in ARGV[1] and ARGV[2] when it is
+// missing or behind, so versions only move forward.
+var applyScript = goredis.NewScript(`
+local version = tonumber(redis.call("HGET", KEYS[1], "version"))
+if not version or version < tonumber(ARGV[1]) then
+	version = tonumber(ARGV[1])
+	redis.call("HSET", KEYS[1], "version", ARGV[1], "content", ARGV[2])
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[6])
+if version ~= tonumber(ARGV[3]) then
+	return {0, version, redis.call("HGET", KEYS[1], "content")}
+end
+redis.call("HSET", KEYS[1], "version", version + 1, "content", ARGV[4], "editor", ARGV[5])
+return {1, version + 1, ARGV[4]}`)
+
+// resetScript replaces the content of a room that some replica holds with
+// content stored outside the room, at a version above every version the
+// room has used and at least ARGV[1]. ARGV[4] is the stored version the
+// content builds on. It returns the new version, or 0 when no replica
+// holds the room.
+var resetScript = goredis.NewScript(`
+local current = tonumber(redis.call("HGET", KEYS[1], "version"))
+if not current then
+	return 0
+end
+local version = math.max(current + 1, tonumber(ARGV[1]))
+local saved = math.max(tonumber(redis.call("HGET", KEYS[1], "saved")) or 0, tonumber(ARGV[4]))
+redis.call("HSET", KEYS[1], "version", version, "content", ARGV[2], "editor", ARGV[3], "saved", saved)
+redis.call("PEXPIRE", KEYS[1], ARGV[5])
+return version`)
+
+// savedScript records the stored version in ARGV[1] in the shared state of
+// a room that some replica holds, unless a later version is recorded
+var savedScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+if (tonumber(redis.call("HGET", KEYS[1], "saved")) or 0) < tonumber(ARGV[1]) then
+	redis.call("HSET", KEYS[1], "saved", ARGV[1])
+end
+return 1`)
+
+// roomStateKey returns the Redis key of a room's shared state
+func roomStateKey(roomID string) string {
+	return roomStateKeyPrefix + roomID
+}
+
+// applyShared accepts a content change when baseVersion is the version held
+// in Redis, so replicas never accept two changes against the same version.
+// Every replica's state follows the Redis state. When Redis fails the change
+// is decided locally. Callers must hold the state's mutex.
+func (r *roomStates) applyShared(roomID string, state *roomState, baseVersion int64, content, userID string) (bool, int64, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+
+	result, err := applyScript.Run(ctx, redis.GetClient(), []string{roomStateKey(roomID)},
+		state.version, state.content, baseVersion, content, userID, roomStateTTL.Milliseconds()).Slice()
+	if err == nil && len(result) != 3 {
+		err = fmt.Errorf("unexpected result %v", result)
+	}
+	if err != nil {
+		log.Printf("Failed to apply change to shared state of room %s, applying locally: %v", roomID, err)
+		if baseVersion != state.version {
+			return false, state.version, state.content
+		}
+		state.version++
+		state.content = content
+		state.lastEditor = userID
+		state.dirty = true
+		return true, state.version, state.content
+	}
+
+	accepted, _ := result[0].(int64)
+	version, _ := result[1].(int64)
+	current, _ := result[2].(string)
+	if version > state.version {
+		state.version = version
+		state.content = current
+	}
+	if accepted == 1 {
+		state.lastEditor = userID
+		state.dirty = true
+		return true, version, content
+	}
+	return false, version, current
+}
+
+// refreshShared catches the state of a room up with Redis, covering changes
+// whose backplane messages were missed, and with the version last saved by
+// any replica. Callers must hold the state's mutex.
+func (r *roomStates) refreshShared(roomID string, state *roomState) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+
+	values, err := redis.GetClient().HMGet(ctx, roomStateKey(roomID), "version", "content", "saved").Result()
+	if err != nil {
+		log.Printf("Failed to read shared state of room %s: %v", roomID, err)
+		return
+	}
+
+	versionValue, _ := values[0].(string)
+	content, _ := values[1].(string)
+	var version int64
+	if _, err := fmt.Sscan(versionValue, &version); err != nil {
+		// No replica has changed the room yet
+		return
+	}
+	if version > state.version {
+		state.version = version
+		state.content = content
+	}
+
+	savedValue, _ := values[2].(string)
+	var saved int64
+	if _, err := fmt.Sscan(savedValue, &saved); err == nil && saved > state.saved {
+		state.saved = saved
+	}
+}
+
+// resetShared replaces the shared content of a room with content stored
+// outside the room and returns the room's new version, which is at least
+// version. It returns false when no replica holds the room or Redis fails.
+func (r *roomStates) resetShared(roomID string, version int64, content, userID string, stored int64) (int64, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+
+	shared, err := resetScript.Run(ctx, redis.GetClient(), []string{roomStateKey(roomID)},
+		version, content, userID, stored, roomStateTTL.Milliseconds()).Int64()
+	if err != nil {
+		log.Printf("Failed to reset shared state of room %s: %v", roomID, err)
+		return 0, false
+	}
+	return shared, shared > 0
+}
+
+// recordSavedShared tells the other replicas holding a room which stored
+// version its content was last saved at, so they save on top of it
+func (r *roomStates) recordSavedShared(roomID string, stored int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+
+	if err := savedScript.Run(ctx, redis.GetClient(), []string{roomStateKey(roomID)}, stored).Err(); err != nil {
+		log.Printf("Failed to record saved version of room %s: %v", roomID, err)
+	}
+}
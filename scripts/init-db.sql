@@ -7,8 +7,8 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 -- Create users table
 CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    email VARCHAR(255) UNIQUE NOT NULL,
-    username VARCHAR(100) UNIQUE NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    username VARCHAR(100) NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     first_name VARCHAR(100),
     last_name VARCHAR(100),
@@ -34,7 +34,7 @@ CREATE TABLE IF NOT EXISTS content (
     is_public BOOLEAN DEFAULT FALSE,
     is_template BOOLEAN DEFAULT FALSE,
     version INTEGER DEFAULT 1,
-    creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -80,9 +80,12 @@ CREATE TABLE IF NOT EXISTS shared_content (
 -- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+-- Emails and usernames are unique among accounts that aren't deleted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users(email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_active ON users(username) WHERE deleted_at IS NULL;
 CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
 
-CREATE INDEX IF NOT EXISTS idx_content_creator_id ON content(creator_id);
+CREATE INDEX IF NOT EXISTS idx_content_user_id ON content(user_id);
 CREATE INDEX IF NOT EXISTS idx_content_type ON content(type);
 CREATE INDEX IF NOT EXISTS idx_content_is_public ON content(is_public);
 CREATE INDEX IF NOT EXISTS idx_content_created_at ON content(created_at);
@@ -134,7 +137,7 @@ VALUES (
 ) ON CONFLICT (email) DO NOTHING;
 
 -- Insert sample content types
-INSERT INTO content (title, description, type, content, creator_id, is_public, is_template)
+INSERT INTO content (title, description, type, content, user_id, is_public, is_template)
 SELECT 
     'Welcome to Open-Same',
     'A collaborative digital content creation platform',
@@ -199,9 +202,9 @@ BEGIN
         COALESCE(col.role, 'none') as collaboration_role,
         COALESCE(col.status, 'none') as collaboration_status
     FROM content c
-    LEFT JOIN users u ON c.creator_id = u.id
+    LEFT JOIN users u ON c.user_id = u.id
     LEFT JOIN collaborations col ON c.id = col.content_id AND col.user_id = user_uuid
-    WHERE c.creator_id = user_uuid OR col.user_id = user_uuid
+    WHERE c.user_id = user_uuid OR col.user_id = user_uuid
     ORDER BY c.updated_at DESC;
 END;
 $$ LANGUAGE plpgsql;
@@ -222,7 +225,7 @@ SELECT
     u.username as creator_username,
     c.view_count
 FROM content c
-JOIN users u ON c.creator_id = u.id
+JOIN users u ON c.user_id = u.id
 WHERE c.is_public = TRUE AND c.deleted_at IS NULL
 ORDER BY c.created_at DESC;
 